@@ -0,0 +1,556 @@
+// Package alerts evaluates threshold rules against worker metrics on the admin.
+// It has no UI or network dependencies: callers feed it samples and a clock,
+// and receive fired/resolved alerts through callbacks.
+package alerts
+
+import (
+	"adminadmin/internal/config"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metric identifies what a rule watches
+type Metric string
+
+const (
+	MetricCPU         Metric = "cpu"
+	MetricRAM         Metric = "ram"
+	MetricGPU         Metric = "gpu"
+	MetricDisk        Metric = "disk"
+	MetricUnreachable Metric = "unreachable" // No metrics received for ForSeconds
+)
+
+// Metrics lists all supported metrics (for UI selectors)
+var Metrics = []Metric{MetricCPU, MetricRAM, MetricGPU, MetricDisk, MetricUnreachable}
+
+const (
+	rulesFile  = "alert_rules.json"
+	maxHistory = 500
+)
+
+// Rule fires when Metric stays above Threshold for ForSeconds.
+// For MetricUnreachable, Threshold is ignored and ForSeconds is the allowed silence.
+// WorkerID and Tag narrow the rule; both empty means it applies to every worker.
+type Rule struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Metric     Metric  `json:"metric"`
+	Threshold  float64 `json:"threshold"`
+	ForSeconds int     `json:"for_seconds"`
+	WorkerID   string  `json:"worker_id,omitempty"`
+	Tag        string  `json:"tag,omitempty"`
+	Enabled    bool    `json:"enabled"`
+}
+
+// Describe returns a short human-readable description of the rule condition
+func (r Rule) Describe() string {
+	var cond string
+	if r.Metric == MetricUnreachable {
+		cond = fmt.Sprintf("unreachable for %ds", r.ForSeconds)
+	} else {
+		cond = fmt.Sprintf("%s > %.0f%% for %ds", strings.ToUpper(string(r.Metric)), r.Threshold, r.ForSeconds)
+	}
+	switch {
+	case r.WorkerID != "":
+		cond += " on " + r.WorkerID
+	case r.Tag != "":
+		cond += " on tag " + r.Tag
+	}
+	return cond
+}
+
+// Target identifies the worker a sample belongs to
+type Target struct {
+	ID       string
+	Hostname string
+	Tags     []string
+}
+
+// Sample is one metrics reading from a worker
+type Sample struct {
+	CPU  float64
+	RAM  float64
+	GPU  float64
+	Disk float64
+}
+
+func (s Sample) value(m Metric) float64 {
+	switch m {
+	case MetricCPU:
+		return s.CPU
+	case MetricRAM:
+		return s.RAM
+	case MetricGPU:
+		return s.GPU
+	case MetricDisk:
+		return s.Disk
+	}
+	return 0
+}
+
+// Alert is a fired rule instance for one worker
+type Alert struct {
+	ID           string
+	RuleID       string
+	RuleName     string
+	Description  string
+	WorkerID     string
+	Hostname     string
+	Value        float64
+	FiredAt      time.Time
+	ResolvedAt   time.Time // Zero while the alert is active
+	Acknowledged bool
+	Silenced     bool
+}
+
+// Active reports whether the alert has not been resolved yet
+func (a Alert) Active() bool {
+	return a.ResolvedAt.IsZero()
+}
+
+// key identifies a (rule, worker) pair
+type key struct {
+	ruleID   string
+	workerID string
+}
+
+type seenTarget struct {
+	target   Target
+	lastSeen time.Time
+}
+
+// Engine evaluates rules and keeps active alerts and history
+type Engine struct {
+	mu       sync.Mutex
+	rules    []Rule
+	pending  map[key]time.Time // Condition first observed true
+	active   map[key]*Alert
+	history  []Alert
+	seen     map[string]*seenTarget
+	silences map[key]time.Time // Silenced until; empty workerID silences the rule everywhere
+	nextID   int
+
+	onFire    func(Alert)
+	onResolve func(Alert)
+}
+
+// DefaultRules returns the rules used when no rules file exists
+func DefaultRules() []Rule {
+	return []Rule{
+		{ID: "cpu-high", Name: "High CPU", Metric: MetricCPU, Threshold: 90, ForSeconds: 120, Enabled: true},
+		{ID: "disk-full", Name: "Disk almost full", Metric: MetricDisk, Threshold: 95, ForSeconds: 60, Enabled: true},
+		{ID: "unreachable", Name: "Worker unreachable", Metric: MetricUnreachable, ForSeconds: 30, Enabled: true},
+	}
+}
+
+// NewEngine creates an engine with the given rules
+func NewEngine(rules []Rule) *Engine {
+	return &Engine{
+		rules:    append([]Rule(nil), rules...),
+		pending:  make(map[key]time.Time),
+		active:   make(map[key]*Alert),
+		seen:     make(map[string]*seenTarget),
+		silences: make(map[key]time.Time),
+	}
+}
+
+// LoadRules reads rules from the config directory, falling back to DefaultRules
+func LoadRules() ([]Rule, error) {
+	rules := DefaultRules()
+	if err := config.LoadJSON(rulesFile, &rules); err != nil {
+		return DefaultRules(), err
+	}
+	return rules, nil
+}
+
+// SaveRules persists the engine's current rules to the config directory
+func (e *Engine) SaveRules() error {
+	return config.SaveJSON(rulesFile, e.Rules())
+}
+
+// SetCallbacks sets the callbacks invoked when alerts fire or resolve.
+// Callbacks run synchronously on the caller's goroutine, outside the engine lock.
+func (e *Engine) SetCallbacks(onFire, onResolve func(Alert)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onFire = onFire
+	e.onResolve = onResolve
+}
+
+// Rules returns a copy of the configured rules
+func (e *Engine) Rules() []Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Rule(nil), e.rules...)
+}
+
+// AddRule adds a rule, or replaces the existing rule with the same ID; alerts of a
+// replaced rule that was disabled or no longer applies to their worker are resolved.
+// An empty ID is replaced with a generated one.
+func (e *Engine) AddRule(rule Rule, now time.Time) Rule {
+	e.mu.Lock()
+
+	if rule.ID == "" {
+		rule.ID = fmt.Sprintf("rule-%d", now.UnixNano())
+	}
+	replaced := false
+	for i := range e.rules {
+		if e.rules[i].ID == rule.ID {
+			e.rules[i] = rule
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		e.rules = append(e.rules, rule)
+	}
+
+	var resolved []Alert
+	for k := range e.active {
+		if k.ruleID == rule.ID && !e.appliesLocked(rule, k.workerID) {
+			resolved = append(resolved, e.resolveLocked(k, now))
+		}
+	}
+	for k := range e.pending {
+		if k.ruleID == rule.ID && !e.appliesLocked(rule, k.workerID) {
+			delete(e.pending, k)
+		}
+	}
+	onResolve := e.onResolve
+	e.mu.Unlock()
+
+	notify(onResolve, resolved)
+	return rule
+}
+
+// RemoveRule deletes a rule and resolves any alerts it had fired
+func (e *Engine) RemoveRule(ruleID string, now time.Time) {
+	e.mu.Lock()
+	for i := range e.rules {
+		if e.rules[i].ID == ruleID {
+			e.rules = append(e.rules[:i], e.rules[i+1:]...)
+			break
+		}
+	}
+	var resolved []Alert
+	for k := range e.active {
+		if k.ruleID == ruleID {
+			resolved = append(resolved, e.resolveLocked(k, now))
+		}
+	}
+	for k := range e.pending {
+		if k.ruleID == ruleID {
+			delete(e.pending, k)
+		}
+	}
+	onResolve := e.onResolve
+	e.mu.Unlock()
+
+	notify(onResolve, resolved)
+}
+
+// Observe records a metrics sample from a worker and evaluates threshold rules
+func (e *Engine) Observe(target Target, sample Sample, now time.Time) {
+	e.mu.Lock()
+
+	e.seen[target.ID] = &seenTarget{target: target, lastSeen: now}
+
+	var fired, resolved []Alert
+	for _, rule := range e.rules {
+		k := key{rule.ID, target.ID}
+		if rule.Metric == MetricUnreachable || !rule.Enabled || !rule.matches(target) {
+			// Any sample means the worker is reachable again, and a rule that was
+			// disabled or retargeted no longer holds for this worker
+			delete(e.pending, k)
+			if a, ok := e.resolveIfActive(k, now); ok {
+				resolved = append(resolved, a)
+			}
+			continue
+		}
+		f, r := e.evaluateLocked(rule, target, sample.value(rule.Metric) > rule.Threshold, sample.value(rule.Metric), now)
+		fired = append(fired, f...)
+		resolved = append(resolved, r...)
+	}
+	e.refreshSilencesLocked(now)
+
+	onFire, onResolve := e.onFire, e.onResolve
+	e.mu.Unlock()
+
+	notify(onFire, fired)
+	notify(onResolve, resolved)
+}
+
+// Tick evaluates time-based rules (unreachable workers). Call it periodically.
+func (e *Engine) Tick(now time.Time) {
+	e.mu.Lock()
+
+	var fired, resolved []Alert
+	for _, rule := range e.rules {
+		if rule.Metric != MetricUnreachable {
+			continue
+		}
+		for _, st := range e.seen {
+			if !rule.Enabled || !rule.matches(st.target) {
+				if a, ok := e.resolveIfActive(key{rule.ID, st.target.ID}, now); ok {
+					resolved = append(resolved, a)
+				}
+				continue
+			}
+			silent := now.Sub(st.lastSeen)
+			if silent < time.Duration(rule.ForSeconds)*time.Second {
+				continue
+			}
+			k := key{rule.ID, st.target.ID}
+			if _, ok := e.active[k]; ok {
+				continue
+			}
+			fired = append(fired, e.fireLocked(k, rule, st.target, silent.Seconds(), now))
+		}
+	}
+	e.refreshSilencesLocked(now)
+
+	onFire, onResolve := e.onFire, e.onResolve
+	e.mu.Unlock()
+
+	notify(onFire, fired)
+	notify(onResolve, resolved)
+}
+
+// Forget stops tracking a worker (e.g. removed on purpose) and resolves its alerts
+func (e *Engine) Forget(workerID string, now time.Time) {
+	e.mu.Lock()
+	delete(e.seen, workerID)
+	var resolved []Alert
+	for k := range e.active {
+		if k.workerID == workerID {
+			resolved = append(resolved, e.resolveLocked(k, now))
+		}
+	}
+	for k := range e.pending {
+		if k.workerID == workerID {
+			delete(e.pending, k)
+		}
+	}
+	onResolve := e.onResolve
+	e.mu.Unlock()
+
+	notify(onResolve, resolved)
+}
+
+// UpdateTarget refreshes the hostname/tags of a tracked worker without counting as a sample
+func (e *Engine) UpdateTarget(target Target) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if st, ok := e.seen[target.ID]; ok {
+		st.target = target
+	}
+}
+
+// Acknowledge marks an active alert as acknowledged
+func (e *Engine) Acknowledge(alertID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, a := range e.active {
+		if a.ID == alertID {
+			a.Acknowledged = true
+		}
+	}
+}
+
+// Silence suppresses notifications for a rule until now+d; alerts count as unsilenced
+// again from the first Observe or Tick after that.
+// An empty workerID silences the rule for every worker.
+func (e *Engine) Silence(ruleID, workerID string, d time.Duration, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.silences[key{ruleID, workerID}] = now.Add(d)
+	for k, a := range e.active {
+		if k.ruleID == ruleID && (workerID == "" || k.workerID == workerID) {
+			a.Silenced = true
+		}
+	}
+}
+
+// Unsilence removes a silence created by Silence
+func (e *Engine) Unsilence(ruleID, workerID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.silences, key{ruleID, workerID})
+	for k, a := range e.active {
+		if k.ruleID == ruleID && (workerID == "" || k.workerID == workerID) {
+			a.Silenced = false
+		}
+	}
+}
+
+// Active returns currently firing alerts, newest first
+func (e *Engine) Active() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	list := make([]Alert, 0, len(e.active))
+	for _, a := range e.active {
+		list = append(list, *a)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].FiredAt.After(list[j].FiredAt) })
+	return list
+}
+
+// ActiveCount returns the number of firing alerts that are neither acknowledged nor silenced
+func (e *Engine) ActiveCount() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n := 0
+	for _, a := range e.active {
+		if !a.Acknowledged && !a.Silenced {
+			n++
+		}
+	}
+	return n
+}
+
+// History returns fired and resolved alerts, newest first
+func (e *Engine) History() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	list := make([]Alert, len(e.history))
+	for i, a := range e.history {
+		list[len(e.history)-1-i] = a
+	}
+	return list
+}
+
+// ClearHistory drops all history entries
+func (e *Engine) ClearHistory() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.history = nil
+}
+
+// matches reports whether the rule applies to the target
+func (r Rule) matches(t Target) bool {
+	if r.WorkerID != "" && r.WorkerID != t.ID {
+		return false
+	}
+	if r.Tag != "" {
+		for _, tag := range t.Tags {
+			if strings.EqualFold(tag, r.Tag) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// evaluateLocked applies one threshold rule to one reading
+func (e *Engine) evaluateLocked(rule Rule, target Target, breached bool, value float64, now time.Time) (fired, resolved []Alert) {
+	k := key{rule.ID, target.ID}
+
+	if !breached {
+		delete(e.pending, k)
+		if a, ok := e.resolveIfActive(k, now); ok {
+			resolved = append(resolved, a)
+		}
+		return
+	}
+
+	if a, ok := e.active[k]; ok {
+		a.Value = value
+		return
+	}
+
+	since, ok := e.pending[k]
+	if !ok {
+		since = now
+		e.pending[k] = now
+	}
+	if now.Sub(since) >= time.Duration(rule.ForSeconds)*time.Second {
+		delete(e.pending, k)
+		fired = append(fired, e.fireLocked(k, rule, target, value, now))
+	}
+	return
+}
+
+// fireLocked creates an active alert and records it in history
+func (e *Engine) fireLocked(k key, rule Rule, target Target, value float64, now time.Time) Alert {
+	e.nextID++
+	a := &Alert{
+		ID:          fmt.Sprintf("alert-%d", e.nextID),
+		RuleID:      rule.ID,
+		RuleName:    rule.Name,
+		Description: rule.Describe(),
+		WorkerID:    target.ID,
+		Hostname:    target.Hostname,
+		Value:       value,
+		FiredAt:     now,
+		Silenced:    e.isSilencedLocked(k, now),
+	}
+	e.active[k] = a
+	e.appendHistoryLocked(*a)
+	return *a
+}
+
+func (e *Engine) resolveIfActive(k key, now time.Time) (Alert, bool) {
+	if _, ok := e.active[k]; !ok {
+		return Alert{}, false
+	}
+	return e.resolveLocked(k, now), true
+}
+
+// resolveLocked closes an active alert and records the resolution in history
+func (e *Engine) resolveLocked(k key, now time.Time) Alert {
+	a := e.active[k]
+	delete(e.active, k)
+	a.ResolvedAt = now
+	e.appendHistoryLocked(*a)
+	return *a
+}
+
+func (e *Engine) appendHistoryLocked(a Alert) {
+	e.history = append(e.history, a)
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+	}
+}
+
+// appliesLocked reports whether rule can hold for the worker: it is enabled and
+// matches the worker as last seen
+func (e *Engine) appliesLocked(rule Rule, workerID string) bool {
+	if !rule.Enabled {
+		return false
+	}
+	st, ok := e.seen[workerID]
+	return !ok || rule.matches(st.target)
+}
+
+// refreshSilencesLocked updates the Silenced flag of active alerts, so alerts count
+// again once their silence has ended
+func (e *Engine) refreshSilencesLocked(now time.Time) {
+	for k, a := range e.active {
+		a.Silenced = e.isSilencedLocked(k, now)
+	}
+}
+
+func (e *Engine) isSilencedLocked(k key, now time.Time) bool {
+	for _, sk := range []key{k, {k.ruleID, ""}} {
+		if until, ok := e.silences[sk]; ok {
+			if now.Before(until) {
+				return true
+			}
+			delete(e.silences, sk)
+		}
+	}
+	return false
+}
+
+func notify(fn func(Alert), alerts []Alert) {
+	if fn == nil {
+		return
+	}
+	for _, a := range alerts {
+		fn(a)
+	}
+}
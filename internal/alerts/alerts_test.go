@@ -0,0 +1,274 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+)
+
+var (
+	t0     = time.Unix(1700000000, 0)
+	web1   = Target{ID: "10.0.0.1", Hostname: "web1", Tags: []string{"web"}}
+	db1    = Target{ID: "10.0.0.2", Hostname: "db1", Tags: []string{"db"}}
+	hotCPU = Sample{CPU: 95}
+	idle   = Sample{CPU: 5}
+)
+
+func at(seconds int) time.Time {
+	return t0.Add(time.Duration(seconds) * time.Second)
+}
+
+func cpuRule() Rule {
+	return Rule{ID: "cpu", Name: "CPU", Metric: MetricCPU, Threshold: 90, ForSeconds: 60, Enabled: true}
+}
+
+func unreachableRule() Rule {
+	return Rule{ID: "down", Name: "Down", Metric: MetricUnreachable, ForSeconds: 120, Enabled: true}
+}
+
+// recorder collects the engine's fire and resolve callbacks
+type recorder struct {
+	fired, resolved []Alert
+}
+
+func newTestEngine(rules ...Rule) (*Engine, *recorder) {
+	e := NewEngine(rules)
+	rec := &recorder{}
+	e.SetCallbacks(
+		func(a Alert) { rec.fired = append(rec.fired, a) },
+		func(a Alert) { rec.resolved = append(rec.resolved, a) })
+	return e, rec
+}
+
+func TestEngine(t *testing.T) {
+	tests := []struct {
+		name         string
+		rules        []Rule
+		steps        func(e *Engine)
+		fired        int
+		resolved     int
+		active       int
+		activeCount  int
+		activeWorker string
+	}{
+		{
+			name:  "fires after the duration",
+			rules: []Rule{cpuRule()},
+			steps: func(e *Engine) {
+				e.Observe(web1, hotCPU, at(0))
+				e.Observe(web1, hotCPU, at(30))
+				e.Observe(web1, hotCPU, at(60))
+			},
+			fired: 1, active: 1, activeCount: 1, activeWorker: web1.ID,
+		},
+		{
+			name:  "short spike does not fire",
+			rules: []Rule{cpuRule()},
+			steps: func(e *Engine) {
+				e.Observe(web1, hotCPU, at(0))
+				e.Observe(web1, idle, at(30))
+				e.Observe(web1, hotCPU, at(60))
+			},
+		},
+		{
+			name:  "resolves below the threshold",
+			rules: []Rule{cpuRule()},
+			steps: func(e *Engine) {
+				e.Observe(web1, hotCPU, at(0))
+				e.Observe(web1, hotCPU, at(60))
+				e.Observe(web1, idle, at(90))
+			},
+			fired: 1, resolved: 1,
+		},
+		{
+			name:  "disabling the rule resolves its alerts",
+			rules: []Rule{cpuRule()},
+			steps: func(e *Engine) {
+				e.Observe(web1, hotCPU, at(0))
+				e.Observe(web1, hotCPU, at(60))
+				rule := cpuRule()
+				rule.Enabled = false
+				e.AddRule(rule, at(70))
+				e.Observe(web1, hotCPU, at(200))
+			},
+			fired: 1, resolved: 1,
+		},
+		{
+			name:  "disabling the rule drops pending conditions",
+			rules: []Rule{cpuRule()},
+			steps: func(e *Engine) {
+				e.Observe(web1, hotCPU, at(0))
+				rule := cpuRule()
+				rule.Enabled = false
+				e.AddRule(rule, at(10))
+				e.AddRule(cpuRule(), at(20))
+				e.Observe(web1, hotCPU, at(60))
+			},
+		},
+		{
+			name:  "retargeting to another worker resolves",
+			rules: []Rule{cpuRule()},
+			steps: func(e *Engine) {
+				e.Observe(web1, hotCPU, at(0))
+				e.Observe(web1, hotCPU, at(60))
+				rule := cpuRule()
+				rule.WorkerID = db1.ID
+				e.AddRule(rule, at(70))
+			},
+			fired: 1, resolved: 1,
+		},
+		{
+			name:  "retargeting to a tag keeps matching workers",
+			rules: []Rule{cpuRule()},
+			steps: func(e *Engine) {
+				e.Observe(web1, hotCPU, at(0))
+				e.Observe(db1, hotCPU, at(0))
+				e.Observe(web1, hotCPU, at(60))
+				e.Observe(db1, hotCPU, at(60))
+				rule := cpuRule()
+				rule.Tag = "WEB"
+				e.AddRule(rule, at(70))
+			},
+			fired: 2, resolved: 1, active: 1, activeCount: 1, activeWorker: web1.ID,
+		},
+		{
+			name:  "worker losing the tag resolves",
+			rules: []Rule{{ID: "cpu", Metric: MetricCPU, Threshold: 90, ForSeconds: 60, Tag: "web", Enabled: true}},
+			steps: func(e *Engine) {
+				e.Observe(web1, hotCPU, at(0))
+				e.Observe(web1, hotCPU, at(60))
+				e.Observe(Target{ID: web1.ID, Hostname: web1.Hostname}, hotCPU, at(70))
+			},
+			fired: 1, resolved: 1,
+		},
+		{
+			name:  "removing the rule resolves",
+			rules: []Rule{cpuRule()},
+			steps: func(e *Engine) {
+				e.Observe(web1, hotCPU, at(0))
+				e.Observe(web1, hotCPU, at(60))
+				e.RemoveRule("cpu", at(70))
+			},
+			fired: 1, resolved: 1,
+		},
+		{
+			name:  "silenced alerts are not counted",
+			rules: []Rule{cpuRule()},
+			steps: func(e *Engine) {
+				e.Observe(web1, hotCPU, at(0))
+				e.Observe(web1, hotCPU, at(60))
+				e.Silence("cpu", "", time.Hour, at(70))
+				e.Observe(web1, hotCPU, at(80))
+			},
+			fired: 1, active: 1, activeWorker: web1.ID,
+		},
+		{
+			name:  "silence ends on the next observation",
+			rules: []Rule{cpuRule()},
+			steps: func(e *Engine) {
+				e.Observe(web1, hotCPU, at(0))
+				e.Observe(web1, hotCPU, at(60))
+				e.Silence("cpu", web1.ID, time.Minute, at(70))
+				e.Observe(web1, hotCPU, at(131))
+			},
+			fired: 1, active: 1, activeCount: 1, activeWorker: web1.ID,
+		},
+		{
+			name:  "silence ends on the next tick",
+			rules: []Rule{cpuRule()},
+			steps: func(e *Engine) {
+				e.Observe(web1, hotCPU, at(0))
+				e.Observe(web1, hotCPU, at(60))
+				e.Silence("cpu", "", time.Minute, at(70))
+				e.Tick(at(131))
+			},
+			fired: 1, active: 1, activeCount: 1, activeWorker: web1.ID,
+		},
+		{
+			name:  "unreachable fires on tick",
+			rules: []Rule{unreachableRule()},
+			steps: func(e *Engine) {
+				e.Observe(web1, idle, at(0))
+				e.Tick(at(60))
+				e.Tick(at(120))
+				e.Tick(at(180))
+			},
+			fired: 1, active: 1, activeCount: 1, activeWorker: web1.ID,
+		},
+		{
+			name:  "unreachable resolves on the next sample",
+			rules: []Rule{unreachableRule()},
+			steps: func(e *Engine) {
+				e.Observe(web1, idle, at(0))
+				e.Tick(at(120))
+				e.Observe(web1, idle, at(130))
+				e.Tick(at(140))
+			},
+			fired: 1, resolved: 1,
+		},
+		{
+			name:  "disabling unreachable resolves",
+			rules: []Rule{unreachableRule()},
+			steps: func(e *Engine) {
+				e.Observe(web1, idle, at(0))
+				e.Tick(at(120))
+				rule := unreachableRule()
+				rule.Enabled = false
+				e.AddRule(rule, at(130))
+				e.Tick(at(300))
+			},
+			fired: 1, resolved: 1,
+		},
+		{
+			name:  "forgotten workers are not unreachable",
+			rules: []Rule{unreachableRule()},
+			steps: func(e *Engine) {
+				e.Observe(web1, idle, at(0))
+				e.Tick(at(120))
+				e.Forget(web1.ID, at(130))
+				e.Tick(at(300))
+			},
+			fired: 1, resolved: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, rec := newTestEngine(tt.rules...)
+			tt.steps(e)
+			if len(rec.fired) != tt.fired || len(rec.resolved) != tt.resolved {
+				t.Errorf("fired %d and resolved %d alerts, want %d and %d",
+					len(rec.fired), len(rec.resolved), tt.fired, tt.resolved)
+			}
+			active := e.Active()
+			if len(active) != tt.active {
+				t.Fatalf("%d active alerts, want %d", len(active), tt.active)
+			}
+			if tt.active > 0 && active[0].WorkerID != tt.activeWorker {
+				t.Errorf("active alert on %s, want %s", active[0].WorkerID, tt.activeWorker)
+			}
+			if got := e.ActiveCount(); got != tt.activeCount {
+				t.Errorf("active count %d, want %d", got, tt.activeCount)
+			}
+			if len(e.History()) != tt.fired+tt.resolved {
+				t.Errorf("%d history entries, want %d", len(e.History()), tt.fired+tt.resolved)
+			}
+		})
+	}
+}
+
+func TestEngineSilencedAtFire(t *testing.T) {
+	e, rec := newTestEngine(cpuRule())
+	e.Silence("cpu", web1.ID, time.Hour, at(0))
+	e.Observe(web1, hotCPU, at(0))
+	e.Observe(web1, hotCPU, at(60))
+	if len(rec.fired) != 1 || !rec.fired[0].Silenced {
+		t.Fatalf("fired %+v, want one silenced alert", rec.fired)
+	}
+	e.Unsilence("cpu", web1.ID)
+	if e.ActiveCount() != 1 {
+		t.Error("unsilenced alert is not counted")
+	}
+	e.Acknowledge(rec.fired[0].ID)
+	if e.ActiveCount() != 0 {
+		t.Error("acknowledged alert is still counted")
+	}
+}
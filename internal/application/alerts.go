@@ -0,0 +1,152 @@
+package application
+
+import (
+	"adminadmin/internal/alerts"
+	"adminadmin/internal/notify"
+	"adminadmin/internal/ui"
+	"fmt"
+	"log"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// alertTickInterval is how often time-based alert rules (unreachable) are evaluated
+const alertTickInterval = 5 * time.Second
+
+// startAlerting creates the alert engine for the admin role and starts the evaluation ticker
+func (a *App) startAlerting() {
+	if a.getAlertEngine() != nil {
+		return
+	}
+
+	rules, err := alerts.LoadRules()
+	if err != nil {
+		log.Printf("APP WARNING: Failed to load alert rules, using defaults: %v\n", err)
+	}
+	engine := alerts.NewEngine(rules)
+	engine.SetCallbacks(a.onAlertFired, a.onAlertResolved)
+	a.alertMu.Lock()
+	a.alertEngine = engine
+	a.alertMu.Unlock()
+	log.Printf("APP: Alert engine started with %d rules\n", len(rules))
+
+	stop := make(chan struct{})
+	a.alertsStop = stop
+	go func() {
+		ticker := time.NewTicker(alertTickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				engine.Tick(now)
+			}
+		}
+	}()
+}
+
+// stopAlerting stops the evaluation ticker and closes the alerts window
+func (a *App) stopAlerting() {
+	if a.alertsStop != nil {
+		close(a.alertsStop)
+		a.alertsStop = nil
+	}
+	if a.alertsWindow != nil {
+		a.alertsWindow.Close()
+		a.alertsWindow = nil
+	}
+	a.alertMu.Lock()
+	a.alertEngine = nil
+	a.alertMu.Unlock()
+}
+
+// getAlertEngine returns the alert engine, or nil outside the admin role
+func (a *App) getAlertEngine() *alerts.Engine {
+	a.alertMu.RLock()
+	defer a.alertMu.RUnlock()
+	return a.alertEngine
+}
+
+// observeMetrics feeds a metrics update into the alert engine
+func (a *App) observeMetrics(workerID string, cpuUsage, ramUsage, gpuUsage, diskUsage float64) {
+	engine := a.getAlertEngine()
+	if engine == nil {
+		return
+	}
+	engine.Observe(a.alertTarget(workerID), alerts.Sample{
+		CPU:  cpuUsage,
+		RAM:  ramUsage,
+		GPU:  gpuUsage,
+		Disk: diskUsage,
+	}, time.Now())
+}
+
+// alertTarget builds the alert target for a worker from the current state
+func (a *App) alertTarget(workerID string) alerts.Target {
+	target := alerts.Target{ID: workerID, Hostname: workerID}
+	if device := a.state.GetConnectedDeviceByID(workerID); device != nil {
+		target.Hostname = device.Hostname
+		target.Tags = device.AllTags()
+	}
+	return target
+}
+
+func (a *App) onAlertFired(alert alerts.Alert) {
+	log.Printf("APP: ALERT FIRED: %s on %s (%s) value=%.1f\n", alert.RuleName, alert.Hostname, alert.WorkerID, alert.Value)
+	if !alert.Silenced {
+		a.fyneApp.SendNotification(fyne.NewNotification(
+			fmt.Sprintf("Alert: %s", alert.RuleName),
+			fmt.Sprintf("%s (%s): %s", alert.Hostname, alert.WorkerID, alert.Description),
+		))
+		a.notifyAlert(notify.EventAlertFired, alert, alert.Description)
+	}
+	a.refreshAlerts()
+}
+
+func (a *App) onAlertResolved(alert alerts.Alert) {
+	log.Printf("APP: Alert resolved: %s on %s (%s)\n", alert.RuleName, alert.Hostname, alert.WorkerID)
+	if !alert.Silenced {
+		a.fyneApp.SendNotification(fyne.NewNotification(
+			fmt.Sprintf("Resolved: %s", alert.RuleName),
+			fmt.Sprintf("%s (%s) is back to normal", alert.Hostname, alert.WorkerID),
+		))
+		a.notifyAlert(notify.EventAlertResolved, alert, fmt.Sprintf("%s is back to normal", alert.RuleName))
+	}
+	a.refreshAlerts()
+}
+
+// refreshAlerts updates the dashboard alert count and the alerts window, if open
+func (a *App) refreshAlerts() {
+	engine := a.getAlertEngine()
+	if engine == nil {
+		return
+	}
+	if a.dashboardCtrl != nil {
+		a.dashboardCtrl.SetAlertCount(engine.ActiveCount())
+	}
+	if a.alertsWindow != nil {
+		a.alertsWindow.Refresh()
+	}
+}
+
+// showAlertsWindow opens (or focuses) the alerts window
+func (a *App) showAlertsWindow() {
+	engine := a.getAlertEngine()
+	if engine == nil {
+		return
+	}
+	if a.alertsWindow == nil {
+		a.alertsWindow = ui.NewAlertsWindow(a.fyneApp, engine,
+			func() {
+				if err := engine.SaveRules(); err != nil {
+					log.Printf("APP ERROR: Failed to save alert rules: %v\n", err)
+				}
+				a.refreshAlerts()
+			},
+			func() { a.alertsWindow = nil },
+		)
+	}
+	a.alertsWindow.Show()
+}
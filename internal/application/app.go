@@ -1,15 +1,21 @@
 package application
 
 import (
+	"adminadmin/internal/alerts"
 	"adminadmin/internal/network"
+	"adminadmin/internal/notify"
 	"adminadmin/internal/state"
 	"adminadmin/internal/ui"
+	"errors"
 	"fmt"
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
+	"golang.org/x/crypto/ssh"
 	"log"
 	"sync"
+	"time"
 )
 
 type App struct {
@@ -20,21 +26,65 @@ type App struct {
 	clientsMu    sync.RWMutex
 	workerServer *network.WorkerServer
 	sshServer    *network.SSHServer
-	sshPassword  string
+
+	// Admin's SSH key, loaded on first use (see sshkeys.go)
+	sshKey     ssh.Signer
+	sshKeyOnce sync.Once
 
 	// Dashboard controller (persistent for smooth gauge animations)
 	dashboardCtrl *ui.AdminDashboardController
 
 	// SSH terminal window (separate window with tabs)
 	sshTerminalWindow *ui.SSHTerminalWindow
+
+	// "Run on Selection" window (see broadcast.go)
+	broadcastWindow *ui.BroadcastWindow
+
+	// Trusted SSH host keys review window (see knownhosts.go)
+	knownHostsWindow *ui.KnownHostsWindow
+
+	// Open screen viewers by worker ID, and the worker's "screen is being viewed"
+	// banner (see screen.go)
+	screenViewers map[string]*ui.ScreenViewer
+	screenBanner  *ui.ScreenSharingBanner
+
+	// The one "session ending" dialog and the workers it asks about (main thread
+	// only, see sessionlimits.go)
+	sessionWarning      dialog.Dialog
+	sessionWarningLabel *widget.Label
+	sessionWarnings     map[string]pendingSessionWarning // By worker ID
+
+	// Alerting (admin role only, see alerts.go); alertEngine is read from network
+	// goroutines, so it is only accessed under alertMu (see getAlertEngine)
+	alertMu      sync.RWMutex
+	alertEngine  *alerts.Engine
+	alertsWindow *ui.AlertsWindow
+	alertsStop   chan struct{}
+
+	// Webhook/script notifications for both roles (see notify.go)
+	notifier            *notify.Notifier
+	notificationsWindow *ui.NotificationsWindow
+
+	// Admin-assigned worker tags by worker ID, persisted (see tags.go)
+	adminTags map[string][]string
+	tagsMu    sync.RWMutex
+
+	// Command mode terminal history by worker hostname, persisted (see history.go)
+	commandHistory map[string][]string
+	historyMu      sync.Mutex
 }
 
 func NewApp(fyneApp fyne.App) *App {
 	return &App{
-		fyneApp:      fyneApp,
-		state:        state.NewAppState(),
-		adminClients: make(map[string]*network.AdminClient),
-		sshPassword:  network.DefaultSSHPassword, // Default SSH password: admin
+		fyneApp:       fyneApp,
+		state:         state.NewAppState(),
+		adminClients:  make(map[string]*network.AdminClient),
+		notifier:      newNotifier(),
+		adminTags:     loadAdminTags(),
+		screenViewers: make(map[string]*ui.ScreenViewer),
+		screenBanner:  ui.NewScreenSharingBanner(),
+
+		commandHistory: loadCommandHistory(),
 	}
 }
 
@@ -62,6 +112,7 @@ func (a *App) Run() {
 	log.Println("APP: Showing window and entering main loop...")
 	a.window.ShowAndRun()
 	log.Println("=== APPLICATION SHUTTING DOWN ===")
+	a.notifier.Close()
 }
 
 func (a *App) showRoleSelection() {
@@ -79,6 +130,7 @@ func (a *App) showRoleSelection() {
 func (a *App) selectAdminRole() {
 	log.Println("=== USER SELECTED: ADMIN ROLE ===")
 	a.state.SetRole(state.RoleAdmin)
+	a.startAlerting()
 	a.showAdminConnectScreen()
 }
 
@@ -89,16 +141,31 @@ func (a *App) selectWorkerRole() {
 	// Start worker server
 	log.Printf("APP: Creating worker server on port %d...\n", network.DefaultWorkerPort)
 	a.workerServer = network.NewWorkerServer(network.DefaultWorkerPort)
+	a.loadSelfTags()
+	a.workerServer.SetOnKeyRequest(a.confirmKeyRequest)
+	a.loadScreenSettings()
+	a.workerServer.SetOnScreenConfirm(a.confirmScreenView)
+	a.workerServer.SetOnScreenView(a.onScreenViewed)
+	a.workerServer.SetOnScreenViewers(a.setScreenViewers)
 
 	// Set callbacks for admin connection events
 	a.workerServer.SetCallbacks(
 		func(hostname string) {
 			log.Printf("APP: Admin connected: %s\n", hostname)
 			a.state.SetConnectedAdmin(&state.AdminInfo{Hostname: hostname})
+			a.notifier.Notify(notify.Event{
+				Type:     notify.EventAdminConnected,
+				Hostname: hostname,
+				Message:  fmt.Sprintf("Admin %s connected", hostname),
+			})
 			a.showWorkerConnectedScreen()
 		},
 		func() {
 			log.Println("APP: Admin disconnected")
+			a.notifier.Notify(notify.Event{
+				Type:    notify.EventAdminDisconnected,
+				Message: "Admin disconnected",
+			})
 			a.state.ClearConnection()
 			a.showWorkerWaitingScreen()
 		},
@@ -113,13 +180,24 @@ func (a *App) selectWorkerRole() {
 
 	// Start SSH server
 	a.sshServer = network.NewSSHServer(network.DefaultSSHPort)
-	if err := a.sshServer.Start(a.sshPassword); err != nil {
+	a.sshServer.SetAuthCallback(a.onSSHAuth)
+	generatedPassword := a.loadSSHUsers()
+	a.loadSSHAuthSettings()
+	a.loadSFTPSettings()
+	a.loadForwardingSettings()
+	a.loadRewriteSettings()
+	a.loadSessionLimits()
+	a.loadAuditLog()
+	if err := a.sshServer.Start(); err != nil {
 		log.Printf("APP WARNING: Failed to start SSH server: %v\n", err)
 	} else {
 		log.Printf("APP: SSH server started on port %d\n", network.DefaultSSHPort)
 	}
 
 	a.showWorkerWaitingScreen()
+	if generatedPassword != "" {
+		a.showGeneratedPassword(generatedPassword)
+	}
 }
 
 func (a *App) showAdminConnectScreen() {
@@ -147,6 +225,22 @@ func (a *App) showAdminDashboard() {
 			func(id string) { a.selectWorker(id) },
 			func(ip string) { a.showSSHDialog(ip) },
 		)
+		a.dashboardCtrl.SetOnSpeedTest(func(id string) { a.runSpeedTest(id) })
+		a.dashboardCtrl.SetOnAlerts(func() { a.showAlertsWindow() })
+		a.dashboardCtrl.SetOnNotifications(func() { a.showNotificationsWindow() })
+		a.dashboardCtrl.SetOnBroadcast(func() { a.showBroadcastWindow("") })
+		a.dashboardCtrl.SetOnPairKey(a.pairSSHKey)
+		a.dashboardCtrl.SetOnScreen(a.showScreenViewer)
+		a.dashboardCtrl.SetOnKnownHosts(a.showKnownHostsWindow)
+		a.dashboardCtrl.SetOnTranscripts(a.showTranscriptLoggingDialog)
+		a.dashboardCtrl.SetOnEditTags(func(id string) { a.showEditTagsDialog(id) })
+		a.dashboardCtrl.SetOnGroupActions(
+			func(tag string) { a.showBroadcastWindow(tag) },
+			func(tag string) { a.disconnectGroup(tag) },
+		)
+		if engine := a.getAlertEngine(); engine != nil {
+			a.dashboardCtrl.SetAlertCount(engine.ActiveCount())
+		}
 	}
 
 	content := a.dashboardCtrl.GetContent()
@@ -182,10 +276,10 @@ func (a *App) showAddWorkerDialog() {
 	)
 }
 
-// updateDashboardMetrics updates only the gauge values without rebuilding UI
-func (a *App) updateDashboardMetrics() {
+// updateDashboardMetrics updates gauge and overview values for a worker without rebuilding UI
+func (a *App) updateDashboardMetrics(workerID string) {
 	if a.dashboardCtrl != nil {
-		a.dashboardCtrl.UpdateMetricsOnly()
+		a.dashboardCtrl.UpdateWorkerMetrics(workerID)
 	}
 }
 
@@ -195,20 +289,34 @@ func (a *App) showWorkerWaitingScreen() {
 	if a.workerServer != nil {
 		localIP = a.workerServer.GetLocalIP()
 	}
+	var users []network.SSHUser
+	passwordAuth := false
+	if a.sshServer != nil {
+		if a.sshServer.GetUsers() != nil {
+			users, _ = a.sshServer.GetUsers().List()
+		}
+		passwordAuth = a.sshServer.GetAuthSettings().PasswordAuth
+	}
 	content := ui.NewWorkerWaitingScreen(
 		localIP,
 		network.DefaultWorkerPort,
 		func() { a.backToRoleSelection() },
-		func(username, password string) {
-			// Update SSH credentials when user changes them
-			if a.sshServer != nil {
-				a.sshServer.SetCredentials(username, password)
-				log.Printf("APP: SSH credentials updated - username: %s\n", username)
-			}
-		},
+		users,
+		passwordAuth,
+		a.setPasswordAuth,
+		func() { a.showSSHUsersDialog() },
+		func() { a.showBannedAddressesDialog() },
+		func() { a.showNotificationsWindow() },
+		func() { a.showSelfTagsDialog() },
+		func() { a.showSFTPDialog() },
+		func() { a.showForwardingDialog() },
+		func() { a.showRewriteDialog() },
+		func() { a.showSessionLimitsDialog() },
+		func() { a.showAuditDialog() },
+		func() { a.showScreenSharingDialog() },
 	)
 	a.runOnMain(func() {
-		a.window.SetContent(content)
+		a.window.SetContent(container.NewBorder(a.screenBanner.Content(), nil, nil, nil, content))
 	})
 	log.Println("APP: Worker waiting screen displayed")
 }
@@ -220,7 +328,7 @@ func (a *App) showWorkerConnectedScreen() {
 		func() { a.backToRoleSelection() },
 	)
 	a.runOnMain(func() {
-		a.window.SetContent(content)
+		a.window.SetContent(container.NewBorder(a.screenBanner.Content(), nil, nil, nil, content))
 		// Make window compact when connected
 		a.window.Resize(fyne.NewSize(350, 120))
 	})
@@ -240,6 +348,10 @@ func (a *App) disconnectAll() {
 		log.Printf("APP: Disconnecting from %s...\n", ip)
 		client.Disconnect()
 		delete(a.adminClients, ip)
+		// Intentional disconnects must not trigger "unreachable" alerts
+		if engine := a.getAlertEngine(); engine != nil {
+			engine.Forget(ip, time.Now())
+		}
 	}
 	a.clientsMu.Unlock()
 	a.state.ClearConnection()
@@ -253,11 +365,28 @@ func (a *App) backToRoleSelection() {
 	// Cleanup dashboard controller
 	a.dashboardCtrl = nil
 
+	// Stop alert evaluation
+	a.stopAlerting()
+
+	if a.notificationsWindow != nil {
+		a.notificationsWindow.Close()
+		a.notificationsWindow = nil
+	}
+
 	// Cleanup SSH terminal window
 	if a.sshTerminalWindow != nil {
 		a.sshTerminalWindow.Close()
 		a.sshTerminalWindow = nil
 	}
+	if a.broadcastWindow != nil {
+		a.broadcastWindow.Close()
+		a.broadcastWindow = nil
+	}
+	if a.knownHostsWindow != nil {
+		a.knownHostsWindow.Close()
+		a.knownHostsWindow = nil
+	}
+	a.closeScreenViewers()
 
 	// Cleanup admin clients
 	a.clientsMu.Lock()
@@ -280,6 +409,7 @@ func (a *App) backToRoleSelection() {
 	if a.sshServer != nil {
 		log.Println("APP: Stopping SSH server...")
 		a.sshServer.Stop()
+		a.sshServer.GetAuditLog().Close()
 		a.sshServer = nil
 		log.Println("APP: SSH server stopped")
 	}
@@ -310,14 +440,19 @@ func (a *App) connectToWorker(ip string) {
 
 	// Create admin client with update callbacks
 	log.Println("APP: Creating admin client...")
-	client := network.NewAdminClient(
+	var client *network.AdminClient
+	client = network.NewAdminClient(
 		// onUpdate - full device info received
 		func(deviceInfo *state.DeviceInfo) {
 			log.Println("APP: Received device info update callback")
 			log.Printf("APP: Device - Hostname: %s, OS: %s, IP: %s\n",
 				deviceInfo.Hostname, deviceInfo.OS, deviceInfo.IPAddress)
 			deviceInfo.ID = ip // Use IP as ID
+			deviceInfo.LastSeen = time.Now()
+			a.applyAdminTags(deviceInfo)
 			a.state.AddConnectedDevice(deviceInfo)
+			a.notifyWorker(notify.EventWorkerConnected, ip,
+				fmt.Sprintf("Connected to worker %s (%s)", deviceInfo.Hostname, ip))
 			// Force rebuild since we have a new worker
 			if a.dashboardCtrl != nil {
 				a.dashboardCtrl.ForceRebuild()
@@ -326,31 +461,96 @@ func (a *App) connectToWorker(ip string) {
 			a.showAdminDashboard()
 		},
 		// onMetricsUpdate - real-time metrics (just update values, don't rebuild)
-		func(cpuUsage, ramUsage, gpuUsage float64) {
-			a.state.UpdateDeviceMetricsByID(ip, cpuUsage, ramUsage, gpuUsage)
-			// Only update gauges if this is the selected worker
-			if a.state.GetSelectedWorkerID() == ip {
-				a.updateDashboardMetrics()
-			}
+		func(cpuUsage, ramUsage, gpuUsage, diskUsage float64) {
+			a.state.UpdateDeviceMetricsByID(ip, cpuUsage, ramUsage, gpuUsage, diskUsage)
+			a.observeMetrics(ip, cpuUsage, ramUsage, gpuUsage, diskUsage)
+			// Update values in place (details gauges only if this is the selected worker)
+			a.updateDashboardMetrics(ip)
 		},
 	)
 
+	client.SetOnDisconnect(func(err error) {
+		// Drop the client so the worker can be reconnected (disconnectAll may already have)
+		a.clientsMu.Lock()
+		if a.adminClients[ip] == client {
+			delete(a.adminClients, ip)
+		}
+		a.clientsMu.Unlock()
+		a.screenViewerDisconnected(ip)
+		a.clearSessionWarning(ip)
+
+		message := fmt.Sprintf("Disconnected from worker %s", ip)
+		var ended *network.SessionEndedError
+		if errors.As(err, &ended) {
+			message = fmt.Sprintf("Worker %s ended the session: %s", ip, ended.Notice.Message)
+			// Ended on purpose, so the worker is not unreachable
+			if engine := a.getAlertEngine(); engine != nil {
+				engine.Forget(ip, time.Now())
+			}
+			a.showSessionEnded(ip, ended.Notice)
+		} else if err != nil {
+			message = fmt.Sprintf("Lost connection to worker %s: %v", ip, err)
+		}
+		a.notifyWorker(notify.EventWorkerDisconnected, ip, message)
+	})
+	client.SetOnSessionWarning(func(notice network.SessionNoticePayload) {
+		a.showSessionWarning(ip, client, notice)
+	})
+
+	// Store the client before connecting, since a worker refusing the session
+	// disconnects it right away
+	a.clientsMu.Lock()
+	a.adminClients[ip] = client
+	a.clientsMu.Unlock()
+
 	// Connect to worker
 	log.Printf("APP: Initiating connection to %s:%d...\n", ip, network.DefaultWorkerPort)
 	if err := client.Connect(ip, network.DefaultWorkerPort); err != nil {
 		log.Printf("APP ERROR: Connection failed: %v\n", err)
+		a.clientsMu.Lock()
+		delete(a.adminClients, ip)
+		a.clientsMu.Unlock()
 		dialog.ShowError(err, a.window)
 		return
 	}
 
-	// Store the client
-	a.clientsMu.Lock()
-	a.adminClients[ip] = client
-	a.clientsMu.Unlock()
-
 	log.Println("APP: Connection initiated successfully")
 }
 
+// runSpeedTest measures throughput to a worker in the background and stores the result
+func (a *App) runSpeedTest(workerID string) {
+	a.clientsMu.RLock()
+	client, ok := a.adminClients[workerID]
+	a.clientsMu.RUnlock()
+	if !ok {
+		dialog.ShowError(fmt.Errorf("not connected to %s", workerID), a.window)
+		return
+	}
+
+	log.Printf("APP: Running speed test against %s\n", workerID)
+	if a.dashboardCtrl != nil {
+		a.dashboardCtrl.SetSpeedTestStatus(workerID, "Network Speed: testing...")
+	}
+
+	go func() {
+		result, err := client.RunSpeedTest()
+		if err != nil {
+			log.Printf("APP ERROR: Speed test failed: %v\n", err)
+			if a.dashboardCtrl != nil {
+				a.dashboardCtrl.SetSpeedTestStatus(workerID, "Network Speed: test failed")
+			}
+			a.runOnMain(func() {
+				dialog.ShowError(fmt.Errorf("speed test failed: %w", err), a.window)
+			})
+			return
+		}
+
+		a.state.UpdateDeviceSpeedTestByID(workerID, result.LatencyMs, result.DownloadMbps,
+			result.UploadMbps, result.String(), result.TestedAt)
+		a.updateDashboardMetrics(workerID)
+	}()
+}
+
 func (a *App) showSSHDialog(workerIP string) {
 	log.Printf("APP: Showing SSH dialog for %s\n", workerIP)
 
@@ -365,13 +565,19 @@ func (a *App) showSSHDialog(workerIP string) {
 	userEntry.SetPlaceHolder("Username")
 	userEntry.SetText(network.DefaultSSHUsername) // Default: admin
 
+	// Left empty, only the paired SSH key is offered
 	passwordEntry := widget.NewPasswordEntry()
-	passwordEntry.SetPlaceHolder("Password")
-	passwordEntry.SetText(network.DefaultSSHPassword) // Default: admin
+	passwordEntry.SetPlaceHolder("Password (empty = SSH key)")
+
+	// The interactive shell runs in a terminal emulator; command mode runs one command at a time.
+	// A detachable shell keeps running on the worker after the tab closes.
+	modeSelect := widget.NewSelect([]string{sshModeShell, sshModeDetachable, sshModeCommand}, nil)
+	modeSelect.SetSelected(sshModeShell)
 
 	formItems := []*widget.FormItem{
 		widget.NewFormItem("Username", userEntry),
 		widget.NewFormItem("Password", passwordEntry),
+		widget.NewFormItem("Mode", modeSelect),
 	}
 
 	dialog.ShowForm(
@@ -381,40 +587,123 @@ func (a *App) showSSHDialog(workerIP string) {
 		formItems,
 		func(ok bool) {
 			if ok {
-				a.connectSSH(workerIP, hostname, userEntry.Text, passwordEntry.Text)
+				a.connectSSH(workerIP, hostname, userEntry.Text, passwordEntry.Text, modeSelect.Selected)
 			}
 		},
 		a.window,
 	)
 }
 
-func (a *App) connectSSH(ip, hostname, user, password string) {
+// SSH dialog session modes
+const (
+	sshModeShell      = "Interactive shell"
+	sshModeDetachable = "Detachable shell"
+	sshModeCommand    = "Command mode"
+)
+
+func (a *App) connectSSH(ip, hostname, user, password, mode string) {
 	log.Printf("APP: Connecting SSH to %s as %s\n", ip, user)
 
 	sshClient := network.NewSSHClient()
-	err := sshClient.Connect(ip, network.DefaultSSHPort, user, password)
-	if err != nil {
-		dialog.ShowError(fmt.Errorf("SSH connection failed: %v", err), a.window)
-		return
-	}
+	sshClient.SetSigner(a.clientKey())
+	sshClient.SetHostKeyConfirm(a.confirmHostKey)
+
+	// Connecting may wait for the admin to confirm a new host key, so it must not block the UI
+	go func() {
+		err := sshClient.Connect(ip, network.DefaultSSHPort, user, password)
+		var shell *network.ShellSession
+		var detached []network.DetachableSessionInfo
+		if err == nil {
+			switch mode {
+			case sshModeShell:
+				// The terminal reports its real size once laid out
+				shell, err = sshClient.StartShell("xterm-256color", 80, 24)
+			case sshModeDetachable:
+				// With sessions left running, let the admin pick one; otherwise start one
+				detached, err = sshClient.DetachableSessions()
+				if err == nil && len(detached) == 0 {
+					shell, err = startDetachableShell(sshClient)
+				}
+			}
+			if err != nil {
+				sshClient.Close()
+			}
+		}
+		a.runOnMain(func() {
+			if err != nil {
+				var changed *network.HostKeyChangedError
+				if errors.As(err, &changed) {
+					a.showHostKeyChangedDialog(changed)
+					return
+				}
+				dialog.ShowError(fmt.Errorf("SSH connection failed: %v", err), a.window)
+				return
+			}
+			switch {
+			case shell != nil:
+				a.openShellTab(newSharedSSHClient(sshClient), shell, ip, hostname)
+			case mode == sshModeDetachable:
+				log.Printf("APP: %d detachable session(s) running on %s\n", len(detached), ip)
+				a.openSessionsTab(newSharedSSHClient(sshClient), ip, hostname)
+			default:
+				a.openSSHTab(sshClient, ip, hostname)
+			}
+		})
+	}()
+}
 
-	// Create SSH terminal window if not exists
+// ensureSSHTerminalWindow creates the SSH terminal window if it does not exist
+func (a *App) ensureSSHTerminalWindow() {
 	if a.sshTerminalWindow == nil {
 		a.sshTerminalWindow = ui.NewSSHTerminalWindow(a.fyneApp, func() {
 			// Cleanup when window closes
 			a.sshTerminalWindow = nil
 		})
+		a.sshTerminalWindow.SetSplitSupport(a.splitTargets, a.openSplitPane)
+	}
+}
+
+// openShellTab adds a terminal emulator tab running an interactive shell; closing it
+// detaches from a detachable session instead of ending it
+func (a *App) openShellTab(conn *sharedSSHClient, shell *network.ShellSession, ip, hostname string) {
+	a.ensureSSHTerminalWindow()
+	conn.acquire()
+	a.sshTerminalWindow.AddShellTab(ip, hostname, ip, shell, func() {
+		shell.Close()
+		conn.release()
+	}, a.terminalActions(conn, ip, hostname))
+	a.sshTerminalWindow.Show()
+}
+
+// terminalActions returns the header buttons of a worker's terminal tabs
+func (a *App) terminalActions(conn *sharedSSHClient, ip, hostname string) ui.TerminalActions {
+	return ui.TerminalActions{
+		OnFiles:    func() { a.openFilesTab(conn, ip, hostname) },
+		OnTunnels:  func() { a.openTunnelsTab(conn, ip, hostname) },
+		OnAudit:    func() { a.openAuditTab(conn, ip, hostname) },
+		OnUsers:    func() { a.openUsersTab(conn, ip, hostname) },
+		OnSessions: func() { a.openSessionsTab(conn, ip, hostname) },
 	}
+}
+
+// openSSHTab adds a command mode terminal tab for a connected SSH client
+func (a *App) openSSHTab(sshClient *network.SSHClient, ip, hostname string) {
+	a.ensureSSHTerminalWindow()
 
 	// Add tab for this connection
 	tabID := ip
-	a.sshTerminalWindow.AddTab(tabID, hostname, ip, func(cmd string) string {
-		output, err := sshClient.ExecuteCommand(cmd)
-		if err != nil {
-			return fmt.Sprintf("Error: %v\n%s", err, output)
+	conn := newSharedSSHClient(sshClient)
+	conn.acquire()
+	tools := a.commandTools(sshClient, hostname)
+	onClose := conn.release
+	if logFile := a.openTranscriptLog(hostname); logFile != nil {
+		tools.TranscriptLog = logFile
+		onClose = func() {
+			logFile.Close()
+			conn.release()
 		}
-		return output
-	})
+	}
+	a.sshTerminalWindow.AddTab(tabID, hostname, ip, sshClient.StartExec, onClose, a.terminalActions(conn, ip, hostname), tools)
 
 	// Show the window
 	a.sshTerminalWindow.Show()
@@ -0,0 +1,91 @@
+package application
+
+import (
+	"adminadmin/internal/network"
+	"fmt"
+	"log"
+	"strconv"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// loadAuditLog opens the worker's SSH audit log with the stored settings
+func (a *App) loadAuditLog() {
+	settings, err := network.LoadAuditSettings()
+	if err != nil {
+		log.Printf("APP WARNING: Failed to load audit settings: %v\n", err)
+	}
+	audit, err := network.OpenAuditLog(network.AuditDir(), settings)
+	if err != nil {
+		log.Printf("APP ERROR: SSH sessions will not be audited: %v\n", err)
+		return
+	}
+	a.sshServer.SetAuditLog(audit)
+}
+
+// showAuditDialog lets the worker's user configure session recording and retention
+func (a *App) showAuditDialog() {
+	if a.sshServer == nil {
+		return
+	}
+	audit := a.sshServer.GetAuditLog()
+	if audit == nil {
+		dialog.ShowInformation("Audit Log", "The audit log could not be opened; see the log for details.", a.window)
+		return
+	}
+	settings := audit.Settings()
+
+	recordCheck := widget.NewCheck("Record full session transcripts", nil)
+	recordCheck.SetChecked(settings.RecordSessions)
+	retentionEntry := widget.NewEntry()
+	retentionEntry.SetText(strconv.Itoa(settings.RetentionDays))
+	sizeEntry := widget.NewEntry()
+	sizeEntry.SetText(strconv.Itoa(settings.MaxSizeMB))
+
+	location := widget.NewLabel(network.AuditDir())
+	location.Selectable = true
+
+	formItems := []*widget.FormItem{
+		widget.NewFormItem("", recordCheck),
+		widget.NewFormItem("Keep for (days)", retentionEntry),
+		widget.NewFormItem("Size limit (MB)", sizeEntry),
+		widget.NewFormItem("Stored in", location),
+	}
+	formItems[0].HintText = "Typed input is recorded too, including passwords typed at prompts such as sudo"
+	formItems[1].HintText = "0 keeps everything"
+	formItems[2].HintText = "Oldest recordings and logs are removed first; 0 is unlimited"
+
+	dialog.ShowForm("Audit Log", "Save", "Cancel", formItems, func(ok bool) {
+		if !ok {
+			return
+		}
+		retention, err1 := strconv.Atoi(retentionEntry.Text)
+		maxSize, err2 := strconv.Atoi(sizeEntry.Text)
+		if err1 != nil || err2 != nil || retention < 0 || maxSize < 0 {
+			dialog.ShowError(fmt.Errorf("retention and size limit must be whole numbers, 0 or more"), a.window)
+			return
+		}
+		settings := network.AuditSettings{
+			RecordSessions: recordCheck.Checked,
+			RetentionDays:  retention,
+			MaxSizeMB:      maxSize,
+		}
+		audit.SetSettings(settings)
+		if err := network.SaveAuditSettings(settings); err != nil {
+			log.Printf("APP ERROR: Failed to save audit settings: %v\n", err)
+		}
+		log.Printf("APP: Audit settings updated - recording: %v, retention: %d days, limit: %d MB\n",
+			settings.RecordSessions, settings.RetentionDays, settings.MaxSizeMB)
+	}, a.window)
+}
+
+// openAuditTab opens the audit log viewer of a worker on an existing SSH connection
+func (a *App) openAuditTab(conn *sharedSSHClient, ip, hostname string) {
+	if a.sshTerminalWindow == nil {
+		return
+	}
+	conn.acquire()
+	a.sshTerminalWindow.AddAuditTab(ip, hostname, ip, conn.client, conn.release)
+	a.sshTerminalWindow.Show()
+}
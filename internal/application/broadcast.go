@@ -0,0 +1,57 @@
+package application
+
+import (
+	"adminadmin/internal/network"
+	"adminadmin/internal/ui"
+	"log"
+	"sort"
+)
+
+// showBroadcastWindow opens the "Run on Selection" window with the workers carrying
+// tag preselected (all workers if tag is empty)
+func (a *App) showBroadcastWindow(tag string) {
+	if a.broadcastWindow != nil {
+		a.broadcastWindow.Close()
+	}
+
+	var workers []ui.BroadcastWorker
+	for _, d := range a.state.GetConnectedDevicesList() {
+		workers = append(workers, ui.BroadcastWorker{ID: d.ID, Name: d.Hostname, Tags: d.AllTags()})
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].Name < workers[j].Name })
+
+	var window *ui.BroadcastWindow
+	window = ui.NewBroadcastWindow(a.fyneApp, workers, tag,
+		func(req ui.BroadcastRequest) { go a.runBroadcast(window, req) },
+		func() {
+			if a.broadcastWindow == window {
+				a.broadcastWindow = nil
+			}
+		},
+	)
+	a.broadcastWindow = window
+	window.Show()
+}
+
+// runBroadcast runs a broadcast request and streams results into the window
+func (a *App) runBroadcast(window *ui.BroadcastWindow, req ui.BroadcastRequest) {
+	targets := make([]network.BroadcastTarget, len(req.WorkerIDs))
+	for i, id := range req.WorkerIDs {
+		targets[i] = network.BroadcastTarget{ID: id, Name: id}
+		if device := a.state.GetConnectedDeviceByID(id); device != nil {
+			targets[i].Name = device.Hostname
+		}
+	}
+
+	log.Printf("APP: Broadcasting %q to %d workers\n", req.Command, len(targets))
+	results := network.Broadcast(targets, req.Command, network.BroadcastOptions{
+		Port:        network.DefaultSSHPort,
+		User:        req.User,
+		Password:    req.Password,
+		Signer:      a.clientKey(),
+		ConfirmHost: a.confirmHostKey,
+		Concurrency: req.Concurrency,
+		Timeout:     req.Timeout,
+	}, window.AddResult)
+	window.Finish(results)
+}
@@ -0,0 +1,65 @@
+package application
+
+import (
+	"adminadmin/internal/network"
+	"fmt"
+	"log"
+	"sync"
+
+	"fyne.io/fyne/v2/dialog"
+)
+
+// sharedSSHClient is an SSH connection used by several tabs (a terminal and its
+// file browser); it is closed when the last tab using it closes
+type sharedSSHClient struct {
+	client *network.SSHClient
+	mu     sync.Mutex
+	refs   int
+}
+
+func newSharedSSHClient(client *network.SSHClient) *sharedSSHClient {
+	return &sharedSSHClient{client: client}
+}
+
+// acquire registers one more tab using the connection
+func (s *sharedSSHClient) acquire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refs++
+}
+
+// release unregisters a tab, closing the connection after the last one
+func (s *sharedSSHClient) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refs--
+	if s.refs == 0 {
+		s.client.Close()
+	}
+}
+
+// openFilesTab opens a file browser tab on an existing SSH connection
+func (a *App) openFilesTab(conn *sharedSSHClient, ip, hostname string) {
+	conn.acquire()
+	go func() {
+		files, err := conn.client.OpenFiles()
+		a.runOnMain(func() {
+			if err != nil {
+				conn.release()
+				log.Printf("APP ERROR: Failed to open file browser for %s: %v\n", ip, err)
+				dialog.ShowError(fmt.Errorf("cannot browse files on %s: %w", hostname, err), a.window)
+				return
+			}
+			if a.sshTerminalWindow == nil {
+				files.Close()
+				conn.release()
+				return
+			}
+			a.sshTerminalWindow.AddFilesTab(ip, hostname, ip, files, func() {
+				files.Close()
+				conn.release()
+			})
+			a.sshTerminalWindow.Show()
+		})
+	}()
+}
@@ -0,0 +1,53 @@
+package application
+
+import (
+	"adminadmin/internal/network"
+	"adminadmin/internal/ui"
+	"log"
+)
+
+// loadCommandHistory reads the command mode history of every worker from the config directory
+func loadCommandHistory() map[string][]string {
+	history, err := network.LoadCommandHistory()
+	if err != nil {
+		log.Printf("APP WARNING: Failed to load command history: %v\n", err)
+	}
+	return history
+}
+
+// recordCommand adds a command run on a worker to its history and persists it
+func (a *App) recordCommand(hostname, cmd string) {
+	a.historyMu.Lock()
+	a.commandHistory[hostname] = network.AppendHistory(a.commandHistory[hostname], cmd)
+	err := network.SaveCommandHistory(a.commandHistory)
+	a.historyMu.Unlock()
+	if err != nil {
+		log.Printf("APP ERROR: Failed to save command history: %v\n", err)
+	}
+}
+
+// commandTools returns the history, completion and snippets of a command mode tab
+func (a *App) commandTools(sshClient *network.SSHClient, hostname string) ui.CommandTools {
+	a.historyMu.Lock()
+	history := append([]string(nil), a.commandHistory[hostname]...)
+	a.historyMu.Unlock()
+
+	return ui.CommandTools{
+		History:   history,
+		OnHistory: func(cmd string) { a.recordCommand(hostname, cmd) },
+		Complete:  sshClient.Complete,
+		Snippets: func() []network.Snippet {
+			// Reloaded every time so edits made from other tabs show up
+			snippets, err := network.LoadSnippets()
+			if err != nil {
+				log.Printf("APP WARNING: Failed to load snippets: %v\n", err)
+			}
+			return snippets
+		},
+		OnSaveSnippets: func(snippets []network.Snippet) {
+			if err := network.SaveSnippets(snippets); err != nil {
+				log.Printf("APP ERROR: Failed to save snippets: %v\n", err)
+			}
+		},
+	}
+}
@@ -0,0 +1,61 @@
+package application
+
+import (
+	"adminadmin/internal/network"
+	"adminadmin/internal/ui"
+	"fmt"
+	"log"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/crypto/ssh"
+)
+
+// confirmHostKey asks the admin whether to trust a worker's SSH host key seen for
+// the first time. Called from connection goroutines; blocks until the admin answers.
+func (a *App) confirmHostKey(host string, key ssh.PublicKey) bool {
+	answer := make(chan bool, 1)
+	a.runOnMain(func() {
+		dialog.ShowConfirm("Unknown SSH Host",
+			fmt.Sprintf("The authenticity of %s can't be established.\n\n"+
+				"%s key fingerprint:\n%s\n\n"+
+				"Compare it with the host key shown on the worker's waiting screen before trusting it.\n"+
+				"Trust this host and continue connecting?", host, key.Type(), ssh.FingerprintSHA256(key)),
+			func(ok bool) { answer <- ok }, a.window)
+	})
+	return <-answer
+}
+
+// showHostKeyChangedDialog warns that a worker presented a different host key than the trusted one
+func (a *App) showHostKeyChangedDialog(err *network.HostKeyChangedError) {
+	log.Printf("APP WARNING: %v\n", err)
+	message := fmt.Sprintf("WARNING: THE SSH HOST KEY FOR %s HAS CHANGED!\n\n"+
+		"Someone could be intercepting the connection (man-in-the-middle attack),\n"+
+		"or the worker's host key was regenerated.\n\n"+
+		"Presented key:\n%s\n\nTrusted key:\n%s\n\n"+
+		"The connection was refused. If the change is expected, remove the old entry\n"+
+		"under Known Hosts and connect again.", err.Host, err.Fingerprint, strings.Join(err.Known, "\n"))
+
+	label := widget.NewLabelWithStyle(message, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	dialog.ShowCustomConfirm("Host Key Changed", "Review Known Hosts", "Close",
+		label, func(review bool) {
+			if review {
+				a.showKnownHostsWindow()
+			}
+		}, a.window)
+}
+
+// showKnownHostsWindow opens the known hosts review window
+func (a *App) showKnownHostsWindow() {
+	if a.knownHostsWindow != nil {
+		a.knownHostsWindow.Refresh()
+		a.knownHostsWindow.Show()
+		return
+	}
+	a.knownHostsWindow = ui.NewKnownHostsWindow(a.fyneApp, func() {
+		a.knownHostsWindow = nil
+	})
+	a.knownHostsWindow.Show()
+}
@@ -0,0 +1,76 @@
+package application
+
+import (
+	"adminadmin/internal/alerts"
+	"adminadmin/internal/notify"
+	"adminadmin/internal/ui"
+	"fmt"
+	"log"
+)
+
+// newNotifier loads notification settings and starts the notifier
+func newNotifier() *notify.Notifier {
+	settings, err := notify.LoadSettings()
+	if err != nil {
+		log.Printf("APP WARNING: Failed to load notification settings: %v\n", err)
+	}
+	return notify.New(settings)
+}
+
+// notifyWorker sends a worker connection event, filling in the hostname from state
+func (a *App) notifyWorker(evtType notify.EventType, workerID, message string) {
+	hostname := workerID
+	if device := a.state.GetConnectedDeviceByID(workerID); device != nil {
+		hostname = device.Hostname
+	}
+	a.notifier.Notify(notify.Event{
+		Type:     evtType,
+		Worker:   workerID,
+		Hostname: hostname,
+		Message:  message,
+	})
+}
+
+// notifyAlert sends an alert fired/resolved event
+func (a *App) notifyAlert(evtType notify.EventType, alert alerts.Alert, message string) {
+	a.notifier.Notify(notify.Event{
+		Type:     evtType,
+		Worker:   alert.WorkerID,
+		Hostname: alert.Hostname,
+		Message:  message,
+		Details: map[string]string{
+			"rule":  alert.RuleName,
+			"value": fmt.Sprintf("%.1f", alert.Value),
+		},
+	})
+}
+
+// onSSHAuth sends an SSH login event for every authentication attempt on the worker
+func (a *App) onSSHAuth(user, remoteAddr string, success bool) {
+	evt := notify.Event{
+		Type:       notify.EventSSHLoginSuccess,
+		User:       user,
+		RemoteAddr: remoteAddr,
+		Message:    fmt.Sprintf("SSH login as %s from %s", user, remoteAddr),
+	}
+	if !success {
+		evt.Type = notify.EventSSHLoginFailure
+		evt.Message = fmt.Sprintf("Failed SSH login as %s from %s", user, remoteAddr)
+	}
+	a.notifier.Notify(evt)
+}
+
+// showNotificationsWindow opens (or focuses) the notification settings window
+func (a *App) showNotificationsWindow() {
+	if a.notificationsWindow == nil {
+		a.notificationsWindow = ui.NewNotificationsWindow(a.fyneApp, a.notifier,
+			func(settings notify.Settings) {
+				if err := notify.SaveSettings(settings); err != nil {
+					log.Printf("APP ERROR: Failed to save notification settings: %v\n", err)
+				}
+			},
+			func() { a.notificationsWindow = nil },
+		)
+	}
+	a.notificationsWindow.Show()
+}
@@ -0,0 +1,65 @@
+package application
+
+import (
+	"adminadmin/internal/network"
+	"adminadmin/internal/ui"
+	"errors"
+	"log"
+	"sort"
+)
+
+// splitTargets lists the connected workers a shell tab can be split to
+func (a *App) splitTargets() []ui.SplitTarget {
+	var targets []ui.SplitTarget
+	for _, d := range a.state.GetConnectedDevicesList() {
+		targets = append(targets, ui.SplitTarget{ID: d.ID, Name: d.Hostname})
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Name < targets[j].Name })
+	return targets
+}
+
+// openSplitPane connects the shell of a new pane in a split shell tab; it runs off the
+// main thread
+func (a *App) openSplitPane(req ui.SplitRequest) (ui.ShellPane, error) {
+	ip, hostname := req.WorkerID, req.WorkerID
+	if device := a.state.GetConnectedDeviceByID(ip); device != nil {
+		hostname = device.Hostname
+	}
+	log.Printf("APP: Connecting SSH to %s as %s for a split pane\n", ip, req.User)
+
+	sshClient := network.NewSSHClient()
+	sshClient.SetSigner(a.clientKey())
+	sshClient.SetHostKeyConfirm(a.confirmHostKey)
+	if err := sshClient.Connect(ip, network.DefaultSSHPort, req.User, req.Password); err != nil {
+		var changed *network.HostKeyChangedError
+		if errors.As(err, &changed) {
+			a.runOnMain(func() { a.showHostKeyChangedDialog(changed) })
+		}
+		return ui.ShellPane{}, err
+	}
+
+	var shell *network.ShellSession
+	var err error
+	if req.Detachable {
+		shell, err = startDetachableShell(sshClient)
+	} else {
+		shell, err = sshClient.StartShell("xterm-256color", 80, 24)
+	}
+	if err != nil {
+		sshClient.Close()
+		return ui.ShellPane{}, err
+	}
+
+	conn := newSharedSSHClient(sshClient)
+	conn.acquire()
+	return ui.ShellPane{
+		Hostname: hostname,
+		IP:       ip,
+		Shell:    shell,
+		OnClose: func() {
+			shell.Close()
+			conn.release()
+		},
+		Actions: a.terminalActions(conn, ip, hostname),
+	}, nil
+}
@@ -0,0 +1,30 @@
+package application
+
+import (
+	"adminadmin/internal/network"
+	"adminadmin/internal/ui"
+	"log"
+)
+
+// loadRewriteSettings applies the stored command rewriting rules to the SSH server
+func (a *App) loadRewriteSettings() {
+	settings, err := network.LoadRewriteSettings()
+	if err != nil {
+		log.Printf("APP WARNING: Failed to load command rewriting settings: %v\n", err)
+	}
+	a.sshServer.SetRewriteSettings(settings)
+}
+
+// showRewriteDialog lets the worker's user edit the command rewriting rules
+func (a *App) showRewriteDialog() {
+	if a.sshServer == nil {
+		return
+	}
+	ui.ShowRewriteRulesDialog(a.sshServer.GetRewriteSettings(), a.window, func(settings network.RewriteSettings) {
+		a.sshServer.SetRewriteSettings(settings)
+		if err := network.SaveRewriteSettings(settings); err != nil {
+			log.Printf("APP ERROR: Failed to save command rewriting settings: %v\n", err)
+		}
+		log.Printf("APP: Command rewriting updated - enabled: %v, %d rule(s)\n", settings.Enabled, len(settings.Rules))
+	})
+}
@@ -0,0 +1,142 @@
+package application
+
+import (
+	"adminadmin/internal/network"
+	"adminadmin/internal/notify"
+	"adminadmin/internal/ui"
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showScreenViewer opens (or focuses) the window showing a worker's screen
+func (a *App) showScreenViewer(workerID string) {
+	if viewer := a.screenViewers[workerID]; viewer != nil {
+		viewer.Show()
+		return
+	}
+
+	a.clientsMu.RLock()
+	client, ok := a.adminClients[workerID]
+	a.clientsMu.RUnlock()
+	if !ok {
+		dialog.ShowError(fmt.Errorf("not connected to %s", workerID), a.window)
+		return
+	}
+	hostname := workerID
+	if device := a.state.GetConnectedDeviceByID(workerID); device != nil {
+		hostname = device.Hostname
+	}
+
+	log.Printf("APP: Opening screen viewer for %s\n", hostname)
+	var viewer *ui.ScreenViewer
+	viewer = ui.NewScreenViewer(a.fyneApp, hostname, client, func() {
+		if a.screenViewers[workerID] == viewer {
+			delete(a.screenViewers, workerID)
+		}
+	})
+	a.screenViewers[workerID] = viewer
+	viewer.Show()
+}
+
+// screenViewerDisconnected tells a worker's screen viewer, if open, that the
+// connection ended
+func (a *App) screenViewerDisconnected(workerID string) {
+	a.runOnMain(func() {
+		if viewer := a.screenViewers[workerID]; viewer != nil {
+			viewer.SetDisconnected()
+		}
+	})
+}
+
+// closeScreenViewers closes every screen viewer window
+func (a *App) closeScreenViewers() {
+	for _, viewer := range a.screenViewers {
+		viewer.Close()
+	}
+}
+
+// loadScreenSettings applies the stored screen sharing settings to the worker server
+func (a *App) loadScreenSettings() {
+	settings, err := network.LoadScreenSettings()
+	if err != nil {
+		log.Printf("APP WARNING: Failed to load screen sharing settings: %v\n", err)
+	}
+	a.workerServer.SetScreenSettings(settings)
+}
+
+// showScreenSharingDialog lets the worker's user allow admins to see the screen
+func (a *App) showScreenSharingDialog() {
+	if a.workerServer == nil {
+		return
+	}
+	settings := a.workerServer.GetScreenSettings()
+
+	enabledCheck := widget.NewCheck("Allow admins to view this screen", nil)
+	enabledCheck.SetChecked(settings.Enabled)
+	confirmCheck := widget.NewCheck("Ask me before every screenshot and live view", nil)
+	confirmCheck.SetChecked(settings.Confirm)
+	hint := widget.NewLabel("Turn off asking only on unattended machines such as kiosks.\n" +
+		"A red bar shows while an admin is looking at the screen.")
+	hint.Importance = widget.LowImportance
+
+	formItems := []*widget.FormItem{
+		widget.NewFormItem("", enabledCheck),
+		widget.NewFormItem("", confirmCheck),
+		widget.NewFormItem("", hint),
+	}
+	dialog.ShowForm("Screen Sharing", "Save", "Cancel", formItems, func(ok bool) {
+		if !ok {
+			return
+		}
+		settings := network.ScreenSettings{
+			Enabled: enabledCheck.Checked,
+			Confirm: confirmCheck.Checked,
+		}
+		a.workerServer.SetScreenSettings(settings)
+		if err := network.SaveScreenSettings(settings); err != nil {
+			log.Printf("APP ERROR: Failed to save screen sharing settings: %v\n", err)
+		}
+		log.Printf("APP: Screen sharing settings updated - enabled: %v, confirm: %v\n",
+			settings.Enabled, settings.Confirm)
+	}, a.window)
+}
+
+// confirmScreenView asks the worker's user whether an admin may see the screen
+func (a *App) confirmScreenView(adminHostname string, live bool) bool {
+	what := "take a screenshot of this screen"
+	if live {
+		what = "watch this screen live"
+	}
+	answer := make(chan bool, 1)
+	a.runOnMain(func() {
+		dialog.ShowConfirm("Screen Sharing",
+			fmt.Sprintf("Admin %s wants to %s.\n\nAllow it?", adminHostname, what),
+			func(ok bool) { answer <- ok }, a.window)
+	})
+	return <-answer
+}
+
+// setScreenViewers shows or hides the banner telling the worker's user who is looking
+// at the screen
+func (a *App) setScreenViewers(admins []string) {
+	a.runOnMain(func() {
+		a.screenBanner.SetViewers(admins)
+	})
+}
+
+// onScreenViewed sends a notification when an admin looks at this worker's screen
+func (a *App) onScreenViewed(adminHostname string, live bool) {
+	message := fmt.Sprintf("Admin %s took a screenshot", adminHostname)
+	if live {
+		message = fmt.Sprintf("Admin %s started a live view of the screen", adminHostname)
+	}
+	log.Printf("APP: %s\n", message)
+	a.notifier.Notify(notify.Event{
+		Type:     notify.EventScreenViewed,
+		Hostname: adminHostname,
+		Message:  message,
+	})
+}
@@ -0,0 +1,130 @@
+package application
+
+import (
+	"adminadmin/internal/network"
+	"adminadmin/internal/ui"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// pendingSessionWarning is a worker's warning shown in the session ending dialog
+type pendingSessionWarning struct {
+	hostname string
+	client   *network.AdminClient
+	notice   network.SessionNoticePayload
+}
+
+// loadSessionLimits applies the stored session limits to the worker and SSH servers
+func (a *App) loadSessionLimits() {
+	settings, err := network.LoadSessionLimitSettings()
+	if err != nil {
+		log.Printf("APP WARNING: Failed to load session limits: %v\n", err)
+	}
+	a.workerServer.SetSessionLimits(settings.Admin)
+	a.sshServer.SetSessionLimits(settings.SSH)
+}
+
+// showSessionLimitsDialog lets the worker's user edit the idle and session limits
+func (a *App) showSessionLimitsDialog() {
+	if a.workerServer == nil || a.sshServer == nil {
+		return
+	}
+	current := network.SessionLimitSettings{
+		SSH:   a.sshServer.GetSessionLimits(),
+		Admin: a.workerServer.GetSessionLimits(),
+	}
+	ui.ShowSessionLimitsDialog(current, a.window, func(settings network.SessionLimitSettings) {
+		a.workerServer.SetSessionLimits(settings.Admin)
+		a.sshServer.SetSessionLimits(settings.SSH)
+		if err := network.SaveSessionLimitSettings(settings); err != nil {
+			log.Printf("APP ERROR: Failed to save session limits: %v\n", err)
+		}
+		log.Printf("APP: Session limits updated - SSH: %+v, admin: %+v\n", settings.SSH, settings.Admin)
+	})
+}
+
+// showSessionWarning tells the admin that a worker is about to end an idle or long
+// session; staying connected pings the worker, which counts as activity. Warnings of
+// several workers share one dialog, answered for all of them at once.
+func (a *App) showSessionWarning(workerID string, client *network.AdminClient, notice network.SessionNoticePayload) {
+	hostname := workerID
+	if device := a.state.GetConnectedDeviceByID(workerID); device != nil {
+		hostname = device.Hostname
+	}
+	a.runOnMain(func() {
+		if a.sessionWarnings == nil {
+			a.sessionWarnings = make(map[string]pendingSessionWarning)
+		}
+		a.sessionWarnings[workerID] = pendingSessionWarning{hostname: hostname, client: client, notice: notice}
+		if a.sessionWarning != nil {
+			a.updateSessionWarning()
+			return
+		}
+
+		a.sessionWarningLabel = widget.NewLabel("")
+		a.sessionWarningLabel.Wrapping = fyne.TextWrapWord
+		a.updateSessionWarning()
+		d := dialog.NewCustomConfirm("Session Ending", "Stay Connected", "Disconnect", a.sessionWarningLabel, func(stay bool) {
+			warnings := a.sessionWarnings
+			a.sessionWarnings, a.sessionWarning = nil, nil
+			for id, w := range warnings {
+				if !stay {
+					w.client.Disconnect()
+					continue
+				}
+				if err := w.client.SendPing(); err != nil {
+					log.Printf("APP ERROR: Failed to keep session with %s open: %v\n", id, err)
+				}
+			}
+		}, a.window)
+		d.Resize(fyne.NewSize(480, 0))
+		a.sessionWarning = d
+		d.Show()
+	})
+}
+
+// updateSessionWarning shows the pending warnings in the session ending dialog
+func (a *App) updateSessionWarning() {
+	lines := make([]string, 0, len(a.sessionWarnings))
+	for _, w := range a.sessionWarnings {
+		lines = append(lines, fmt.Sprintf("%s: %s", w.hostname, w.notice.Message))
+	}
+	sort.Strings(lines)
+	a.sessionWarningLabel.SetText(strings.Join(lines, "\n"))
+}
+
+// clearSessionWarning drops a disconnected worker from the session ending dialog,
+// closing it when no other worker is left
+func (a *App) clearSessionWarning(workerID string) {
+	a.runOnMain(func() {
+		if _, ok := a.sessionWarnings[workerID]; !ok {
+			return
+		}
+		delete(a.sessionWarnings, workerID)
+		if len(a.sessionWarnings) > 0 {
+			a.updateSessionWarning()
+			return
+		}
+		if d := a.sessionWarning; d != nil {
+			a.sessionWarning = nil
+			d.Hide() // Answers "Disconnect" for the workers left, which are none
+		}
+	})
+}
+
+// showSessionEnded tells the admin why a worker ended or refused its session
+func (a *App) showSessionEnded(workerID string, notice network.SessionNoticePayload) {
+	hostname := workerID
+	if device := a.state.GetConnectedDeviceByID(workerID); device != nil {
+		hostname = device.Hostname
+	}
+	a.runOnMain(func() {
+		dialog.ShowInformation(fmt.Sprintf("Disconnected from %s", hostname), notice.Message, a.window)
+	})
+}
@@ -0,0 +1,58 @@
+package application
+
+import (
+	"adminadmin/internal/network"
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2/dialog"
+)
+
+// startDetachableShell starts a detachable session on the worker and attaches to it
+func startDetachableShell(sshClient *network.SSHClient) (*network.ShellSession, error) {
+	id, err := sshClient.NewDetachableSession("xterm-256color", 80, 24)
+	if err != nil {
+		return nil, err
+	}
+	return sshClient.AttachShell(id, "xterm-256color", 80, 24)
+}
+
+// openSessionsTab opens the list of a worker's detachable sessions on an existing SSH connection
+func (a *App) openSessionsTab(conn *sharedSSHClient, ip, hostname string) {
+	a.ensureSSHTerminalWindow()
+	conn.acquire()
+	a.sshTerminalWindow.AddSessionsTab(ip, hostname, ip, conn.client,
+		func(id string) {
+			a.openDetachableShell(conn, ip, hostname, func() (*network.ShellSession, error) {
+				return conn.client.AttachShell(id, "xterm-256color", 80, 24)
+			})
+		},
+		func() {
+			a.openDetachableShell(conn, ip, hostname, func() (*network.ShellSession, error) {
+				return startDetachableShell(conn.client)
+			})
+		},
+		conn.release)
+	a.sshTerminalWindow.Show()
+}
+
+// openDetachableShell opens a shell tab for the session attach connects to
+func (a *App) openDetachableShell(conn *sharedSSHClient, ip, hostname string, attach func() (*network.ShellSession, error)) {
+	conn.acquire()
+	go func() {
+		shell, err := attach()
+		a.runOnMain(func() {
+			defer conn.release()
+			if err != nil {
+				log.Printf("APP ERROR: Failed to attach to a session on %s: %v\n", ip, err)
+				dialog.ShowError(fmt.Errorf("cannot attach to a session on %s: %w", hostname, err), a.window)
+				return
+			}
+			if a.sshTerminalWindow == nil {
+				shell.Close()
+				return
+			}
+			a.openShellTab(conn, shell, ip, hostname)
+		})
+	}()
+}
@@ -0,0 +1,68 @@
+package application
+
+import (
+	"adminadmin/internal/network"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// loadSFTPSettings applies the stored SFTP settings to the SSH server
+func (a *App) loadSFTPSettings() {
+	settings, err := network.LoadSFTPSettings()
+	if err != nil {
+		log.Printf("APP WARNING: Failed to load SFTP settings: %v\n", err)
+	}
+	a.sshServer.SetSFTPSettings(settings)
+}
+
+// showSFTPDialog lets the worker's user configure the SFTP subsystem
+func (a *App) showSFTPDialog() {
+	if a.sshServer == nil {
+		return
+	}
+	settings := a.sshServer.GetSFTPSettings()
+
+	enabledCheck := widget.NewCheck("Allow SFTP / scp file access", nil)
+	enabledCheck.SetChecked(settings.Enabled)
+
+	rootEntry := widget.NewEntry()
+	rootEntry.SetPlaceHolder("Home directory")
+	rootEntry.SetText(settings.Root)
+	browseButton := widget.NewButton("Browse...", func() {
+		dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+			if err == nil && dir != nil {
+				rootEntry.SetText(dir.Path())
+			}
+		}, a.window)
+	})
+
+	readOnlyCheck := widget.NewCheck("Read-only (downloads only)", nil)
+	readOnlyCheck.SetChecked(settings.ReadOnly)
+
+	formItems := []*widget.FormItem{
+		widget.NewFormItem("", enabledCheck),
+		widget.NewFormItem("Root folder", container.NewBorder(nil, nil, nil, browseButton, rootEntry)),
+		widget.NewFormItem("", readOnlyCheck),
+	}
+
+	dialog.ShowForm("File Sharing (SFTP)", "Save", "Cancel", formItems, func(ok bool) {
+		if !ok {
+			return
+		}
+		settings := network.SFTPSettings{
+			Enabled:  enabledCheck.Checked,
+			Root:     rootEntry.Text,
+			ReadOnly: readOnlyCheck.Checked,
+		}
+		a.sshServer.SetSFTPSettings(settings)
+		if err := network.SaveSFTPSettings(settings); err != nil {
+			log.Printf("APP ERROR: Failed to save SFTP settings: %v\n", err)
+		}
+		log.Printf("APP: SFTP settings updated - enabled: %v, root: %q, read-only: %v\n",
+			settings.Enabled, settings.Root, settings.ReadOnly)
+	}, a.window)
+}
@@ -0,0 +1,169 @@
+package application
+
+import (
+	"adminadmin/internal/network"
+	"adminadmin/internal/ui"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/crypto/ssh"
+)
+
+// loadSSHUsers loads the worker's SSH accounts into the SSH and worker servers. It returns
+// the password of the account created on first use, which must be shown once.
+func (a *App) loadSSHUsers() string {
+	users, generated, err := network.LoadSSHUsers()
+	if err != nil {
+		log.Printf("APP ERROR: Failed to load SSH accounts: %v\n", err)
+	}
+	a.sshServer.SetUsers(users)
+	a.workerServer.SetSSHUsers(users)
+	return generated
+}
+
+// loadSSHAuthSettings applies the stored login options to the SSH server. Called after
+// loadSSHUsers, which migrates them from the old credentials file.
+func (a *App) loadSSHAuthSettings() {
+	settings, err := network.LoadSSHAuthSettings()
+	if err != nil {
+		log.Printf("APP WARNING: Failed to load SSH login settings: %v\n", err)
+	}
+	a.sshServer.SetAuthSettings(settings)
+}
+
+// setPasswordAuth turns password logins on or off for every SSH account
+func (a *App) setPasswordAuth(enabled bool) {
+	if a.sshServer == nil {
+		return
+	}
+	settings := network.SSHAuthSettings{PasswordAuth: enabled}
+	a.sshServer.SetAuthSettings(settings)
+	if err := network.SaveSSHAuthSettings(settings); err != nil {
+		log.Printf("APP ERROR: Failed to save SSH login settings: %v\n", err)
+	}
+	log.Printf("APP: SSH password login enabled: %v\n", enabled)
+}
+
+// showGeneratedPassword shows the password of the first SSH account; it is only stored hashed
+func (a *App) showGeneratedPassword(password string) {
+	entry := widget.NewEntry()
+	entry.SetText(password)
+	a.runOnMain(func() {
+		dialog.ShowCustom("SSH Account Created", "Done", container.NewVBox(
+			widget.NewLabel(fmt.Sprintf("The SSH account %q was created with this password.\n"+
+				"Write it down now: it is stored only as a hash and will not be shown again.", network.DefaultSSHUsername)),
+			entry,
+		), a.window)
+	})
+}
+
+// showSSHUsersDialog opens the editor for the worker's SSH accounts
+func (a *App) showSSHUsersDialog() {
+	if a.sshServer == nil || a.sshServer.GetUsers() == nil {
+		return
+	}
+	ui.ShowSSHUsersDialog(a.sshServer.GetUsers(), a.window, a.showWorkerWaitingScreen)
+}
+
+// showBannedAddressesDialog lists the addresses the SSH server refuses after failed logins
+func (a *App) showBannedAddressesDialog() {
+	if a.sshServer == nil {
+		return
+	}
+	ui.ShowBannedAddressesDialog(a.sshServer.BannedAddresses, a.sshServer.Unban, a.window)
+}
+
+// openUsersTab opens the SSH account editor of a worker on an existing SSH connection
+func (a *App) openUsersTab(conn *sharedSSHClient, ip, hostname string) {
+	if a.sshTerminalWindow == nil {
+		return
+	}
+	conn.acquire()
+	a.sshTerminalWindow.AddUsersTab(ip, hostname, ip, conn.client, conn.release)
+	a.sshTerminalWindow.Show()
+}
+
+// confirmKeyRequest asks the worker's user whether to trust an admin's SSH key.
+// Called from a network goroutine; blocks until the user answers.
+func (a *App) confirmKeyRequest(adminHostname, username, fingerprint string) bool {
+	answer := make(chan bool, 1)
+	a.runOnMain(func() {
+		dialog.ShowConfirm("Authorize SSH Key",
+			fmt.Sprintf("Admin %s wants to log in to this worker over SSH as %s without a password.\n\n"+
+				"Key fingerprint:\n%s\n\nAllow this key?", adminHostname, username, fingerprint),
+			func(ok bool) { answer <- ok }, a.window)
+	})
+	return <-answer
+}
+
+// clientKey returns the admin's SSH key, loading or generating it on first use
+func (a *App) clientKey() ssh.Signer {
+	a.sshKeyOnce.Do(func() {
+		signer, err := network.LoadOrCreateClientKey()
+		if err != nil {
+			log.Printf("APP ERROR: SSH key unavailable, falling back to passwords: %v\n", err)
+			return
+		}
+		a.sshKey = signer
+	})
+	return a.sshKey
+}
+
+// pairSSHKey asks which of a worker's SSH accounts to pair with and sends the admin's
+// public key to it, so later SSH logins need no password
+func (a *App) pairSSHKey(workerID string) {
+	signer := a.clientKey()
+	if signer == nil {
+		dialog.ShowError(fmt.Errorf("no SSH key available (see log)"), a.window)
+		return
+	}
+
+	a.clientsMu.RLock()
+	client := a.adminClients[workerID]
+	a.clientsMu.RUnlock()
+	if client == nil {
+		return
+	}
+
+	hostname := workerID
+	if device := a.state.GetConnectedDeviceByID(workerID); device != nil {
+		hostname = device.Hostname
+	}
+	adminHostname, _ := os.Hostname()
+	fingerprint := ssh.FingerprintSHA256(signer.PublicKey())
+
+	usernameEntry := widget.NewEntry()
+	usernameEntry.SetText(network.DefaultSSHUsername)
+	formItems := []*widget.FormItem{widget.NewFormItem("SSH account", usernameEntry)}
+	formItems[0].HintText = "The worker's account to log in as with this key"
+
+	dialog.ShowForm("Pair SSH Key", "Pair", "Cancel", formItems, func(ok bool) {
+		username := strings.TrimSpace(usernameEntry.Text)
+		if !ok || username == "" {
+			return
+		}
+		progress := dialog.NewCustomWithoutButtons("Pair SSH Key",
+			widget.NewLabel(fmt.Sprintf("Waiting for %s to accept key for %s\n%s ...", hostname, username, fingerprint)), a.window)
+		progress.Show()
+
+		go func() {
+			err := client.RequestKeyAuthorization(signer.PublicKey(), username, "adminadmin@"+adminHostname)
+			a.runOnMain(func() {
+				progress.Hide()
+				if err != nil {
+					log.Printf("APP: SSH key pairing with %s failed: %v\n", workerID, err)
+					dialog.ShowError(fmt.Errorf("pairing with %s failed: %w", hostname, err), a.window)
+					return
+				}
+				log.Printf("APP: SSH key paired with %s (account %s)\n", workerID, username)
+				dialog.ShowInformation("Pair SSH Key",
+					fmt.Sprintf("%s accepted your key. SSH logins as %s no longer need a password.", hostname, username), a.window)
+			})
+		}()
+	}, a.window)
+}
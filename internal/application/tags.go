@@ -0,0 +1,181 @@
+package application
+
+import (
+	"adminadmin/internal/config"
+	"adminadmin/internal/state"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	// adminTagsFile maps worker ID (IP) to the tags the admin assigned to it
+	adminTagsFile = "worker_tags.json"
+	// selfTagsFile holds the tags this machine declares when running as a worker
+	selfTagsFile = "worker_self_tags.json"
+)
+
+// loadAdminTags reads the admin-assigned tags from the config directory
+func loadAdminTags() map[string][]string {
+	tags := make(map[string][]string)
+	if err := config.LoadJSON(adminTagsFile, &tags); err != nil {
+		log.Printf("APP WARNING: Failed to load worker tags: %v\n", err)
+	}
+	return tags
+}
+
+// setAdminTags assigns tags to a worker and persists them
+func (a *App) setAdminTags(workerID string, tags []string) {
+	tags = state.NormalizeTags(tags)
+	a.tagsMu.Lock()
+	if len(tags) == 0 {
+		delete(a.adminTags, workerID)
+	} else {
+		a.adminTags[workerID] = tags
+	}
+	err := config.SaveJSON(adminTagsFile, a.adminTags)
+	a.tagsMu.Unlock()
+	if err != nil {
+		log.Printf("APP ERROR: Failed to save worker tags: %v\n", err)
+	}
+
+	a.state.SetDeviceAdminTags(workerID, tags)
+	log.Printf("APP: Tags for %s set to %v\n", workerID, tags)
+}
+
+// applyAdminTags copies the stored admin tags onto a newly received device
+func (a *App) applyAdminTags(device *state.DeviceInfo) {
+	a.tagsMu.RLock()
+	defer a.tagsMu.RUnlock()
+	device.AdminTags = append([]string(nil), a.adminTags[device.ID]...)
+}
+
+// showEditTagsDialog lets the admin edit the tags assigned to a worker
+func (a *App) showEditTagsDialog(workerID string) {
+	device := a.state.GetConnectedDeviceByID(workerID)
+	if device == nil {
+		return
+	}
+
+	tagsEntry := widget.NewEntry()
+	tagsEntry.SetPlaceHolder("e.g. build-agent, linux")
+	tagsEntry.SetText(strings.Join(device.AdminTags, ", "))
+
+	formItems := []*widget.FormItem{
+		widget.NewFormItem("Tags", tagsEntry),
+	}
+	if len(device.Tags) > 0 {
+		formItems = append(formItems, widget.NewFormItem("Declared by worker",
+			widget.NewLabel(strings.Join(device.Tags, ", "))))
+	}
+
+	dialog.ShowForm(fmt.Sprintf("Tags for %s", device.Hostname), "Save", "Cancel", formItems,
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			a.setAdminTags(workerID, state.ParseTags(tagsEntry.Text))
+			a.rebuildDashboard()
+		}, a.window)
+}
+
+// showSelfTagsDialog lets the worker declare its own tags (sent to admins on connect)
+func (a *App) showSelfTagsDialog() {
+	var tags []string
+	if err := config.LoadJSON(selfTagsFile, &tags); err != nil {
+		log.Printf("APP WARNING: Failed to load worker self tags: %v\n", err)
+	}
+
+	tagsEntry := widget.NewEntry()
+	tagsEntry.SetPlaceHolder("e.g. kiosk, lobby")
+	tagsEntry.SetText(strings.Join(tags, ", "))
+
+	formItems := []*widget.FormItem{
+		widget.NewFormItem("Tags", tagsEntry),
+	}
+
+	dialog.ShowForm("Worker Tags", "Save", "Cancel", formItems, func(ok bool) {
+		if !ok {
+			return
+		}
+		tags := state.ParseTags(tagsEntry.Text)
+		if err := config.SaveJSON(selfTagsFile, tags); err != nil {
+			log.Printf("APP ERROR: Failed to save worker self tags: %v\n", err)
+		}
+		if a.workerServer != nil {
+			a.workerServer.SetTags(tags)
+		}
+		log.Printf("APP: Worker self tags set to %v\n", tags)
+	}, a.window)
+}
+
+// loadSelfTags applies the stored self-declared tags to the worker server
+func (a *App) loadSelfTags() {
+	var tags []string
+	if err := config.LoadJSON(selfTagsFile, &tags); err != nil {
+		log.Printf("APP WARNING: Failed to load worker self tags: %v\n", err)
+	}
+	a.workerServer.SetTags(tags)
+}
+
+// rebuildDashboard rebuilds the dashboard after the worker list or grouping changed
+func (a *App) rebuildDashboard() {
+	if a.dashboardCtrl != nil {
+		a.dashboardCtrl.ForceRebuild()
+	}
+	a.showAdminDashboard()
+}
+
+// groupWorkerIDs returns the IDs of the connected workers carrying a tag, sorted
+func (a *App) groupWorkerIDs(tag string) []string {
+	var ids []string
+	for _, d := range a.state.GetDevicesWithTag(tag) {
+		ids = append(ids, d.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// disconnectGroup disconnects every worker carrying a tag after confirmation
+func (a *App) disconnectGroup(tag string) {
+	ids := a.groupWorkerIDs(tag)
+	if len(ids) == 0 {
+		return
+	}
+	dialog.ShowConfirm("Disconnect Group",
+		fmt.Sprintf("Disconnect %d worker(s) tagged %q?", len(ids), tag),
+		func(ok bool) {
+			if ok {
+				a.disconnectWorkers(ids)
+			}
+		}, a.window)
+}
+
+// disconnectWorkers disconnects the given workers and removes them from the dashboard
+func (a *App) disconnectWorkers(ids []string) {
+	a.clientsMu.Lock()
+	for _, ip := range ids {
+		if client, ok := a.adminClients[ip]; ok {
+			log.Printf("APP: Disconnecting from %s...\n", ip)
+			client.Disconnect()
+			delete(a.adminClients, ip)
+		}
+		// Intentional disconnects must not trigger "unreachable" alerts
+		if engine := a.getAlertEngine(); engine != nil {
+			engine.Forget(ip, time.Now())
+		}
+		a.state.RemoveConnectedDevice(ip)
+	}
+	a.clientsMu.Unlock()
+
+	if a.state.GetWorkerCount() == 0 {
+		a.showAdminConnectScreen()
+		return
+	}
+	a.rebuildDashboard()
+}
@@ -0,0 +1,41 @@
+package application
+
+import (
+	"adminadmin/internal/network"
+	"adminadmin/internal/ui"
+	"log"
+	"os"
+)
+
+// openTranscriptLog opens a worker's transcript log if automatic logging is on
+func (a *App) openTranscriptLog(hostname string) *os.File {
+	settings, err := network.LoadTranscriptSettings()
+	if err != nil {
+		log.Printf("APP WARNING: Failed to load transcript logging settings: %v\n", err)
+	}
+	if !settings.AutoLog {
+		return nil
+	}
+	logFile, err := settings.OpenLog(hostname)
+	if err != nil {
+		log.Printf("APP ERROR: Failed to open transcript log for %s: %v\n", hostname, err)
+		return nil
+	}
+	return logFile
+}
+
+// showTranscriptLoggingDialog lets the admin turn automatic transcript logging on or off;
+// the change applies to tabs opened afterwards
+func (a *App) showTranscriptLoggingDialog() {
+	settings, err := network.LoadTranscriptSettings()
+	if err != nil {
+		log.Printf("APP WARNING: Failed to load transcript logging settings: %v\n", err)
+	}
+	ui.ShowTranscriptLoggingDialog(settings, a.window, func(settings network.TranscriptSettings) {
+		if err := network.SaveTranscriptSettings(settings); err != nil {
+			log.Printf("APP ERROR: Failed to save transcript logging settings: %v\n", err)
+			return
+		}
+		log.Printf("APP: Transcript logging %v, directory %s\n", settings.AutoLog, settings.LogDir())
+	})
+}
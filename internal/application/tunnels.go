@@ -0,0 +1,113 @@
+package application
+
+import (
+	"adminadmin/internal/network"
+	"fmt"
+	"log"
+	"maps"
+
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// loadForwardingSettings applies the stored port forwarding settings to the SSH server
+func (a *App) loadForwardingSettings() {
+	settings, err := network.LoadForwardingSettings()
+	if err != nil {
+		log.Printf("APP WARNING: Failed to load port forwarding settings: %v\n", err)
+	}
+	a.sshServer.SetForwardingSettings(settings)
+}
+
+// showForwardingDialog lets the worker's user choose which accounts may forward ports through it
+func (a *App) showForwardingDialog() {
+	if a.sshServer == nil {
+		return
+	}
+	settings := a.sshServer.GetForwardingSettings()
+	var accounts []network.SSHUser
+	if db := a.sshServer.GetUsers(); db != nil {
+		accounts, _ = db.List()
+	}
+
+	type permChecks struct {
+		account       network.SSHUser
+		local, remote *widget.Check
+	}
+	checks := make(map[string]permChecks)
+	var formItems []*widget.FormItem
+	for _, account := range accounts {
+		perm := settings.For(account)
+		c := permChecks{account, widget.NewCheck("Local", nil), widget.NewCheck("Remote", nil)}
+		c.local.SetChecked(perm.Local)
+		c.remote.SetChecked(perm.Remote)
+		checks[account.Username] = c
+		formItems = append(formItems, widget.NewFormItem(account.Username, container.NewHBox(c.local, c.remote)))
+	}
+	otherLocal := widget.NewCheck("Local", nil)
+	otherLocal.SetChecked(settings.Default.Local)
+	otherRemote := widget.NewCheck("Remote", nil)
+	otherRemote.SetChecked(settings.Default.Remote)
+	formItems = append(formItems, widget.NewFormItem("Full accounts added later", container.NewHBox(otherLocal, otherRemote)))
+	formItems[len(formItems)-1].HintText = "Local reaches ports from this computer; remote opens ports on its loopback. " +
+		"Accounts limited to some commands never forward unless checked above."
+
+	dialog.ShowForm("Port Forwarding", "Save", "Cancel", formItems, func(ok bool) {
+		if !ok {
+			return
+		}
+		settings := network.ForwardingSettings{
+			Default: network.ForwardPermission{Local: otherLocal.Checked, Remote: otherRemote.Checked},
+			Users:   maps.Clone(settings.Users),
+		}
+		if settings.Users == nil {
+			settings.Users = make(map[string]network.ForwardPermission)
+		}
+		// Only accounts set apart from what they'd get anyway keep an entry, so the
+		// others follow later changes to the default
+		for name, c := range checks {
+			perm := network.ForwardPermission{Local: c.local.Checked, Remote: c.remote.Checked}
+			delete(settings.Users, name)
+			if settings.For(c.account) != perm {
+				settings.Users[name] = perm
+			}
+			log.Printf("APP: Port forwarding for %s - local: %v, remote: %v\n", name, perm.Local, perm.Remote)
+		}
+		a.sshServer.SetForwardingSettings(settings)
+		if err := network.SaveForwardingSettings(settings); err != nil {
+			log.Printf("APP ERROR: Failed to save port forwarding settings: %v\n", err)
+		}
+	}, a.window)
+}
+
+// openTunnelsTab opens the port forwarding panel of a worker on an existing SSH connection
+func (a *App) openTunnelsTab(conn *sharedSSHClient, ip, hostname string) {
+	if a.sshTerminalWindow == nil {
+		return
+	}
+	tunnels, err := network.LoadTunnels()
+	if err != nil {
+		log.Printf("APP WARNING: Failed to load tunnels: %v\n", err)
+		dialog.ShowError(fmt.Errorf("saved tunnels could not be read: %w", err), a.window)
+	}
+
+	conn.acquire()
+	a.sshTerminalWindow.AddTunnelsTab(ip, hostname, ip, conn.client, tunnels[hostname], func(specs []network.TunnelSpec) {
+		// Reload so tunnels of workers edited in other tabs are kept
+		all, err := network.LoadTunnels()
+		if err != nil {
+			log.Printf("APP ERROR: Failed to load tunnels: %v\n", err)
+			return
+		}
+		if len(specs) == 0 {
+			delete(all, hostname)
+		} else {
+			all[hostname] = specs
+		}
+		if err := network.SaveTunnels(all); err != nil {
+			log.Printf("APP ERROR: Failed to save tunnels: %v\n", err)
+		}
+	}, conn.release)
+	a.sshTerminalWindow.Show()
+}
@@ -0,0 +1,67 @@
+// Package config provides access to the adminadmin configuration directory
+// and simple JSON-backed settings files stored inside it.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// AppDirName is the directory name used under the user's config directory
+const AppDirName = "adminadmin"
+
+// Dir returns the adminadmin config directory, creating it if needed.
+// Falls back to the current directory if the user config dir is unavailable.
+func Dir() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		configDir = "."
+	}
+	dir := filepath.Join(configDir, AppDirName)
+	os.MkdirAll(dir, 0700)
+	return dir
+}
+
+// Path returns the full path of a file inside the config directory
+func Path(name string) string {
+	return filepath.Join(Dir(), name)
+}
+
+// LoadJSON reads a JSON settings file into v.
+// A missing file is not an error; v is left unchanged so callers can pre-fill defaults.
+func LoadJSON(name string, v interface{}) error {
+	data, err := os.ReadFile(Path(name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// SaveJSON writes v as indented JSON to a settings file.
+// The file is written to a temp file first and renamed so a crash never leaves it half-written.
+func SaveJSON(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+
+	path := Path(name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save %s: %w", name, err)
+	}
+	return nil
+}
@@ -7,16 +7,33 @@ import (
 	"log"
 	"net"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 // AdminClient represents an admin client that connects to worker nodes
 type AdminClient struct {
 	conn            net.Conn
+	writer          *messageWriter
 	connected       bool
 	onUpdate        func(*state.DeviceInfo)
-	onMetricsUpdate func(cpuUsage, ramUsage, gpuUsage float64)
+	onMetricsUpdate func(cpuUsage, ramUsage, gpuUsage, diskUsage float64)
+	onDisconnect    func(err error)
+	onSessionNotice func(notice SessionNoticePayload)
+
+	// Running speed test, if any (see speedtest.go)
+	speedMu   sync.Mutex
+	speedTest *speedTestChannels
+
+	// Pending SSH key pairing request, if any (see keypairing.go)
+	keyMu     sync.Mutex
+	keyResult chan AuthorizeKeyResultPayload
+
+	// Pending screenshot and live screen view, if any (see screen.go)
+	screenMu sync.Mutex
+	screen   screenChannels
 }
 
 // contains is a helper function to check if a string contains a substring
@@ -25,16 +42,30 @@ func contains(s, substr string) bool {
 }
 
 // NewAdminClient creates a new admin client
-func NewAdminClient(onUpdate func(*state.DeviceInfo), onMetricsUpdate func(cpuUsage, ramUsage, gpuUsage float64)) *AdminClient {
+func NewAdminClient(onUpdate func(*state.DeviceInfo), onMetricsUpdate func(cpuUsage, ramUsage, gpuUsage, diskUsage float64)) *AdminClient {
 	return &AdminClient{
 		onUpdate:        onUpdate,
 		onMetricsUpdate: onMetricsUpdate,
 	}
 }
 
+// SetOnDisconnect sets a callback invoked once when the connection ends.
+// err is nil for a requested Disconnect, a *SessionEndedError if the worker ended
+// or refused the session, and the read error otherwise.
+func (a *AdminClient) SetOnDisconnect(onDisconnect func(err error)) {
+	a.onDisconnect = onDisconnect
+}
+
+// SetOnSessionWarning sets a callback invoked when the worker is about to end the
+// session, e.g. because it was idle; any message to the worker, such as SendPing,
+// counts as activity and keeps an idle session open
+func (a *AdminClient) SetOnSessionWarning(onWarning func(notice SessionNoticePayload)) {
+	a.onSessionNotice = onWarning
+}
+
 // Connect connects to a worker node
 func (a *AdminClient) Connect(address string, port int) error {
-	addr := fmt.Sprintf("%s:%d", address, port)
+	addr := net.JoinHostPort(address, strconv.Itoa(port))
 
 	log.Printf("ADMIN: Attempting to connect to worker at %s...\n", addr)
 	log.Println("ADMIN: If this takes a long time, check firewall settings on the Worker PC")
@@ -81,6 +112,7 @@ func (a *AdminClient) Connect(address string, port int) error {
 	}
 
 	a.conn = conn
+	a.writer = newMessageWriter(conn)
 	a.connected = true
 	log.Printf("ADMIN: TCP connection established to %s\n", addr)
 
@@ -101,13 +133,7 @@ func (a *AdminClient) sendAdminInfo() {
 	payload := AdminInfoPayload{
 		Hostname: hostname,
 	}
-	payloadBytes, _ := json.Marshal(payload)
-	msg := Message{
-		Type:    MsgTypeAdminInfo,
-		Payload: payloadBytes,
-	}
-	encoder := json.NewEncoder(a.conn)
-	encoder.Encode(msg)
+	a.writer.send(MsgTypeAdminInfo, payload)
 	log.Printf("ADMIN: Sent admin info (hostname: %s)\n", hostname)
 }
 
@@ -118,9 +144,7 @@ func (a *AdminClient) Disconnect() error {
 	}
 
 	// Send disconnect message
-	msg := Message{Type: MsgTypeDisconnect}
-	encoder := json.NewEncoder(a.conn)
-	encoder.Encode(msg)
+	a.writer.send(MsgTypeDisconnect, nil)
 
 	a.connected = false
 	return a.conn.Close()
@@ -137,20 +161,27 @@ func (a *AdminClient) SendPing() error {
 		return fmt.Errorf("not connected")
 	}
 
-	msg := Message{Type: MsgTypePing}
-	encoder := json.NewEncoder(a.conn)
-	return encoder.Encode(msg)
+	return a.writer.send(MsgTypePing, nil)
 }
 
 func (a *AdminClient) receiveUpdates() {
 	log.Println("ADMIN: Receive updates goroutine started")
 
+	var readErr error
 	defer func() {
 		log.Println("ADMIN: Receive updates goroutine ending")
+		// A requested Disconnect clears connected before the read fails
+		requested := !a.connected
 		a.connected = false
 		if a.conn != nil {
 			a.conn.Close()
 		}
+		if a.onDisconnect != nil {
+			if requested {
+				readErr = nil
+			}
+			a.onDisconnect(readErr)
+		}
 	}()
 
 	decoder := json.NewDecoder(a.conn)
@@ -160,9 +191,24 @@ func (a *AdminClient) receiveUpdates() {
 		var msg Message
 		if err := decoder.Decode(&msg); err != nil {
 			log.Printf("ADMIN: Connection error: %v\n", err)
+			readErr = err
 			return
 		}
 
+		// Speed test and screen traffic is high-volume; route it without per-message logging
+		switch msg.Type {
+		case MsgTypeSpeedTestData, MsgTypeSpeedTestResult:
+			a.dispatchSpeedTest(msg)
+			continue
+		case MsgTypePong:
+			if a.dispatchSpeedTest(msg) {
+				continue
+			}
+		case MsgTypeScreenFrame:
+			a.dispatchScreenFrame(msg)
+			continue
+		}
+
 		log.Printf("ADMIN: Received message type: %s\n", msg.Type)
 
 		switch msg.Type {
@@ -188,8 +234,10 @@ func (a *AdminClient) receiveUpdates() {
 				RAMUsed:       payload.RAMUsed,
 				GPUName:       payload.GPUName,
 				GPUUsage:      payload.GPUUsage,
+				DiskUsage:     payload.DiskUsage,
 				InternetSpeed: payload.InternetSpeed,
 				Uptime:        payload.Uptime,
+				Tags:          payload.Tags,
 			}
 
 			if a.onUpdate != nil {
@@ -207,12 +255,34 @@ func (a *AdminClient) receiveUpdates() {
 			}
 
 			if a.onMetricsUpdate != nil {
-				a.onMetricsUpdate(payload.CPUUsage, payload.RAMUsage, payload.GPUUsage)
+				a.onMetricsUpdate(payload.CPUUsage, payload.RAMUsage, payload.GPUUsage, payload.DiskUsage)
 			}
 
 		case MsgTypePong:
 			log.Println("ADMIN: Received pong from worker")
 
+		case MsgTypeAuthorizeKeyResult:
+			a.dispatchKeyResult(msg)
+
+		case MsgTypeSessionWarning:
+			var notice SessionNoticePayload
+			if err := json.Unmarshal(msg.Payload, &notice); err != nil {
+				log.Printf("ADMIN ERROR: Error parsing session warning: %v\n", err)
+				continue
+			}
+			log.Printf("ADMIN: Worker warns: %s\n", notice.Message)
+			if a.onSessionNotice != nil {
+				a.onSessionNotice(notice)
+			}
+
+		case MsgTypeSessionEnded:
+			// Returning closes the connection, which the worker waits for
+			var notice SessionNoticePayload
+			json.Unmarshal(msg.Payload, &notice)
+			log.Printf("ADMIN: Worker ended the session: %s\n", notice.Message)
+			readErr = &SessionEndedError{Notice: notice}
+			return
+
 		default:
 			log.Printf("ADMIN: Unknown message type: %s\n", msg.Type)
 		}
@@ -0,0 +1,535 @@
+package network
+
+import (
+	"adminadmin/internal/config"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	auditSettingsFile = "audit.json"
+	auditLogName      = "audit.log"
+	recordingExt      = ".cast"
+
+	// auditRotateSize is the size at which audit.log is closed and a new one started
+	auditRotateSize = 5 << 20
+)
+
+// Audit event types
+const (
+	AuditLogin         = "login"
+	AuditLoginFailed   = "login_failed"
+	AuditLogout        = "logout"
+	AuditShell         = "shell"
+	AuditExec          = "exec"
+	AuditSFTP          = "sftp"
+	AuditForward       = "forward"
+	AuditRemoteForward = "remote_forward"
+	AuditUserAdmin     = "user_admin" // An account was changed remotely
+	AuditBan           = "ban"        // A source address was banned after failed logins
+)
+
+// AuditSettings configures the worker's SSH audit log and session recordings
+type AuditSettings struct {
+	RecordSessions bool `json:"record_sessions"` // Keep asciicast transcripts of shells and commands
+	RetentionDays  int  `json:"retention_days"`  // Delete older logs and recordings; 0 keeps them forever
+	MaxSizeMB      int  `json:"max_size_mb"`     // Delete the oldest files above this total; 0 is unlimited
+}
+
+// DefaultAuditSettings returns the settings used until the worker changes them
+func DefaultAuditSettings() AuditSettings {
+	return AuditSettings{RetentionDays: 90, MaxSizeMB: 200}
+}
+
+// LoadAuditSettings loads the audit settings, falling back to the defaults
+func LoadAuditSettings() (AuditSettings, error) {
+	settings := DefaultAuditSettings()
+	err := config.LoadJSON(auditSettingsFile, &settings)
+	return settings, err
+}
+
+// SaveAuditSettings persists the audit settings
+func SaveAuditSettings(settings AuditSettings) error {
+	return config.SaveJSON(auditSettingsFile, settings)
+}
+
+// AuditDir returns the directory holding the audit log and recordings
+func AuditDir() string {
+	return config.Path("audit")
+}
+
+// AuditEvent is one line of the audit log
+type AuditEvent struct {
+	Time      time.Time `json:"time"`
+	Session   string    `json:"session"` // Identifies the SSH connection
+	Event     string    `json:"event"`
+	User      string    `json:"user"`
+	Remote    string    `json:"remote"`
+	Command   string    `json:"command,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	Recording string    `json:"recording,omitempty"` // Name of the session's asciicast file
+}
+
+// RecordingInfo describes a stored session recording
+type RecordingInfo struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// AuditLog appends SSH activity to audit.log as JSON lines and stores session
+// recordings next to it. Entries are never rewritten: the log is rotated when it
+// grows, and only whole rotated files and recordings are removed by retention.
+// A nil *AuditLog records nothing.
+type AuditLog struct {
+	dir string
+
+	mu        sync.Mutex
+	file      *os.File
+	size      int64
+	settings  AuditSettings
+	recording map[string]bool // Recordings still being written, which prune keeps
+}
+
+// OpenAuditLog opens the audit log in dir, creating it if needed
+func OpenAuditLog(dir string, settings AuditSettings) (*AuditLog, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	l := &AuditLog{dir: dir, settings: settings, recording: make(map[string]bool)}
+	if err := l.openFile(); err != nil {
+		return nil, err
+	}
+	l.prune()
+	return l, nil
+}
+
+// openFile opens audit.log for appending; l.mu must be held or l not yet shared
+func (l *AuditLog) openFile() error {
+	f, err := os.OpenFile(filepath.Join(l.dir, auditLogName), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// SetSettings changes the recording, retention and size settings and applies them
+func (l *AuditLog) SetSettings(settings AuditSettings) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.settings = settings
+	l.mu.Unlock()
+	l.prune()
+}
+
+// Settings returns the current settings
+func (l *AuditLog) Settings() AuditSettings {
+	if l == nil {
+		return DefaultAuditSettings()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.settings
+}
+
+// Close closes the log file
+func (l *AuditLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Record appends an event, stamping the time if unset
+func (l *AuditLog) Record(event AuditEvent) {
+	if l == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		log.Printf("SSH: Failed to write audit log: %v\n", err)
+		return
+	}
+	if l.size >= auditRotateSize {
+		l.rotate()
+	}
+}
+
+// rotate renames the full audit.log and starts a new one; l.mu must be held
+func (l *AuditLog) rotate() {
+	l.file.Close()
+	l.file = nil
+	current := filepath.Join(l.dir, auditLogName)
+	rotated := filepath.Join(l.dir, "audit-"+time.Now().Format("20060102-150405.000")+".log")
+	if err := os.Rename(current, rotated); err != nil {
+		log.Printf("SSH: Failed to rotate audit log: %v\n", err)
+	}
+	if err := l.openFile(); err != nil {
+		log.Printf("SSH: %v\n", err)
+	}
+	go l.prune()
+}
+
+// auditFile is a file prune may delete
+type auditFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// prune deletes rotated logs and recordings past the retention period, then the
+// oldest ones until everything fits in the size limit. The current audit.log and
+// recordings of sessions still running are kept.
+func (l *AuditLog) prune() {
+	l.mu.Lock()
+	settings := l.settings
+	open := maps.Clone(l.recording)
+	l.mu.Unlock()
+	var files []auditFile
+	var total int64
+	filepath.Walk(l.dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		total += info.Size()
+		if name := filepath.Base(p); name != auditLogName && !open[name] {
+			files = append(files, auditFile{path: p, size: info.Size(), modTime: info.ModTime()})
+		}
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	cutoff := time.Time{}
+	if settings.RetentionDays > 0 {
+		cutoff = time.Now().AddDate(0, 0, -settings.RetentionDays)
+	}
+	limit := int64(settings.MaxSizeMB) << 20
+	removed := 0
+	for _, f := range files {
+		expired := f.modTime.Before(cutoff)
+		tooBig := limit > 0 && total > limit
+		if !expired && !tooBig {
+			break
+		}
+		if err := os.Remove(f.path); err != nil {
+			continue
+		}
+		total -= f.size
+		removed++
+	}
+	if removed > 0 {
+		log.Printf("SSH: Audit retention removed %d old file(s)\n", removed)
+	}
+}
+
+// Events returns up to limit of the most recent events, oldest first
+func (l *AuditLog) Events(limit int) ([]AuditEvent, error) {
+	if l == nil {
+		return nil, fmt.Errorf("audit log unavailable")
+	}
+	logs, _ := filepath.Glob(filepath.Join(l.dir, "audit-*.log"))
+	sort.Strings(logs)
+	logs = append(logs, filepath.Join(l.dir, auditLogName))
+
+	var events []AuditEvent
+	for _, name := range logs {
+		f, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1<<20)
+		for scanner.Scan() {
+			var event AuditEvent
+			if json.Unmarshal(scanner.Bytes(), &event) == nil {
+				events = append(events, event)
+			}
+		}
+		f.Close()
+	}
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	return events, nil
+}
+
+// Recordings lists the stored session recordings, newest first
+func (l *AuditLog) Recordings() ([]RecordingInfo, error) {
+	if l == nil {
+		return nil, fmt.Errorf("audit log unavailable")
+	}
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+	var recordings []RecordingInfo
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), recordingExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		recordings = append(recordings, RecordingInfo{Name: entry.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	sort.Slice(recordings, func(i, j int) bool { return recordings[i].ModTime.After(recordings[j].ModTime) })
+	return recordings, nil
+}
+
+// ReadRecording returns the contents of a recording listed by Recordings
+func (l *AuditLog) ReadRecording(name string) ([]byte, error) {
+	if l == nil {
+		return nil, fmt.Errorf("audit log unavailable")
+	}
+	if name != filepath.Base(name) || !strings.HasSuffix(name, recordingExt) {
+		return nil, fmt.Errorf("invalid recording name %q", name)
+	}
+	return os.ReadFile(filepath.Join(l.dir, name))
+}
+
+// sessionRecorder writes one shell or command as an asciicast v2 file
+type sessionRecorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	w       *bufio.Writer
+	name    string
+	start   time.Time
+	pending map[string][]byte // Incomplete UTF-8 sequences held back per stream
+	audit   *AuditLog         // Pruned once the recording is complete
+}
+
+// asciicastHeader is the first line of an asciicast v2 file
+type asciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// startRecording creates a recording for a shell or command if recording is enabled.
+// It returns nil when it isn't, or when the file can't be created.
+func (l *AuditLog) startRecording(session, title string, pty *ptyRequestMsg) (*sessionRecorder, string) {
+	if l == nil || !l.Settings().RecordSessions {
+		return nil, ""
+	}
+	start := time.Now()
+	name := fmt.Sprintf("%s-%s%s", start.Format("20060102-150405.000"), session, recordingExt)
+	f, err := os.OpenFile(filepath.Join(l.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		log.Printf("SSH: Failed to start session recording: %v\n", err)
+		return nil, ""
+	}
+
+	header := asciicastHeader{Version: 2, Width: 80, Height: 24, Timestamp: start.Unix(), Title: title}
+	if pty != nil {
+		header.Width, header.Height = int(pty.Cols), int(pty.Rows)
+		if pty.Term != "" {
+			header.Env = map[string]string{"TERM": pty.Term}
+		}
+	}
+	l.mu.Lock()
+	l.recording[name] = true
+	l.mu.Unlock()
+	r := &sessionRecorder{file: f, w: bufio.NewWriter(f), name: name, start: start, pending: make(map[string][]byte), audit: l}
+	line, _ := json.Marshal(header)
+	r.w.Write(append(line, '\n'))
+	return r, name
+}
+
+// write adds an event: "o" for output, "i" for input, "r" for a resize
+func (r *sessionRecorder) write(kind string, data []byte) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return
+	}
+	// Hold back a multi-byte character split across reads so it isn't mangled
+	data = append(r.pending[kind], data...)
+	complete, rest := splitIncompleteUTF8(data)
+	r.pending[kind] = append([]byte(nil), rest...)
+	if len(complete) == 0 {
+		return
+	}
+	elapsed := time.Since(r.start).Seconds()
+	line, err := json.Marshal([]interface{}{elapsed, kind, string(complete)})
+	if err != nil {
+		return
+	}
+	r.w.Write(append(line, '\n'))
+}
+
+// resize records a terminal size change
+func (r *sessionRecorder) resize(cols, rows uint32) {
+	r.write("r", []byte(fmt.Sprintf("%dx%d", cols, rows)))
+}
+
+// Close flushes and closes the recording, then applies the size limit to it
+func (r *sessionRecorder) Close() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return
+	}
+	r.w.Flush()
+	r.file.Close()
+	r.file = nil
+	r.audit.mu.Lock()
+	delete(r.audit.recording, r.name)
+	r.audit.mu.Unlock()
+	go r.audit.prune()
+}
+
+// splitIncompleteUTF8 splits off a trailing partial UTF-8 sequence
+func splitIncompleteUTF8(b []byte) (complete, rest []byte) {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i], b[i:]
+			}
+			break
+		}
+	}
+	return b, nil
+}
+
+// recordingChannel copies what passes through a session channel into a recording
+type recordingChannel struct {
+	ssh.Channel
+	rec *sessionRecorder
+}
+
+func (c *recordingChannel) Read(p []byte) (int, error) {
+	n, err := c.Channel.Read(p)
+	if n > 0 {
+		c.rec.write("i", p[:n])
+	}
+	return n, err
+}
+
+func (c *recordingChannel) Write(p []byte) (int, error) {
+	c.rec.write("o", p)
+	return c.Channel.Write(p)
+}
+
+// Stderr records stderr output like regular output, as a terminal shows both
+func (c *recordingChannel) Stderr() io.ReadWriter {
+	return &recordingStderr{ReadWriter: c.Channel.Stderr(), rec: c.rec}
+}
+
+// recordingStderr copies what is written to a session's stderr into its recording
+type recordingStderr struct {
+	io.ReadWriter
+	rec *sessionRecorder
+}
+
+func (s *recordingStderr) Write(p []byte) (int, error) {
+	s.rec.write("o", p)
+	return s.ReadWriter.Write(p)
+}
+
+// Asciicast is a parsed session recording
+type Asciicast struct {
+	Width    int
+	Height   int
+	Title    string
+	Started  time.Time
+	Events   []AsciicastEvent
+	Duration float64 // Seconds until the last event
+}
+
+// AsciicastEvent is one event of a recording; Kind is "o" (output), "i" (input) or "r" (resize)
+type AsciicastEvent struct {
+	Time float64
+	Kind string
+	Data string
+}
+
+// ParseAsciicast parses an asciicast v2 recording
+func ParseAsciicast(data []byte) (*Asciicast, error) {
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("empty recording")
+	}
+	var header asciicastHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, fmt.Errorf("invalid recording header: %w", err)
+	}
+	if header.Version != 2 {
+		return nil, fmt.Errorf("unsupported asciicast version %d", header.Version)
+	}
+
+	cast := &Asciicast{
+		Width:   header.Width,
+		Height:  header.Height,
+		Title:   header.Title,
+		Started: time.Unix(header.Timestamp, 0),
+	}
+	for scanner.Scan() {
+		var raw []interface{}
+		if json.Unmarshal(scanner.Bytes(), &raw) != nil || len(raw) != 3 {
+			continue
+		}
+		t, ok1 := raw[0].(float64)
+		kind, ok2 := raw[1].(string)
+		text, ok3 := raw[2].(string)
+		if !ok1 || !ok2 || !ok3 {
+			continue
+		}
+		cast.Events = append(cast.Events, AsciicastEvent{Time: t, Kind: kind, Data: text})
+		cast.Duration = t
+	}
+	return cast, scanner.Err()
+}
@@ -0,0 +1,42 @@
+package network
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Retention must not delete the recording of a session that is still running
+func TestAuditPruneKeepsOpenRecordings(t *testing.T) {
+	dir := t.TempDir()
+	audit, err := OpenAuditLog(dir, AuditSettings{RecordSessions: true, RetentionDays: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer audit.Close()
+
+	rec, name := audit.startRecording("session", "ops: shell", nil)
+	if rec == nil {
+		t.Fatal("recording did not start")
+	}
+	path := filepath.Join(dir, name)
+	old := time.Now().AddDate(0, 0, -7)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	audit.prune()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("open recording was pruned: %v", err)
+	}
+
+	rec.Close()
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	audit.prune()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("finished recording past retention was kept (%v)", err)
+	}
+}
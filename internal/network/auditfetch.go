@@ -0,0 +1,96 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// auditSubsystem is the SSH subsystem the admin reads a worker's audit log through
+const auditSubsystem = "adminadmin-audit"
+
+// auditRequest is sent by the client after opening the audit subsystem
+type auditRequest struct {
+	Op    string `json:"op"`              // "events", "recordings" or "recording"
+	Limit int    `json:"limit,omitempty"` // For "events"
+	Name  string `json:"name,omitempty"`  // For "recording"
+}
+
+// auditResponse is the server's single reply to an auditRequest
+type auditResponse struct {
+	Events     []AuditEvent    `json:"events,omitempty"`
+	Recordings []RecordingInfo `json:"recordings,omitempty"`
+	Data       []byte          `json:"data,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// serveAudit answers one audit request on a session channel
+func serveAudit(channel ssh.Channel, user string, audit *AuditLog) {
+	var req auditRequest
+	if err := json.NewDecoder(channel).Decode(&req); err != nil {
+		sendExitStatus(channel, nil, err)
+		return
+	}
+
+	var resp auditResponse
+	var err error
+	switch req.Op {
+	case "events":
+		resp.Events, err = audit.Events(req.Limit)
+	case "recordings":
+		resp.Recordings, err = audit.Recordings()
+	case "recording":
+		resp.Data, err = audit.ReadRecording(req.Name)
+		if err == nil {
+			log.Printf("SSH: %s downloaded recording %s\n", user, req.Name)
+		}
+	default:
+		err = fmt.Errorf("unknown audit request %q", req.Op)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	json.NewEncoder(channel).Encode(resp)
+	sendExitStatus(channel, nil, nil)
+}
+
+// auditCall runs one request against the worker's audit subsystem
+func (c *SSHClient) auditCall(req auditRequest) (*auditResponse, error) {
+	var resp auditResponse
+	if err := c.callSubsystem(auditSubsystem, req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("%s", resp.Error)
+	}
+	return &resp, nil
+}
+
+// AuditEvents fetches up to limit of the worker's most recent audit events, oldest first
+func (c *SSHClient) AuditEvents(limit int) ([]AuditEvent, error) {
+	resp, err := c.auditCall(auditRequest{Op: "events", Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Events, nil
+}
+
+// AuditRecordings lists the worker's session recordings, newest first
+func (c *SSHClient) AuditRecordings() ([]RecordingInfo, error) {
+	resp, err := c.auditCall(auditRequest{Op: "recordings"})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Recordings, nil
+}
+
+// AuditRecording downloads a session recording in asciicast v2 format
+func (c *SSHClient) AuditRecording(name string) ([]byte, error) {
+	resp, err := c.auditCall(auditRequest{Op: "recording", Name: name})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
@@ -0,0 +1,158 @@
+package network
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	DefaultBroadcastConcurrency = 8
+	DefaultBroadcastTimeout     = 60 * time.Second
+)
+
+// BroadcastTarget is one worker a broadcast command runs on
+type BroadcastTarget struct {
+	ID   string // Worker ID (IP)
+	Name string // Display name (hostname)
+}
+
+// BroadcastOptions controls how a broadcast command is run
+type BroadcastOptions struct {
+	Port        int
+	User        string
+	Password    string
+	Signer      ssh.Signer         // Optional key tried before the password
+	ConfirmHost ConfirmHostKeyFunc // Asks whether to trust new host keys (unknown hosts fail without it)
+	Concurrency int                // Maximum workers running at once
+	Timeout     time.Duration      // Per-worker limit including connect
+}
+
+// BroadcastResult is the outcome of a broadcast command on one worker
+type BroadcastResult struct {
+	Target   BroadcastTarget
+	Output   string
+	ExitCode int
+	Err      error // Connection or transport failure (or timeout); nil if the command ran
+	Duration time.Duration
+}
+
+// OK reports whether the command ran and exited with status 0
+func (r BroadcastResult) OK() bool {
+	return r.Err == nil && r.ExitCode == 0
+}
+
+// Status returns a short human-readable status
+func (r BroadcastResult) Status() string {
+	if r.Err != nil {
+		return fmt.Sprintf("error: %v", r.Err)
+	}
+	return fmt.Sprintf("exit %d", r.ExitCode)
+}
+
+// Broadcast runs command on every target over SSH in parallel, at most
+// opts.Concurrency at a time. onResult (optional) is called from worker
+// goroutines as each target finishes. Results are returned in target order.
+func Broadcast(targets []BroadcastTarget, command string, opts BroadcastOptions, onResult func(BroadcastResult)) []BroadcastResult {
+	if opts.Port == 0 {
+		opts.Port = DefaultSSHPort
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultBroadcastConcurrency
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultBroadcastTimeout
+	}
+
+	log.Printf("BROADCAST: Running %q on %d workers (concurrency %d, timeout %s)\n",
+		command, len(targets), opts.Concurrency, opts.Timeout)
+
+	results := make([]BroadcastResult, len(targets))
+	sem := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target BroadcastTarget) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = runWithTimeout(target, command, opts)
+			if onResult != nil {
+				onResult(results[i])
+			}
+		}(i, target)
+	}
+	wg.Wait()
+	return results
+}
+
+// runWithTimeout runs the command on one target, closing the connection if it exceeds the timeout
+func runWithTimeout(target BroadcastTarget, command string, opts BroadcastOptions) BroadcastResult {
+	start := time.Now()
+	client := NewSSHClient()
+	client.SetSigner(opts.Signer)
+	client.SetHostKeyConfirm(opts.ConfirmHost)
+	done := make(chan BroadcastResult, 1)
+
+	go func() {
+		defer client.Close()
+		result := BroadcastResult{Target: target}
+		if err := client.Connect(target.ID, opts.Port, opts.User, opts.Password); err != nil {
+			result.Err = err
+		} else {
+			result.Output, result.ExitCode, result.Err = client.ExecuteCommandWithStatus(command)
+		}
+		done <- result
+	}()
+
+	timer := time.NewTimer(opts.Timeout)
+	defer timer.Stop()
+
+	var result BroadcastResult
+	select {
+	case result = <-done:
+	case <-timer.C:
+		result = BroadcastResult{Target: target, Err: fmt.Errorf("timed out after %s", opts.Timeout)}
+	}
+	// Unblocks a command still running after a timeout
+	client.Abort()
+
+	result.Duration = time.Since(start)
+	log.Printf("BROADCAST: %s finished in %s (%s)\n", target.ID, result.Duration.Round(time.Millisecond), result.Status())
+	return result
+}
+
+// OutputGroup is a set of workers that produced identical output and status
+type OutputGroup struct {
+	Status  string
+	Output  string
+	Targets []BroadcastTarget
+}
+
+// GroupByOutput groups results with identical status and output (ignoring
+// surrounding whitespace), largest group first
+func GroupByOutput(results []BroadcastResult) []OutputGroup {
+	index := make(map[string]int)
+	var groups []OutputGroup
+	for _, r := range results {
+		output := strings.TrimSpace(strings.ReplaceAll(r.Output, "\r\n", "\n"))
+		key := r.Status() + "\x00" + output
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, OutputGroup{Status: r.Status(), Output: output})
+		}
+		groups[i].Targets = append(groups[i].Targets, r.Target)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].Targets) > len(groups[j].Targets)
+	})
+	return groups
+}
@@ -0,0 +1,243 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// completeSubsystem is the SSH subsystem the command mode terminal completes paths and
+// command names through
+const completeSubsystem = "adminadmin-complete"
+
+// maxCompletions caps the candidates of one completion
+const maxCompletions = 200
+
+// completeRequest is sent by the client after opening the completion subsystem
+type completeRequest struct {
+	Line string `json:"line"`          // Command line typed so far; its last word is completed
+	Cwd  string `json:"cwd,omitempty"` // Directory relative paths start from; empty for the account's start directory
+}
+
+// completeResponse is the server's single reply to a completeRequest
+type completeResponse struct {
+	Word       string   `json:"word"`
+	Candidates []string `json:"candidates,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Completion is the worker's answer to a completion request
+type Completion struct {
+	Word       string   // Last word of the line, which the candidates replace
+	Candidates []string // Sorted; directories end with a path separator
+}
+
+// CommonPrefix returns the longest prefix shared by all candidates, or Word if there
+// are none
+func (c Completion) CommonPrefix() string {
+	if len(c.Candidates) == 0 {
+		return c.Word
+	}
+	prefix := c.Candidates[0]
+	for _, candidate := range c.Candidates[1:] {
+		for !strings.HasPrefix(candidate, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// Complete asks the worker to complete the last word of line: a command name when it is
+// the first word, otherwise a path relative to the tracked cwd
+func (c *SSHClient) Complete(line string) (Completion, error) {
+	c.mu.Lock()
+	cwd := c.cwd
+	c.mu.Unlock()
+
+	var resp completeResponse
+	if err := c.callSubsystem(completeSubsystem, completeRequest{Line: line, Cwd: cwd}, &resp); err != nil {
+		return Completion{}, err
+	}
+	if resp.Error != "" {
+		return Completion{}, fmt.Errorf("%s", resp.Error)
+	}
+	return Completion{Word: resp.Word, Candidates: resp.Candidates}, nil
+}
+
+// serveCompletion answers one completion request on a session channel. Allowlisted
+// accounts only get their allowed programs. Accounts with a shell get any path, as they
+// could list it anyway; the others only get paths if SFTP is on for them, confined to
+// the SFTP root like SFTP clients are.
+func serveCompletion(channel ssh.Channel, user SSHUser, settings SFTPSettings) {
+	var req completeRequest
+	if err := json.NewDecoder(channel).Decode(&req); err != nil {
+		sendExitStatus(channel, nil, err)
+		return
+	}
+
+	word := req.Line
+	if i := strings.LastIndexAny(word, " \t"); i >= 0 {
+		word = word[i+1:]
+	}
+	firstWord := strings.TrimSpace(req.Line) == word || strings.TrimSpace(req.Line) == ""
+
+	resp := completeResponse{Word: word}
+	switch {
+	case firstWord && !strings.ContainsAny(word, `/\`):
+		resp.Candidates = completeCommand(word, user)
+	case user.AllowsShell():
+		resp.Candidates = completePath(word, completionCwd(req, user), nil)
+	case settings.Enabled && user.AllowsSFTP():
+		root, err := sftpRootDir(settings)
+		var handler *sftpHandler
+		if err == nil {
+			handler, err = newSFTPHandler(root, true)
+		}
+		if err != nil {
+			resp.Error = err.Error()
+			break
+		}
+		resp.Candidates = completePath(word, completionCwd(req, user), handler)
+	}
+	json.NewEncoder(channel).Encode(resp)
+	sendExitStatus(channel, nil, nil)
+}
+
+// completeCommand returns the programs on PATH starting with prefix
+func completeCommand(prefix string, user SSHUser) []string {
+	found := make(map[string]bool)
+	if user.Policy == PolicyAllowlist {
+		for _, name := range user.AllowedCommands {
+			if hasPrefixFold(name, prefix) {
+				found[name] = true
+			}
+		}
+		return sortedCandidates(found)
+	}
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !hasPrefixFold(name, prefix) || entry.IsDir() {
+				continue
+			}
+			if runtime.GOOS == "windows" {
+				ext := strings.ToLower(filepath.Ext(name))
+				if ext != ".exe" && ext != ".bat" && ext != ".cmd" && ext != ".com" {
+					continue
+				}
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			} else if info, err := entry.Info(); err != nil || info.Mode()&0111 == 0 {
+				continue
+			}
+			found[name] = true
+		}
+	}
+	return sortedCandidates(found)
+}
+
+// completionCwd returns the directory relative paths of a request start from
+func completionCwd(req completeRequest, user SSHUser) string {
+	if req.Cwd != "" {
+		return req.Cwd
+	}
+	return newSessionState(user).cwd
+}
+
+// completePath returns the files and directories word could be the start of, relative
+// to cwd; "~" stands for the home directory. Hidden files are only offered for a
+// prefix starting with ".". With a root, directories outside it, including through
+// symlinks, are not listed.
+func completePath(word, cwd string, root *sftpHandler) []string {
+	dirPart, prefix := "", word
+	if i := strings.LastIndexAny(word, `/\`); i >= 0 {
+		dirPart, prefix = word[:i+1], word[i+1:]
+	}
+
+	dir := dirPart
+	if strings.HasPrefix(dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, dir[2:])
+		}
+	}
+	if dir == "" {
+		dir = cwd
+	} else if !filepath.IsAbs(dir) && !strings.HasPrefix(dir, "/") {
+		dir = filepath.Join(cwd, dir)
+	}
+
+	if root != nil {
+		var ok bool
+		if dir, ok = root.confine(dir); !ok {
+			return nil
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	found := make(map[string]bool)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !hasPrefixFold(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		if entry.IsDir() {
+			name += string(filepath.Separator)
+		}
+		found[dirPart+name] = true
+	}
+	return sortedCandidates(found)
+}
+
+// confine maps a local directory to the same directory checked by resolve, or returns
+// false if it lies outside the root
+func (h *sftpHandler) confine(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(h.root, dir)
+	if err != nil || !isWithin(h.root, dir) {
+		// The shell may report its directory with symlinks resolved
+		real, err := evalExisting(dir)
+		if err != nil || !isWithin(h.realRoot, real) {
+			return "", false
+		}
+		rel, _ = filepath.Rel(h.realRoot, real)
+	}
+	local, err := h.resolve(filepath.ToSlash(rel), true)
+	return local, err == nil
+}
+
+// hasPrefixFold reports whether s starts with prefix, ignoring case on Windows
+func hasPrefixFold(s, prefix string) bool {
+	if runtime.GOOS == "windows" {
+		return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+	}
+	return strings.HasPrefix(s, prefix)
+}
+
+// sortedCandidates returns the found names sorted, at most maxCompletions of them
+func sortedCandidates(found map[string]bool) []string {
+	candidates := make([]string, 0, len(found))
+	for name := range found {
+		candidates = append(candidates, name)
+	}
+	sort.Strings(candidates)
+	if len(candidates) > maxCompletions {
+		candidates = candidates[:maxCompletions]
+	}
+	return candidates
+}
@@ -0,0 +1,65 @@
+package network
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestCompletePathRoot(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{filepath.Join(root, "docs"), filepath.Join(root, "data"), outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{filepath.Join(root, "dump.txt"), filepath.Join(outside, "secret.txt")} {
+		if err := os.WriteFile(file, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sep := string(filepath.Separator)
+	handler, err := newSFTPHandler(root, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		word string
+		cwd  string
+		want []string
+	}{
+		{"relative", "d", root, []string{"data" + sep, "docs" + sep, "dump.txt"}},
+		{"subdirectory", "docs/", root, nil},
+		{"absolute inside", root + "/du", base, []string{root + "/dump.txt"}},
+		{"absolute outside", outside + "/", root, nil},
+		{"parent directory", "../outside/", root, nil},
+		{"cwd outside", "s", outside, nil},
+		{"symlink outside", "escape/", root, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := completePath(tt.word, tt.cwd, handler); !slices.Equal(got, tt.want) {
+				t.Errorf("completed %q to %q, want %q", tt.word, got, tt.want)
+			}
+		})
+	}
+
+	// Without a root, as for accounts with a shell, any directory is completed
+	if got := completePath(outside+"/s", root, nil); !slices.Equal(got, []string{outside + "/secret.txt"}) {
+		t.Errorf("unconfined completion gave %q", got)
+	}
+	if got := completePath("../outside/", root, nil); !slices.Equal(got, []string{"../outside/secret.txt"}) {
+		t.Errorf("unconfined relative completion gave %q", got)
+	}
+}
@@ -0,0 +1,440 @@
+package network
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// sessionsSubsystem is the SSH subsystem the admin lists, creates and kills detachable
+// shell sessions through
+const sessionsSubsystem = "adminadmin-sessions"
+
+// SessionEnvVar is the environment variable a client sets (SSH "env" request) before
+// requesting a shell to attach to a detachable session instead of starting a new
+// shell. The session's shell has it set to its own ID.
+const SessionEnvVar = "ADMINADMIN_SESSION"
+
+const (
+	maxDetachableSessions = 16        // Per worker
+	maxScrollback         = 256 << 10 // Output kept per session and replayed on attach
+	scrollbackLineSlack   = 4 << 10   // How far trimming looks for a line break to cut at
+	detachableKillWait    = 3 * time.Second
+)
+
+// DetachableSessionInfo describes a detachable shell session running on a worker
+type DetachableSessionInfo struct {
+	ID           string    `json:"id"`
+	Owner        string    `json:"owner"`
+	Started      time.Time `json:"started"`
+	Attached     bool      `json:"attached"`
+	AttachedFrom string    `json:"attached_from,omitempty"` // Remote address of the attached admin
+	LastDetached time.Time `json:"last_detached"`
+	Cols         uint32    `json:"cols"`
+	Rows         uint32    `json:"rows"`
+}
+
+// sessionsRequest is sent by the client after opening the sessions subsystem
+type sessionsRequest struct {
+	Op   string `json:"op"`             // "list", "create" or "kill"
+	ID   string `json:"id,omitempty"`   // For "kill"
+	Term string `json:"term,omitempty"` // For "create"
+	Cols uint32 `json:"cols,omitempty"`
+	Rows uint32 `json:"rows,omitempty"`
+}
+
+// sessionsResponse is the server's single reply to a sessionsRequest
+type sessionsResponse struct {
+	Sessions []DetachableSessionInfo `json:"sessions,omitempty"` // For "list"
+	ID       string                  `json:"id,omitempty"`       // For "create"
+	Error    string                  `json:"error,omitempty"`
+}
+
+// detachableSession is a shell on a PTY that outlives the SSH channels attached to it.
+// Its output is kept in a scrollback buffer while no channel is attached.
+type detachableSession struct {
+	id      string
+	owner   string
+	started time.Time
+	cmd     *exec.Cmd
+	tty     *os.File
+	rec     *sessionRecorder // Records the whole session across attachments, nil if off
+	recName string
+	done    chan struct{} // Closed once the shell exited
+	waitErr error         // Set before done is closed
+
+	mu           sync.Mutex
+	scrollback   []byte
+	attached     *sessionAttachment
+	attachedFrom string
+	lastDetached time.Time
+	cols, rows   uint32
+}
+
+// sessionAttachment is one channel attached to a detachable session. Its mutex keeps
+// the scrollback replay ahead of live output.
+type sessionAttachment struct {
+	mu      sync.Mutex
+	channel ssh.Channel
+}
+
+func (a *sessionAttachment) write(p []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.channel.Write(p)
+}
+
+// detachableSessions is a worker's registry of detachable sessions
+type detachableSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*detachableSession
+}
+
+func newDetachableSessions() *detachableSessions {
+	return &detachableSessions{sessions: make(map[string]*detachableSession)}
+}
+
+// lookup returns the running session with the given ID if it belongs to owner
+func (r *detachableSessions) lookup(id, owner string) *detachableSession {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d := r.sessions[id]
+	if d == nil || d.owner != owner {
+		return nil
+	}
+	return d
+}
+
+// list describes owner's sessions, oldest first
+func (r *detachableSessions) list(owner string) []DetachableSessionInfo {
+	r.mu.Lock()
+	var owned []*detachableSession
+	for _, d := range r.sessions {
+		if d.owner == owner {
+			owned = append(owned, d)
+		}
+	}
+	r.mu.Unlock()
+
+	infos := make([]DetachableSessionInfo, 0, len(owned))
+	for _, d := range owned {
+		infos = append(infos, d.info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Started.Before(infos[j].Started) })
+	return infos
+}
+
+// create starts owner's shell on a new PTY and registers it. The session's output, and
+// the input of every channel attached to it, go to rec until the shell exits.
+func (r *detachableSessions) create(owner string, cmd *exec.Cmd, term string, cols, rows uint32, rec *sessionRecorder, recName string) (*detachableSession, error) {
+	if !ptySupported {
+		return nil, errors.New("detachable sessions need pseudo-terminals, which this worker doesn't support")
+	}
+	id, err := newDetachableID()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.sessions) >= maxDetachableSessions {
+		return nil, fmt.Errorf("worker already runs %d detachable sessions", len(r.sessions))
+	}
+
+	cmd.Env = append(os.Environ(), SessionEnvVar+"="+id)
+	if term != "" {
+		cmd.Env = append(cmd.Env, "TERM="+term)
+	}
+	tty, err := startPTY(cmd, cols, rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start terminal: %w", err)
+	}
+	d := &detachableSession{
+		id:           id,
+		owner:        owner,
+		started:      time.Now(),
+		cmd:          cmd,
+		tty:          tty,
+		rec:          rec,
+		recName:      recName,
+		done:         make(chan struct{}),
+		lastDetached: time.Now(),
+		cols:         cols,
+		rows:         rows,
+	}
+	r.sessions[id] = d
+	go d.pump(func() {
+		r.mu.Lock()
+		delete(r.sessions, id)
+		r.mu.Unlock()
+	})
+	log.Printf("SSH: Detachable session %s started for %s (pid %d)\n", id, owner, cmd.Process.Pid)
+	return d, nil
+}
+
+// killAll ends every session, e.g. when the server stops
+func (r *detachableSessions) killAll() {
+	r.mu.Lock()
+	all := make([]*detachableSession, 0, len(r.sessions))
+	for _, d := range r.sessions {
+		all = append(all, d)
+	}
+	r.mu.Unlock()
+	for _, d := range all {
+		go d.kill()
+	}
+}
+
+// newDetachableID returns a short random session ID, e.g. "3fa9c2e1"
+func newDetachableID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// pump copies the shell's output into the scrollback and to the attached channel until
+// the shell exits, then calls onExit
+func (d *detachableSession) pump(onExit func()) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := d.tty.Read(buf)
+		if n > 0 {
+			d.rec.write("o", buf[:n])
+			d.mu.Lock()
+			d.appendScrollback(buf[:n])
+			a := d.attached
+			d.mu.Unlock()
+			if a != nil {
+				a.write(buf[:n])
+			}
+		}
+		if err != nil {
+			// Reading fails once the shell (and everything holding the terminal) exits
+			break
+		}
+	}
+	d.waitErr = d.cmd.Wait()
+	d.tty.Close()
+	d.rec.Close()
+	close(d.done)
+	onExit()
+	log.Printf("SSH: Detachable session %s of %s ended\n", d.id, d.owner)
+}
+
+// appendScrollback adds output to the scrollback, dropping the oldest output beyond
+// maxScrollback, at a line break when there is one close by. Called with d.mu held.
+func (d *detachableSession) appendScrollback(p []byte) {
+	d.scrollback = append(d.scrollback, p...)
+	excess := len(d.scrollback) - maxScrollback
+	if excess <= 0 {
+		return
+	}
+	rest := d.scrollback[excess:]
+	if i := bytes.IndexByte(rest[:min(len(rest), scrollbackLineSlack)], '\n'); i >= 0 {
+		rest = rest[i+1:]
+	}
+	d.scrollback = append(d.scrollback[:0], rest...)
+}
+
+// info describes the session
+func (d *detachableSession) info() DetachableSessionInfo {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	info := DetachableSessionInfo{
+		ID:       d.id,
+		Owner:    d.owner,
+		Started:  d.started,
+		Attached: d.attached != nil,
+		Cols:     d.cols,
+		Rows:     d.rows,
+	}
+	if d.attached != nil {
+		info.AttachedFrom = d.attachedFrom
+	} else {
+		info.LastDetached = d.lastDetached
+	}
+	return info
+}
+
+// attach makes channel the session's terminal: the scrollback is replayed to it, and a
+// channel attached before is told and closed, so a session has one terminal at a time
+func (d *detachableSession) attach(channel ssh.Channel, from string) *sessionAttachment {
+	a := &sessionAttachment{channel: channel}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	d.mu.Lock()
+	replay := append([]byte(nil), d.scrollback...)
+	previous := d.attached
+	d.attached = a
+	d.attachedFrom = from
+	d.mu.Unlock()
+
+	if previous != nil {
+		io.WriteString(previous.channel.Stderr(), fmt.Sprintf("\r\nadminadmin: session %s was attached from %s\r\n", d.id, from))
+		previous.channel.Close()
+	}
+	channel.Write(replay)
+	return a
+}
+
+// detach clears a, unless another channel has been attached since
+func (d *detachableSession) detach(a *sessionAttachment) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.attached == a {
+		d.attached = nil
+		d.attachedFrom = ""
+		d.lastDetached = time.Now()
+	}
+}
+
+// resize applies the attached terminal's size
+func (d *detachableSession) resize(cols, rows uint32) {
+	d.mu.Lock()
+	d.cols, d.rows = cols, rows
+	d.mu.Unlock()
+	d.rec.resize(cols, rows)
+	if err := resizePTY(d.tty, cols, rows); err != nil {
+		log.Printf("SSH: Failed to resize PTY: %v\n", err)
+	}
+}
+
+// kill hangs up the shell, and kills it if it is still running after detachableKillWait
+func (d *detachableSession) kill() {
+	sig, ok := sshSignals["HUP"]
+	if !ok {
+		sig = os.Kill
+	}
+	if err := signalProcess(d.cmd.Process, sig); err != nil {
+		log.Printf("SSH: Failed to hang up detachable session %s: %v\n", d.id, err)
+	}
+	select {
+	case <-d.done:
+	case <-time.After(detachableKillWait):
+		signalProcess(d.cmd.Process, os.Kill)
+	}
+}
+
+// serveAttached connects a session channel to a detachable session until the channel
+// closes (the client detached) or the shell exits, whose status is then sent. Input is
+// recorded here; output is recorded once by pump, not per attached channel.
+func (s *SSHServer) serveAttached(channel ssh.Channel, d *detachableSession, from string) {
+	a := d.attach(channel, from)
+	detached := make(chan struct{})
+	go func() {
+		io.Copy(d.tty, &recordingChannel{Channel: channel, rec: d.rec})
+		d.detach(a)
+		close(detached)
+	}()
+
+	select {
+	case <-d.done:
+		d.detach(a)
+		sendExitStatus(channel, d.cmd.ProcessState, d.waitErr)
+	case <-detached:
+		log.Printf("SSH: Detached from session %s\n", d.id)
+	}
+}
+
+// serveSessions answers one detachable session request on a session channel. Accounts
+// only see and control their own sessions.
+func (s *SSHServer) serveSessions(conn *ssh.ServerConn, channel ssh.Channel, user SSHUser) {
+	var req sessionsRequest
+	if err := json.NewDecoder(channel).Decode(&req); err != nil {
+		sendExitStatus(channel, nil, err)
+		return
+	}
+
+	var resp sessionsResponse
+	var err error
+	switch req.Op {
+	case "list":
+		resp.Sessions = s.detachable.list(conn.User())
+	case "create":
+		if !user.AllowsShell() {
+			err = errors.New("this account may not open a shell")
+			break
+		}
+		cmd := shellCommand()
+		cmd.Dir = newSessionState(user).cwd
+		// Recorded like a normal shell, for as long as the session lives
+		rec, recName := s.GetAuditLog().startRecording(auditSessionID(conn), conn.User()+": detachable shell",
+			&ptyRequestMsg{Term: req.Term, Cols: req.Cols, Rows: req.Rows})
+		var d *detachableSession
+		d, err = s.detachable.create(conn.User(), cmd, req.Term, req.Cols, req.Rows, rec, recName)
+		if err != nil {
+			rec.Close()
+			break
+		}
+		resp.ID = d.id
+		s.recordAudit(conn, AuditEvent{Event: AuditShell, Detail: "started detachable session " + d.id, Recording: recName})
+	case "kill":
+		d := s.detachable.lookup(req.ID, conn.User())
+		if d == nil {
+			err = fmt.Errorf("no session %q", req.ID)
+			break
+		}
+		s.recordAudit(conn, AuditEvent{Event: AuditShell, Detail: "killed detachable session " + d.id})
+		d.kill()
+	default:
+		err = fmt.Errorf("unknown sessions request %q", req.Op)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	json.NewEncoder(channel).Encode(resp)
+	sendExitStatus(channel, nil, nil)
+}
+
+// DetachableSessions lists the logged-in account's detachable sessions on the worker
+func (c *SSHClient) DetachableSessions() ([]DetachableSessionInfo, error) {
+	var resp sessionsResponse
+	if err := c.callSubsystem(sessionsSubsystem, sessionsRequest{Op: "list"}, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("%s", resp.Error)
+	}
+	return resp.Sessions, nil
+}
+
+// NewDetachableSession starts a detachable shell on the worker and returns its ID; it
+// keeps running until it exits or is killed, whether or not anyone is attached
+func (c *SSHClient) NewDetachableSession(term string, cols, rows int) (string, error) {
+	var resp sessionsResponse
+	req := sessionsRequest{Op: "create", Term: term, Cols: uint32(cols), Rows: uint32(rows)}
+	if err := c.callSubsystem(sessionsSubsystem, req, &resp); err != nil {
+		return "", err
+	}
+	if resp.Error != "" {
+		return "", fmt.Errorf("%s", resp.Error)
+	}
+	return resp.ID, nil
+}
+
+// KillDetachableSession ends a detachable session
+func (c *SSHClient) KillDetachableSession(id string) error {
+	var resp sessionsResponse
+	if err := c.callSubsystem(sessionsSubsystem, sessionsRequest{Op: "kill", ID: id}, &resp); err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("%s", resp.Error)
+	}
+	return nil
+}
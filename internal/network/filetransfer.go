@@ -0,0 +1,299 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// transferChunkSize is how much is copied between progress reports and cancellation checks
+const transferChunkSize = 256 * 1024
+
+// RemoteFile describes an entry in a worker directory
+type RemoteFile struct {
+	Name    string
+	Path    string
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
+	IsDir   bool
+}
+
+// TransferProgress reports how far an upload or download is
+type TransferProgress struct {
+	File       string // File being copied right now
+	Done       int64  // Bytes copied so far
+	Total      int64  // Bytes to copy in total
+	FilesDone  int
+	FilesTotal int
+}
+
+// RemoteFiles browses and transfers files on a worker over the worker's SFTP subsystem
+type RemoteFiles struct {
+	client *sftp.Client
+}
+
+// OpenFiles starts an SFTP session on the SSH connection
+func (c *SSHClient) OpenFiles() (*RemoteFiles, error) {
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+	if client == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	sc, err := sftp.NewClient(client)
+	if err != nil {
+		return nil, fmt.Errorf("file access unavailable (is SFTP enabled on the worker?): %w", err)
+	}
+	log.Println("SSH Client: SFTP session opened")
+	return &RemoteFiles{client: sc}, nil
+}
+
+// Close ends the SFTP session
+func (f *RemoteFiles) Close() error {
+	return f.client.Close()
+}
+
+// Home returns the directory the session starts in
+func (f *RemoteFiles) Home() string {
+	wd, err := f.client.Getwd()
+	if err != nil || wd == "" {
+		return "/"
+	}
+	return wd
+}
+
+// List returns the entries of a directory, folders first, then by name
+func (f *RemoteFiles) List(dir string) ([]RemoteFile, error) {
+	infos, err := f.client.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]RemoteFile, 0, len(infos))
+	for _, info := range infos {
+		files = append(files, RemoteFile{
+			Name:    info.Name(),
+			Path:    path.Join(dir, info.Name()),
+			Size:    info.Size(),
+			Mode:    info.Mode(),
+			ModTime: info.ModTime(),
+			IsDir:   info.IsDir(),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].IsDir != files[j].IsDir {
+			return files[i].IsDir
+		}
+		return strings.ToLower(files[i].Name) < strings.ToLower(files[j].Name)
+	})
+	return files, nil
+}
+
+// Mkdir creates a directory
+func (f *RemoteFiles) Mkdir(p string) error {
+	return f.client.Mkdir(p)
+}
+
+// Rename renames or moves a file or directory
+func (f *RemoteFiles) Rename(from, to string) error {
+	return f.client.Rename(from, to)
+}
+
+// Remove deletes a file, or a directory with everything in it
+func (f *RemoteFiles) Remove(p string) error {
+	info, err := f.client.Lstat(p)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return f.client.Remove(p)
+	}
+	return f.client.RemoveAll(p)
+}
+
+// transfer tracks a running upload or download
+type transfer struct {
+	ctx      context.Context
+	progress func(TransferProgress)
+	state    TransferProgress
+	last     time.Time
+}
+
+// report calls the progress callback, at most every 100ms unless forced
+func (t *transfer) report(force bool) {
+	if t.progress == nil {
+		return
+	}
+	if !force && time.Since(t.last) < 100*time.Millisecond {
+		return
+	}
+	t.last = time.Now()
+	t.progress(t.state)
+}
+
+// copy copies one file in chunks, checking for cancellation between chunks
+func (t *transfer) copy(dst io.Writer, src io.Reader, name string) error {
+	t.state.File = name
+	t.report(true)
+	buf := make([]byte, transferChunkSize)
+	for {
+		if err := t.ctx.Err(); err != nil {
+			return err
+		}
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			t.state.Done += int64(n)
+			t.report(false)
+		}
+		if err == io.EOF {
+			t.state.FilesDone++
+			t.report(true)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Upload copies a local file or folder into a worker directory. It stops with
+// ctx.Err() when ctx is cancelled; files copied so far are kept.
+func (f *RemoteFiles) Upload(ctx context.Context, localPath, remoteDir string, progress func(TransferProgress)) error {
+	t := &transfer{ctx: ctx, progress: progress}
+
+	// Size everything up first so progress is meaningful
+	err := filepath.Walk(localPath, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			t.state.Total += info.Size()
+			t.state.FilesTotal++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	base := filepath.Dir(localPath)
+	err = filepath.Walk(localPath, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, p)
+		if err != nil {
+			return err
+		}
+		target := path.Join(remoteDir, filepath.ToSlash(rel))
+
+		switch {
+		case info.IsDir():
+			if err := f.client.MkdirAll(target); err != nil {
+				return fmt.Errorf("failed to create %s: %w", target, err)
+			}
+		case info.Mode().IsRegular():
+			src, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer src.Close()
+			dst, err := f.client.Create(target)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", target, err)
+			}
+			if err := t.copy(dst, src, rel); err != nil {
+				dst.Close()
+				return err
+			}
+			if err := dst.Close(); err != nil {
+				return err
+			}
+		default:
+			log.Printf("SSH Client: Skipping upload of special file %s\n", p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("SSH Client: Uploaded %s to %s (%d files, %d bytes)\n", localPath, remoteDir, t.state.FilesDone, t.state.Done)
+	return nil
+}
+
+// Download copies a worker file or folder into a local directory. It stops with
+// ctx.Err() when ctx is cancelled; files copied so far are kept.
+func (f *RemoteFiles) Download(ctx context.Context, remotePath, localDir string, progress func(TransferProgress)) error {
+	t := &transfer{ctx: ctx, progress: progress}
+
+	walker := f.client.Walk(remotePath)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return err
+		}
+		if walker.Stat().Mode().IsRegular() {
+			t.state.Total += walker.Stat().Size()
+			t.state.FilesTotal++
+		}
+	}
+
+	base := path.Dir(remotePath)
+	walker = f.client.Walk(remotePath)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(walker.Path(), base), "/")
+		target := filepath.Join(localDir, filepath.FromSlash(rel))
+
+		info := walker.Stat()
+		switch {
+		case info.IsDir():
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			if err := f.downloadFile(t, walker.Path(), target, rel); err != nil {
+				return err
+			}
+		}
+	}
+	log.Printf("SSH Client: Downloaded %s to %s (%d files, %d bytes)\n", remotePath, localDir, t.state.FilesDone, t.state.Done)
+	return nil
+}
+
+func (f *RemoteFiles) downloadFile(t *transfer, remotePath, localPath, name string) error {
+	src, err := f.client.Open(remotePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+	if err := t.copy(dst, src, name); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
@@ -0,0 +1,263 @@
+package network
+
+import (
+	"adminadmin/internal/config"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const forwardingSettingsFile = "forwarding.json"
+
+// ForwardPermission says which kinds of port forwarding a user may use
+type ForwardPermission struct {
+	Local  bool `json:"local"`  // direct-tcpip: the client reaches ports the worker can connect to
+	Remote bool `json:"remote"` // tcpip-forward: the worker listens on its loopback for the client
+}
+
+// ForwardingSettings configures port forwarding on the worker's SSH server
+type ForwardingSettings struct {
+	Default ForwardPermission            `json:"default"` // Unrestricted users without an entry of their own
+	Users   map[string]ForwardPermission `json:"users"`
+}
+
+// DefaultForwardingSettings returns the settings used until the worker changes them:
+// local forwarding allowed for unrestricted accounts, remote forwarding refused
+func DefaultForwardingSettings() ForwardingSettings {
+	return ForwardingSettings{Default: ForwardPermission{Local: true}}
+}
+
+// For returns the permission of a user. Accounts limited to some commands only get
+// the permission of their own entry: forwarding would let them reach anything the
+// worker can, around their command policy.
+func (f ForwardingSettings) For(user SSHUser) ForwardPermission {
+	if perm, ok := f.Users[user.Username]; ok {
+		return perm
+	}
+	if !user.Unrestricted() {
+		return ForwardPermission{}
+	}
+	return f.Default
+}
+
+// LoadForwardingSettings loads the port forwarding settings, falling back to the defaults
+func LoadForwardingSettings() (ForwardingSettings, error) {
+	settings := DefaultForwardingSettings()
+	err := config.LoadJSON(forwardingSettingsFile, &settings)
+	return settings, err
+}
+
+// SaveForwardingSettings persists the port forwarding settings
+func SaveForwardingSettings(settings ForwardingSettings) error {
+	return config.SaveJSON(forwardingSettingsFile, settings)
+}
+
+// directTCPIPMsg is the payload of a "direct-tcpip" channel open (RFC 4254 section 7.2)
+type directTCPIPMsg struct {
+	Host       string
+	Port       uint32
+	OriginAddr string
+	OriginPort uint32
+}
+
+// tcpipForwardMsg is the payload of "tcpip-forward" and "cancel-tcpip-forward" requests
+type tcpipForwardMsg struct {
+	BindAddr string
+	BindPort uint32
+}
+
+// tcpipForwardReply is the reply to a "tcpip-forward" request for port 0
+type tcpipForwardReply struct {
+	Port uint32
+}
+
+// forwardedTCPIPMsg is the payload of a "forwarded-tcpip" channel open
+type forwardedTCPIPMsg struct {
+	Addr       string
+	Port       uint32
+	OriginAddr string
+	OriginPort uint32
+}
+
+// handleDirectTCPIP serves a local forward: it connects to the requested address
+// from the worker and pipes the channel to it
+func (s *SSHServer) handleDirectTCPIP(conn *ssh.ServerConn, newChannel ssh.NewChannel, sessions *sshConnSessions) {
+	if user, ok := s.currentUser(conn); !ok || !s.GetForwardingSettings().For(user).Local {
+		log.Printf("SSH: Local forwarding refused for %s\n", conn.User())
+		s.recordAudit(conn, AuditEvent{Event: AuditForward, Detail: "refused"})
+		newChannel.Reject(ssh.Prohibited, "port forwarding is not allowed")
+		return
+	}
+	var msg directTCPIPMsg
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &msg); err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, "invalid direct-tcpip request")
+		return
+	}
+
+	target := net.JoinHostPort(msg.Host, strconv.Itoa(int(msg.Port)))
+	s.recordAudit(conn, AuditEvent{Event: AuditForward, Detail: target})
+	tcpConn, err := net.DialTimeout("tcp", target, 10*time.Second)
+	if err != nil {
+		log.Printf("SSH: Local forward for %s to %s failed: %v\n", conn.User(), target, err)
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		tcpConn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	log.Printf("SSH: Local forward for %s to %s\n", conn.User(), target)
+	pipe(sessions.wrap(channel), tcpConn)
+}
+
+// remoteForwards holds the listeners opened for one connection's remote forwards
+type remoteForwards struct {
+	mu        sync.Mutex
+	listeners map[string]net.Listener // Keyed by the address the client asked for and the port bound
+}
+
+// closeAll stops every remote forward of the connection
+func (r *remoteForwards) closeAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for addr, l := range r.listeners {
+		l.Close()
+		delete(r.listeners, addr)
+	}
+}
+
+// handleGlobalRequests serves the connection's out-of-band requests until it closes
+func (s *SSHServer) handleGlobalRequests(conn *ssh.ServerConn, reqs <-chan *ssh.Request) {
+	forwards := &remoteForwards{listeners: make(map[string]net.Listener)}
+	defer forwards.closeAll()
+
+	for req := range reqs {
+		switch req.Type {
+		case "tcpip-forward":
+			s.startRemoteForward(conn, req, forwards)
+		case "cancel-tcpip-forward":
+			var msg tcpipForwardMsg
+			if ssh.Unmarshal(req.Payload, &msg) != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			key := net.JoinHostPort(msg.BindAddr, strconv.Itoa(int(msg.BindPort)))
+			forwards.mu.Lock()
+			l, ok := forwards.listeners[key]
+			delete(forwards.listeners, key)
+			forwards.mu.Unlock()
+			if ok {
+				l.Close()
+			}
+			req.Reply(ok, nil)
+		default:
+			if req.WantReply {
+				req.Reply(false, nil)
+			}
+		}
+	}
+}
+
+// startRemoteForward listens on the worker for a "tcpip-forward" request and opens a
+// "forwarded-tcpip" channel back to the client for every connection. Like OpenSSH
+// without GatewayPorts, the listener is bound to loopback whatever address was asked for.
+func (s *SSHServer) startRemoteForward(conn *ssh.ServerConn, req *ssh.Request, forwards *remoteForwards) {
+	var msg tcpipForwardMsg
+	if ssh.Unmarshal(req.Payload, &msg) != nil {
+		req.Reply(false, nil)
+		return
+	}
+	if user, ok := s.currentUser(conn); !ok || !s.GetForwardingSettings().For(user).Remote {
+		log.Printf("SSH: Remote forwarding refused for %s\n", conn.User())
+		s.recordAudit(conn, AuditEvent{Event: AuditRemoteForward, Detail: "refused"})
+		req.Reply(false, nil)
+		return
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(msg.BindPort))))
+	if err != nil {
+		log.Printf("SSH: Remote forward for %s on port %d failed: %v\n", conn.User(), msg.BindPort, err)
+		req.Reply(false, nil)
+		return
+	}
+	port := uint32(listener.Addr().(*net.TCPAddr).Port)
+	// Port 0 asks for any free port; the client cancels with the one in our reply
+	key := net.JoinHostPort(msg.BindAddr, strconv.Itoa(int(port)))
+
+	forwards.mu.Lock()
+	if _, exists := forwards.listeners[key]; exists {
+		forwards.mu.Unlock()
+		listener.Close()
+		req.Reply(false, nil)
+		return
+	}
+	forwards.listeners[key] = listener
+	forwards.mu.Unlock()
+
+	if msg.BindPort == 0 {
+		req.Reply(true, ssh.Marshal(tcpipForwardReply{Port: port}))
+	} else {
+		req.Reply(true, nil)
+	}
+	log.Printf("SSH: Remote forward for %s listening on %s\n", conn.User(), listener.Addr())
+	s.recordAudit(conn, AuditEvent{Event: AuditRemoteForward, Detail: listener.Addr().String()})
+
+	go func() {
+		for {
+			tcpConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				origin := tcpConn.RemoteAddr().(*net.TCPAddr)
+				channel, requests, err := conn.OpenChannel("forwarded-tcpip", ssh.Marshal(forwardedTCPIPMsg{
+					Addr:       msg.BindAddr,
+					Port:       port,
+					OriginAddr: origin.IP.String(),
+					OriginPort: uint32(origin.Port),
+				}))
+				if err != nil {
+					log.Printf("SSH: Client refused forwarded connection: %v\n", err)
+					tcpConn.Close()
+					return
+				}
+				go ssh.DiscardRequests(requests)
+				pipe(channel, tcpConn)
+			}()
+		}
+	}()
+}
+
+// pipe copies between two connections until both directions are done, then closes them
+func pipe(a io.ReadWriteCloser, b io.ReadWriteCloser) {
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(a, b)
+		closeWrite(a)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(b, a)
+		closeWrite(b)
+		done <- struct{}{}
+	}()
+	<-done
+	<-done
+	a.Close()
+	b.Close()
+}
+
+// closeWrite half-closes a connection so the other side sees EOF, if it supports that
+func closeWrite(c io.Closer) {
+	if cw, ok := c.(interface{ CloseWrite() error }); ok {
+		cw.CloseWrite()
+	}
+}
@@ -0,0 +1,28 @@
+package network
+
+import "testing"
+
+func TestForwardingPermissionFor(t *testing.T) {
+	settings := DefaultForwardingSettings()
+	settings.Users = map[string]ForwardPermission{
+		"tunnel": {Local: true},
+		"locked": {},
+	}
+	tests := []struct {
+		name string
+		user SSHUser
+		want ForwardPermission
+	}{
+		{"shell account gets the default", SSHUser{Username: "admin", Policy: PolicyShell}, ForwardPermission{Local: true}},
+		{"exec account gets the default", SSHUser{Username: "ci", Policy: PolicyExec}, ForwardPermission{Local: true}},
+		{"allowlist account gets nothing by default", SSHUser{Username: "kiosk", Policy: PolicyAllowlist}, ForwardPermission{}},
+		{"disabled account gets nothing", SSHUser{Username: "old", Policy: PolicyShell, Disabled: true}, ForwardPermission{}},
+		{"own entry allows an allowlist account", SSHUser{Username: "tunnel", Policy: PolicyAllowlist}, ForwardPermission{Local: true}},
+		{"own entry refuses a shell account", SSHUser{Username: "locked", Policy: PolicyShell}, ForwardPermission{}},
+	}
+	for _, tt := range tests {
+		if got := settings.For(tt.user); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package network
+
+import "adminadmin/internal/config"
+
+// historyFile stores the commands run in command mode terminals, keyed by worker hostname
+const historyFile = "command_history.json"
+
+// MaxHistory is how many commands are kept per worker
+const MaxHistory = 1000
+
+// LoadCommandHistory loads the command history of every worker, oldest command first
+func LoadCommandHistory() (map[string][]string, error) {
+	history := make(map[string][]string)
+	err := config.LoadJSON(historyFile, &history)
+	return history, err
+}
+
+// SaveCommandHistory persists the command history
+func SaveCommandHistory(history map[string][]string) error {
+	return config.SaveJSON(historyFile, history)
+}
+
+// AppendHistory adds cmd to the end of history unless it repeats the last command,
+// dropping the oldest commands beyond MaxHistory
+func AppendHistory(history []string, cmd string) []string {
+	if len(history) > 0 && history[len(history)-1] == cmd {
+		return history
+	}
+	history = append(history, cmd)
+	if len(history) > MaxHistory {
+		history = history[len(history)-MaxHistory:]
+	}
+	return history
+}
@@ -0,0 +1,143 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// keyPairingTimeout bounds how long the admin waits for the worker's user to answer
+const keyPairingTimeout = 2 * time.Minute
+
+// ================== Admin side ==================
+
+// RequestKeyAuthorization asks the worker to add publicKey to the SSH account username.
+// The worker's user has to confirm, so this can block for a while.
+func (a *AdminClient) RequestKeyAuthorization(publicKey ssh.PublicKey, username, comment string) error {
+	if !a.connected || a.writer == nil {
+		return fmt.Errorf("not connected")
+	}
+
+	a.keyMu.Lock()
+	if a.keyResult != nil {
+		a.keyMu.Unlock()
+		return fmt.Errorf("key pairing already in progress")
+	}
+	result := make(chan AuthorizeKeyResultPayload, 1)
+	a.keyResult = result
+	a.keyMu.Unlock()
+
+	defer func() {
+		a.keyMu.Lock()
+		a.keyResult = nil
+		a.keyMu.Unlock()
+	}()
+
+	payload := AuthorizeKeyPayload{
+		PublicKey: string(ssh.MarshalAuthorizedKey(publicKey)),
+		Comment:   comment,
+		Username:  username,
+	}
+	if err := a.writer.send(MsgTypeAuthorizeKey, payload); err != nil {
+		return fmt.Errorf("failed to send key: %w", err)
+	}
+	log.Printf("ADMIN: Sent SSH key %s for authorization\n", ssh.FingerprintSHA256(publicKey))
+
+	select {
+	case r := <-result:
+		if r.Error != "" {
+			return fmt.Errorf("worker could not add the key: %s", r.Error)
+		}
+		if !r.Accepted {
+			return fmt.Errorf("the key was declined on the worker")
+		}
+		return nil
+	case <-time.After(keyPairingTimeout):
+		return fmt.Errorf("no answer from the worker within %s", keyPairingTimeout)
+	}
+}
+
+// dispatchKeyResult hands an authorize-key reply to the waiting request, if any
+func (a *AdminClient) dispatchKeyResult(msg Message) {
+	var r AuthorizeKeyResultPayload
+	if err := json.Unmarshal(msg.Payload, &r); err != nil {
+		log.Printf("ADMIN ERROR: Invalid key authorization result: %v\n", err)
+		return
+	}
+	a.keyMu.Lock()
+	ch := a.keyResult
+	a.keyMu.Unlock()
+	if ch == nil {
+		return
+	}
+	select {
+	case ch <- r:
+	default:
+	}
+}
+
+// ================== Worker side ==================
+
+// handleAuthorizeKey asks the local user (via onKeyRequest) whether to trust the admin's
+// key and adds it to the requested account if they agree. Runs in its own goroutine.
+func (w *WorkerServer) handleAuthorizeKey(writer *messageWriter, adminHostname string, raw json.RawMessage) {
+	reply := func(r AuthorizeKeyResultPayload) {
+		if err := writer.send(MsgTypeAuthorizeKeyResult, r); err != nil {
+			log.Printf("WORKER: Failed to send key authorization result: %v\n", err)
+		}
+	}
+
+	var req AuthorizeKeyPayload
+	if err := json.Unmarshal(raw, &req); err != nil {
+		reply(AuthorizeKeyResultPayload{Error: "invalid request"})
+		return
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(req.PublicKey))
+	if err != nil {
+		reply(AuthorizeKeyResultPayload{Error: "invalid public key"})
+		return
+	}
+	fingerprint := ssh.FingerprintSHA256(key)
+	username := req.Username
+	if username == "" {
+		username = DefaultSSHUsername
+	}
+
+	w.callbackMu.Lock()
+	onKeyRequest := w.onKeyRequest
+	users := w.sshUsers
+	w.callbackMu.Unlock()
+	if users == nil {
+		reply(AuthorizeKeyResultPayload{Error: "SSH accounts unavailable"})
+		return
+	}
+	if _, ok := users.Get(username); !ok {
+		reply(AuthorizeKeyResultPayload{Error: fmt.Sprintf("no SSH account named %s", username)})
+		return
+	}
+	if users.HasKey(username, key) {
+		log.Printf("WORKER: SSH key %s from %s is already authorized for %s\n", fingerprint, adminHostname, username)
+		reply(AuthorizeKeyResultPayload{Accepted: true})
+		return
+	}
+
+	if onKeyRequest == nil || !onKeyRequest(adminHostname, username, fingerprint) {
+		log.Printf("WORKER: SSH key %s from %s declined\n", fingerprint, adminHostname)
+		reply(AuthorizeKeyResultPayload{Accepted: false})
+		return
+	}
+
+	comment := req.Comment
+	if comment == "" {
+		comment = adminHostname
+	}
+	if err := users.AddKey(username, key, comment); err != nil {
+		log.Printf("WORKER ERROR: Failed to add SSH key: %v\n", err)
+		reply(AuthorizeKeyResultPayload{Error: err.Error()})
+		return
+	}
+	reply(AuthorizeKeyResultPayload{Accepted: true})
+}
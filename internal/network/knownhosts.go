@@ -0,0 +1,153 @@
+package network
+
+import (
+	"adminadmin/internal/config"
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// KnownHostsFile stores the worker host keys the admin has trusted (OpenSSH format)
+const KnownHostsFile = "known_hosts"
+
+// knownHostsMu serializes known_hosts checks, prompts and edits, so parallel
+// connections (e.g. a broadcast) ask about each new host only once
+var knownHostsMu sync.Mutex
+
+// ConfirmHostKeyFunc asks the user whether to trust a host key seen for the first time.
+// It may block until the user answers.
+type ConfirmHostKeyFunc func(host string, key ssh.PublicKey) bool
+
+// KnownHost is one entry of the known_hosts file
+type KnownHost struct {
+	Hosts       []string
+	Key         ssh.PublicKey
+	Fingerprint string
+}
+
+// HostKeyChangedError is returned when a host presents a different key than the trusted one
+type HostKeyChangedError struct {
+	Host        string
+	Fingerprint string   // Key the host presented now
+	Known       []string // Fingerprints trusted for this host
+}
+
+func (e *HostKeyChangedError) Error() string {
+	return fmt.Sprintf("host key for %s has changed (now %s, expected %s)",
+		e.Host, e.Fingerprint, strings.Join(e.Known, ", "))
+}
+
+// ErrHostKeyRejected is returned when the user declines a new host key
+var ErrHostKeyRejected = errors.New("host key not trusted")
+
+// hostKeyCallback verifies host keys against known_hosts, trusting new hosts on first
+// use if confirm agrees and failing hard when a known host's key has changed
+func hostKeyCallback(confirm ConfirmHostKeyFunc) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		knownHostsMu.Lock()
+		defer knownHostsMu.Unlock()
+
+		path := config.Path(KnownHostsFile)
+		// knownhosts.New requires the file to exist
+		if f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0600); err == nil {
+			f.Close()
+		}
+		check, err := knownhosts.New(path)
+		if err != nil {
+			return fmt.Errorf("failed to read known_hosts: %w", err)
+		}
+
+		err = check(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if err == nil || !errors.As(err, &keyErr) {
+			return err
+		}
+
+		fingerprint := ssh.FingerprintSHA256(key)
+		if len(keyErr.Want) > 0 {
+			known := make([]string, len(keyErr.Want))
+			for i, k := range keyErr.Want {
+				known[i] = ssh.FingerprintSHA256(k.Key)
+			}
+			log.Printf("SSH Client: WARNING - host key for %s changed to %s\n", hostname, fingerprint)
+			return &HostKeyChangedError{Host: hostname, Fingerprint: fingerprint, Known: known}
+		}
+
+		if confirm == nil || !confirm(hostname, key) {
+			log.Printf("SSH Client: Host key %s for %s not trusted\n", fingerprint, hostname)
+			return ErrHostKeyRejected
+		}
+		if err := appendKnownHost(path, hostname, key); err != nil {
+			return fmt.Errorf("failed to save host key: %w", err)
+		}
+		log.Printf("SSH Client: Trusted host key %s for %s\n", fingerprint, hostname)
+		return nil
+	}
+}
+
+// appendKnownHost adds a host key line to the known_hosts file
+func appendKnownHost(path, hostname string, key ssh.PublicKey) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key) + "\n")
+	return err
+}
+
+// LoadKnownHosts reads the known_hosts file; a missing file means no entries
+func LoadKnownHosts() ([]KnownHost, error) {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+	return loadKnownHosts()
+}
+
+func loadKnownHosts() ([]KnownHost, error) {
+	data, err := os.ReadFile(config.Path(KnownHostsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []KnownHost
+	for len(bytes.TrimSpace(data)) > 0 {
+		_, addrs, key, _, rest, err := ssh.ParseKnownHosts(data)
+		if err != nil {
+			return hosts, fmt.Errorf("failed to parse known_hosts: %w", err)
+		}
+		hosts = append(hosts, KnownHost{Hosts: addrs, Key: key, Fingerprint: ssh.FingerprintSHA256(key)})
+		data = rest
+	}
+	return hosts, nil
+}
+
+// RemoveKnownHost removes the entry with the given key fingerprint, so the host is
+// treated as new on the next connection
+func RemoveKnownHost(fingerprint string) error {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+
+	hosts, err := loadKnownHosts()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, h := range hosts {
+		if h.Fingerprint == fingerprint {
+			continue
+		}
+		buf.WriteString(knownhosts.Line(h.Hosts, h.Key) + "\n")
+	}
+	return os.WriteFile(config.Path(KnownHostsFile), buf.Bytes(), 0600)
+}
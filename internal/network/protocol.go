@@ -2,19 +2,30 @@ package network
 
 import (
 	"encoding/json"
+	"io"
+	"sync"
 )
 
 // MessageType defines the type of network message
 type MessageType string
 
 const (
-	MsgTypeSystemInfo MessageType = "system_info"
-	MsgTypeMetrics    MessageType = "metrics"
-	MsgTypeAdminInfo  MessageType = "admin_info"
-	MsgTypeCommand    MessageType = "command"
-	MsgTypePing       MessageType = "ping"
-	MsgTypePong       MessageType = "pong"
-	MsgTypeDisconnect MessageType = "disconnect"
+	MsgTypeSystemInfo         MessageType = "system_info"
+	MsgTypeMetrics            MessageType = "metrics"
+	MsgTypeAdminInfo          MessageType = "admin_info"
+	MsgTypeCommand            MessageType = "command"
+	MsgTypePing               MessageType = "ping"
+	MsgTypePong               MessageType = "pong"
+	MsgTypeDisconnect         MessageType = "disconnect"
+	MsgTypeSpeedTest          MessageType = "speed_test"
+	MsgTypeSpeedTestData      MessageType = "speed_test_data"
+	MsgTypeSpeedTestResult    MessageType = "speed_test_result"
+	MsgTypeAuthorizeKey       MessageType = "authorize_key"
+	MsgTypeAuthorizeKeyResult MessageType = "authorize_key_result"
+	MsgTypeSessionWarning     MessageType = "session_warning"
+	MsgTypeSessionEnded       MessageType = "session_ended"
+	MsgTypeScreenRequest      MessageType = "screen_request"
+	MsgTypeScreenFrame        MessageType = "screen_frame"
 )
 
 // Message represents a network message
@@ -35,15 +46,20 @@ type SystemInfoPayload struct {
 	RAMUsed       uint64  `json:"ram_used"`
 	GPUName       string  `json:"gpu_name"`
 	GPUUsage      float64 `json:"gpu_usage"`
+	DiskUsage     float64 `json:"disk_usage"`
 	InternetSpeed string  `json:"internet_speed"`
 	Uptime        uint64  `json:"uptime"`
+
+	// Tags the worker declares for itself (e.g. "build-agent"); the admin may add more
+	Tags []string `json:"tags,omitempty"`
 }
 
 // MetricsPayload contains real-time metrics update
 type MetricsPayload struct {
-	CPUUsage float64 `json:"cpu_usage"`
-	RAMUsage float64 `json:"ram_usage"`
-	GPUUsage float64 `json:"gpu_usage"`
+	CPUUsage  float64 `json:"cpu_usage"`
+	RAMUsage  float64 `json:"ram_usage"`
+	GPUUsage  float64 `json:"gpu_usage"`
+	DiskUsage float64 `json:"disk_usage"`
 }
 
 // AdminInfoPayload contains admin device info sent to worker
@@ -56,3 +72,100 @@ type CommandPayload struct {
 	Command string   `json:"command"`
 	Args    []string `json:"args"`
 }
+
+// PingPayload is echoed back unchanged in the pong so the sender can measure round-trip time
+type PingPayload struct {
+	Seq    int   `json:"seq"`
+	SentAt int64 `json:"sent_at"` // UnixNano on the sender's clock
+}
+
+// SpeedTestPayload asks the worker to start a throughput test phase
+type SpeedTestPayload struct {
+	Direction string `json:"direction"` // "download" (worker -> admin) or "upload" (admin -> worker)
+	Bytes     int    `json:"bytes"`     // Total payload size to transfer
+	ChunkSize int    `json:"chunk_size"`
+}
+
+// SpeedTestDataPayload carries one chunk of test data
+type SpeedTestDataPayload struct {
+	Data  []byte `json:"data"`
+	Final bool   `json:"final"`
+}
+
+// SpeedTestResultPayload is sent by the worker once an upload phase completes
+type SpeedTestResultPayload struct {
+	Bytes      int64   `json:"bytes"`       // Test data received, before base64 encoding
+	DurationMs float64 `json:"duration_ms"` // Time between first and last chunk
+}
+
+// AuthorizeKeyPayload asks the worker to trust the admin's SSH public key
+type AuthorizeKeyPayload struct {
+	PublicKey string `json:"public_key"` // authorized_keys format
+	Comment   string `json:"comment"`
+	Username  string `json:"username,omitempty"` // SSH account to add the key to; empty means "admin"
+}
+
+// AuthorizeKeyResultPayload is the worker's answer to an AuthorizeKeyPayload
+type AuthorizeKeyResultPayload struct {
+	Accepted bool   `json:"accepted"`
+	Error    string `json:"error,omitempty"`
+}
+
+// SessionNoticePayload warns the admin that the worker is about to end the session,
+// or tells it why the worker ended or refused it
+type SessionNoticePayload struct {
+	Reason           string `json:"reason"`                      // SessionIdle, SessionMaxDuration or SessionTooMany
+	Message          string `json:"message"`                     // Shown to the user
+	RemainingSeconds int    `json:"remaining_seconds,omitempty"` // Warnings only: time left before the disconnect
+}
+
+// ScreenRequestPayload asks the worker for a screenshot, or to start or stop streaming
+// its screen
+type ScreenRequestPayload struct {
+	Action     string `json:"action"`                // ScreenCapture, ScreenStart or ScreenStop
+	IntervalMs int    `json:"interval_ms,omitempty"` // ScreenStart: time between captures
+	Quality    int    `json:"quality,omitempty"`     // ScreenStart: JPEG quality, 1-100
+	MaxWidth   int    `json:"max_width,omitempty"`   // ScreenStart: wider frames are scaled down; 0 for the default, negative keeps the size
+}
+
+// ScreenFramePayload carries one image of the worker's screen. Streams only send a
+// frame when the screen changed.
+type ScreenFramePayload struct {
+	Stream     bool   `json:"stream"` // Part of a stream rather than the answer to ScreenCapture
+	Seq        int    `json:"seq"`
+	Width      int    `json:"width"` // Size of the screen; the image may be scaled down
+	Height     int    `json:"height"`
+	Format     string `json:"format,omitempty"` // "png" or "jpeg"
+	Data       []byte `json:"data,omitempty"`
+	CapturedAt int64  `json:"captured_at"`         // UnixNano on the worker's clock
+	Unchanged  int    `json:"unchanged,omitempty"` // Captures skipped since the last frame because nothing changed
+	Error      string `json:"error,omitempty"`     // Capturing failed; a stream stops after an error
+}
+
+// messageWriter serializes writes of JSON messages to a connection.
+// Several goroutines (metrics loop, pong replies, speed tests) share one conn,
+// so every write must go through the same encoder under a lock.
+type messageWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newMessageWriter(w io.Writer) *messageWriter {
+	return &messageWriter{enc: json.NewEncoder(w)}
+}
+
+// send marshals payload (if any) and writes a single message
+func (m *messageWriter) send(msgType MessageType, payload interface{}) error {
+	msg := Message{Type: msgType}
+	if payload != nil {
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		msg.Payload = payloadBytes
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.enc.Encode(msg)
+}
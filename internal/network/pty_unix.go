@@ -0,0 +1,80 @@
+//go:build !windows
+
+package network
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/creack/pty"
+)
+
+// ptySupported reports whether this platform can allocate pseudo-terminals
+const ptySupported = true
+
+// startPTY starts cmd attached to a new pseudo-terminal of the given size
+func startPTY(cmd *exec.Cmd, cols, rows uint32) (*os.File, error) {
+	return pty.StartWithSize(cmd, ptySize(cols, rows))
+}
+
+// resizePTY applies a window-change to a running pseudo-terminal
+func resizePTY(tty *os.File, cols, rows uint32) error {
+	return pty.Setsize(tty, ptySize(cols, rows))
+}
+
+func ptySize(cols, rows uint32) *pty.Winsize {
+	if cols == 0 || cols > 0xffff {
+		cols = 80
+	}
+	if rows == 0 || rows > 0xffff {
+		rows = 24
+	}
+	return &pty.Winsize{Cols: uint16(cols), Rows: uint16(rows)}
+}
+
+// newProcessGroup makes cmd lead a process group of its own, so a signal from the
+// client reaches everything it starts, e.g. the pipeline behind "sh -c"
+func newProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// signalProcess delivers sig to the process group led by p. Commands started with
+// newProcessGroup or on a PTY (which gets a session of its own) lead one.
+func signalProcess(p *os.Process, sig os.Signal) error {
+	if s, ok := sig.(syscall.Signal); ok {
+		return syscall.Kill(-p.Pid, s)
+	}
+	return p.Signal(sig)
+}
+
+// exitSignalName returns the SSH name of the signal that killed a process, or ""
+func exitSignalName(state *os.ProcessState) string {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return ""
+	}
+	for name, sig := range sshSignals {
+		if sig == status.Signal() {
+			return name
+		}
+	}
+	return ""
+}
+
+// sshSignals maps SSH signal names (RFC 4254 section 6.10) to local signals
+var sshSignals = map[string]os.Signal{
+	"ABRT": syscall.SIGABRT,
+	"ALRM": syscall.SIGALRM,
+	"FPE":  syscall.SIGFPE,
+	"HUP":  syscall.SIGHUP,
+	"ILL":  syscall.SIGILL,
+	"INT":  syscall.SIGINT,
+	"KILL": syscall.SIGKILL,
+	"PIPE": syscall.SIGPIPE,
+	"QUIT": syscall.SIGQUIT,
+	"SEGV": syscall.SIGSEGV,
+	"TERM": syscall.SIGTERM,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+}
@@ -0,0 +1,42 @@
+//go:build windows
+
+package network
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// ptySupported reports whether this platform can allocate pseudo-terminals
+const ptySupported = false
+
+// startPTY is not available on Windows; pty-req is refused so clients fall back to pipes
+func startPTY(cmd *exec.Cmd, cols, rows uint32) (*os.File, error) {
+	return nil, errors.New("pseudo-terminals are not supported on Windows")
+}
+
+// resizePTY is a no-op on Windows
+func resizePTY(tty *os.File, cols, rows uint32) error {
+	return nil
+}
+
+// newProcessGroup is a no-op on Windows
+func newProcessGroup(cmd *exec.Cmd) {}
+
+// signalProcess kills p; Windows has no signals to forward
+func signalProcess(p *os.Process, sig os.Signal) error {
+	return p.Signal(sig)
+}
+
+// exitSignalName always returns "": Windows processes end with an exit code
+func exitSignalName(state *os.ProcessState) string {
+	return ""
+}
+
+// sshSignals maps SSH signal names to local signals; Windows can only kill a process
+var sshSignals = map[string]os.Signal{
+	"INT":  os.Kill,
+	"KILL": os.Kill,
+	"TERM": os.Kill,
+}
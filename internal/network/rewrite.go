@@ -0,0 +1,106 @@
+package network
+
+import (
+	"adminadmin/internal/config"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// rewriteSettingsFile stores the worker's command rewriting rules
+const rewriteSettingsFile = "command_rewrite.json"
+
+// RewriteRule adds flags to exec commands matching a pattern, e.g. "-y" to
+// "apt install" so it doesn't wait for a confirmation nobody can answer
+type RewriteRule struct {
+	Match  string `json:"match"`        // Regular expression matched against the command
+	Append string `json:"append"`       // Flags inserted after the match; ones the command already has are skipped
+	OS     string `json:"os,omitempty"` // Only on this GOOS ("windows", "linux", ...); empty means any
+}
+
+// Validate checks that the rule's pattern compiles and it appends something
+func (r RewriteRule) Validate() error {
+	if _, err := regexp.Compile(r.Match); err != nil || r.Match == "" {
+		return fmt.Errorf("invalid pattern %q", r.Match)
+	}
+	if strings.TrimSpace(r.Append) == "" {
+		return fmt.Errorf("rule %q appends nothing", r.Match)
+	}
+	return nil
+}
+
+// RewriteSettings configures command rewriting on the worker's SSH server
+type RewriteSettings struct {
+	Enabled bool          `json:"enabled"`
+	Rules   []RewriteRule `json:"rules"`
+}
+
+// DefaultRewriteSettings returns rewriting switched off, with rules for the common
+// package managers ready to be enabled
+func DefaultRewriteSettings() RewriteSettings {
+	return RewriteSettings{
+		Rules: []RewriteRule{
+			{Match: `\bapt(-get)? install\b`, Append: "-y", OS: "linux"},
+			{Match: `\b(yum|dnf) install\b`, Append: "-y", OS: "linux"},
+			{Match: `(?i)\bwinget (install|upgrade)\b`, Append: "--accept-source-agreements --accept-package-agreements --disable-interactivity", OS: "windows"},
+			{Match: `(?i)\bchoco install\b`, Append: "-y", OS: "windows"},
+		},
+	}
+}
+
+// LoadRewriteSettings loads the command rewriting settings, falling back to the defaults
+func LoadRewriteSettings() (RewriteSettings, error) {
+	settings := DefaultRewriteSettings()
+	err := config.LoadJSON(rewriteSettingsFile, &settings)
+	return settings, err
+}
+
+// SaveRewriteSettings persists the command rewriting settings
+func SaveRewriteSettings(settings RewriteSettings) error {
+	return config.SaveJSON(rewriteSettingsFile, settings)
+}
+
+// Rewrite applies the rules to command and returns the command to run, which is
+// command itself when rewriting is off or no rule matched. Flags go right after the
+// first match, so "apt install x && make" becomes "apt install -y x && make".
+func (s RewriteSettings) Rewrite(command string) string {
+	if !s.Enabled {
+		return command
+	}
+	rewritten := command
+	for _, rule := range s.Rules {
+		if rule.OS != "" && rule.OS != runtime.GOOS {
+			continue
+		}
+		re, err := regexp.Compile(rule.Match)
+		if err != nil {
+			continue
+		}
+		loc := re.FindStringIndex(rewritten)
+		if loc == nil {
+			continue
+		}
+		have := strings.Fields(rewritten)
+		var add []string
+		for _, flag := range strings.Fields(rule.Append) {
+			if !containsFold(have, flag) {
+				add = append(add, flag)
+			}
+		}
+		if len(add) > 0 {
+			rewritten = rewritten[:loc[1]] + " " + strings.Join(add, " ") + rewritten[loc[1]:]
+		}
+	}
+	return rewritten
+}
+
+// containsFold reports whether words contains word, ignoring case
+func containsFold(words []string, word string) bool {
+	for _, w := range words {
+		if strings.EqualFold(w, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,541 @@
+package network
+
+import (
+	"adminadmin/internal/config"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Actions of a ScreenRequestPayload
+const (
+	ScreenCapture = "capture" // One full-size PNG screenshot
+	ScreenStart   = "start"   // Stream JPEG frames whenever the screen changes
+	ScreenStop    = "stop"
+)
+
+const screenSettingsFile = "screen_sharing.json"
+
+const (
+	screenCaptureTimeout  = 2 * time.Minute // Leaves time for the worker's user to confirm
+	maxScreenCaptures     = 2               // Screenshots in progress per admin connection
+	minScreenInterval     = 200 * time.Millisecond
+	maxScreenInterval     = time.Minute
+	defaultScreenInterval = time.Second
+	defaultScreenQuality  = 60
+	defaultScreenMaxWidth = 1280
+)
+
+// ScreenSettings controls whether admins may see the worker's screen
+type ScreenSettings struct {
+	Enabled bool `json:"enabled"` // Off by default: admins can't capture the screen at all
+	Confirm bool `json:"confirm"` // Ask the local user before every screenshot and live view
+}
+
+// DefaultScreenSettings returns the settings used until the worker changes them
+func DefaultScreenSettings() ScreenSettings {
+	return ScreenSettings{Confirm: true}
+}
+
+// LoadScreenSettings loads the screen sharing settings, falling back to the defaults
+func LoadScreenSettings() (ScreenSettings, error) {
+	settings := DefaultScreenSettings()
+	err := config.LoadJSON(screenSettingsFile, &settings)
+	return settings, err
+}
+
+// SaveScreenSettings persists the screen sharing settings
+func SaveScreenSettings(settings ScreenSettings) error {
+	return config.SaveJSON(screenSettingsFile, settings)
+}
+
+// ScreenStreamOptions configures a live view of a worker's screen
+type ScreenStreamOptions struct {
+	Interval time.Duration // Time between captures; frames are only sent when the screen changed
+	Quality  int           // JPEG quality, 1-100; 0 for the default
+	MaxWidth int           // Frames are scaled down to this width; 0 for the default, -1 for full size
+}
+
+// ScreenFrame is a decoded picture of a worker's screen
+type ScreenFrame struct {
+	Image        image.Image
+	ScreenWidth  int // The screen's size; Image is smaller if the frame was scaled down
+	ScreenHeight int
+	Format       string // "png" or "jpeg"
+	Bytes        int    // Encoded size
+	CapturedAt   time.Time
+	Seq          int
+	Unchanged    int // Captures skipped since the previous frame because nothing changed
+}
+
+// ================== Admin side ==================
+
+// screenChannels routes screen frames from the receive loop
+type screenChannels struct {
+	capture chan ScreenFramePayload             // Pending CaptureScreen, if any
+	onFrame func(frame *ScreenFrame, err error) // Running stream, if any
+}
+
+// CaptureScreen asks the worker for a full-size screenshot and waits for it
+func (a *AdminClient) CaptureScreen() (*ScreenFrame, error) {
+	if !a.connected || a.writer == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	a.screenMu.Lock()
+	if a.screen.capture != nil {
+		a.screenMu.Unlock()
+		return nil, fmt.Errorf("screenshot already in progress")
+	}
+	result := make(chan ScreenFramePayload, 1)
+	a.screen.capture = result
+	a.screenMu.Unlock()
+
+	defer func() {
+		a.screenMu.Lock()
+		a.screen.capture = nil
+		a.screenMu.Unlock()
+	}()
+
+	if err := a.writer.send(MsgTypeScreenRequest, ScreenRequestPayload{Action: ScreenCapture}); err != nil {
+		return nil, fmt.Errorf("failed to request screenshot: %w", err)
+	}
+	select {
+	case payload := <-result:
+		return decodeScreenFrame(payload)
+	case <-time.After(screenCaptureTimeout):
+		return nil, fmt.Errorf("no screenshot from the worker within %s", screenCaptureTimeout)
+	}
+}
+
+// StartScreenStream starts a live view of the worker's screen, replacing a running one.
+// onFrame is called from the receive loop for every changed frame, or with the error
+// that ended the stream; it must not block.
+func (a *AdminClient) StartScreenStream(opts ScreenStreamOptions, onFrame func(frame *ScreenFrame, err error)) error {
+	if !a.connected || a.writer == nil {
+		return fmt.Errorf("not connected")
+	}
+	a.screenMu.Lock()
+	a.screen.onFrame = onFrame
+	a.screenMu.Unlock()
+
+	req := ScreenRequestPayload{
+		Action:     ScreenStart,
+		IntervalMs: int(opts.Interval / time.Millisecond),
+		Quality:    opts.Quality,
+		MaxWidth:   opts.MaxWidth,
+	}
+	if err := a.writer.send(MsgTypeScreenRequest, req); err != nil {
+		a.StopScreenStream()
+		return fmt.Errorf("failed to start live view: %w", err)
+	}
+	log.Printf("ADMIN: Started live screen view (every %s)\n", opts.Interval)
+	return nil
+}
+
+// StopScreenStream ends the live view, if any
+func (a *AdminClient) StopScreenStream() error {
+	a.screenMu.Lock()
+	running := a.screen.onFrame != nil
+	a.screen.onFrame = nil
+	a.screenMu.Unlock()
+	if !running || !a.connected || a.writer == nil {
+		return nil
+	}
+	return a.writer.send(MsgTypeScreenRequest, ScreenRequestPayload{Action: ScreenStop})
+}
+
+// dispatchScreenFrame hands a screen frame to the pending screenshot or the live view
+func (a *AdminClient) dispatchScreenFrame(msg Message) {
+	var payload ScreenFramePayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		log.Printf("ADMIN ERROR: Invalid screen frame: %v\n", err)
+		return
+	}
+
+	a.screenMu.Lock()
+	capture, onFrame := a.screen.capture, a.screen.onFrame
+	if payload.Stream && payload.Error != "" {
+		// The worker stopped the stream
+		a.screen.onFrame = nil
+	}
+	a.screenMu.Unlock()
+
+	if !payload.Stream {
+		if capture != nil {
+			select {
+			case capture <- payload:
+			default:
+			}
+		}
+		return
+	}
+	if onFrame != nil {
+		frame, err := decodeScreenFrame(payload)
+		onFrame(frame, err)
+	}
+}
+
+// decodeScreenFrame decodes the image of a frame, or returns the worker's error
+func decodeScreenFrame(payload ScreenFramePayload) (*ScreenFrame, error) {
+	if payload.Error != "" {
+		return nil, fmt.Errorf("worker could not capture its screen: %s", payload.Error)
+	}
+	var img image.Image
+	var err error
+	switch payload.Format {
+	case "png":
+		img, err = png.Decode(bytes.NewReader(payload.Data))
+	case "jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(payload.Data))
+	default:
+		err = fmt.Errorf("unknown image format %q", payload.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid screen frame: %w", err)
+	}
+	return &ScreenFrame{
+		Image:        img,
+		ScreenWidth:  payload.Width,
+		ScreenHeight: payload.Height,
+		Format:       payload.Format,
+		Bytes:        len(payload.Data),
+		CapturedAt:   time.Unix(0, payload.CapturedAt),
+		Seq:          payload.Seq,
+		Unchanged:    payload.Unchanged,
+	}, nil
+}
+
+// ================== Worker side ==================
+
+// workerScreen is the screen sharing state of one admin connection
+type workerScreen struct {
+	mu       sync.Mutex
+	stop     chan struct{} // Closed to end the running stream, if any
+	captures int           // Screenshots in progress
+}
+
+// handleScreenRequest answers a screenshot request or starts or stops the stream.
+// Screenshots and streams wait for the worker's consent off the read loop.
+func (w *WorkerServer) handleScreenRequest(writer *messageWriter, screen *workerScreen, adminHostname string, raw json.RawMessage) {
+	var req ScreenRequestPayload
+	if err := json.Unmarshal(raw, &req); err != nil {
+		log.Printf("WORKER: Invalid screen request: %v\n", err)
+		return
+	}
+	refuse := func(stream bool, reason string) {
+		log.Printf("WORKER: Refused screen request from %s: %s\n", adminHostname, reason)
+		writer.send(MsgTypeScreenFrame, ScreenFramePayload{Stream: stream, Error: reason})
+	}
+
+	switch req.Action {
+	case ScreenCapture:
+		if !screen.beginCapture() {
+			refuse(false, "too many screenshots in progress")
+			return
+		}
+		go func() {
+			defer screen.endCapture()
+			if reason := w.allowScreenView(adminHostname, false); reason != "" {
+				refuse(false, reason)
+				return
+			}
+			done := w.beginScreenView(adminHostname)
+			frame := w.captureFrame(ScreenFramePayload{}, nil)
+			done()
+			if err := writer.send(MsgTypeScreenFrame, frame); err != nil {
+				log.Printf("WORKER: Failed to send screenshot: %v\n", err)
+			}
+		}()
+	case ScreenStart:
+		stop := screen.restart()
+		go func() {
+			if reason := w.allowScreenView(adminHostname, true); reason != "" {
+				refuse(true, reason)
+				return
+			}
+			select {
+			case <-stop:
+				return // Stopped or replaced while the user was asked
+			default:
+			}
+			done := w.beginScreenView(adminHostname)
+			defer done()
+			w.streamScreen(writer, req, stop)
+		}()
+	case ScreenStop:
+		screen.stopStream()
+		log.Printf("WORKER: Live screen view by %s stopped\n", adminHostname)
+	default:
+		log.Printf("WORKER: Unknown screen request %q\n", req.Action)
+	}
+}
+
+// beginCapture reserves one of the connection's screenshot slots
+func (s *workerScreen) beginCapture() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.captures >= maxScreenCaptures {
+		return false
+	}
+	s.captures++
+	return true
+}
+
+func (s *workerScreen) endCapture() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.captures--
+}
+
+// restart stops the running stream, if any, and returns the stop channel of a new one
+func (s *workerScreen) restart() chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		close(s.stop)
+	}
+	s.stop = make(chan struct{})
+	return s.stop
+}
+
+// stopStream ends the running stream, if any
+func (s *workerScreen) stopStream() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+// allowScreenView checks the settings and, if they ask for it, the worker's user
+// before an admin sees the screen. It returns why the view is refused, or "".
+func (w *WorkerServer) allowScreenView(adminHostname string, live bool) string {
+	w.callbackMu.Lock()
+	settings := w.screenSettings
+	onScreenConfirm := w.onScreenConfirm
+	w.callbackMu.Unlock()
+
+	if !settings.Enabled {
+		return "screen sharing is turned off on this worker"
+	}
+	if settings.Confirm {
+		if onScreenConfirm == nil {
+			return "nobody at the worker can confirm screen sharing"
+		}
+		if !onScreenConfirm(adminHostname, live) {
+			return "the worker's user declined screen sharing"
+		}
+	}
+	w.notifyScreenView(adminHostname, live)
+	return ""
+}
+
+// beginScreenView counts an admin as looking at the screen until the returned
+// function is called
+func (w *WorkerServer) beginScreenView(adminHostname string) func() {
+	w.screenViewsMu.Lock()
+	w.screenViews[adminHostname]++
+	w.screenViewsMu.Unlock()
+	w.notifyScreenViewers()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			w.screenViewsMu.Lock()
+			if w.screenViews[adminHostname]--; w.screenViews[adminHostname] <= 0 {
+				delete(w.screenViews, adminHostname)
+			}
+			w.screenViewsMu.Unlock()
+			w.notifyScreenViewers()
+		})
+	}
+}
+
+// notifyScreenViewers reports the admins currently looking at the screen
+func (w *WorkerServer) notifyScreenViewers() {
+	w.screenViewsMu.Lock()
+	viewers := make([]string, 0, len(w.screenViews))
+	for admin := range w.screenViews {
+		viewers = append(viewers, admin)
+	}
+	w.screenViewsMu.Unlock()
+	sort.Strings(viewers)
+
+	w.callbackMu.Lock()
+	onScreenViewers := w.onScreenViewers
+	w.callbackMu.Unlock()
+	if onScreenViewers != nil {
+		onScreenViewers(viewers)
+	}
+}
+
+// streamScreen captures the screen every interval and sends a JPEG frame whenever it
+// changed, until stop is closed or capturing fails
+func (w *WorkerServer) streamScreen(writer *messageWriter, req ScreenRequestPayload, stop chan struct{}) {
+	interval := time.Duration(req.IntervalMs) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultScreenInterval
+	}
+	interval = min(max(interval, minScreenInterval), maxScreenInterval)
+	quality := req.Quality
+	if quality <= 0 || quality > 100 {
+		quality = defaultScreenQuality
+	}
+	maxWidth := req.MaxWidth
+	if maxWidth == 0 {
+		maxWidth = defaultScreenMaxWidth
+	}
+	log.Printf("WORKER: Streaming screen every %s (quality %d)\n", interval, quality)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastHash uint64
+	seq, unchanged := 0, 0
+	for {
+		var hash uint64
+		frame := w.captureFrame(ScreenFramePayload{Stream: true, Seq: seq + 1}, func(img *image.RGBA) ([]byte, string, error) {
+			hash = hashPixels(img)
+			if seq > 0 && hash == lastHash {
+				return nil, "", nil
+			}
+			if maxWidth > 0 && img.Bounds().Dx() > maxWidth {
+				img = scaleImage(img, maxWidth)
+			}
+			var buf bytes.Buffer
+			err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+			return buf.Bytes(), "jpeg", err
+		})
+
+		switch {
+		case frame.Error != "":
+			writer.send(MsgTypeScreenFrame, frame)
+			log.Printf("WORKER: Screen stream stopped: %s\n", frame.Error)
+			return
+		case frame.Data == nil:
+			unchanged++
+		default:
+			seq++
+			lastHash = hash
+			frame.Unchanged = unchanged
+			unchanged = 0
+			if err := writer.send(MsgTypeScreenFrame, frame); err != nil {
+				log.Printf("WORKER: Failed to send screen frame: %v\n", err)
+				return
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-w.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// captureFrame captures the screen into frame, encoding it with encode (a full-size
+// PNG if nil). encode returns no data to skip the frame.
+func (w *WorkerServer) captureFrame(frame ScreenFramePayload, encode func(img *image.RGBA) ([]byte, string, error)) ScreenFramePayload {
+	frame.CapturedAt = time.Now().UnixNano()
+	w.callbackMu.Lock()
+	capturer := w.screen
+	enabled := w.screenSettings.Enabled
+	w.callbackMu.Unlock()
+	if !enabled {
+		// Turned off while a stream was running
+		frame.Error = "screen sharing was turned off on this worker"
+		return frame
+	}
+
+	captured, err := capturer.Capture()
+	if err != nil {
+		frame.Error = err.Error()
+		return frame
+	}
+	img := toRGBA(captured)
+	frame.Width, frame.Height = img.Bounds().Dx(), img.Bounds().Dy()
+
+	if encode == nil {
+		encode = func(img *image.RGBA) ([]byte, string, error) {
+			var buf bytes.Buffer
+			err := png.Encode(&buf, img)
+			return buf.Bytes(), "png", err
+		}
+	}
+	frame.Data, frame.Format, err = encode(img)
+	if err != nil {
+		frame.Error = err.Error()
+	}
+	return frame
+}
+
+// notifyScreenView tells the worker's user that an admin looks at the screen
+func (w *WorkerServer) notifyScreenView(adminHostname string, live bool) {
+	w.callbackMu.Lock()
+	onScreenView := w.onScreenView
+	w.callbackMu.Unlock()
+	if live {
+		log.Printf("WORKER: Admin %s started a live view of the screen\n", adminHostname)
+	} else {
+		log.Printf("WORKER: Admin %s took a screenshot\n", adminHostname)
+	}
+	if onScreenView != nil {
+		onScreenView(adminHostname, live)
+	}
+}
+
+// toRGBA returns img as an *image.RGBA starting at (0, 0)
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// hashPixels fingerprints an image to tell whether the screen changed
+func hashPixels(img *image.RGBA) uint64 {
+	h := fnv.New64a()
+	h.Write(img.Pix)
+	return h.Sum64()
+}
+
+// scaleImage scales img down to width, averaging the source pixels each target pixel
+// covers so text stays legible
+func scaleImage(img *image.RGBA, width int) *image.RGBA {
+	src := img.Bounds()
+	height := max(src.Dy()*width/src.Dx(), 1)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*src.Dy()/height, max((y+1)*src.Dy()/height, y*src.Dy()/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*src.Dx()/width, max((x+1)*src.Dx()/width, x*src.Dx()/width+1)
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := img.Pix[sy*img.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r, g, b, a = r+int(p[0]), g+int(p[1]), b+int(p[2]), a+int(p[3])
+					n++
+				}
+			}
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0], d[1], d[2], d[3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+	return dst
+}
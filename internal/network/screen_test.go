@@ -0,0 +1,246 @@
+package network
+
+import (
+	"adminadmin/internal/system"
+	"encoding/json"
+	"image"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// screenHarness drives a worker's screen request handling over an in-memory connection
+type screenHarness struct {
+	t      *testing.T
+	worker *WorkerServer
+	writer *messageWriter
+	screen *workerScreen
+	frames chan ScreenFramePayload
+}
+
+func newScreenHarness(t *testing.T, capturer system.ScreenCapturer, settings ScreenSettings) *screenHarness {
+	t.Helper()
+	workerEnd, adminEnd := net.Pipe()
+	t.Cleanup(func() {
+		workerEnd.Close()
+		adminEnd.Close()
+	})
+
+	h := &screenHarness{
+		t:      t,
+		worker: NewWorkerServer(0),
+		writer: newMessageWriter(workerEnd),
+		screen: &workerScreen{},
+		frames: make(chan ScreenFramePayload, 16),
+	}
+	h.worker.SetScreenCapturer(capturer)
+	h.worker.SetScreenSettings(settings)
+	t.Cleanup(h.screen.stopStream)
+
+	go func() {
+		decoder := json.NewDecoder(adminEnd)
+		for {
+			var msg Message
+			if err := decoder.Decode(&msg); err != nil {
+				return
+			}
+			var frame ScreenFramePayload
+			if msg.Type != MsgTypeScreenFrame || json.Unmarshal(msg.Payload, &frame) != nil {
+				t.Errorf("unexpected message %s", msg.Type)
+				continue
+			}
+			h.frames <- frame
+		}
+	}()
+	return h
+}
+
+func (h *screenHarness) request(req ScreenRequestPayload) {
+	raw, _ := json.Marshal(req)
+	h.worker.handleScreenRequest(h.writer, h.screen, "admin-pc", raw)
+}
+
+func (h *screenHarness) frame() ScreenFramePayload {
+	h.t.Helper()
+	select {
+	case frame := <-h.frames:
+		return frame
+	case <-time.After(5 * time.Second):
+		h.t.Fatal("no screen frame from the worker")
+		return ScreenFramePayload{}
+	}
+}
+
+func (h *screenHarness) noFrame(wait time.Duration) {
+	h.t.Helper()
+	select {
+	case frame := <-h.frames:
+		h.t.Fatalf("unexpected frame %d (error %q)", frame.Seq, frame.Error)
+	case <-time.After(wait):
+	}
+}
+
+// fixedTime returns a clock for FakeScreen that only moves when the test says so
+func fixedTime() (now func() time.Time, advance func()) {
+	var mu sync.Mutex
+	t := time.Unix(1700000000, 0)
+	return func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return t
+		}, func() {
+			mu.Lock()
+			defer mu.Unlock()
+			t = t.Add(time.Second)
+		}
+}
+
+func TestScreenCaptureDisabledByDefault(t *testing.T) {
+	h := newScreenHarness(t, &system.FakeScreen{Width: 64, Height: 48}, DefaultScreenSettings())
+	h.request(ScreenRequestPayload{Action: ScreenCapture})
+	frame := h.frame()
+	if !strings.Contains(frame.Error, "turned off") || frame.Data != nil {
+		t.Fatalf("got %+v, want a refusal", frame)
+	}
+}
+
+func TestScreenCapture(t *testing.T) {
+	h := newScreenHarness(t, &system.FakeScreen{Width: 320, Height: 200}, ScreenSettings{Enabled: true})
+	var views []bool
+	h.worker.SetOnScreenView(func(admin string, live bool) { views = append(views, live) })
+
+	h.request(ScreenRequestPayload{Action: ScreenCapture})
+	frame := h.frame()
+	if frame.Error != "" || frame.Stream || frame.Format != "png" {
+		t.Fatalf("got %+v, want a PNG screenshot", frame)
+	}
+	img, err := decodeScreenFrame(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Image.Bounds().Size(); got != image.Pt(320, 200) || img.ScreenWidth != 320 {
+		t.Errorf("screenshot is %v (screen %dx%d), want 320x200", got, img.ScreenWidth, img.ScreenHeight)
+	}
+	if len(views) != 1 || views[0] {
+		t.Errorf("view notifications %v, want one screenshot", views)
+	}
+}
+
+func TestScreenConfirm(t *testing.T) {
+	for _, allow := range []bool{false, true} {
+		h := newScreenHarness(t, &system.FakeScreen{Width: 64, Height: 48}, ScreenSettings{Enabled: true, Confirm: true})
+		asked := 0
+		h.worker.SetOnScreenConfirm(func(admin string, live bool) bool {
+			asked++
+			return allow
+		})
+		h.request(ScreenRequestPayload{Action: ScreenCapture})
+		frame := h.frame()
+		if asked != 1 {
+			t.Errorf("allow=%v: asked %d times, want once", allow, asked)
+		}
+		if declined := strings.Contains(frame.Error, "declined"); declined == allow {
+			t.Errorf("allow=%v: got error %q", allow, frame.Error)
+		}
+	}
+}
+
+// blockingScreen holds every capture until release is closed
+type blockingScreen struct {
+	system.FakeScreen
+	release chan struct{}
+}
+
+func (b *blockingScreen) Capture() (image.Image, error) {
+	<-b.release
+	return b.FakeScreen.Capture()
+}
+
+func TestScreenCaptureLimit(t *testing.T) {
+	capturer := &blockingScreen{FakeScreen: system.FakeScreen{Width: 64, Height: 48}, release: make(chan struct{})}
+	h := newScreenHarness(t, capturer, ScreenSettings{Enabled: true})
+	var viewers [][]string
+	var viewersMu sync.Mutex
+	h.worker.SetOnScreenViewers(func(admins []string) {
+		viewersMu.Lock()
+		defer viewersMu.Unlock()
+		viewers = append(viewers, admins)
+	})
+
+	for i := 0; i < maxScreenCaptures+1; i++ {
+		go h.request(ScreenRequestPayload{Action: ScreenCapture})
+	}
+	if frame := h.frame(); !strings.Contains(frame.Error, "too many") {
+		t.Fatalf("got %+v, want the extra screenshot refused", frame)
+	}
+	close(capturer.release)
+	for i := 0; i < maxScreenCaptures; i++ {
+		if frame := h.frame(); frame.Error != "" {
+			t.Fatalf("screenshot %d failed: %s", i, frame.Error)
+		}
+	}
+
+	// Slots are free again once the screenshots are sent
+	time.Sleep(50 * time.Millisecond)
+	h.request(ScreenRequestPayload{Action: ScreenCapture})
+	if frame := h.frame(); frame.Error != "" {
+		t.Fatalf("screenshot after the limit failed: %s", frame.Error)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	viewersMu.Lock()
+	defer viewersMu.Unlock()
+	if len(viewers) == 0 || viewers[0][0] != "admin-pc" || len(viewers[len(viewers)-1]) != 0 {
+		t.Errorf("viewer updates %v, want admin-pc watching and then nobody", viewers)
+	}
+}
+
+func TestScreenStream(t *testing.T) {
+	now, advance := fixedTime()
+	h := newScreenHarness(t, &system.FakeScreen{Width: 1600, Height: 900, Now: now}, ScreenSettings{Enabled: true})
+
+	h.request(ScreenRequestPayload{Action: ScreenStart, IntervalMs: 200, MaxWidth: 800})
+	first := h.frame()
+	if first.Error != "" || !first.Stream || first.Format != "jpeg" || first.Seq != 1 {
+		t.Fatalf("got %+v, want the first JPEG frame", first)
+	}
+	img, err := decodeScreenFrame(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Image.Bounds().Size(); got != image.Pt(800, 450) || first.Width != 1600 {
+		t.Errorf("frame is %v of a %dx%d screen, want 800x450 of 1600x900", got, first.Width, first.Height)
+	}
+
+	// Nothing changes on screen, so nothing is sent
+	h.noFrame(700 * time.Millisecond)
+
+	advance()
+	second := h.frame()
+	if second.Seq != 2 || second.Unchanged == 0 {
+		t.Errorf("got frame %d after %d unchanged captures, want frame 2 after some", second.Seq, second.Unchanged)
+	}
+
+	h.request(ScreenRequestPayload{Action: ScreenStop})
+	advance()
+	h.noFrame(500 * time.Millisecond)
+}
+
+func TestScreenStreamTurnedOff(t *testing.T) {
+	now, advance := fixedTime()
+	h := newScreenHarness(t, &system.FakeScreen{Width: 64, Height: 48, Now: now}, ScreenSettings{Enabled: true})
+	h.request(ScreenRequestPayload{Action: ScreenStart, IntervalMs: 200})
+	if frame := h.frame(); frame.Error != "" {
+		t.Fatal(frame.Error)
+	}
+
+	h.worker.SetScreenSettings(ScreenSettings{})
+	advance()
+	frame := h.frame()
+	if !frame.Stream || !strings.Contains(frame.Error, "turned off") {
+		t.Fatalf("got %+v, want the stream ended", frame)
+	}
+	h.noFrame(500 * time.Millisecond)
+}
@@ -0,0 +1,257 @@
+package network
+
+import (
+	"adminadmin/internal/config"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// sessionLimitsFile stores the idle and session limits of the worker's servers
+const sessionLimitsFile = "session_limits.json"
+
+// sessionWarningLead is how long before a limit disconnects a session the client is
+// warned; limits shorter than twice this are warned at half-time
+const sessionWarningLead = time.Minute
+
+// sessionEndGrace is how long an admin has to close the connection after being told
+// its session ended
+const sessionEndGrace = 5 * time.Second
+
+// Reasons a worker ends or refuses a session
+const (
+	SessionIdle        = "idle"
+	SessionMaxDuration = "max_duration"
+	SessionTooMany     = "too_many_sessions"
+)
+
+// SessionLimits bounds the sessions of one of the worker's servers. Zero turns a limit off.
+type SessionLimits struct {
+	IdleMinutes       int `json:"idle_minutes"`        // Disconnect after this long without client activity
+	MaxSessionMinutes int `json:"max_session_minutes"` // Disconnect this long after login, active or not
+	MaxSessions       int `json:"max_sessions"`        // Refuse sessions beyond this many at once
+}
+
+// SessionLimitSettings holds the limits of the SSH server and of admin connections
+type SessionLimitSettings struct {
+	SSH   SessionLimits `json:"ssh"`
+	Admin SessionLimits `json:"admin"`
+}
+
+// DefaultSessionLimitSettings disconnects SSH sessions after an hour without activity.
+// Admin connections are not limited: an open dashboard only receives metrics, so it
+// would look idle.
+func DefaultSessionLimitSettings() SessionLimitSettings {
+	return SessionLimitSettings{
+		SSH: SessionLimits{IdleMinutes: 60},
+	}
+}
+
+// LoadSessionLimitSettings loads the session limits, falling back to the defaults
+func LoadSessionLimitSettings() (SessionLimitSettings, error) {
+	settings := DefaultSessionLimitSettings()
+	err := config.LoadJSON(sessionLimitsFile, &settings)
+	return settings, err
+}
+
+// SaveSessionLimitSettings persists the session limits
+func SaveSessionLimitSettings(settings SessionLimitSettings) error {
+	return config.SaveJSON(sessionLimitsFile, settings)
+}
+
+// SessionEndedError is passed to the admin's disconnect callback when the worker ended
+// or refused the session, e.g. after it was idle for too long
+type SessionEndedError struct {
+	Notice SessionNoticePayload
+}
+
+func (e *SessionEndedError) Error() string {
+	return e.Notice.Message
+}
+
+// tooManySessions is the notice of a session refused because limit sessions are already open
+func tooManySessions(limit int) SessionNoticePayload {
+	return SessionNoticePayload{
+		Reason:  SessionTooMany,
+		Message: fmt.Sprintf("Refused: this worker allows %d session(s) at once and all are in use.", limit),
+	}
+}
+
+// formatMinutes renders a limit or remaining time for notices, e.g. "30 minutes"
+func formatMinutes(d time.Duration) string {
+	if d < time.Minute {
+		secs := int(d.Round(time.Second) / time.Second)
+		if secs == 1 {
+			return "1 second"
+		}
+		return fmt.Sprintf("%d seconds", secs)
+	}
+	mins := int(d.Round(time.Minute) / time.Minute)
+	if mins == 1 {
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", mins)
+}
+
+// sessionWatch ends a session that stays idle or open for longer than its limits,
+// warning the client first
+type sessionWatch struct {
+	limits     func() SessionLimits // Read on every check, so changed settings apply to open sessions
+	started    time.Time
+	lastActive atomic.Int64 // UnixNano of the last client activity
+
+	mu    sync.Mutex
+	ended *SessionNoticePayload
+}
+
+func newSessionWatch(limits func() SessionLimits) *sessionWatch {
+	w := &sessionWatch{limits: limits, started: time.Now()}
+	w.touch()
+	return w
+}
+
+// touch records client activity, pushing the idle deadline back
+func (w *sessionWatch) touch() {
+	w.lastActive.Store(time.Now().UnixNano())
+}
+
+// endReason returns why the watch ended the session, or nil if it didn't
+func (w *sessionWatch) endReason() *SessionNoticePayload {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ended
+}
+
+// deadline returns the earliest limit the session runs into, if any: when it
+// disconnects, how long before that to warn, and the reason
+func (w *sessionWatch) deadline() (at time.Time, lead time.Duration, reason string, limit time.Duration) {
+	limits := w.limits()
+	if limits.IdleMinutes > 0 {
+		limit = time.Duration(limits.IdleMinutes) * time.Minute
+		at = time.Unix(0, w.lastActive.Load()).Add(limit)
+		reason = SessionIdle
+	}
+	if limits.MaxSessionMinutes > 0 {
+		max := time.Duration(limits.MaxSessionMinutes) * time.Minute
+		if end := w.started.Add(max); at.IsZero() || end.Before(at) {
+			at, reason, limit = end, SessionMaxDuration, max
+		}
+	}
+	lead = sessionWarningLead
+	if limit/2 < lead {
+		lead = limit / 2
+	}
+	return at, lead, reason, limit
+}
+
+// run checks the limits every second until done is closed. warn is called once for
+// each upcoming disconnect, end when a limit is reached; run returns after end.
+func (w *sessionWatch) run(done <-chan bool, warn, end func(SessionNoticePayload)) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var warned time.Time // Deadline the client was last warned about
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		at, lead, reason, limit := w.deadline()
+		if at.IsZero() {
+			continue
+		}
+		now := time.Now()
+		if !now.Before(at) {
+			notice := SessionNoticePayload{Reason: reason}
+			if reason == SessionIdle {
+				notice.Message = fmt.Sprintf("Disconnected after %s without activity.", formatMinutes(limit))
+			} else {
+				notice.Message = fmt.Sprintf("Disconnected: the session reached its limit of %s.", formatMinutes(limit))
+			}
+			w.mu.Lock()
+			w.ended = &notice
+			w.mu.Unlock()
+			end(notice)
+			return
+		}
+		if now.Before(at.Add(-lead)) || at.Equal(warned) {
+			continue
+		}
+		warned = at
+		remaining := at.Sub(now)
+		notice := SessionNoticePayload{Reason: reason, RemainingSeconds: int(remaining.Round(time.Second) / time.Second)}
+		if reason == SessionIdle {
+			notice.Message = fmt.Sprintf("No activity for %s. The session will be disconnected in %s unless it is used.",
+				formatMinutes(limit-remaining), formatMinutes(remaining))
+		} else {
+			notice.Message = fmt.Sprintf("The session reaches its limit of %s and will be disconnected in %s.",
+				formatMinutes(limit), formatMinutes(remaining))
+		}
+		warn(notice)
+	}
+}
+
+// sshConnSessions tracks the activity and open session channels of one SSH connection,
+// so limit warnings can be written to every open terminal
+type sshConnSessions struct {
+	watch *sessionWatch
+
+	mu       sync.Mutex
+	channels map[ssh.Channel]struct{}
+}
+
+func newSSHConnSessions(limits func() SessionLimits) *sshConnSessions {
+	return &sshConnSessions{
+		watch:    newSessionWatch(limits),
+		channels: make(map[ssh.Channel]struct{}),
+	}
+}
+
+// add registers an open session channel and returns it wrapped so client input counts
+// as activity; remove must be called with the unwrapped channel when it closes
+func (c *sshConnSessions) add(channel ssh.Channel) ssh.Channel {
+	c.mu.Lock()
+	c.channels[channel] = struct{}{}
+	c.mu.Unlock()
+	return c.wrap(channel)
+}
+
+func (c *sshConnSessions) remove(channel ssh.Channel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.channels, channel)
+}
+
+// wrap returns channel with reads counting as activity
+func (c *sshConnSessions) wrap(channel ssh.Channel) ssh.Channel {
+	return &activityChannel{Channel: channel, watch: c.watch}
+}
+
+// notify writes a notice to the stderr of every open session channel
+func (c *sshConnSessions) notify(notice SessionNoticePayload) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for channel := range c.channels {
+		io.WriteString(channel.Stderr(), "\r\nadminadmin: "+notice.Message+"\r\n")
+	}
+}
+
+// activityChannel records every read from the client as session activity
+type activityChannel struct {
+	ssh.Channel
+	watch *sessionWatch
+}
+
+func (c *activityChannel) Read(p []byte) (int, error) {
+	n, err := c.Channel.Read(p)
+	if n > 0 {
+		c.watch.touch()
+	}
+	return n, err
+}
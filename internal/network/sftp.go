@@ -0,0 +1,449 @@
+package network
+
+import (
+	"adminadmin/internal/config"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+const sftpSettingsFile = "sftp.json"
+
+// SFTPSettings configures the worker's SFTP subsystem
+type SFTPSettings struct {
+	Enabled  bool   `json:"enabled"`
+	Root     string `json:"root"`      // Directory SFTP clients see as "/"; empty means the home directory
+	ReadOnly bool   `json:"read_only"` // Refuse uploads, deletes, renames and other changes
+}
+
+// DefaultSFTPSettings returns the settings used until the worker changes them
+func DefaultSFTPSettings() SFTPSettings {
+	return SFTPSettings{Enabled: true}
+}
+
+// LoadSFTPSettings loads the SFTP settings, falling back to the defaults
+func LoadSFTPSettings() (SFTPSettings, error) {
+	settings := DefaultSFTPSettings()
+	err := config.LoadJSON(sftpSettingsFile, &settings)
+	return settings, err
+}
+
+// SaveSFTPSettings persists the SFTP settings
+func SaveSFTPSettings(settings SFTPSettings) error {
+	return config.SaveJSON(sftpSettingsFile, settings)
+}
+
+// sftpRootDir returns the absolute directory served as "/"
+func sftpRootDir(settings SFTPSettings) (string, error) {
+	root := settings.Root
+	if root == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("no SFTP root configured and no home directory: %w", err)
+		}
+		root = home
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", fmt.Errorf("SFTP root unavailable: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("SFTP root %s is not a directory", root)
+	}
+	return root, nil
+}
+
+// serveSFTP runs the SFTP subsystem on a session channel until the client disconnects.
+// Files are accessed with the permissions of the OS account running the worker.
+func serveSFTP(channel ssh.Channel, user string, settings SFTPSettings) {
+	root, err := sftpRootDir(settings)
+	if err != nil {
+		log.Printf("SSH: SFTP for %s refused: %v\n", user, err)
+		return
+	}
+	handler, err := newSFTPHandler(root, settings.ReadOnly)
+	if err != nil {
+		log.Printf("SSH: SFTP for %s refused: %v\n", user, err)
+		return
+	}
+
+	log.Printf("SSH: SFTP session for %s started (root %s, read-only=%v)\n", user, root, settings.ReadOnly)
+	server := sftp.NewRequestServer(keepOpenChannel{channel}, sftp.Handlers{
+		FileGet:  handler,
+		FilePut:  handler,
+		FileCmd:  handler,
+		FileList: handler,
+	}, sftp.WithStartDirectory("/"))
+	err = server.Serve()
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	if err != nil {
+		log.Printf("SSH: SFTP session for %s ended: %v\n", user, err)
+	} else {
+		log.Printf("SSH: SFTP session for %s ended\n", user)
+	}
+	server.Close()
+	// Clients like scp treat a session without an exit status as failed
+	sendExitStatus(channel, nil, err)
+}
+
+// keepOpenChannel stops the SFTP server from closing the channel when it finishes,
+// so the exit status can still be sent; the channel is closed by handleChannel
+type keepOpenChannel struct {
+	ssh.Channel
+}
+
+func (keepOpenChannel) Close() error { return nil }
+
+// sftpHandler serves the local filesystem below root. Client paths are always
+// interpreted relative to root, and symlinks leading outside it are refused.
+type sftpHandler struct {
+	root     string
+	realRoot string // root with symlinks resolved
+	readOnly bool
+}
+
+func newSFTPHandler(root string, readOnly bool) (*sftpHandler, error) {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+	return &sftpHandler{root: root, realRoot: realRoot, readOnly: readOnly}, nil
+}
+
+// resolve maps a client path to a local path. With follow, a final symlink must also
+// stay inside the root; without it only the parent directory is checked, so links
+// themselves can be listed, removed or renamed.
+func (h *sftpHandler) resolve(p string, follow bool) (string, error) {
+	clean := path.Clean("/" + p)
+	local := filepath.Join(h.root, filepath.FromSlash(clean))
+
+	check := local
+	if !follow && clean != "/" {
+		check = filepath.Dir(local)
+	}
+	real, err := evalExisting(check)
+	if err != nil {
+		return "", err
+	}
+	if !isWithin(h.realRoot, real) {
+		log.Printf("SSH: SFTP refused path outside the root: %s\n", p)
+		return "", sftp.ErrSSHFxPermissionDenied
+	}
+	return local, nil
+}
+
+// evalExisting resolves symlinks in the longest existing prefix of p; the
+// remaining (not yet created) components are appended unchanged
+func evalExisting(p string) (string, error) {
+	var rest []string
+	for {
+		real, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			return filepath.Join(append([]string{real}, rest...)...), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return "", err
+		}
+		rest = append([]string{filepath.Base(p)}, rest...)
+		p = parent
+	}
+}
+
+// isWithin reports whether p is root or below it
+func isWithin(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// writable returns an error if changes are not allowed
+func (h *sftpHandler) writable() error {
+	if h.readOnly {
+		return sftp.ErrSSHFxPermissionDenied
+	}
+	return nil
+}
+
+// Fileread opens a file for download
+func (h *sftpHandler) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	local, err := h.resolve(r.Filepath, true)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(local)
+}
+
+// Filewrite opens a file for upload
+func (h *sftpHandler) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	return h.OpenFile(r)
+}
+
+// OpenFile opens a file for reading and writing
+func (h *sftpHandler) OpenFile(r *sftp.Request) (sftp.WriterAtReaderAt, error) {
+	if err := h.writable(); err != nil {
+		return nil, err
+	}
+	local, err := h.resolve(r.Filepath, true)
+	if err != nil {
+		return nil, err
+	}
+
+	pflags := r.Pflags()
+	flags := os.O_WRONLY
+	if pflags.Read {
+		flags = os.O_RDWR
+	}
+	if pflags.Append {
+		flags |= os.O_APPEND
+	}
+	if pflags.Creat {
+		flags |= os.O_CREATE
+	}
+	if pflags.Trunc {
+		flags |= os.O_TRUNC
+	}
+	if pflags.Excl {
+		flags |= os.O_EXCL
+	}
+
+	mode := os.FileMode(0644)
+	if r.AttrFlags().Permissions {
+		mode = r.Attributes().FileMode().Perm()
+	}
+	return os.OpenFile(local, flags, mode)
+}
+
+// Filecmd handles changes: setstat, rename, remove, mkdir, rmdir and links
+func (h *sftpHandler) Filecmd(r *sftp.Request) error {
+	if err := h.writable(); err != nil {
+		return err
+	}
+
+	switch r.Method {
+	case "Setstat":
+		local, err := h.resolve(r.Filepath, true)
+		if err != nil {
+			return err
+		}
+		return h.setstat(local, r)
+
+	case "Rename", "PosixRename":
+		from, err := h.resolve(r.Filepath, false)
+		if err != nil {
+			return err
+		}
+		to, err := h.resolve(r.Target, false)
+		if err != nil {
+			return err
+		}
+		// Plain SFTP rename must not replace an existing file
+		if r.Method == "Rename" {
+			if _, err := os.Lstat(to); err == nil {
+				return sftp.ErrSSHFxFailure
+			}
+		}
+		return os.Rename(from, to)
+
+	case "Remove":
+		local, err := h.resolve(r.Filepath, false)
+		if err != nil {
+			return err
+		}
+		info, err := os.Lstat(local)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return sftp.ErrSSHFxFailure
+		}
+		return os.Remove(local)
+
+	case "Rmdir":
+		local, err := h.resolve(r.Filepath, false)
+		if err != nil {
+			return err
+		}
+		info, err := os.Lstat(local)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return sftp.ErrSSHFxFailure
+		}
+		return os.Remove(local)
+
+	case "Mkdir":
+		local, err := h.resolve(r.Filepath, false)
+		if err != nil {
+			return err
+		}
+		return os.Mkdir(local, 0755)
+
+	case "Link":
+		from, err := h.resolve(r.Filepath, true)
+		if err != nil {
+			return err
+		}
+		to, err := h.resolve(r.Target, false)
+		if err != nil {
+			return err
+		}
+		return os.Link(from, to)
+
+	case "Symlink":
+		// r.Filepath is the link target and r.Target the new link
+		link, err := h.resolve(r.Target, false)
+		if err != nil {
+			return err
+		}
+		target := r.Filepath
+		if path.IsAbs(target) {
+			if target, err = h.resolve(target, false); err != nil {
+				return err
+			}
+		} else {
+			target = filepath.FromSlash(target)
+		}
+		return os.Symlink(target, link)
+	}
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+// PosixRename renames, replacing the target if it exists
+func (h *sftpHandler) PosixRename(r *sftp.Request) error {
+	return h.Filecmd(r)
+}
+
+// setstat applies the attributes sent by the client
+func (h *sftpHandler) setstat(local string, r *sftp.Request) error {
+	flags := r.AttrFlags()
+	attrs := r.Attributes()
+	if flags.Size {
+		if err := os.Truncate(local, int64(attrs.Size)); err != nil {
+			return err
+		}
+	}
+	if flags.Permissions {
+		if err := os.Chmod(local, attrs.FileMode().Perm()); err != nil {
+			return err
+		}
+	}
+	if flags.Acmodtime {
+		if err := os.Chtimes(local, time.Unix(int64(attrs.Atime), 0), time.Unix(int64(attrs.Mtime), 0)); err != nil {
+			return err
+		}
+	}
+	// Ownership changes (UidGid) are ignored: the worker cannot give files away
+	return nil
+}
+
+// Filelist handles directory listings, stat and readlink
+func (h *sftpHandler) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	switch r.Method {
+	case "List":
+		local, err := h.resolve(r.Filepath, true)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := os.ReadDir(local)
+		if err != nil {
+			return nil, err
+		}
+		infos := make([]os.FileInfo, 0, len(entries))
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil {
+				continue // Removed while listing
+			}
+			infos = append(infos, info)
+		}
+		return fileInfoList(infos), nil
+
+	case "Stat":
+		local, err := h.resolve(r.Filepath, true)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(local)
+		if err != nil {
+			return nil, err
+		}
+		return fileInfoList{info}, nil
+	}
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+// Lstat stats a path without following a final symlink
+func (h *sftpHandler) Lstat(r *sftp.Request) (sftp.ListerAt, error) {
+	local, err := h.resolve(r.Filepath, false)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(local)
+	if err != nil {
+		return nil, err
+	}
+	return fileInfoList{info}, nil
+}
+
+// Readlink returns a link target; absolute targets are shown relative to the root
+func (h *sftpHandler) Readlink(p string) (string, error) {
+	local, err := h.resolve(p, false)
+	if err != nil {
+		return "", err
+	}
+	target, err := os.Readlink(local)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(target) {
+		return filepath.ToSlash(target), nil
+	}
+	for _, root := range []string{h.root, h.realRoot} {
+		if isWithin(root, target) {
+			rel, _ := filepath.Rel(root, target)
+			return path.Clean("/" + filepath.ToSlash(rel)), nil
+		}
+	}
+	return "", sftp.ErrSSHFxPermissionDenied
+}
+
+// RealPath canonicalizes a client path; the root is "/"
+func (h *sftpHandler) RealPath(p string) (string, error) {
+	return path.Clean("/" + p), nil
+}
+
+// fileInfoList implements sftp.ListerAt over a slice
+type fileInfoList []os.FileInfo
+
+func (l fileInfoList) ListAt(dst []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(dst, l[offset:])
+	if n < len(dst) {
+		return n, io.EOF
+	}
+	return n, nil
+}
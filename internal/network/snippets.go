@@ -0,0 +1,64 @@
+package network
+
+import (
+	"adminadmin/internal/config"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// snippetsFile stores the saved command snippets, which can be used on any worker
+const snippetsFile = "snippets.json"
+
+// snippetParam matches a parameter in a snippet's command, e.g. "{{path}}"
+var snippetParam = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// Snippet is a saved command, optionally with parameters filled in when it is used
+type Snippet struct {
+	Name        string `json:"name"`
+	Command     string `json:"command"` // Parameters are written {{name}}
+	Description string `json:"description,omitempty"`
+}
+
+// Validate checks that the snippet has a name and a command
+func (s Snippet) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return fmt.Errorf("snippet needs a name")
+	}
+	if strings.TrimSpace(s.Command) == "" {
+		return fmt.Errorf("snippet %q has no command", s.Name)
+	}
+	return nil
+}
+
+// Params returns the names of the snippet's parameters in the order they first appear
+func (s Snippet) Params() []string {
+	var params []string
+	seen := make(map[string]bool)
+	for _, m := range snippetParam.FindAllStringSubmatch(s.Command, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			params = append(params, m[1])
+		}
+	}
+	return params
+}
+
+// Expand returns the command with every parameter replaced by its value in values
+func (s Snippet) Expand(values map[string]string) string {
+	return snippetParam.ReplaceAllStringFunc(s.Command, func(param string) string {
+		return values[snippetParam.FindStringSubmatch(param)[1]]
+	})
+}
+
+// LoadSnippets loads the saved snippets
+func LoadSnippets() ([]Snippet, error) {
+	var snippets []Snippet
+	err := config.LoadJSON(snippetsFile, &snippets)
+	return snippets, err
+}
+
+// SaveSnippets persists the snippets
+func SaveSnippets(snippets []Snippet) error {
+	return config.SaveJSON(snippetsFile, snippets)
+}
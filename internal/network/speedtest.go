@@ -0,0 +1,299 @@
+package network
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	speedTestPings     = 5
+	speedTestBytes     = 8 * 1024 * 1024 // 8 MB per direction
+	speedTestChunkSize = 64 * 1024
+	speedTestTimeout   = 60 * time.Second
+)
+
+// SpeedTestResult holds the outcome of an admin <-> worker throughput test. Rates count
+// the test data itself, not its base64 and JSON framing, which takes about a third more
+// on the connection.
+type SpeedTestResult struct {
+	LatencyMs    float64
+	DownloadMbps float64 // worker -> admin
+	UploadMbps   float64 // admin -> worker
+	TestedAt     time.Time
+}
+
+// String formats the result the same way InternetSpeed is shown in the UI
+func (r SpeedTestResult) String() string {
+	return fmt.Sprintf("↓ %.1f Mbps / ↑ %.1f Mbps (%.1f ms)", r.DownloadMbps, r.UploadMbps, r.LatencyMs)
+}
+
+// mbps converts a byte count and duration to megabits per second
+func mbps(bytes int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(bytes) * 8 / d.Seconds() / 1e6
+}
+
+// speedTestChannels routes speed test replies from the receive loop to RunSpeedTest
+type speedTestChannels struct {
+	pong   chan PingPayload
+	data   chan SpeedTestDataPayload
+	result chan SpeedTestResultPayload
+	done   chan struct{} // closed when the test ends so the receive loop never blocks
+}
+
+// ================== Admin side ==================
+
+// RunSpeedTest measures latency and throughput to the worker over the existing connection.
+// It blocks until the test finishes; only one test can run per connection at a time.
+func (a *AdminClient) RunSpeedTest() (*SpeedTestResult, error) {
+	if !a.connected || a.writer == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	a.speedMu.Lock()
+	if a.speedTest != nil {
+		a.speedMu.Unlock()
+		return nil, fmt.Errorf("speed test already running")
+	}
+	ch := &speedTestChannels{
+		pong:   make(chan PingPayload, speedTestPings),
+		data:   make(chan SpeedTestDataPayload, 16),
+		result: make(chan SpeedTestResultPayload, 1),
+		done:   make(chan struct{}),
+	}
+	a.speedTest = ch
+	a.speedMu.Unlock()
+
+	defer func() {
+		a.speedMu.Lock()
+		a.speedTest = nil
+		a.speedMu.Unlock()
+		close(ch.done)
+	}()
+
+	log.Println("ADMIN: Starting speed test...")
+
+	latency, err := a.measureLatency(ch)
+	if err != nil {
+		return nil, fmt.Errorf("latency test failed: %w", err)
+	}
+
+	download, err := a.measureDownload(ch)
+	if err != nil {
+		return nil, fmt.Errorf("download test failed: %w", err)
+	}
+
+	upload, err := a.measureUpload(ch)
+	if err != nil {
+		return nil, fmt.Errorf("upload test failed: %w", err)
+	}
+
+	result := &SpeedTestResult{
+		LatencyMs:    latency,
+		DownloadMbps: download,
+		UploadMbps:   upload,
+		TestedAt:     time.Now(),
+	}
+	log.Printf("ADMIN: Speed test complete: %s\n", result)
+	return result, nil
+}
+
+// measureLatency sends a few pings and returns the average round-trip time in milliseconds
+func (a *AdminClient) measureLatency(ch *speedTestChannels) (float64, error) {
+	var total time.Duration
+	for seq := 0; seq < speedTestPings; seq++ {
+		ping := PingPayload{Seq: seq, SentAt: time.Now().UnixNano()}
+		if err := a.writer.send(MsgTypePing, ping); err != nil {
+			return 0, err
+		}
+
+		rtt, err := waitForPong(ch, seq)
+		if err != nil {
+			return 0, err
+		}
+		total += rtt
+	}
+	return float64(total.Microseconds()) / 1000 / speedTestPings, nil
+}
+
+// waitForPong waits for the pong matching seq and returns its round-trip time
+func waitForPong(ch *speedTestChannels, seq int) (time.Duration, error) {
+	timeout := time.After(speedTestTimeout)
+	for {
+		select {
+		case pong := <-ch.pong:
+			if pong.Seq == seq {
+				return time.Since(time.Unix(0, pong.SentAt)), nil
+			}
+		case <-timeout:
+			return 0, fmt.Errorf("timed out waiting for pong")
+		}
+	}
+}
+
+// measureDownload asks the worker to stream test data and times its arrival
+func (a *AdminClient) measureDownload(ch *speedTestChannels) (float64, error) {
+	start := time.Now()
+	req := SpeedTestPayload{Direction: "download", Bytes: speedTestBytes, ChunkSize: speedTestChunkSize}
+	if err := a.writer.send(MsgTypeSpeedTest, req); err != nil {
+		return 0, err
+	}
+
+	var received int64
+	timeout := time.After(speedTestTimeout)
+	for {
+		select {
+		case chunk := <-ch.data:
+			received += int64(len(chunk.Data))
+			if chunk.Final {
+				return mbps(received, time.Since(start)), nil
+			}
+		case <-timeout:
+			return 0, fmt.Errorf("timed out after receiving %d bytes", received)
+		}
+	}
+}
+
+// measureUpload streams test data to the worker and waits for its receipt
+func (a *AdminClient) measureUpload(ch *speedTestChannels) (float64, error) {
+	chunk := make([]byte, speedTestChunkSize)
+	rand.Read(chunk)
+
+	start := time.Now()
+	var sent int64
+	for remaining := speedTestBytes; remaining > 0; remaining -= speedTestChunkSize {
+		n := speedTestChunkSize
+		if remaining < n {
+			n = remaining
+		}
+		payload := SpeedTestDataPayload{Data: chunk[:n], Final: remaining <= speedTestChunkSize}
+		if err := a.writer.send(MsgTypeSpeedTestData, payload); err != nil {
+			return 0, err
+		}
+		sent += int64(n)
+	}
+
+	select {
+	case result := <-ch.result:
+		return uploadMbps(result, sent, time.Since(start)), nil
+	case <-time.After(speedTestTimeout):
+		return 0, fmt.Errorf("timed out waiting for worker after sending %d bytes", sent)
+	}
+}
+
+// uploadMbps rates an upload from the worker's receipt, timed from the first chunk's
+// arrival to the last so that data still sitting in the admin's send buffers isn't
+// counted as sent. Falls back to the admin's own count and clock if the worker could
+// not time it, as when everything arrived in one chunk.
+func uploadMbps(result SpeedTestResultPayload, sent int64, elapsed time.Duration) float64 {
+	if result.Bytes <= 0 || result.DurationMs <= 0 {
+		return mbps(sent, elapsed)
+	}
+	return mbps(result.Bytes, time.Duration(result.DurationMs*float64(time.Millisecond)))
+}
+
+// dispatchSpeedTest hands a speed test reply to the running test, if any.
+// Returns false if no test is running so the caller can log the message.
+func (a *AdminClient) dispatchSpeedTest(msg Message) bool {
+	a.speedMu.Lock()
+	ch := a.speedTest
+	a.speedMu.Unlock()
+	if ch == nil {
+		return false
+	}
+
+	switch msg.Type {
+	case MsgTypePong:
+		var pong PingPayload
+		if err := json.Unmarshal(msg.Payload, &pong); err == nil {
+			select {
+			case ch.pong <- pong:
+			default:
+			}
+		}
+	case MsgTypeSpeedTestData:
+		var chunk SpeedTestDataPayload
+		if err := json.Unmarshal(msg.Payload, &chunk); err == nil {
+			select {
+			case ch.data <- chunk:
+			case <-ch.done:
+			}
+		}
+	case MsgTypeSpeedTestResult:
+		var result SpeedTestResultPayload
+		if err := json.Unmarshal(msg.Payload, &result); err == nil {
+			select {
+			case ch.result <- result:
+			default:
+			}
+		}
+	}
+	return true
+}
+
+// ================== Worker side ==================
+
+// workerSpeedTest tracks an in-progress upload phase on one connection
+type workerSpeedTest struct {
+	mu       sync.Mutex
+	started  time.Time
+	received int64
+}
+
+// receive accounts for one uploaded chunk and replies with a result on the final one
+func (s *workerSpeedTest) receive(writer *messageWriter, chunk SpeedTestDataPayload) {
+	s.mu.Lock()
+	if s.received == 0 {
+		s.started = time.Now()
+	}
+	s.received += int64(len(chunk.Data))
+	if !chunk.Final {
+		s.mu.Unlock()
+		return
+	}
+	result := SpeedTestResultPayload{
+		Bytes:      s.received,
+		DurationMs: float64(time.Since(s.started).Microseconds()) / 1000,
+	}
+	s.received = 0
+	s.mu.Unlock()
+
+	log.Printf("WORKER: Speed test upload received %d bytes in %.1f ms\n", result.Bytes, result.DurationMs)
+	if err := writer.send(MsgTypeSpeedTestResult, result); err != nil {
+		log.Printf("WORKER: Failed to send speed test result: %v\n", err)
+	}
+}
+
+// sendSpeedTestData streams random test data to the admin for the download phase
+func (w *WorkerServer) sendSpeedTestData(writer *messageWriter, req SpeedTestPayload) {
+	chunkSize := req.ChunkSize
+	if chunkSize <= 0 || chunkSize > 1024*1024 {
+		chunkSize = speedTestChunkSize
+	}
+	total := req.Bytes
+	if total <= 0 || total > 64*1024*1024 {
+		total = speedTestBytes
+	}
+
+	chunk := make([]byte, chunkSize)
+	rand.Read(chunk)
+
+	log.Printf("WORKER: Speed test download: sending %d bytes\n", total)
+	for remaining := total; remaining > 0; remaining -= chunkSize {
+		n := chunkSize
+		if remaining < n {
+			n = remaining
+		}
+		payload := SpeedTestDataPayload{Data: chunk[:n], Final: remaining <= chunkSize}
+		if err := writer.send(MsgTypeSpeedTestData, payload); err != nil {
+			log.Printf("WORKER: Speed test download aborted: %v\n", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package network
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+// The worker reports the test data it received, not the larger base64 encoding of it
+func TestSpeedTestCountsPayloadBytes(t *testing.T) {
+	workerEnd, adminEnd := net.Pipe()
+	defer workerEnd.Close()
+	defer adminEnd.Close()
+	writer := newMessageWriter(workerEnd)
+
+	results := make(chan SpeedTestResultPayload, 1)
+	go func() {
+		var msg Message
+		var result SpeedTestResultPayload
+		if json.NewDecoder(adminEnd).Decode(&msg) == nil && json.Unmarshal(msg.Payload, &result) == nil {
+			results <- result
+		}
+	}()
+
+	var test workerSpeedTest
+	chunk := make([]byte, 1000)
+	test.receive(writer, SpeedTestDataPayload{Data: chunk})
+	test.receive(writer, SpeedTestDataPayload{Data: chunk})
+	test.receive(writer, SpeedTestDataPayload{Data: chunk[:500], Final: true})
+
+	select {
+	case result := <-results:
+		if result.Bytes != 2500 {
+			t.Errorf("worker counted %d bytes, want the 2500 bytes of test data", result.Bytes)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no speed test result from the worker")
+	}
+
+	if got := mbps(1_000_000, time.Second); got != 8 {
+		t.Errorf("1 MB in a second is %.2f Mbps, want 8", got)
+	}
+
+	// Upload rates use the worker's timing unless it could not take one
+	if got := uploadMbps(SpeedTestResultPayload{Bytes: 1_000_000, DurationMs: 500}, 1_000_000, 2*time.Second); got != 16 {
+		t.Errorf("upload timed by the worker is %.2f Mbps, want 16", got)
+	}
+	if got := uploadMbps(SpeedTestResultPayload{Bytes: 1_000_000}, 1_000_000, time.Second); got != 8 {
+		t.Errorf("untimed upload is %.2f Mbps, want 8", got)
+	}
+}
@@ -3,9 +3,11 @@
 package network
 
 import (
+	"adminadmin/internal/config"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/pem"
 	"fmt"
 	"io"
@@ -13,10 +15,10 @@ import (
 	"net"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -25,24 +27,30 @@ import (
 const (
 	DefaultSSHPort     = 2222 // Use non-standard port to avoid conflicts
 	DefaultSSHUsername = "admin"
-	DefaultSSHPassword = "admin"
 )
 
-// SSHCredentials holds SSH login credentials
-type SSHCredentials struct {
-	Username string
-	Password string
-}
-
 // SSHServer provides SSH access to the worker
 type SSHServer struct {
-	listener    net.Listener
-	port        int
-	quit        chan bool
-	config      *ssh.ServerConfig
-	mu          sync.Mutex
-	running     bool
-	credentials SSHCredentials
+	listener   net.Listener
+	port       int
+	quit       chan bool
+	config     *ssh.ServerConfig
+	mu         sync.Mutex
+	running    bool
+	users      *SSHUserDB // nil accepts no logins
+	auth       SSHAuthSettings
+	sftp       SFTPSettings
+	forwarding ForwardingSettings
+	rewrite    RewriteSettings
+	audit      *AuditLog // nil records nothing
+	guard      *loginGuard
+	slots      chan struct{} // One per open connection, up to maxSSHConnections
+	limits     SessionLimits
+	sessions   int // Logged-in connections, counted against limits.MaxSessions
+	detachable *detachableSessions
+
+	// Called after every successful login and every rejected password
+	onAuth func(user, remoteAddr string, success bool)
 }
 
 // NewSSHServer creates a new SSH server
@@ -51,46 +59,196 @@ func NewSSHServer(port int) *SSHServer {
 		port = DefaultSSHPort
 	}
 	return &SSHServer{
-		port: port,
-		quit: make(chan bool),
-		credentials: SSHCredentials{
-			Username: DefaultSSHUsername,
-			Password: DefaultSSHPassword,
-		},
+		port:       port,
+		quit:       make(chan bool),
+		auth:       DefaultSSHAuthSettings(),
+		sftp:       DefaultSFTPSettings(),
+		forwarding: DefaultForwardingSettings(),
+		rewrite:    DefaultRewriteSettings(),
+		guard:      newLoginGuard(),
+		limits:     DefaultSessionLimitSettings().SSH,
+		slots:      make(chan struct{}, maxSSHConnections),
+		detachable: newDetachableSessions(),
+	}
+}
+
+// BannedAddresses returns the source addresses currently refused after failed logins
+func (s *SSHServer) BannedAddresses() []BannedAddress {
+	return s.guard.list()
+}
+
+// Unban lifts the ban of an address; an empty ip lifts every ban
+func (s *SSHServer) Unban(ip string) {
+	s.guard.unban(ip)
+	if ip == "" {
+		log.Println("SSH: All bans lifted")
+	} else {
+		log.Printf("SSH: Ban of %s lifted\n", ip)
 	}
 }
 
-// SetCredentials sets custom SSH credentials
-func (s *SSHServer) SetCredentials(username, password string) {
+// SetUsers sets the accounts that may log in; changes to them apply to the next login
+// and the next shell, command or subsystem request
+func (s *SSHServer) SetUsers(users *SSHUserDB) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if username != "" {
-		s.credentials.Username = username
+	s.users = users
+}
+
+// GetUsers returns the account database, nil if none was set
+func (s *SSHServer) GetUsers() *SSHUserDB {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.users
+}
+
+// SetAuthSettings sets the accepted login methods; they apply to the next login attempt
+func (s *SSHServer) SetAuthSettings(settings SSHAuthSettings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auth = settings
+}
+
+// GetAuthSettings returns the accepted login methods
+func (s *SSHServer) GetAuthSettings() SSHAuthSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.auth
+}
+
+// currentUser looks up the account of a connection; disabled or deleted accounts
+// are refused so the change takes effect on connections already open
+func (s *SSHServer) currentUser(c ssh.ConnMetadata) (SSHUser, bool) {
+	db := s.GetUsers()
+	if db == nil {
+		return SSHUser{}, false
 	}
-	if password != "" {
-		s.credentials.Password = password
+	user, ok := db.Get(c.User())
+	return user, ok && !user.Disabled
+}
+
+// SetSFTPSettings sets the SFTP subsystem settings; they apply to new SFTP sessions
+func (s *SSHServer) SetSFTPSettings(settings SFTPSettings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sftp = settings
+}
+
+// GetSFTPSettings returns the current SFTP subsystem settings
+func (s *SSHServer) GetSFTPSettings() SFTPSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sftp
+}
+
+// SetForwardingSettings sets who may use port forwarding; it applies to new forwards
+func (s *SSHServer) SetForwardingSettings(settings ForwardingSettings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.forwarding = settings
+}
+
+// GetForwardingSettings returns the current port forwarding settings
+func (s *SSHServer) GetForwardingSettings() ForwardingSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.forwarding
+}
+
+// SetRewriteSettings sets the command rewriting rules; they apply to new exec requests
+func (s *SSHServer) SetRewriteSettings(settings RewriteSettings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rewrite = settings
+}
+
+// GetRewriteSettings returns the current command rewriting rules
+func (s *SSHServer) GetRewriteSettings() RewriteSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rewrite
+}
+
+// SetSessionLimits sets the idle, duration and concurrency limits; open sessions
+// are held to the new idle and duration limits too
+func (s *SSHServer) SetSessionLimits(limits SessionLimits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limits = limits
+}
+
+// GetSessionLimits returns the current session limits
+func (s *SSHServer) GetSessionLimits() SessionLimits {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limits
+}
+
+// acquireSession counts a new logged-in connection, or returns false if
+// limits.MaxSessions are already open
+func (s *SSHServer) acquireSession() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.limits.MaxSessions > 0 && s.sessions >= s.limits.MaxSessions {
+		return s.limits.MaxSessions, false
 	}
+	s.sessions++
+	return 0, true
 }
 
-// GetCredentials returns the current SSH credentials
-func (s *SSHServer) GetCredentials() SSHCredentials {
+func (s *SSHServer) releaseSession() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.credentials
+	s.sessions--
 }
 
-// Start starts the SSH server
-func (s *SSHServer) Start(password string) error {
+// SetAuditLog sets where logins, commands and session recordings are recorded
+func (s *SSHServer) SetAuditLog(audit *AuditLog) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.audit = audit
+}
 
-	if s.running {
-		return fmt.Errorf("SSH server already running")
+// GetAuditLog returns the audit log, nil if there is none
+func (s *SSHServer) GetAuditLog() *AuditLog {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.audit
+}
+
+// recordAudit adds an event for a connection to the audit log
+func (s *SSHServer) recordAudit(c ssh.ConnMetadata, event AuditEvent) {
+	event.Session = auditSessionID(c)
+	event.User = c.User()
+	event.Remote = c.RemoteAddr().String()
+	s.GetAuditLog().Record(event)
+}
+
+// auditSessionID returns a short ID tying a connection's audit events together
+func auditSessionID(c ssh.ConnMetadata) string {
+	id := hex.EncodeToString(c.SessionID())
+	if len(id) > 12 {
+		id = id[:12]
 	}
+	return id
+}
 
-	// Use provided password or default
-	if password != "" {
-		s.credentials.Password = password
+// SetAuthCallback sets a callback invoked after every authentication attempt
+func (s *SSHServer) SetAuthCallback(onAuth func(user, remoteAddr string, success bool)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onAuth = onAuth
+}
+
+// Start starts the SSH server. Logins are accepted for the enabled accounts set with
+// SetUsers, with one of the account's keys or, unless password login is turned off
+// (see SetAuthSettings), its password.
+func (s *SSHServer) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return fmt.Errorf("SSH server already running")
 	}
 
 	// Generate or load host key
@@ -99,19 +257,10 @@ func (s *SSHServer) Start(password string) error {
 		return fmt.Errorf("failed to get host key: %w", err)
 	}
 
-	// Configure SSH server with credentials check
-	expectedUser := s.credentials.Username
-	expectedPass := s.credentials.Password
 	s.config = &ssh.ServerConfig{
-		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
-			// Check username and password
-			if c.User() == expectedUser && string(pass) == expectedPass {
-				log.Printf("SSH: User %s authenticated successfully\n", c.User())
-				return nil, nil
-			}
-			log.Printf("SSH: Failed authentication attempt for user %s\n", c.User())
-			return nil, fmt.Errorf("password rejected")
-		},
+		PasswordCallback:  s.checkPassword,
+		PublicKeyCallback: s.checkPublicKey,
+		MaxAuthTries:      maxAuthTries,
 	}
 	s.config.AddHostKey(hostKey)
 
@@ -129,6 +278,66 @@ func (s *SSHServer) Start(password string) error {
 	return nil
 }
 
+// checkPassword validates a password login against the account database, if password
+// login is on. Each failure
+// from an address is answered more slowly, and too many ban it for a while.
+func (s *SSHServer) checkPassword(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+	ip := remoteIP(c.RemoteAddr())
+	if s.guard.banned(ip) {
+		return nil, fmt.Errorf("too many failed logins")
+	}
+	if !s.GetAuthSettings().PasswordAuth {
+		log.Printf("SSH: Rejected password login for %s (password login disabled)\n", c.User())
+		s.recordAudit(c, AuditEvent{Event: AuditLoginFailed, Detail: "password login disabled"})
+		s.reportAuth(c, false)
+		return nil, fmt.Errorf("password login disabled")
+	}
+	db := s.GetUsers()
+	if db != nil {
+		if _, ok := db.CheckPassword(c.User(), pass); ok {
+			return &ssh.Permissions{Extensions: map[string]string{"auth-method": "password"}}, nil
+		}
+	}
+	log.Printf("SSH: Failed authentication attempt for user %s\n", c.User())
+	s.recordAudit(c, AuditEvent{Event: AuditLoginFailed, Detail: "wrong password"})
+	s.reportAuth(c, false)
+
+	delay, ban := s.guard.failed(ip)
+	if ban > 0 {
+		log.Printf("SSH WARNING: Banned %s for %s after %d failed logins\n", ip, ban, maxAuthFailures)
+		s.recordAudit(c, AuditEvent{Event: AuditBan, Detail: "for " + ban.String()})
+		return nil, fmt.Errorf("too many failed logins")
+	}
+	time.Sleep(delay)
+	return nil, fmt.Errorf("password rejected")
+}
+
+// checkPublicKey accepts the keys listed for an enabled account.
+// Rejections are not reported: clients routinely offer several keys before one fits.
+func (s *SSHServer) checkPublicKey(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	db := s.GetUsers()
+	if db == nil || s.guard.banned(remoteIP(c.RemoteAddr())) {
+		return nil, fmt.Errorf("no accounts")
+	}
+	if _, ok := db.CheckKey(c.User(), key); !ok {
+		return nil, fmt.Errorf("public key rejected")
+	}
+	return &ssh.Permissions{Extensions: map[string]string{
+		"auth-method":     "publickey",
+		"key-fingerprint": ssh.FingerprintSHA256(key),
+	}}, nil
+}
+
+// reportAuth passes an authentication outcome to the auth callback, if set
+func (s *SSHServer) reportAuth(c ssh.ConnMetadata, success bool) {
+	s.mu.Lock()
+	onAuth := s.onAuth
+	s.mu.Unlock()
+	if onAuth != nil {
+		onAuth(c.User(), c.RemoteAddr().String(), success)
+	}
+}
+
 // Stop stops the SSH server
 func (s *SSHServer) Stop() error {
 	s.mu.Lock()
@@ -140,6 +349,7 @@ func (s *SSHServer) Stop() error {
 
 	s.running = false
 	close(s.quit)
+	s.detachable.killAll()
 	if s.listener != nil {
 		return s.listener.Close()
 	}
@@ -174,7 +384,18 @@ func (s *SSHServer) acceptConnections() {
 					continue
 				}
 			}
-			go s.handleConnection(conn)
+			// Refuse instead of queueing, so a flood can't pile up goroutines
+			select {
+			case s.slots <- struct{}{}:
+			default:
+				log.Printf("SSH WARNING: Refused connection from %s, %d connections open\n", conn.RemoteAddr(), maxSSHConnections)
+				conn.Close()
+				continue
+			}
+			go func() {
+				defer func() { <-s.slots }()
+				s.handleConnection(conn)
+			}()
 		}
 	}
 }
@@ -182,22 +403,77 @@ func (s *SSHServer) acceptConnections() {
 func (s *SSHServer) handleConnection(conn net.Conn) {
 	defer conn.Close()
 
-	// Perform SSH handshake
+	ip := remoteIP(conn.RemoteAddr())
+	if s.guard.banned(ip) {
+		log.Printf("SSH: Refused connection from banned address %s\n", ip)
+		return
+	}
+
+	// Perform SSH handshake; the deadline keeps silent clients from holding a slot
+	conn.SetDeadline(time.Now().Add(sshHandshakeTimeout))
 	sshConn, chans, reqs, err := ssh.NewServerConn(conn, s.config)
 	if err != nil {
 		log.Printf("SSH: Handshake failed: %v\n", err)
 		return
 	}
+	conn.SetDeadline(time.Time{})
 	defer sshConn.Close()
+	s.guard.succeeded(ip)
 
 	log.Printf("SSH: New connection from %s (%s)\n", sshConn.RemoteAddr(), sshConn.ClientVersion())
+	authDetail := "password"
+	if sshConn.Permissions != nil {
+		if fp := sshConn.Permissions.Extensions["key-fingerprint"]; fp != "" {
+			log.Printf("SSH: User %s authenticated with key %s\n", sshConn.User(), fp)
+			authDetail = "key " + fp
+		} else {
+			log.Printf("SSH: User %s authenticated with password\n", sshConn.User())
+		}
+	}
+	s.reportAuth(sshConn, true)
 
-	// Discard out-of-band requests
-	go ssh.DiscardRequests(reqs)
+	if limit, ok := s.acquireSession(); !ok {
+		s.refuseSession(sshConn, chans, reqs, tooManySessions(limit))
+		return
+	}
+	defer s.releaseSession()
+
+	started := time.Now()
+	s.recordAudit(sshConn, AuditEvent{Event: AuditLogin, Detail: authDetail})
+
+	// Disconnect the client once it is idle or open for too long, warning its terminals first
+	sessions := newSSHConnSessions(s.GetSessionLimits)
+	done := make(chan bool)
+	defer close(done)
+	go sessions.watch.run(done, func(notice SessionNoticePayload) {
+		log.Printf("SSH: Warned %s from %s: %s\n", sshConn.User(), sshConn.RemoteAddr(), notice.Message)
+		sessions.notify(notice)
+	}, func(notice SessionNoticePayload) {
+		log.Printf("SSH: Disconnecting %s from %s: %s\n", sshConn.User(), sshConn.RemoteAddr(), notice.Message)
+		sessions.notify(notice)
+		sshConn.Close()
+	})
+
+	defer func() {
+		detail := "after " + time.Since(started).Round(time.Second).String()
+		if notice := sessions.watch.endReason(); notice != nil {
+			detail += " (" + notice.Reason + ")"
+		}
+		s.recordAudit(sshConn, AuditEvent{Event: AuditLogout, Detail: detail})
+	}()
+
+	// Out-of-band requests carry remote port forwarding
+	go s.handleGlobalRequests(sshConn, reqs)
 
 	// Handle channels
 	for newChannel := range chans {
-		if newChannel.ChannelType() != "session" {
+		sessions.watch.touch()
+		switch newChannel.ChannelType() {
+		case "session":
+		case "direct-tcpip":
+			go s.handleDirectTCPIP(sshConn, newChannel, sessions)
+			continue
+		default:
 			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
 			continue
 		}
@@ -208,7 +484,26 @@ func (s *SSHServer) handleConnection(conn net.Conn) {
 			continue
 		}
 
-		go s.handleChannel(channel, requests)
+		go s.handleChannel(sshConn, channel, requests, sessions)
+	}
+}
+
+// refuseSession turns away a logged-in connection beyond the session limit. The client
+// sees the reason when its channels are rejected; the connection closes after the first
+// one, or after the handshake timeout if it opens none.
+func (s *SSHServer) refuseSession(conn *ssh.ServerConn, chans <-chan ssh.NewChannel, reqs <-chan *ssh.Request, notice SessionNoticePayload) {
+	log.Printf("SSH: Refused session for %s from %s: %s\n", conn.User(), conn.RemoteAddr(), notice.Message)
+	s.recordAudit(conn, AuditEvent{Event: AuditLogin, Detail: "refused: " + notice.Reason})
+	go ssh.DiscardRequests(reqs)
+
+	timeout := time.NewTimer(sshHandshakeTimeout)
+	defer timeout.Stop()
+	select {
+	case newChannel, ok := <-chans:
+		if ok {
+			newChannel.Reject(ssh.ResourceShortage, notice.Message)
+		}
+	case <-timeout.C:
 	}
 }
 
@@ -217,7 +512,11 @@ type sessionState struct {
 	cwd string
 }
 
-func newSessionState() *sessionState {
+// newSessionState starts in the account's working directory, or the home directory
+func newSessionState(user SSHUser) *sessionState {
+	if user.WorkDir != "" {
+		return &sessionState{cwd: user.WorkDir}
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		home = "."
@@ -225,28 +524,208 @@ func newSessionState() *sessionState {
 	return &sessionState{cwd: home}
 }
 
-func (s *SSHServer) handleChannel(channel ssh.Channel, requests <-chan *ssh.Request) {
+// handleChannel serves one session channel. The shell, command or subsystem runs in its
+// own goroutine so window-change and signal requests keep being handled while it runs.
+func (s *SSHServer) handleChannel(conn *ssh.ServerConn, channel ssh.Channel, requests <-chan *ssh.Request, sessions *sshConnSessions) {
 	defer channel.Close()
+	defer sessions.remove(channel)
+	channel = sessions.add(channel)
 
-	state := newSessionState()
+	var state *sessionState
+	sess := &channelSession{}
+	var pty *ptyRequestMsg
+	var rec *sessionRecorder
+	var attached *detachableSession
+	attachID := ""
+	started := false
 
 	for req := range requests {
+		sessions.watch.touch()
 		switch req.Type {
-		case "shell":
+		case "pty-req":
+			var msg ptyRequestMsg
+			if !ptySupported || ssh.Unmarshal(req.Payload, &msg) != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			pty = &msg
+			log.Printf("SSH: PTY requested (%s, %dx%d)\n", msg.Term, msg.Cols, msg.Rows)
 			req.Reply(true, nil)
-			s.startShell(channel)
-			return
-		case "exec":
+		case "env":
+			// Only the variable selecting a detachable session to attach to is accepted
+			var msg struct{ Name, Value string }
+			if started || ssh.Unmarshal(req.Payload, &msg) != nil || msg.Name != SessionEnvVar {
+				req.Reply(false, nil)
+				continue
+			}
+			attachID = msg.Value
 			req.Reply(true, nil)
-			if len(req.Payload) > 4 {
-				cmdLen := int(req.Payload[0])<<24 | int(req.Payload[1])<<16 | int(req.Payload[2])<<8 | int(req.Payload[3])
-				if cmdLen > 0 && cmdLen <= len(req.Payload)-4 {
-					cmd := string(req.Payload[4 : 4+cmdLen])
-					s.executeCommand(channel, cmd, state)
+		case "shell", "exec":
+			var execMsg struct{ Command string }
+			if started || (req.Type == "exec" && ssh.Unmarshal(req.Payload, &execMsg) != nil) {
+				req.Reply(false, nil)
+				continue
+			}
+			user, ok := s.currentUser(conn)
+			if !ok || (req.Type == "shell" && !user.AllowsShell()) {
+				log.Printf("SSH: Refused %s for %s (policy %s)\n", req.Type, conn.User(), user.Policy)
+				req.Reply(false, nil)
+				continue
+			}
+			if req.Type == "shell" && attachID != "" {
+				attached = s.detachable.lookup(attachID, conn.User())
+				if attached == nil {
+					log.Printf("SSH: %s asked to attach to unknown session %q\n", conn.User(), attachID)
+					req.Reply(false, nil)
+					continue
 				}
+				started = true
+				req.Reply(true, nil)
+				s.recordAudit(conn, AuditEvent{Event: AuditShell, Detail: "attached to detachable session " + attachID,
+					Recording: attached.recName})
+				sess.attach(attached.cmd, nil)
+				if pty != nil {
+					attached.resize(pty.Cols, pty.Rows)
+				}
+				go func(d *detachableSession) {
+					defer channel.Close()
+					s.serveAttached(channel, d, conn.RemoteAddr().String())
+				}(attached)
+				continue
+			}
+			started = true
+			req.Reply(true, nil)
+			state = newSessionState(user)
+
+			event, title := AuditEvent{Event: AuditShell}, conn.User()+": shell"
+			notice := ""
+			if req.Type == "exec" {
+				event.Event, event.Command, title = AuditExec, execMsg.Command, conn.User()+": "+execMsg.Command
+				// Check what the client sent; rewrite rules are the admin's own
+				if !user.AllowsCommand(execMsg.Command) {
+					log.Printf("SSH: Refused command %q for %s (not in allowlist)\n", execMsg.Command, conn.User())
+					event.Detail = "refused by policy"
+					s.recordAudit(conn, event)
+					go func() {
+						defer channel.Close()
+						io.WriteString(channel.Stderr(), "Command not allowed for this account\r\n")
+						channel.SendRequest("exit-status", false, ssh.Marshal(&exitStatusMsg{Status: 126}))
+					}()
+					continue
+				}
+				if rewritten := s.GetRewriteSettings().Rewrite(execMsg.Command); rewritten != execMsg.Command {
+					log.Printf("SSH: Rewrote command %q to %q\n", execMsg.Command, rewritten)
+					event.Detail = "rewritten from: " + execMsg.Command
+					notice = "adminadmin: running rewritten command: " + rewritten + "\r\n"
+					execMsg.Command = rewritten
+					event.Command, title = rewritten, conn.User()+": "+rewritten
+				}
+			}
+			var recName string
+			rec, recName = s.GetAuditLog().startRecording(auditSessionID(conn), title, pty)
+			event.Recording = recName
+			s.recordAudit(conn, event)
+			var target ssh.Channel = channel
+			if rec != nil {
+				target = &recordingChannel{Channel: channel, rec: rec}
+			}
+
+			go func(isShell bool, command string, rec *sessionRecorder) {
+				defer channel.Close()
+				defer rec.Close()
+				if notice != "" {
+					io.WriteString(channel.Stderr(), notice)
+				}
+				if isShell {
+					s.startShell(target, state, pty, sess)
+				} else {
+					s.executeCommand(target, command, state, pty, sess)
+				}
+			}(req.Type == "shell", execMsg.Command, rec)
+		case "subsystem":
+			var msg subsystemRequestMsg
+			if started || ssh.Unmarshal(req.Payload, &msg) != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			user, ok := s.currentUser(conn)
+			if !ok {
+				req.Reply(false, nil)
+				continue
+			}
+			if msg.Subsystem == auditSubsystem && s.GetAuditLog() != nil && user.Unrestricted() {
+				started = true
+				req.Reply(true, nil)
+				go func() {
+					defer channel.Close()
+					serveAudit(channel, conn.User(), s.GetAuditLog())
+				}()
+				continue
+			}
+			if msg.Subsystem == usersSubsystem && user.Unrestricted() {
+				started = true
+				req.Reply(true, nil)
+				go func() {
+					defer channel.Close()
+					s.serveUsers(conn, channel, s.GetUsers())
+				}()
+				continue
+			}
+			if msg.Subsystem == completeSubsystem {
+				started = true
+				req.Reply(true, nil)
+				go func() {
+					defer channel.Close()
+					serveCompletion(channel, user, s.GetSFTPSettings())
+				}()
+				continue
+			}
+			if msg.Subsystem == sessionsSubsystem {
+				if !ptySupported || !user.AllowsShell() {
+					log.Printf("SSH: Refused detachable sessions for %s (policy %s)\n", conn.User(), user.Policy)
+					req.Reply(false, nil)
+					continue
+				}
+				started = true
+				req.Reply(true, nil)
+				go func() {
+					defer channel.Close()
+					s.serveSessions(conn, channel, user)
+				}()
+				continue
+			}
+			if msg.Subsystem != "sftp" {
+				req.Reply(false, nil)
+				continue
+			}
+			settings := s.GetSFTPSettings()
+			if !settings.Enabled || !user.AllowsSFTP() {
+				log.Printf("SSH: SFTP requested by %s but disabled\n", conn.User())
+				req.Reply(false, nil)
+				continue
+			}
+			started = true
+			req.Reply(true, nil)
+			s.recordAudit(conn, AuditEvent{Event: AuditSFTP})
+			go func() {
+				defer channel.Close()
+				serveSFTP(channel, conn.User(), settings)
+			}()
+		case "window-change":
+			var msg windowChangeMsg
+			if ssh.Unmarshal(req.Payload, &msg) == nil {
+				sess.resize(msg.Cols, msg.Rows)
+				rec.resize(msg.Cols, msg.Rows)
+				if attached != nil {
+					attached.resize(msg.Cols, msg.Rows)
+				}
+			}
+			req.Reply(true, nil)
+		case "signal":
+			var msg signalMsg
+			if ssh.Unmarshal(req.Payload, &msg) == nil {
+				sess.signal(msg.Signal)
 			}
-			return
-		case "pty-req":
 			req.Reply(true, nil)
 		default:
 			req.Reply(false, nil)
@@ -254,68 +733,57 @@ func (s *SSHServer) handleChannel(channel ssh.Channel, requests <-chan *ssh.Requ
 	}
 }
 
-func (s *SSHServer) startShell(channel ssh.Channel) {
-	var cmd *exec.Cmd
+// startShell runs the user's shell, on a PTY if the client requested one
+func (s *SSHServer) startShell(channel ssh.Channel, state *sessionState, pty *ptyRequestMsg, sess *channelSession) {
+	cmd := shellCommand()
+	cmd.Dir = state.cwd
 
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command("cmd.exe")
-	} else {
-		shell := os.Getenv("SHELL")
-		if shell == "" {
-			shell = "/bin/sh"
-		}
-		cmd = exec.Command(shell)
+	if pty != nil {
+		runInPTY(channel, cmd, pty, sess)
+		return
 	}
 
 	cmd.Stdin = channel
 	cmd.Stdout = channel
-	cmd.Stderr = channel
+	cmd.Stderr = channel.Stderr()
+	newProcessGroup(cmd)
 
 	if err := cmd.Start(); err != nil {
 		log.Printf("SSH: Failed to start shell: %v\n", err)
 		io.WriteString(channel, fmt.Sprintf("Failed to start shell: %v\r\n", err))
+		sendExitStatus(channel, nil, err)
 		return
 	}
+	sess.attach(cmd, nil)
 
-	cmd.Wait()
+	err := cmd.Wait()
+	sendExitStatus(channel, cmd.ProcessState, err)
 }
 
-func (s *SSHServer) executeCommand(channel ssh.Channel, cmdStr string, state *sessionState) {
-	var cmd *exec.Cmd
-	modifiedCmd := cmdStr
+// shellCommand returns the command starting the worker's interactive shell
+func shellCommand() *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd.exe")
+	}
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	return exec.Command(shell)
+}
 
+func (s *SSHServer) executeCommand(channel ssh.Channel, cmdStr string, state *sessionState, pty *ptyRequestMsg, sess *channelSession) {
+	var cmd *exec.Cmd
 	if runtime.GOOS == "windows" {
-		lowerCmd := strings.ToLower(cmdStr)
-		if strings.Contains(lowerCmd, "winget") {
-			if strings.Contains(lowerCmd, "install") || strings.Contains(lowerCmd, "upgrade") {
-				if !strings.Contains(lowerCmd, "--accept-source-agreements") {
-					modifiedCmd += " --accept-source-agreements"
-				}
-				if !strings.Contains(lowerCmd, "--accept-package-agreements") {
-					modifiedCmd += " --accept-package-agreements"
-				}
-				if !strings.Contains(lowerCmd, "--disable-interactivity") {
-					modifiedCmd += " --disable-interactivity"
-				}
-			}
-		}
-		if strings.Contains(lowerCmd, "choco") && strings.Contains(lowerCmd, "install") {
-			if !strings.Contains(lowerCmd, "-y") {
-				modifiedCmd += " -y"
-			}
-		}
-		cmd = exec.Command("cmd.exe", "/c", modifiedCmd)
+		cmd = exec.Command("cmd.exe", "/c", cmdStr)
 	} else {
-		if strings.Contains(cmdStr, "apt-get install") && !strings.Contains(cmdStr, "-y") {
-			modifiedCmd = strings.Replace(cmdStr, "apt-get install", "apt-get install -y", 1)
-		}
-		if strings.Contains(cmdStr, "apt install") && !strings.Contains(cmdStr, "-y") {
-			modifiedCmd = strings.Replace(cmdStr, "apt install", "apt install -y", 1)
-		}
-		if (strings.Contains(cmdStr, "yum install") || strings.Contains(cmdStr, "dnf install")) && !strings.Contains(cmdStr, "-y") {
-			modifiedCmd = strings.Replace(strings.Replace(modifiedCmd, "yum install", "yum install -y", 1), "dnf install", "dnf install -y", 1)
-		}
-		cmd = exec.Command("/bin/sh", "-c", modifiedCmd)
+		cmd = exec.Command("/bin/sh", "-c", cmdStr)
+	}
+	cmd.Dir = state.cwd
+
+	if pty != nil {
+		runInPTY(channel, cmd, pty, sess)
+		return
 	}
 
 	if runtime.GOOS == "windows" {
@@ -335,38 +803,34 @@ func (s *SSHServer) executeCommand(channel ssh.Channel, cmdStr string, state *se
 	}
 
 	cmd.Stdout = channel
-	cmd.Stderr = channel
-
-	exitStatus := 0
-	if err := cmd.Run(); err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			if status, ok := exitErr.Sys().(interface{ ExitStatus() int }); ok {
-				exitStatus = status.ExitStatus()
-			} else {
-				exitStatus = 1
-			}
-		} else {
-			io.WriteString(channel, fmt.Sprintf("Error: %v\r\n", err))
-			exitStatus = 1
-		}
+	cmd.Stderr = channel.Stderr()
+	newProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		io.WriteString(channel.Stderr(), fmt.Sprintf("Error: %v\r\n", err))
+		sendExitStatus(channel, nil, err)
+		return
 	}
+	sess.attach(cmd, nil)
 
-	type exitStatusMsg struct{ Status uint32 }
-	channel.SendRequest("exit-status", false, ssh.Marshal(&exitStatusMsg{
-		Status: uint32(exitStatus),
-	}))
+	err := cmd.Wait()
+	sendExitStatus(channel, cmd.ProcessState, err)
 }
 
 // getHostKeyPath returns the path to store the SSH host key
 func getHostKeyPath() string {
 	// Store in user's config directory
-	configDir, err := os.UserConfigDir()
+	return config.Path("ssh_host_key")
+}
+
+// HostKeyFingerprint returns the SHA256 fingerprint of this machine's SSH host key,
+// so admins can verify it on first connection
+func HostKeyFingerprint() string {
+	hostKey, err := getOrCreateHostKey()
 	if err != nil {
-		configDir = "."
+		return "unavailable"
 	}
-	keyDir := filepath.Join(configDir, "adminadmin")
-	os.MkdirAll(keyDir, 0700)
-	return filepath.Join(keyDir, "ssh_host_key")
+	return ssh.FingerprintSHA256(hostKey.PublicKey())
 }
 
 // getOrCreateHostKey loads existing host key or generates a new one
@@ -412,14 +876,21 @@ func getOrCreateHostKey() (ssh.Signer, error) {
 	return signer, nil
 }
 
-// SSHClient connects to a worker's SSH server and executes commands.
-// Each command runs in its own exec session; working-directory state is
-// tracked client-side and prepended to every command.
+// SSHClient connects to a worker's SSH server. Commands (ExecuteCommand, Exec,
+// StartExec) each run in their own exec session, with the working directory tracked
+// client-side and prepended to every command. Interactive shells (StartShell,
+// AttachShell) are ShellSessions that keep their own state in the remote shell; the
+// subsystems, tunnels and SFTP share the same connection.
 type SSHClient struct {
 	client        *ssh.Client
 	cwd           string // client-tracked working directory
 	remoteWindows bool
+	signer        ssh.Signer         // optional key tried before the password
+	confirmHost   ConfirmHostKeyFunc // asks whether to trust a new host key
 	mu            sync.Mutex
+
+	// Same connection as client, readable without mu so Abort can interrupt a running command
+	live atomic.Pointer[ssh.Client]
 }
 
 // NewSSHClient creates a new SSH client.
@@ -427,12 +898,44 @@ func NewSSHClient() *SSHClient {
 	return &SSHClient{}
 }
 
+// SetSigner sets a private key to authenticate with; it is tried before the password.
+func (c *SSHClient) SetSigner(signer ssh.Signer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.signer = signer
+}
+
+// SetHostKeyConfirm sets the callback asking whether to trust a host not yet in
+// known_hosts. Without it, unknown hosts are rejected.
+func (c *SSHClient) SetHostKeyConfirm(confirm ConfirmHostKeyFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.confirmHost = confirm
+}
+
 // Connect dials the SSH server and detects the remote OS.
+// An empty password means only the key set with SetSigner is offered.
 func (c *SSHClient) Connect(host string, port int, user, password string) error {
+	c.mu.Lock()
+	signer := c.signer
+	confirmHost := c.confirmHost
+	c.mu.Unlock()
+
+	var auth []ssh.AuthMethod
+	if signer != nil {
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if password != "" {
+		auth = append(auth, ssh.Password(password))
+	}
+	if len(auth) == 0 {
+		return fmt.Errorf("no SSH key or password to authenticate with")
+	}
+
 	config := &ssh.ClientConfig{
 		User:            user,
-		Auth:            []ssh.AuthMethod{ssh.Password(password)},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback(confirmHost),
 		Timeout:         10 * time.Second,
 	}
 	addr := fmt.Sprintf("%s:%d", host, port)
@@ -440,7 +943,10 @@ func (c *SSHClient) Connect(host string, port int, user, password string) error
 	if err != nil {
 		return fmt.Errorf("failed to connect: %w", err)
 	}
+	c.mu.Lock()
 	c.client = client
+	c.mu.Unlock()
+	c.live.Store(client)
 
 	// Probe OS: "ver" only works on Windows cmd.exe
 	if out, err2 := c.runExec("ver"); err2 == nil && strings.Contains(strings.ToLower(out), "windows") {
@@ -471,86 +977,24 @@ func (c *SSHClient) Connect(host string, port int, user, password string) error
 // runExec opens a fresh exec session, runs cmd, returns combined output.
 // This is the raw transport — no cwd injection, no filtering.
 func (c *SSHClient) runExec(cmd string) (string, error) {
-	if c.client == nil {
-		return "", fmt.Errorf("not connected")
-	}
-	session, err := c.client.NewSession()
-	if err != nil {
-		return "", err
-	}
-	defer session.Close()
-
-	log.Printf("SSH runExec: sending: %q\n", cmd)
-	out, err := session.CombinedOutput(cmd)
-	result := string(out)
-	log.Printf("SSH runExec: output=%q err=%v\n", result, err)
-
-	// Ignore "exit without status" noise — the output is still valid
-	if err != nil {
-		es := err.Error()
-		if strings.Contains(es, "exited without exit status") ||
-			strings.Contains(es, "exit status") ||
-			strings.Contains(es, "exit signal") {
-			return result, nil
-		}
-		return result, err
-	}
-	return result, nil
+	result, err := c.runExecResult(cmd)
+	return result.Combined, err
 }
 
-// ExecuteCommand runs cmdStr on the remote host inside the tracked cwd.
-// "cd" commands update the tracked cwd rather than being run directly.
+// ExecuteCommand runs cmdStr like Exec and returns stdout and stderr combined,
+// without the exit status.
 func (c *SSHClient) ExecuteCommand(cmdStr string) (string, error) {
+	result, err := c.Exec(cmdStr)
+	return result.Combined, err
+}
+
+// ExecuteCommandWithStatus runs cmdStr in the tracked cwd like ExecuteCommand
+// and also returns the remote exit code. It does not interpret "cd".
+func (c *SSHClient) ExecuteCommandWithStatus(cmdStr string) (string, int, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-
-	if c.client == nil {
-		return "", fmt.Errorf("not connected")
-	}
-
-	trimmed := strings.TrimSpace(cmdStr)
-	lower := strings.ToLower(trimmed)
-
-	// ── cd built-in ──────────────────────────────────────────────────────
-	if lower == "cd" || strings.HasPrefix(lower, "cd ") || strings.HasPrefix(lower, "cd\t") {
-		target := strings.TrimSpace(trimmed[2:])
-
-		var probeCmd string
-		if c.remoteWindows {
-			if target == "" {
-				// bare "cd" on Windows prints current dir
-				probeCmd = "cd"
-			} else {
-				// change drive+dir, then print new dir
-				probeCmd = fmt.Sprintf("cd /d %s && cd", target)
-			}
-		} else {
-			if target == "" || target == "~" {
-				probeCmd = "cd ~ && pwd"
-			} else {
-				probeCmd = fmt.Sprintf("cd %s && pwd", target)
-			}
-		}
-
-		// Wrap with current cwd so relative paths resolve correctly
-		out, err := c.runExec(c.wrapWithCwd(probeCmd))
-		if err != nil {
-			return out, err
-		}
-		// Take the last non-empty line — on Windows "cd" prints the path, possibly
-		// with a trailing prompt line; on Linux pwd prints exactly one line.
-		candidate := lastNonEmptyLine(out)
-		if isValidPath(candidate) {
-			c.cwd = candidate
-			return candidate, nil
-		}
-		// cd failed (bad path etc.) — return the raw output as the error message
-		return strings.TrimSpace(out), nil
-	}
-
-	// ── regular command ──────────────────────────────────────────────────
-	full := c.wrapWithCwd(trimmed)
-	return c.runExec(full)
+	result, err := c.runExecResult(c.wrapWithCwd(strings.TrimSpace(cmdStr)))
+	return result.Combined, result.ExitCode, err
 }
 
 // wrapWithCwd prepends a cd command so every exec runs in the tracked directory.
@@ -597,6 +1041,7 @@ func isValidPath(s string) bool {
 func (c *SSHClient) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.live.Store(nil)
 	if c.client != nil {
 		err := c.client.Close()
 		c.client = nil
@@ -605,6 +1050,14 @@ func (c *SSHClient) Close() error {
 	return nil
 }
 
+// Abort closes the connection immediately, interrupting any running command.
+// Unlike Close it does not wait for the command to finish.
+func (c *SSHClient) Abort() {
+	if client := c.live.Swap(nil); client != nil {
+		client.Close()
+	}
+}
+
 // IsConnected reports whether there is an active connection.
 func (c *SSHClient) IsConnected() bool {
 	c.mu.Lock()
@@ -0,0 +1,278 @@
+package network
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ExecResult is the outcome of one command run over SSH
+type ExecResult struct {
+	Stdout   string
+	Stderr   string
+	Combined string // Stdout and stderr interleaved in the order they arrived
+	ExitCode int    // -1 if the worker reported no exit status
+	Signal   string // Signal that killed the command without the "SIG" prefix, e.g. "KILL"; empty if it exited
+	Canceled bool   // The session was closed with RunningCommand.Cancel before the command ended
+	Duration time.Duration
+}
+
+// Success reports whether the command exited with status 0
+func (r ExecResult) Success() bool {
+	return r.ExitCode == 0 && r.Signal == "" && !r.Canceled
+}
+
+// Status describes how the command ended, e.g. "exit 0" or "killed by KILL"
+func (r ExecResult) Status() string {
+	switch {
+	case r.Canceled:
+		return "canceled"
+	case r.Signal != "":
+		return "killed by " + r.Signal
+	case r.ExitCode < 0:
+		return "no exit status"
+	default:
+		return fmt.Sprintf("exit %d", r.ExitCode)
+	}
+}
+
+// RunningCommand is a command started with StartExec
+type RunningCommand struct {
+	session *ssh.Session
+	started time.Time
+	done    chan struct{}
+
+	mu                       sync.Mutex
+	stdout, stderr, combined bytes.Buffer
+	onOutput                 func(data []byte, stderr bool)
+	canceled                 bool
+
+	// Set before done is closed
+	result ExecResult
+	err    error
+}
+
+// outputStream receives one of a session's output streams
+type outputStream struct {
+	cmd    *RunningCommand
+	stderr bool
+}
+
+func (o *outputStream) Write(p []byte) (int, error) {
+	r := o.cmd
+	r.mu.Lock()
+	if o.stderr {
+		r.stderr.Write(p)
+	} else {
+		r.stdout.Write(p)
+	}
+	r.combined.Write(p)
+	onOutput := r.onOutput
+	r.mu.Unlock()
+	if onOutput != nil {
+		onOutput(append([]byte(nil), p...), o.stderr)
+	}
+	return len(p), nil
+}
+
+// startExec opens an exec session on client running cmd. This is the raw transport,
+// with no cwd injection.
+func startExec(client *ssh.Client, cmd string, onOutput func(data []byte, stderr bool)) (*RunningCommand, error) {
+	if client == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	r := &RunningCommand{session: session, done: make(chan struct{}), onOutput: onOutput}
+	session.Stdout = &outputStream{cmd: r}
+	session.Stderr = &outputStream{cmd: r, stderr: true}
+
+	log.Printf("SSH runExec: sending: %q\n", cmd)
+	r.started = time.Now()
+	if err := session.Start(cmd); err != nil {
+		session.Close()
+		return nil, err
+	}
+	go func() {
+		// Wait returns once the status has arrived and all output was delivered
+		r.finish(session.Wait())
+		session.Close()
+		close(r.done)
+	}()
+	return r, nil
+}
+
+// finish turns the session's end into the command's result
+func (r *RunningCommand) finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.result = ExecResult{
+		Stdout:   r.stdout.String(),
+		Stderr:   r.stderr.String(),
+		Combined: r.combined.String(),
+		ExitCode: -1,
+		Duration: time.Since(r.started),
+		Canceled: r.canceled,
+	}
+
+	var exitErr *ssh.ExitError
+	var missingErr *ssh.ExitMissingError
+	switch {
+	case err == nil:
+		r.result.ExitCode = 0
+	case errors.As(err, &exitErr):
+		r.result.ExitCode = exitErr.ExitStatus()
+		r.result.Signal = strings.TrimPrefix(exitErr.Signal(), "SIG")
+	case errors.As(err, &missingErr), r.canceled:
+		// Some servers close the channel without a status, and a canceled session
+		// ends without one; the output is still valid
+	default:
+		log.Printf("SSH runExec: failed: %v\n", err)
+		r.err = err
+		return
+	}
+	log.Printf("SSH runExec: %s in %s, %d bytes stdout, %d bytes stderr\n",
+		r.result.Status(), r.result.Duration.Round(time.Millisecond), len(r.result.Stdout), len(r.result.Stderr))
+}
+
+// Wait blocks until the command ends and returns its result. A command that runs
+// and fails is not an error: its status is in the result.
+func (r *RunningCommand) Wait() (ExecResult, error) {
+	<-r.done
+	return r.result, r.err
+}
+
+// Done is closed when the command has ended
+func (r *RunningCommand) Done() <-chan struct{} {
+	return r.done
+}
+
+// Elapsed returns how long the command has been running, or ran
+func (r *RunningCommand) Elapsed() time.Duration {
+	select {
+	case <-r.done:
+		return r.result.Duration
+	default:
+		return time.Since(r.started)
+	}
+}
+
+// Interrupt sends SIGINT to the command, like Ctrl-C in a terminal. The worker
+// delivers it to everything the command started.
+func (r *RunningCommand) Interrupt() error {
+	if r.session == nil {
+		return nil
+	}
+	return r.session.Signal(ssh.SIGINT)
+}
+
+// Cancel kills the command and closes its session without waiting for it to exit
+func (r *RunningCommand) Cancel() {
+	if r.session == nil {
+		return
+	}
+	r.mu.Lock()
+	r.canceled = true
+	r.mu.Unlock()
+	r.session.Signal(ssh.SIGKILL)
+	r.session.Close()
+}
+
+// finishedCommand returns a RunningCommand that has already ended with result
+func finishedCommand(result ExecResult, err error, onOutput func(data []byte, stderr bool)) *RunningCommand {
+	if onOutput != nil {
+		if result.Stdout != "" {
+			onOutput([]byte(result.Stdout), false)
+		}
+		if result.Stderr != "" {
+			onOutput([]byte(result.Stderr), true)
+		}
+	}
+	r := &RunningCommand{done: make(chan struct{}), result: result, err: err}
+	close(r.done)
+	return r
+}
+
+// runExecResult runs cmd on a fresh exec session and waits for it; see startExec
+func (c *SSHClient) runExecResult(cmd string) (ExecResult, error) {
+	r, err := startExec(c.client, cmd, nil)
+	if err != nil {
+		return ExecResult{ExitCode: -1}, err
+	}
+	return r.Wait()
+}
+
+// Exec runs cmdStr on the remote host inside the tracked cwd and returns its output
+// and status once it ends. See StartExec.
+func (c *SSHClient) Exec(cmdStr string) (ExecResult, error) {
+	r, err := c.StartExec(cmdStr, nil)
+	if err != nil {
+		return ExecResult{ExitCode: -1}, err
+	}
+	return r.Wait()
+}
+
+// StartExec starts cmdStr on the remote host inside the tracked cwd. onOutput
+// (optional) receives output as it arrives, from network goroutines; stderr tells
+// which stream it came from. "cd" commands update the tracked cwd rather than being
+// run directly: they complete before StartExec returns, and a successful cd prints
+// the new directory.
+func (c *SSHClient) StartExec(cmdStr string, onOutput func(data []byte, stderr bool)) (*RunningCommand, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	trimmed := strings.TrimSpace(cmdStr)
+	lower := strings.ToLower(trimmed)
+	if lower != "cd" && !strings.HasPrefix(lower, "cd ") && !strings.HasPrefix(lower, "cd\t") {
+		return startExec(c.client, c.wrapWithCwd(trimmed), onOutput)
+	}
+
+	result, err := c.changeDir(strings.TrimSpace(trimmed[2:]))
+	return finishedCommand(result, err, onOutput), nil
+}
+
+// changeDir runs a cd to target from the tracked cwd and tracks the new directory
+// if it succeeds; c.mu must be held
+func (c *SSHClient) changeDir(target string) (ExecResult, error) {
+	var probeCmd string
+	if c.remoteWindows {
+		if target == "" {
+			// bare "cd" on Windows prints current dir
+			probeCmd = "cd"
+		} else {
+			// change drive+dir, then print new dir
+			probeCmd = fmt.Sprintf("cd /d %s && cd", target)
+		}
+	} else {
+		if target == "" || target == "~" {
+			probeCmd = "cd ~ && pwd"
+		} else {
+			probeCmd = fmt.Sprintf("cd %s && pwd", target)
+		}
+	}
+
+	// Wrap with current cwd so relative paths resolve correctly
+	result, err := c.runExecResult(c.wrapWithCwd(probeCmd))
+	if err != nil || !result.Success() {
+		return result, err
+	}
+	// Take the last non-empty line — on Windows "cd" prints the path, possibly
+	// with a trailing prompt line; on Linux pwd prints exactly one line.
+	if candidate := lastNonEmptyLine(result.Stdout); isValidPath(candidate) {
+		c.cwd = candidate
+		result.Stdout, result.Combined = candidate, candidate
+	}
+	return result, nil
+}
@@ -0,0 +1,151 @@
+package network
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// maxAuthFailures failed passwords from one address within authFailureWindow ban it
+	maxAuthFailures   = 5
+	authFailureWindow = 10 * time.Minute
+	// firstBanDuration doubles with every further ban of the same address, up to maxBanDuration
+	firstBanDuration = 15 * time.Minute
+	maxBanDuration   = 24 * time.Hour
+	// maxFailureDelay caps the pause before a wrong password is answered
+	maxFailureDelay = 8 * time.Second
+	// maxAuthTries limits password and key attempts per connection, as OpenSSH does
+	maxAuthTries = 6
+
+	// maxSSHConnections limits concurrent connections, including ones still in the handshake
+	maxSSHConnections = 64
+	// sshHandshakeTimeout bounds the key exchange and login of a new connection
+	sshHandshakeTimeout = 30 * time.Second
+)
+
+// BannedAddress is a source address refused after too many failed logins
+type BannedAddress struct {
+	IP       string
+	Until    time.Time
+	Failures int // Failed logins that led to the ban
+	Bans     int // Times this address was banned since the worker started
+}
+
+// sourceState tracks failed logins from one address
+type sourceState struct {
+	failures    int
+	lastFailure time.Time
+	bannedUntil time.Time
+	bans        int
+}
+
+// loginGuard counts failed logins per source address, slows them down and bans
+// addresses that keep failing
+type loginGuard struct {
+	mu      sync.Mutex
+	sources map[string]*sourceState
+}
+
+func newLoginGuard() *loginGuard {
+	return &loginGuard{sources: make(map[string]*sourceState)}
+}
+
+// remoteIP returns the IP of addr without the port
+func remoteIP(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// banned reports whether ip is currently banned
+func (g *loginGuard) banned(ip string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	src := g.sources[ip]
+	return src != nil && time.Now().Before(src.bannedUntil)
+}
+
+// failed records a failed login from ip. It returns how long to wait before answering
+// and, if this failure banned the address, for how long.
+func (g *loginGuard) failed(ip string) (delay, ban time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	g.prune(now)
+
+	src := g.sources[ip]
+	if src == nil {
+		src = &sourceState{}
+		g.sources[ip] = src
+	}
+	if now.Sub(src.lastFailure) > authFailureWindow {
+		src.failures = 0
+	}
+	src.failures++
+	src.lastFailure = now
+
+	if src.failures >= maxAuthFailures {
+		ban = firstBanDuration << src.bans
+		if ban > maxBanDuration || ban <= 0 {
+			ban = maxBanDuration
+		}
+		src.bans++
+		src.bannedUntil = now.Add(ban)
+		return 0, ban
+	}
+	delay = time.Second << (src.failures - 1)
+	if delay > maxFailureDelay {
+		delay = maxFailureDelay
+	}
+	return delay, 0
+}
+
+// succeeded clears the failure count of ip after a successful login
+func (g *loginGuard) succeeded(ip string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if src := g.sources[ip]; src != nil {
+		src.failures = 0
+	}
+}
+
+// prune forgets addresses that have not failed recently and were not banned within
+// maxBanDuration, so later bans of an address grow longer; g.mu must be held
+func (g *loginGuard) prune(now time.Time) {
+	for ip, src := range g.sources {
+		if now.Sub(src.lastFailure) > authFailureWindow && now.Sub(src.bannedUntil) > maxBanDuration {
+			delete(g.sources, ip)
+		}
+	}
+}
+
+// list returns the currently banned addresses, the longest-banned first
+func (g *loginGuard) list() []BannedAddress {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	var bans []BannedAddress
+	for ip, src := range g.sources {
+		if now.Before(src.bannedUntil) {
+			bans = append(bans, BannedAddress{IP: ip, Until: src.bannedUntil, Failures: src.failures, Bans: src.bans})
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Until.After(bans[j].Until) })
+	return bans
+}
+
+// unban lifts the ban of ip, or of every address if ip is empty, and resets their counts
+func (g *loginGuard) unban(ip string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for addr, src := range g.sources {
+		if ip == "" || addr == ip {
+			src.bannedUntil = time.Time{}
+			src.failures = 0
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package network
+
+import (
+	"adminadmin/internal/config"
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// AuthorizedKeysFile listed the public keys allowed to log in to this worker before
+	// keys moved into the accounts in ssh_users.json; it is only read for migration
+	AuthorizedKeysFile = "authorized_keys"
+	// ClientKeyFile is the admin's private key used to log in to workers
+	ClientKeyFile = "id_ed25519"
+)
+
+// AuthorizedKey is one entry of the authorized_keys file
+type AuthorizedKey struct {
+	Key         ssh.PublicKey
+	Comment     string
+	Fingerprint string
+}
+
+// LoadAuthorizedKeys reads the authorized_keys file; a missing file means no keys
+func LoadAuthorizedKeys() ([]AuthorizedKey, error) {
+	data, err := os.ReadFile(config.Path(AuthorizedKeysFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []AuthorizedKey
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			log.Printf("SSH: Skipping invalid authorized_keys line: %v\n", err)
+			continue
+		}
+		keys = append(keys, AuthorizedKey{Key: key, Comment: comment, Fingerprint: ssh.FingerprintSHA256(key)})
+	}
+	return keys, scanner.Err()
+}
+
+// LoadOrCreateClientKey loads the admin's SSH key, generating an Ed25519 key on first use
+func LoadOrCreateClientKey() (ssh.Signer, error) {
+	keyPath := config.Path(ClientKeyFile)
+
+	if keyData, err := os.ReadFile(keyPath); err == nil {
+		signer, err := ssh.ParsePrivateKey(keyData)
+		if err == nil {
+			log.Printf("SSH: Loaded client key %s\n", ssh.FingerprintSHA256(signer.PublicKey()))
+			return signer, nil
+		}
+		return nil, fmt.Errorf("invalid client key %s: %w", keyPath, err)
+	}
+
+	log.Println("SSH: Generating new client key...")
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate client key: %w", err)
+	}
+	block, err := ssh.MarshalPrivateKey(privateKey, "adminadmin")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode client key: %w", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600); err != nil {
+		return nil, fmt.Errorf("failed to save client key: %w", err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("SSH: Client key saved to %s (%s)\n", keyPath, ssh.FingerprintSHA256(signer.PublicKey()))
+	return signer, nil
+}
+
+// passwordAlphabet avoids characters that are easy to confuse when read aloud or typed
+const passwordAlphabet = "abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GeneratePassword returns a random password of the given length
+func GeneratePassword(length int) (string, error) {
+	max := big.NewInt(int64(len(passwordAlphabet)))
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = passwordAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,150 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ptyRequestMsg is the payload of a "pty-req" channel request (RFC 4254 section 6.2)
+type ptyRequestMsg struct {
+	Term     string
+	Cols     uint32
+	Rows     uint32
+	WidthPx  uint32
+	HeightPx uint32
+	Modes    string
+}
+
+// windowChangeMsg is the payload of a "window-change" channel request
+type windowChangeMsg struct {
+	Cols     uint32
+	Rows     uint32
+	WidthPx  uint32
+	HeightPx uint32
+}
+
+// signalMsg is the payload of a "signal" channel request
+type signalMsg struct {
+	Signal string
+}
+
+// subsystemRequestMsg is the payload of a "subsystem" channel request
+type subsystemRequestMsg struct {
+	Subsystem string
+}
+
+// exitStatusMsg is the payload of an "exit-status" channel request
+type exitStatusMsg struct {
+	Status uint32
+}
+
+// exitSignalMsg is the payload of an "exit-signal" channel request
+type exitSignalMsg struct {
+	Signal     string
+	CoreDumped bool
+	Error      string
+	Lang       string
+}
+
+// channelSession is the process running on one session channel, so window-change
+// and signal requests arriving while it runs can reach it
+type channelSession struct {
+	mu  sync.Mutex
+	cmd *exec.Cmd
+	tty *os.File // nil when running without a PTY
+}
+
+// attach records the started process
+func (c *channelSession) attach(cmd *exec.Cmd, tty *os.File) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cmd = cmd
+	c.tty = tty
+}
+
+// resize applies a window-change to the PTY, if any
+func (c *channelSession) resize(cols, rows uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tty == nil {
+		return
+	}
+	if err := resizePTY(c.tty, cols, rows); err != nil {
+		log.Printf("SSH: Failed to resize PTY: %v\n", err)
+	}
+}
+
+// signal delivers an SSH signal (e.g. "INT") to the running process
+func (c *channelSession) signal(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cmd == nil || c.cmd.Process == nil {
+		return
+	}
+	sig, ok := sshSignals[name]
+	if !ok {
+		log.Printf("SSH: Ignoring unsupported signal %q\n", name)
+		return
+	}
+	log.Printf("SSH: Forwarding signal %s to pid %d\n", name, c.cmd.Process.Pid)
+	if err := signalProcess(c.cmd.Process, sig); err != nil {
+		log.Printf("SSH: Failed to signal process: %v\n", err)
+	}
+}
+
+// runInPTY runs cmd on a new PTY wired to the channel and reports its exit status
+func runInPTY(channel ssh.Channel, cmd *exec.Cmd, req *ptyRequestMsg, sess *channelSession) {
+	if req.Term != "" {
+		cmd.Env = append(os.Environ(), "TERM="+req.Term)
+	}
+	tty, err := startPTY(cmd, req.Cols, req.Rows)
+	if err != nil {
+		log.Printf("SSH: Failed to start PTY: %v\n", err)
+		io.WriteString(channel, fmt.Sprintf("Failed to start terminal: %v\r\n", err))
+		sendExitStatus(channel, nil, err)
+		return
+	}
+	defer tty.Close()
+	sess.attach(cmd, tty)
+
+	go io.Copy(tty, channel)
+	// Reading the PTY fails once the process (and everything holding the terminal) exits
+	io.Copy(channel, tty)
+
+	err = cmd.Wait()
+	sendExitStatus(channel, cmd.ProcessState, err)
+}
+
+// sendExitStatus tells the client how the process ended: "exit-signal" if it was
+// killed by a signal, otherwise "exit-status" (1 if it never ran)
+func sendExitStatus(channel ssh.Channel, state *os.ProcessState, err error) {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		state = exitErr.ProcessState
+	}
+	if state != nil {
+		if name := exitSignalName(state); name != "" {
+			channel.SendRequest("exit-signal", false, ssh.Marshal(&exitSignalMsg{Signal: name}))
+			return
+		}
+	}
+
+	status := 0
+	switch {
+	case state != nil:
+		status = state.ExitCode()
+	case err != nil:
+		status = 1
+	}
+	if status < 0 {
+		status = 1
+	}
+	channel.SendRequest("exit-status", false, ssh.Marshal(&exitStatusMsg{Status: uint32(status)}))
+}
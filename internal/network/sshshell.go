@@ -0,0 +1,158 @@
+package network
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ShellSession is a persistent interactive shell on the worker, attached to a PTY
+// when the worker supports one. Output (stdout and stderr) is read with Read and
+// keystrokes are sent with Write, so state like the working directory lives in the
+// remote shell instead of being tracked client-side.
+type ShellSession struct {
+	session *ssh.Session
+	stdin   io.WriteCloser
+	output  io.Reader
+	HasPTY  bool // false if the worker refused the PTY (e.g. Windows) and the shell uses pipes
+
+	// ID of the detachable session the shell is attached to, or "". Closing the
+	// session detaches; the shell keeps running on the worker.
+	SessionID string
+}
+
+// StartShell opens an interactive shell, requesting a PTY of the given terminal type and size
+func (c *SSHClient) StartShell(term string, cols, rows int) (*ShellSession, error) {
+	return c.openShell(term, cols, rows, "")
+}
+
+// AttachShell attaches to a detachable session (see NewDetachableSession); the worker
+// first replays the output the session kept while detached
+func (c *SSHClient) AttachShell(id, term string, cols, rows int) (*ShellSession, error) {
+	return c.openShell(term, cols, rows, id)
+}
+
+// openShell starts a new shell, or attaches to the detachable session sessionID
+func (c *SSHClient) openShell(term string, cols, rows int, sessionID string) (*ShellSession, error) {
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+	if client == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	stderr, err := session.StderrPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	// Merge stdout and stderr so neither stalls the channel while the other is read
+	pr, pw := io.Pipe()
+	var copies sync.WaitGroup
+	copies.Add(2)
+	for _, r := range []io.Reader{stdout, stderr} {
+		go func(r io.Reader) {
+			defer copies.Done()
+			io.Copy(pw, r)
+		}(r)
+	}
+	go func() {
+		copies.Wait()
+		pw.Close()
+	}()
+
+	s := &ShellSession{session: session, stdin: stdin, output: pr, SessionID: sessionID}
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 38400,
+		ssh.TTY_OP_OSPEED: 38400,
+	}
+	if err := session.RequestPty(term, rows, cols, modes); err != nil {
+		log.Printf("SSH Client: PTY refused, using a plain shell: %v\n", err)
+	} else {
+		s.HasPTY = true
+	}
+
+	if sessionID != "" {
+		if err := session.Setenv(SessionEnvVar, sessionID); err != nil {
+			session.Close()
+			return nil, fmt.Errorf("worker refused to attach to session %s: %w", sessionID, err)
+		}
+	}
+	if err := session.Shell(); err != nil {
+		session.Close()
+		if sessionID != "" {
+			return nil, fmt.Errorf("failed to attach to session %s (did it end?): %w", sessionID, err)
+		}
+		return nil, fmt.Errorf("failed to start shell: %w", err)
+	}
+	if sessionID != "" {
+		log.Printf("SSH Client: Attached to session %s (pty=%v, %dx%d)\n", sessionID, s.HasPTY, cols, rows)
+	} else {
+		log.Printf("SSH Client: Shell started (pty=%v, %dx%d)\n", s.HasPTY, cols, rows)
+	}
+	return s, nil
+}
+
+// Read reads shell output (stdout and stderr); it returns io.EOF once the shell exits
+func (s *ShellSession) Read(p []byte) (int, error) {
+	return s.output.Read(p)
+}
+
+// Write sends keystrokes (or any input) to the shell
+func (s *ShellSession) Write(p []byte) (int, error) {
+	return s.stdin.Write(p)
+}
+
+// Resize tells the worker the terminal size changed
+func (s *ShellSession) Resize(cols, rows int) error {
+	if !s.HasPTY {
+		return nil
+	}
+	return s.session.WindowChange(rows, cols)
+}
+
+// Signal forwards a signal (e.g. ssh.SIGINT) to the remote shell process
+func (s *ShellSession) Signal(sig ssh.Signal) error {
+	return s.session.Signal(sig)
+}
+
+// Interrupt interrupts the foreground program the way Ctrl-C does in a terminal
+func (s *ShellSession) Interrupt() error {
+	if s.HasPTY {
+		// The PTY line discipline turns ^C into SIGINT for the foreground process group
+		_, err := s.stdin.Write([]byte{0x03})
+		return err
+	}
+	return s.session.Signal(ssh.SIGINT)
+}
+
+// Wait blocks until the shell exits
+func (s *ShellSession) Wait() error {
+	return s.session.Wait()
+}
+
+// Close ends the shell session
+func (s *ShellSession) Close() error {
+	s.stdin.Close()
+	return s.session.Close()
+}
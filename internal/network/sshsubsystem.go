@@ -0,0 +1,41 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// callSubsystem opens one of the worker's request/response subsystems, sends req as
+// JSON and decodes the single JSON reply into resp
+func (c *SSHClient) callSubsystem(subsystem string, req, resp any) error {
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+	if client == nil {
+		return fmt.Errorf("not connected")
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	defer session.Close()
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := session.RequestSubsystem(subsystem); err != nil {
+		return fmt.Errorf("worker refused the %s subsystem: %w", subsystem, err)
+	}
+	if err := json.NewEncoder(stdin).Encode(req); err != nil {
+		return err
+	}
+	if err := json.NewDecoder(stdout).Decode(resp); err != nil {
+		return fmt.Errorf("invalid %s response: %w", subsystem, err)
+	}
+	return nil
+}
@@ -0,0 +1,470 @@
+package network
+
+import (
+	"adminadmin/internal/config"
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// usersFile stores the worker's SSH accounts
+	usersFile = "ssh_users.json"
+	// authSettingsFile stores the login methods the worker accepts for every account
+	authSettingsFile = "ssh_auth.json"
+)
+
+// SSHAuthSettings are the worker's server-wide login options
+type SSHAuthSettings struct {
+	PasswordAuth bool `json:"password_auth"` // false allows public-key logins only, whatever the accounts' passwords
+}
+
+// DefaultSSHAuthSettings returns the settings used until the worker changes them
+func DefaultSSHAuthSettings() SSHAuthSettings {
+	return SSHAuthSettings{PasswordAuth: true}
+}
+
+// LoadSSHAuthSettings loads the login options, falling back to the defaults
+func LoadSSHAuthSettings() (SSHAuthSettings, error) {
+	settings := DefaultSSHAuthSettings()
+	err := config.LoadJSON(authSettingsFile, &settings)
+	return settings, err
+}
+
+// SaveSSHAuthSettings persists the login options
+func SaveSSHAuthSettings(settings SSHAuthSettings) error {
+	return config.SaveJSON(authSettingsFile, settings)
+}
+
+// Command policies of an SSH account
+const (
+	PolicyShell     = "shell"     // Interactive shell, any command and SFTP
+	PolicyExec      = "exec"      // Any command and SFTP, but no interactive shell
+	PolicyAllowlist = "allowlist" // Only the programs in AllowedCommands; SFTP if "sftp" is listed
+)
+
+// validUsername limits account names to characters that are safe in logs and file names
+var validUsername = regexp.MustCompile(`^[A-Za-z0-9._-]{1,32}$`)
+
+// dummyHash is compared against when a login names an unknown account, so the
+// answer takes as long as for a real one
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("adminadmin"), bcrypt.DefaultCost)
+	return hash
+})
+
+// passwordSet stands in for the hash in accounts handed out by List
+const passwordSet = "*"
+
+// SSHUser is an account that can log in to the worker's SSH server
+type SSHUser struct {
+	Username        string   `json:"username"`
+	PasswordHash    string   `json:"password_hash,omitempty"` // bcrypt; empty means no password login, "*" in List results
+	Keys            []string `json:"keys,omitempty"`          // authorized_keys lines
+	Policy          string   `json:"policy"`
+	AllowedCommands []string `json:"allowed_commands,omitempty"` // Program names, for PolicyAllowlist
+	WorkDir         string   `json:"work_dir,omitempty"`         // Shells and commands start here
+	Disabled        bool     `json:"disabled,omitempty"`
+}
+
+// HasPassword reports whether the account can log in with a password
+func (u SSHUser) HasPassword() bool {
+	return u.PasswordHash != ""
+}
+
+// Unrestricted reports whether the account may run any command. Only such accounts may
+// manage users and read the audit log remotely; they could edit those files anyway.
+func (u SSHUser) Unrestricted() bool {
+	return !u.Disabled && (u.Policy == PolicyShell || u.Policy == PolicyExec)
+}
+
+// AllowsShell reports whether the account may open an interactive shell
+func (u SSHUser) AllowsShell() bool {
+	return u.Policy == PolicyShell
+}
+
+// AllowsSFTP reports whether the account may use the SFTP subsystem
+func (u SSHUser) AllowsSFTP() bool {
+	return u.Policy != PolicyAllowlist || u.allowsProgram("sftp")
+}
+
+// AllowsCommand reports whether the account may run an exec command. Allowlisted
+// accounts may only run a listed program with plain arguments, as the command goes
+// through a shell. The "cd <dir> && " prefix the admin adds to keep the working
+// directory is allowed in front of it.
+func (u SSHUser) AllowsCommand(command string) bool {
+	if u.Policy != PolicyAllowlist {
+		return true
+	}
+	command = trimCdPrefix(command)
+	if strings.ContainsAny(command, shellMetachars) {
+		return false
+	}
+	fields := strings.Fields(command)
+	return len(fields) > 0 && u.allowsProgram(fields[0])
+}
+
+// shellMetachars are the characters that would let an allowlisted command run
+// something else. cmd.exe expands %VAR% and escapes with ^ rather than a backslash,
+// which separates Windows paths.
+var shellMetachars = func() string {
+	chars := ";&|`$()<>\n\r\"'*?"
+	if runtime.GOOS == "windows" {
+		return chars + "%^"
+	}
+	return chars + "\\"
+}()
+
+// trimCdPrefix removes a leading "cd <dir> && " ("cd /d <dir> && " on Windows) from
+// command, as sent by the admin's command mode and broadcast. The directory must be a
+// single plain word; otherwise command is returned unchanged and fails the
+// metacharacter check.
+func trimCdPrefix(command string) string {
+	rest, ok := strings.CutPrefix(command, "cd ")
+	if !ok {
+		return command
+	}
+	if runtime.GOOS == "windows" {
+		rest = strings.TrimPrefix(rest, "/d ")
+	}
+	dir, rest, ok := strings.Cut(rest, " && ")
+	if !ok || dir == "" || strings.ContainsAny(dir, shellMetachars+" \t") {
+		return command
+	}
+	return rest
+}
+
+func (u SSHUser) allowsProgram(program string) bool {
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(program)), ".exe")
+	for _, allowed := range u.AllowedCommands {
+		if strings.EqualFold(allowed, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasKey reports whether key is one of the account's keys
+func (u SSHUser) hasKey(key ssh.PublicKey) bool {
+	marshaled := key.Marshal()
+	for _, line := range u.Keys {
+		k, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err == nil && bytes.Equal(k.Marshal(), marshaled) {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the account for invalid names, policies and keys
+func (u SSHUser) validate() error {
+	if !validUsername.MatchString(u.Username) {
+		return fmt.Errorf("username must be 1-32 letters, digits, '.', '_' or '-'")
+	}
+	switch u.Policy {
+	case PolicyShell, PolicyExec:
+	case PolicyAllowlist:
+		if len(u.AllowedCommands) == 0 {
+			return fmt.Errorf("allowlist policy needs at least one allowed command")
+		}
+	default:
+		return fmt.Errorf("unknown command policy %q", u.Policy)
+	}
+	for _, line := range u.Keys {
+		if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line)); err != nil {
+			return fmt.Errorf("invalid SSH key %q: %w", truncate(line, 40), err)
+		}
+	}
+	if u.WorkDir != "" {
+		if info, err := os.Stat(u.WorkDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("working directory %s does not exist", u.WorkDir)
+		}
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// SSHUserDB is the worker's set of SSH accounts, stored in ssh_users.json
+type SSHUserDB struct {
+	mu    sync.Mutex
+	users []SSHUser
+}
+
+// LoadSSHUsers loads the SSH accounts. On first use they are migrated from the older
+// single-account ssh_credentials.json and authorized_keys files, including its password
+// login switch (see LoadSSHAuthSettings), or, without those, an "admin" account with a
+// random password is created; that password is returned so it can be shown once.
+func LoadSSHUsers() (*SSHUserDB, string, error) {
+	db := &SSHUserDB{}
+	if _, err := os.Stat(config.Path(usersFile)); err == nil {
+		err := config.LoadJSON(usersFile, &db.users)
+		return db, "", err
+	}
+
+	user, password, err := migrateLegacyAccount()
+	if err != nil {
+		return db, "", err
+	}
+	db.users = []SSHUser{user}
+	if err := db.save(); err != nil {
+		return db, "", err
+	}
+	return db, password, nil
+}
+
+// legacyCredentials is the format of ssh_credentials.json, used before ssh_users.json
+type legacyCredentials struct {
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	PasswordAuth bool   `json:"password_auth"`
+}
+
+// legacyCredentialsFile held the single account's plain-text password
+const legacyCredentialsFile = "ssh_credentials.json"
+
+// migrateLegacyAccount builds the first account from the old credentials and
+// authorized_keys, or creates "admin" with a random password (returned). The old
+// password login switch becomes the server-wide SSHAuthSettings.PasswordAuth; the
+// password is kept either way, so turning password login back on restores it.
+func migrateLegacyAccount() (SSHUser, string, error) {
+	user := SSHUser{Username: DefaultSSHUsername, Policy: PolicyShell}
+
+	var legacy legacyCredentials
+	if err := config.LoadJSON(legacyCredentialsFile, &legacy); err != nil {
+		log.Printf("SSH: Ignoring unreadable %s: %v\n", legacyCredentialsFile, err)
+	}
+	keys, err := LoadAuthorizedKeys()
+	if err != nil {
+		log.Printf("SSH: Ignoring unreadable %s: %v\n", AuthorizedKeysFile, err)
+	}
+	for _, k := range keys {
+		line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(k.Key)))
+		if k.Comment != "" {
+			line += " " + k.Comment
+		}
+		user.Keys = append(user.Keys, line)
+	}
+
+	generated := ""
+	if legacy.Username != "" {
+		user.Username = legacy.Username
+		if legacy.Password != "" {
+			if err := user.setPassword(legacy.Password); err != nil {
+				return user, "", err
+			}
+		}
+		if err := SaveSSHAuthSettings(SSHAuthSettings{PasswordAuth: legacy.PasswordAuth}); err != nil {
+			return user, "", err
+		}
+		log.Printf("SSH: Migrated account %s from %s (%d key(s))\n", user.Username, legacyCredentialsFile, len(user.Keys))
+	} else {
+		password, err := GeneratePassword(16)
+		if err != nil {
+			return user, "", err
+		}
+		if err := user.setPassword(password); err != nil {
+			return user, "", err
+		}
+		generated = password
+		log.Printf("SSH: Created account %s with a random password\n", user.Username)
+	}
+
+	// The plain-text password must not outlive the migration
+	if err := os.Remove(config.Path(legacyCredentialsFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("SSH WARNING: Could not remove %s: %v\n", legacyCredentialsFile, err)
+	}
+	return user, generated, nil
+}
+
+// setPassword stores a bcrypt hash of password
+func (u *SSHUser) setPassword(password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	u.PasswordHash = string(hash)
+	return nil
+}
+
+// save writes the accounts to disk; db.mu must be held or db not yet shared
+func (db *SSHUserDB) save() error {
+	return config.SaveJSON(usersFile, db.users)
+}
+
+// List returns a copy of all accounts, with password hashes replaced by "*"
+func (db *SSHUserDB) List() ([]SSHUser, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	users := make([]SSHUser, len(db.users))
+	for i, u := range db.users {
+		u.Keys = append([]string(nil), u.Keys...)
+		u.AllowedCommands = append([]string(nil), u.AllowedCommands...)
+		if u.HasPassword() {
+			u.PasswordHash = passwordSet
+		}
+		users[i] = u
+	}
+	return users, nil
+}
+
+// Get returns the account with the given name
+func (db *SSHUserDB) Get(username string) (SSHUser, bool) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, u := range db.users {
+		if u.Username == username {
+			return u, true
+		}
+	}
+	return SSHUser{}, false
+}
+
+// Save adds or replaces an account. A non-empty password replaces the stored one.
+// Otherwise user.PasswordHash only says whether to keep the current password
+// (non-empty) or turn password login off (empty); a hash can't be set directly.
+// The change is refused if no enabled account could manage users afterwards.
+func (db *SSHUserDB) Save(user SSHUser, password string) error {
+	if err := user.validate(); err != nil {
+		return err
+	}
+	keepPassword := user.HasPassword()
+	user.PasswordHash = ""
+	if password != "" {
+		if err := user.setPassword(password); err != nil {
+			return err
+		}
+		keepPassword = false
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	users := append([]SSHUser(nil), db.users...)
+	replaced := false
+	for i, u := range users {
+		if u.Username == user.Username {
+			if keepPassword {
+				user.PasswordHash = u.PasswordHash
+			}
+			users[i] = user
+			replaced = true
+		}
+	}
+	if !replaced {
+		if keepPassword {
+			return fmt.Errorf("a password is required for the new account %s", user.Username)
+		}
+		users = append(users, user)
+	}
+	if err := checkManageable(users); err != nil {
+		return err
+	}
+	old := db.users
+	db.users = users
+	if err := db.save(); err != nil {
+		db.users = old
+		return err
+	}
+	log.Printf("SSH: Saved account %s (policy %s, disabled %v)\n", user.Username, user.Policy, user.Disabled)
+	return nil
+}
+
+// Delete removes an account
+func (db *SSHUserDB) Delete(username string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var users []SSHUser
+	for _, u := range db.users {
+		if u.Username != username {
+			users = append(users, u)
+		}
+	}
+	if len(users) == len(db.users) {
+		return fmt.Errorf("no account named %s", username)
+	}
+	if err := checkManageable(users); err != nil {
+		return err
+	}
+	old := db.users
+	db.users = users
+	if err := db.save(); err != nil {
+		db.users = old
+		return err
+	}
+	log.Printf("SSH: Deleted account %s\n", username)
+	return nil
+}
+
+// checkManageable refuses account sets that would lock everyone out of user management
+func checkManageable(users []SSHUser) error {
+	for _, u := range users {
+		if u.Unrestricted() && (u.HasPassword() || len(u.Keys) > 0) {
+			return nil
+		}
+	}
+	return fmt.Errorf("at least one enabled account with a shell or exec policy and a password or key must remain")
+}
+
+// CheckPassword returns the enabled account matching username and password
+func (db *SSHUserDB) CheckPassword(username string, password []byte) (SSHUser, bool) {
+	user, ok := db.Get(username)
+	hash := dummyHash()
+	if ok && user.HasPassword() {
+		hash = []byte(user.PasswordHash)
+	}
+	if bcrypt.CompareHashAndPassword(hash, password) != nil || !ok || !user.HasPassword() || user.Disabled {
+		return SSHUser{}, false
+	}
+	return user, true
+}
+
+// CheckKey returns the enabled account matching username that lists key
+func (db *SSHUserDB) CheckKey(username string, key ssh.PublicKey) (SSHUser, bool) {
+	user, ok := db.Get(username)
+	if !ok || user.Disabled || !user.hasKey(key) {
+		return SSHUser{}, false
+	}
+	return user, true
+}
+
+// AddKey adds a public key to an account unless it is already there
+func (db *SSHUserDB) AddKey(username string, key ssh.PublicKey, comment string) error {
+	user, ok := db.Get(username)
+	if !ok {
+		return fmt.Errorf("no account named %s", username)
+	}
+	if user.hasKey(key) {
+		return nil
+	}
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+	if comment = strings.Join(strings.Fields(comment), "_"); comment != "" {
+		line += " " + comment
+	}
+	user.Keys = append(append([]string(nil), user.Keys...), line)
+	if err := db.Save(user, ""); err != nil { // user.PasswordHash is set, so the password is kept
+		return err
+	}
+	log.Printf("SSH: Added key %s to account %s\n", ssh.FingerprintSHA256(key), username)
+	return nil
+}
+
+// HasKey reports whether an account lists key
+func (db *SSHUserDB) HasKey(username string, key ssh.PublicKey) bool {
+	user, ok := db.Get(username)
+	return ok && user.hasKey(key)
+}
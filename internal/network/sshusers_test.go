@@ -0,0 +1,85 @@
+package network
+
+import (
+	"adminadmin/internal/config"
+	"os"
+	"runtime"
+	"testing"
+)
+
+// useTempConfig points the config directory at a fresh temporary directory
+func useTempConfig(t *testing.T) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AppData", t.TempDir())
+}
+
+func TestLoadSSHUsersMigratesPasswordAuth(t *testing.T) {
+	for _, passwordAuth := range []bool{false, true} {
+		useTempConfig(t)
+		legacy := legacyCredentials{Username: "ops", Password: "old-secret", PasswordAuth: passwordAuth}
+		if err := config.SaveJSON(legacyCredentialsFile, legacy); err != nil {
+			t.Fatal(err)
+		}
+
+		db, generated, err := LoadSSHUsers()
+		if err != nil || generated != "" {
+			t.Fatalf("migration returned %q, %v", generated, err)
+		}
+		settings, err := LoadSSHAuthSettings()
+		if err != nil || settings.PasswordAuth != passwordAuth {
+			t.Errorf("password login %v after migrating %v (%v)", settings.PasswordAuth, passwordAuth, err)
+		}
+		// The password survives with login turned off, so turning it on restores it
+		if _, ok := db.CheckPassword("ops", []byte("old-secret")); !ok {
+			t.Errorf("password of the migrated account (password login %v) was lost", passwordAuth)
+		}
+		if _, err := os.Stat(config.Path(legacyCredentialsFile)); !os.IsNotExist(err) {
+			t.Error("the plain-text credentials file was not removed")
+		}
+	}
+}
+
+func TestLoadSSHAuthSettingsDefault(t *testing.T) {
+	useTempConfig(t)
+	if _, _, err := LoadSSHUsers(); err != nil {
+		t.Fatal(err)
+	}
+	settings, err := LoadSSHAuthSettings()
+	if err != nil || !settings.PasswordAuth {
+		t.Errorf("got %+v (%v), want password login on for a new worker", settings, err)
+	}
+}
+
+func TestAllowsCommand(t *testing.T) {
+	user := SSHUser{Username: "ops", Policy: PolicyAllowlist, AllowedCommands: []string{"uptime", "df"}}
+	cd := "cd /var/log && "
+	if runtime.GOOS == "windows" {
+		cd = `cd /d C:\logs && `
+	}
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"uptime", true},
+		{"df -h /", true},
+		{"reboot", false},
+		{"uptime; reboot", false},
+		{"uptime && reboot", false},
+		{"df $(reboot)", false},
+		{cd + "df -h", true},
+		{cd + "reboot", false},
+		{cd + "df && reboot", false},
+		{"cd /tmp;reboot && df", false},
+		{"cd $(reboot) && df", false},
+	}
+	for _, tt := range tests {
+		if got := user.AllowsCommand(tt.command); got != tt.want {
+			t.Errorf("AllowsCommand(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+	if runtime.GOOS == "windows" && (user.AllowsCommand("df %COMSPEC%") || user.AllowsCommand("df ^& reboot")) {
+		t.Error("cmd.exe metacharacters were allowed")
+	}
+}
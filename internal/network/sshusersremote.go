@@ -0,0 +1,107 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// usersSubsystem is the SSH subsystem the admin manages a worker's accounts through
+const usersSubsystem = "adminadmin-users"
+
+// usersRequest is sent by the client after opening the users subsystem
+type usersRequest struct {
+	Op       string  `json:"op"`                 // "list", "save" or "delete"
+	User     SSHUser `json:"user"`               // For "save"
+	Password string  `json:"password,omitempty"` // For "save"; empty keeps the current one
+	Username string  `json:"username,omitempty"` // For "delete"
+}
+
+// usersResponse is the server's single reply to a usersRequest
+type usersResponse struct {
+	Users []SSHUser `json:"users,omitempty"`
+	Error string    `json:"error,omitempty"`
+}
+
+// serveUsers answers one account management request on a session channel and
+// records changes in the audit log
+func (s *SSHServer) serveUsers(conn *ssh.ServerConn, channel ssh.Channel, db *SSHUserDB) {
+	var req usersRequest
+	if err := json.NewDecoder(channel).Decode(&req); err != nil {
+		sendExitStatus(channel, nil, err)
+		return
+	}
+
+	var resp usersResponse
+	var err error
+	switch req.Op {
+	case "list":
+		resp.Users, err = db.List()
+	case "save":
+		err = db.Save(req.User, req.Password)
+		if err == nil {
+			detail := fmt.Sprintf("saved %s (policy %s, disabled %v)", req.User.Username, req.User.Policy, req.User.Disabled)
+			if req.Password != "" {
+				detail += ", new password"
+			}
+			s.recordAudit(conn, AuditEvent{Event: AuditUserAdmin, Detail: detail})
+		}
+	case "delete":
+		err = db.Delete(req.Username)
+		if err == nil {
+			s.recordAudit(conn, AuditEvent{Event: AuditUserAdmin, Detail: "deleted " + req.Username})
+		}
+	default:
+		err = fmt.Errorf("unknown users request %q", req.Op)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	json.NewEncoder(channel).Encode(resp)
+	sendExitStatus(channel, nil, nil)
+}
+
+// RemoteSSHUsers manages a worker's SSH accounts over an SSH connection, with the same
+// methods as the worker's own SSHUserDB. The logged-in account needs a shell or exec policy.
+type RemoteSSHUsers struct {
+	client *SSHClient
+}
+
+// RemoteUsers returns the account manager of the connected worker
+func (c *SSHClient) RemoteUsers() *RemoteSSHUsers {
+	return &RemoteSSHUsers{client: c}
+}
+
+// call runs one request against the worker's users subsystem
+func (r *RemoteSSHUsers) call(req usersRequest) (*usersResponse, error) {
+	var resp usersResponse
+	if err := r.client.callSubsystem(usersSubsystem, req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("%s", resp.Error)
+	}
+	return &resp, nil
+}
+
+// List returns the worker's accounts, with password hashes replaced by "*"
+func (r *RemoteSSHUsers) List() ([]SSHUser, error) {
+	resp, err := r.call(usersRequest{Op: "list"})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Users, nil
+}
+
+// Save adds or replaces an account on the worker, see SSHUserDB.Save
+func (r *RemoteSSHUsers) Save(user SSHUser, password string) error {
+	_, err := r.call(usersRequest{Op: "save", User: user, Password: password})
+	return err
+}
+
+// Delete removes an account from the worker
+func (r *RemoteSSHUsers) Delete(username string) error {
+	_, err := r.call(usersRequest{Op: "delete", Username: username})
+	return err
+}
@@ -0,0 +1,61 @@
+package network
+
+import (
+	"adminadmin/internal/config"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// transcriptSettingsFile stores whether command mode transcripts are logged automatically
+const transcriptSettingsFile = "transcript_logging.json"
+
+// unsafeFileChars matches characters replaced when a hostname is used as a file name
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// TranscriptSettings configures logging of every command mode tab's transcript on the admin
+type TranscriptSettings struct {
+	AutoLog bool   `json:"auto_log"`
+	Dir     string `json:"dir,omitempty"` // Empty for "transcripts" in the config directory
+}
+
+// LoadTranscriptSettings loads the transcript logging settings; logging is off by default
+func LoadTranscriptSettings() (TranscriptSettings, error) {
+	var settings TranscriptSettings
+	err := config.LoadJSON(transcriptSettingsFile, &settings)
+	return settings, err
+}
+
+// SaveTranscriptSettings persists the transcript logging settings
+func SaveTranscriptSettings(settings TranscriptSettings) error {
+	return config.SaveJSON(transcriptSettingsFile, settings)
+}
+
+// LogDir returns the directory the transcript logs are written to
+func (s TranscriptSettings) LogDir() string {
+	if s.Dir != "" {
+		return s.Dir
+	}
+	return config.Path("transcripts")
+}
+
+// OpenLog opens the transcript log of a worker for appending, e.g.
+// "transcripts/web-1-c4719afa.log"; every worker has one file that all its sessions are
+// added to
+func (s TranscriptSettings) OpenLog(hostname string) (*os.File, error) {
+	dir := s.LogDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(filepath.Join(dir, transcriptLogName(hostname)), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+}
+
+// transcriptLogName returns the log file name of a worker. The hostname is made safe
+// for file names and followed by a short hash of the original, so hostnames that only
+// differ in replaced characters or in case don't share a log.
+func transcriptLogName(hostname string) string {
+	sum := sha256.Sum256([]byte(hostname))
+	return unsafeFileChars.ReplaceAllString(hostname, "_") + "-" + hex.EncodeToString(sum[:4]) + ".log"
+}
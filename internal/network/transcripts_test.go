@@ -0,0 +1,19 @@
+package network
+
+import "testing"
+
+func TestTranscriptLogName(t *testing.T) {
+	if got := transcriptLogName("web-1"); got != "web-1-c4719afa.log" {
+		t.Errorf("log of web-1 is %q", got)
+	}
+
+	// Hostnames that read the same once made safe get logs of their own
+	seen := make(map[string]string)
+	for _, hostname := range []string{"web 1", "web/1", "web_1", "Web_1", "wëb_1"} {
+		name := transcriptLogName(hostname)
+		if other, ok := seen[name]; ok {
+			t.Errorf("%q and %q share the log %s", hostname, other, name)
+		}
+		seen[name] = hostname
+	}
+}
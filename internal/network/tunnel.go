@@ -0,0 +1,188 @@
+package network
+
+import (
+	"adminadmin/internal/config"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+const tunnelsFile = "tunnels.json"
+
+// Tunnel kinds
+const (
+	TunnelLocal  = "local"  // Listen on the admin, connect from the worker
+	TunnelRemote = "remote" // Listen on the worker, connect from the admin
+)
+
+// TunnelSpec defines a port forward to or from a worker
+type TunnelSpec struct {
+	Kind       string `json:"kind"`        // TunnelLocal or TunnelRemote
+	ListenPort int    `json:"listen_port"` // Port opened on loopback of the listening side
+	TargetHost string `json:"target_host"` // Resolved on the connecting side
+	TargetPort int    `json:"target_port"`
+}
+
+// Validate checks the spec for missing or out-of-range values
+func (s TunnelSpec) Validate() error {
+	if s.Kind != TunnelLocal && s.Kind != TunnelRemote {
+		return fmt.Errorf("unknown tunnel kind %q", s.Kind)
+	}
+	if s.ListenPort < 1 || s.ListenPort > 65535 {
+		return fmt.Errorf("listen port must be between 1 and 65535")
+	}
+	if s.TargetHost == "" {
+		return fmt.Errorf("target host is required")
+	}
+	if s.TargetPort < 1 || s.TargetPort > 65535 {
+		return fmt.Errorf("target port must be between 1 and 65535")
+	}
+	return nil
+}
+
+// String describes the forward, e.g. "local 127.0.0.1:8080 -> db:5432"
+func (s TunnelSpec) String() string {
+	return fmt.Sprintf("%s %s -> %s", s.Kind, s.listenAddr(), s.targetAddr())
+}
+
+func (s TunnelSpec) listenAddr() string {
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(s.ListenPort))
+}
+
+func (s TunnelSpec) targetAddr() string {
+	return net.JoinHostPort(s.TargetHost, strconv.Itoa(s.TargetPort))
+}
+
+// LoadTunnels loads the saved tunnel definitions, keyed by worker hostname
+func LoadTunnels() (map[string][]TunnelSpec, error) {
+	tunnels := make(map[string][]TunnelSpec)
+	err := config.LoadJSON(tunnelsFile, &tunnels)
+	return tunnels, err
+}
+
+// SaveTunnels persists the tunnel definitions
+func SaveTunnels(tunnels map[string][]TunnelSpec) error {
+	return config.SaveJSON(tunnelsFile, tunnels)
+}
+
+// Tunnel is a running port forward over an SSH connection
+type Tunnel struct {
+	Spec TunnelSpec
+
+	listener net.Listener
+	sent     atomic.Int64 // Bytes from the listening side to the target
+	received atomic.Int64 // Bytes from the target back
+	active   atomic.Int32
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// StartTunnel opens the listener of a forward. Local tunnels listen on the admin's
+// loopback; remote tunnels ask the worker to listen on its loopback.
+func (c *SSHClient) StartTunnel(spec TunnelSpec) (*Tunnel, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+	if client == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	t := &Tunnel{Spec: spec, conns: make(map[net.Conn]struct{})}
+	var dial func() (net.Conn, error)
+	var err error
+	switch spec.Kind {
+	case TunnelLocal:
+		t.listener, err = net.Listen("tcp", spec.listenAddr())
+		dial = func() (net.Conn, error) { return client.Dial("tcp", spec.targetAddr()) }
+	case TunnelRemote:
+		t.listener, err = client.Listen("tcp", spec.listenAddr())
+		if err != nil {
+			err = fmt.Errorf("worker refused the forward (is remote forwarding allowed?): %w", err)
+		}
+		dial = func() (net.Conn, error) { return net.Dial("tcp", spec.targetAddr()) }
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("SSH Client: Tunnel started: %s\n", spec)
+	go t.serve(dial)
+	return t, nil
+}
+
+// serve accepts connections until the tunnel is stopped
+func (t *Tunnel) serve(dial func() (net.Conn, error)) {
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			target, err := dial()
+			if err != nil {
+				log.Printf("SSH Client: Tunnel %s could not reach target: %v\n", t.Spec, err)
+				conn.Close()
+				return
+			}
+			t.track(conn, true)
+			t.track(target, true)
+			t.active.Add(1)
+			pipe(&countingConn{Conn: conn, n: &t.received}, &countingConn{Conn: target, n: &t.sent})
+			t.active.Add(-1)
+			t.track(conn, false)
+			t.track(target, false)
+		}()
+	}
+}
+
+func (t *Tunnel) track(conn net.Conn, add bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if add {
+		t.conns[conn] = struct{}{}
+	} else {
+		delete(t.conns, conn)
+	}
+}
+
+// Stats returns the bytes sent to the target, received from it, and the open connections
+func (t *Tunnel) Stats() (sent, received int64, active int) {
+	return t.sent.Load(), t.received.Load(), int(t.active.Load())
+}
+
+// Stop closes the listener and every connection going through the tunnel
+func (t *Tunnel) Stop() error {
+	err := t.listener.Close()
+	t.mu.Lock()
+	for conn := range t.conns {
+		conn.Close()
+	}
+	t.mu.Unlock()
+	log.Printf("SSH Client: Tunnel stopped: %s\n", t.Spec)
+	return err
+}
+
+// countingConn counts the bytes written to a connection
+type countingConn struct {
+	net.Conn
+	n *atomic.Int64
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// CloseWrite passes a half-close on to the connection, if it supports that
+func (c *countingConn) CloseWrite() error {
+	closeWrite(c.Conn)
+	return nil
+}
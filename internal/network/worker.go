@@ -4,6 +4,7 @@ import (
 	"adminadmin/internal/system"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"strings"
@@ -23,8 +24,28 @@ type WorkerServer struct {
 	sysInfo           system.SystemInfo
 	activeConn        net.Conn
 	connMu            sync.Mutex
+	limits            SessionLimits
+	sessions          int // Connected admins, counted against limits.MaxSessions
 	onAdminConnect    func(hostname string)
 	onAdminDisconnect func()
+
+	// Asks the local user whether to trust an admin's SSH key; may block
+	callbackMu   sync.Mutex
+	onKeyRequest func(adminHostname, username, fingerprint string) bool
+	sshUsers     *SSHUserDB // Accounts paired keys are added to
+
+	// Screen sharing (see screen.go)
+	screen          system.ScreenCapturer
+	screenSettings  ScreenSettings
+	onScreenConfirm func(adminHostname string, live bool) bool
+	onScreenView    func(adminHostname string, live bool)
+	onScreenViewers func(admins []string)
+	screenViewsMu   sync.Mutex
+	screenViews     map[string]int // Screenshots and streams in progress by admin hostname
+
+	// Self-declared tags sent with the system info
+	tagsMu sync.RWMutex
+	tags   []string
 }
 
 // NewWorkerServer creates a new worker server
@@ -33,8 +54,12 @@ func NewWorkerServer(port int) *WorkerServer {
 		port = DefaultWorkerPort
 	}
 	return &WorkerServer{
-		port: port,
-		quit: make(chan bool),
+		port:           port,
+		quit:           make(chan bool),
+		limits:         DefaultSessionLimitSettings().Admin,
+		screen:         system.NewScreenCapturer(),
+		screenSettings: DefaultScreenSettings(),
+		screenViews:    make(map[string]int),
 	}
 }
 
@@ -44,6 +69,91 @@ func (w *WorkerServer) SetCallbacks(onConnect func(hostname string), onDisconnec
 	w.onAdminDisconnect = onDisconnect
 }
 
+// SetOnKeyRequest sets the callback that confirms an admin's request to add its
+// SSH key to an account. It is called from a network goroutine and may block.
+func (w *WorkerServer) SetOnKeyRequest(onKeyRequest func(adminHostname, username, fingerprint string) bool) {
+	w.callbackMu.Lock()
+	defer w.callbackMu.Unlock()
+	w.onKeyRequest = onKeyRequest
+}
+
+// SetSSHUsers sets the SSH accounts that paired keys are added to
+func (w *WorkerServer) SetSSHUsers(users *SSHUserDB) {
+	w.callbackMu.Lock()
+	defer w.callbackMu.Unlock()
+	w.sshUsers = users
+}
+
+// SetScreenCapturer replaces the source of the screenshots sent to admins
+func (w *WorkerServer) SetScreenCapturer(capturer system.ScreenCapturer) {
+	w.callbackMu.Lock()
+	defer w.callbackMu.Unlock()
+	w.screen = capturer
+}
+
+// SetScreenSettings sets whether admins may see the screen; turning sharing off
+// also ends running live views
+func (w *WorkerServer) SetScreenSettings(settings ScreenSettings) {
+	w.callbackMu.Lock()
+	defer w.callbackMu.Unlock()
+	w.screenSettings = settings
+}
+
+// GetScreenSettings returns the current screen sharing settings
+func (w *WorkerServer) GetScreenSettings() ScreenSettings {
+	w.callbackMu.Lock()
+	defer w.callbackMu.Unlock()
+	return w.screenSettings
+}
+
+// SetOnScreenConfirm sets the callback that asks the local user whether an admin may
+// take a screenshot (live is false) or start a live view, if the settings require it.
+// It is called from a network goroutine and may block.
+func (w *WorkerServer) SetOnScreenConfirm(onScreenConfirm func(adminHostname string, live bool) bool) {
+	w.callbackMu.Lock()
+	defer w.callbackMu.Unlock()
+	w.onScreenConfirm = onScreenConfirm
+}
+
+// SetOnScreenView sets a callback invoked when an admin was allowed to take a
+// screenshot (live is false) or start a live view. It is called from a network goroutine.
+func (w *WorkerServer) SetOnScreenView(onScreenView func(adminHostname string, live bool)) {
+	w.callbackMu.Lock()
+	defer w.callbackMu.Unlock()
+	w.onScreenView = onScreenView
+}
+
+// SetOnScreenViewers sets a callback invoked with the admins looking at the screen
+// whenever that changes; an empty list means nobody is. It is called from network
+// goroutines.
+func (w *WorkerServer) SetOnScreenViewers(onScreenViewers func(admins []string)) {
+	w.callbackMu.Lock()
+	defer w.callbackMu.Unlock()
+	w.onScreenViewers = onScreenViewers
+}
+
+// SetSessionLimits sets the idle, duration and concurrency limits of admin
+// connections; connected admins are held to the new idle and duration limits too
+func (w *WorkerServer) SetSessionLimits(limits SessionLimits) {
+	w.connMu.Lock()
+	defer w.connMu.Unlock()
+	w.limits = limits
+}
+
+// GetSessionLimits returns the current limits of admin connections
+func (w *WorkerServer) GetSessionLimits() SessionLimits {
+	w.connMu.Lock()
+	defer w.connMu.Unlock()
+	return w.limits
+}
+
+// SetTags sets the tags this worker declares to admins on connect
+func (w *WorkerServer) SetTags(tags []string) {
+	w.tagsMu.Lock()
+	defer w.tagsMu.Unlock()
+	w.tags = append([]string(nil), tags...)
+}
+
 // Start starts the worker server
 func (w *WorkerServer) Start() error {
 	log.Println("=== WORKER: Starting server ===")
@@ -117,14 +227,30 @@ func (w *WorkerServer) acceptConnections() {
 }
 
 func (w *WorkerServer) handleConnection(conn net.Conn) {
+	// All outgoing messages share one writer so concurrent senders don't interleave
+	writer := newMessageWriter(conn)
+
 	w.connMu.Lock()
+	if limit := w.limits.MaxSessions; limit > 0 && w.sessions >= limit {
+		w.connMu.Unlock()
+		notice := tooManySessions(limit)
+		log.Printf("WORKER: Refused admin from %s: %s\n", conn.RemoteAddr(), notice.Message)
+		endSession(conn, writer, notice)
+		io.Copy(io.Discard, conn)
+		conn.Close()
+		return
+	}
+	w.sessions++
 	w.activeConn = conn
 	w.connMu.Unlock()
 
 	defer func() {
 		conn.Close()
 		w.connMu.Lock()
-		w.activeConn = nil
+		w.sessions--
+		if w.activeConn == conn {
+			w.activeConn = nil
+		}
 		w.connMu.Unlock()
 		if w.onAdminDisconnect != nil {
 			w.onAdminDisconnect()
@@ -134,11 +260,31 @@ func (w *WorkerServer) handleConnection(conn net.Conn) {
 	log.Printf("Admin connected from: %s\n", conn.RemoteAddr())
 
 	// Send system info immediately upon connection
-	w.sendSystemInfo(conn)
+	w.sendSystemInfo(writer)
 
 	// Start sending metrics updates every 2 seconds
 	stopMetrics := make(chan bool)
-	go w.sendMetricsLoop(conn, stopMetrics)
+	go w.sendMetricsLoop(writer, stopMetrics)
+
+	// Metrics don't count as activity, so a forgotten admin window is disconnected
+	// once it has sent nothing for the idle limit
+	watch := newSessionWatch(w.GetSessionLimits)
+	go watch.run(stopMetrics, func(notice SessionNoticePayload) {
+		log.Printf("WORKER: Warned admin %s: %s\n", conn.RemoteAddr(), notice.Message)
+		writer.send(MsgTypeSessionWarning, notice)
+	}, func(notice SessionNoticePayload) {
+		log.Printf("WORKER: Disconnecting admin %s: %s\n", conn.RemoteAddr(), notice.Message)
+		endSession(conn, writer, notice)
+	})
+
+	// Per-connection speed test state (upload phase bookkeeping)
+	speedTest := &workerSpeedTest{}
+
+	// Per-connection live screen view, stopped with the connection
+	screen := &workerScreen{}
+	defer screen.stopStream()
+
+	adminHostname := conn.RemoteAddr().String()
 
 	// Keep connection alive and handle incoming messages
 	decoder := json.NewDecoder(conn)
@@ -149,14 +295,16 @@ func (w *WorkerServer) handleConnection(conn net.Conn) {
 			close(stopMetrics)
 			return
 		}
+		watch.touch()
 
 		switch msg.Type {
 		case MsgTypePing:
-			w.sendPong(conn)
+			w.sendPong(writer, msg.Payload)
 		case MsgTypeAdminInfo:
 			var adminInfo AdminInfoPayload
 			if err := json.Unmarshal(msg.Payload, &adminInfo); err == nil {
 				log.Printf("WORKER: Admin identified as: %s\n", adminInfo.Hostname)
+				adminHostname = adminInfo.Hostname
 				if w.onAdminConnect != nil {
 					w.onAdminConnect(adminInfo.Hostname)
 				}
@@ -168,11 +316,39 @@ func (w *WorkerServer) handleConnection(conn net.Conn) {
 		case MsgTypeCommand:
 			// Handle commands (future implementation)
 			log.Println("Received command (not implemented)")
+		case MsgTypeSpeedTest:
+			var req SpeedTestPayload
+			if err := json.Unmarshal(msg.Payload, &req); err != nil {
+				log.Printf("WORKER: Invalid speed test request: %v\n", err)
+				continue
+			}
+			if req.Direction == "download" {
+				go w.sendSpeedTestData(writer, req)
+			}
+		case MsgTypeSpeedTestData:
+			var chunk SpeedTestDataPayload
+			if err := json.Unmarshal(msg.Payload, &chunk); err != nil {
+				log.Printf("WORKER: Invalid speed test data: %v\n", err)
+				continue
+			}
+			speedTest.receive(writer, chunk)
+		case MsgTypeAuthorizeKey:
+			// Waits for the local user, so it must not block the read loop
+			go w.handleAuthorizeKey(writer, adminHostname, msg.Payload)
+		case MsgTypeScreenRequest:
+			w.handleScreenRequest(writer, screen, adminHostname, msg.Payload)
 		}
 	}
 }
 
-func (w *WorkerServer) sendMetricsLoop(conn net.Conn, stop chan bool) {
+// endSession tells the admin why its session ends and gives it a few seconds to close
+// the connection itself, so the notice isn't lost to a reset; reads fail after that
+func endSession(conn net.Conn, writer *messageWriter, notice SessionNoticePayload) {
+	writer.send(MsgTypeSessionEnded, notice)
+	conn.SetReadDeadline(time.Now().Add(sessionEndGrace))
+}
+
+func (w *WorkerServer) sendMetricsLoop(writer *messageWriter, stop chan bool) {
 	ticker := time.NewTicker(1 * time.Second) // 1 Hz polling rate
 	defer ticker.Stop()
 
@@ -185,17 +361,12 @@ func (w *WorkerServer) sendMetricsLoop(conn net.Conn, stop chan bool) {
 		case <-ticker.C:
 			cpuUsage, ramUsage, gpuUsage := system.GetRealTimeMetrics()
 			payload := MetricsPayload{
-				CPUUsage: cpuUsage,
-				RAMUsage: ramUsage,
-				GPUUsage: gpuUsage,
-			}
-			payloadBytes, _ := json.Marshal(payload)
-			msg := Message{
-				Type:    MsgTypeMetrics,
-				Payload: payloadBytes,
+				CPUUsage:  cpuUsage,
+				RAMUsage:  ramUsage,
+				GPUUsage:  gpuUsage,
+				DiskUsage: system.GetDiskUsage(),
 			}
-			encoder := json.NewEncoder(conn)
-			if err := encoder.Encode(msg); err != nil {
+			if err := writer.send(MsgTypeMetrics, payload); err != nil {
 				log.Printf("WORKER: Failed to send metrics: %v\n", err)
 				return
 			}
@@ -203,7 +374,7 @@ func (w *WorkerServer) sendMetricsLoop(conn net.Conn, stop chan bool) {
 	}
 }
 
-func (w *WorkerServer) sendSystemInfo(conn net.Conn) {
+func (w *WorkerServer) sendSystemInfo(writer *messageWriter) {
 	log.Println("WORKER: Gathering system information...")
 	w.sysInfo = system.GetLocalSystemInfo()
 
@@ -222,31 +393,27 @@ func (w *WorkerServer) sendSystemInfo(conn net.Conn) {
 		RAMUsed:       w.sysInfo.RAMUsed,
 		GPUName:       w.sysInfo.GPUName,
 		GPUUsage:      w.sysInfo.GPUUsage,
+		DiskUsage:     w.sysInfo.DiskUsage,
 		InternetSpeed: w.sysInfo.InternetSpeed,
 		Uptime:        w.sysInfo.Uptime,
 	}
+	w.tagsMu.RLock()
+	payload.Tags = w.tags
+	w.tagsMu.RUnlock()
 
 	log.Printf("WORKER: System Info - Hostname: %s, OS: %s, Arch: %s\n",
 		payload.Hostname, payload.OS, payload.Architecture)
 
-	payloadBytes, _ := json.Marshal(payload)
-	msg := Message{
-		Type:    MsgTypeSystemInfo,
-		Payload: payloadBytes,
-	}
-
-	encoder := json.NewEncoder(conn)
-	if err := encoder.Encode(msg); err != nil {
+	if err := writer.send(MsgTypeSystemInfo, payload); err != nil {
 		log.Printf("WORKER ERROR: Failed to send system info: %v\n", err)
 	} else {
 		log.Println("WORKER: System info sent successfully")
 	}
 }
 
-func (w *WorkerServer) sendPong(conn net.Conn) {
-	msg := Message{Type: MsgTypePong}
-	encoder := json.NewEncoder(conn)
-	encoder.Encode(msg)
+func (w *WorkerServer) sendPong(writer *messageWriter, payload json.RawMessage) {
+	// Echo the ping payload back so the admin can compute round-trip time
+	writer.send(MsgTypePong, payload)
 }
 
 func getLocalIP() string {
@@ -0,0 +1,348 @@
+// Package notify pushes application events (connections, SSH logins, alerts)
+// to external tools by POSTing JSON to webhooks or running local scripts.
+package notify
+
+import (
+	"adminadmin/internal/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// EventType identifies the kind of event being delivered
+type EventType string
+
+const (
+	EventWorkerConnected    EventType = "worker.connected"
+	EventWorkerDisconnected EventType = "worker.disconnected"
+	EventAdminConnected     EventType = "admin.connected"
+	EventAdminDisconnected  EventType = "admin.disconnected"
+	EventScreenViewed       EventType = "admin.screen_viewed"
+	EventSSHLoginSuccess    EventType = "ssh.login.success"
+	EventSSHLoginFailure    EventType = "ssh.login.failure"
+	EventAlertFired         EventType = "alert.fired"
+	EventAlertResolved      EventType = "alert.resolved"
+	EventTest               EventType = "test"
+)
+
+// EventTypes lists all event types a target can subscribe to (for UI selectors)
+var EventTypes = []EventType{
+	EventWorkerConnected,
+	EventWorkerDisconnected,
+	EventAdminConnected,
+	EventAdminDisconnected,
+	EventScreenViewed,
+	EventSSHLoginSuccess,
+	EventSSHLoginFailure,
+	EventAlertFired,
+	EventAlertResolved,
+}
+
+// Event is the JSON document delivered to webhooks and scripts
+type Event struct {
+	Type       EventType         `json:"type"`
+	Time       time.Time         `json:"time"`
+	Source     string            `json:"source"`             // Hostname of the machine emitting the event
+	Worker     string            `json:"worker,omitempty"`   // Worker ID (IP) the event concerns
+	Hostname   string            `json:"hostname,omitempty"` // Hostname of that worker/admin
+	User       string            `json:"user,omitempty"`
+	RemoteAddr string            `json:"remote_addr,omitempty"`
+	Message    string            `json:"message"`
+	Details    map[string]string `json:"details,omitempty"`
+}
+
+// TargetKind selects how a target delivers events
+type TargetKind string
+
+const (
+	KindWebhook TargetKind = "webhook"
+	KindScript  TargetKind = "script"
+)
+
+// Target is one configured destination for events
+type Target struct {
+	Name    string     `json:"name"`
+	Kind    TargetKind `json:"kind"`
+	Enabled bool       `json:"enabled"`
+
+	// Events limits delivery to these types; empty means all events
+	Events []EventType `json:"events,omitempty"`
+
+	// Webhook settings
+	URL        string            `json:"url,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	MaxRetries int               `json:"max_retries,omitempty"`
+
+	// Script settings: the event JSON is written to the script's stdin
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// Wants reports whether the target subscribes to an event type
+func (t Target) Wants(evtType EventType) bool {
+	if evtType == EventTest || len(t.Events) == 0 {
+		return true
+	}
+	for _, e := range t.Events {
+		if e == evtType {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe returns a one-line description of where the target delivers
+func (t Target) Describe() string {
+	if t.Kind == KindScript {
+		return fmt.Sprintf("script: %s", t.Command)
+	}
+	return fmt.Sprintf("webhook: %s", t.URL)
+}
+
+func (t Target) timeout() time.Duration {
+	if t.TimeoutSeconds <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(t.TimeoutSeconds) * time.Second
+}
+
+// Settings holds all configured targets
+type Settings struct {
+	Targets []Target `json:"targets"`
+}
+
+const (
+	settingsFile      = "notifications.json"
+	defaultMaxRetries = 3
+	queueSize         = 256
+)
+
+// LoadSettings reads notification settings from the config directory
+func LoadSettings() (Settings, error) {
+	var settings Settings
+	err := config.LoadJSON(settingsFile, &settings)
+	return settings, err
+}
+
+// SaveSettings writes notification settings to the config directory
+func SaveSettings(settings Settings) error {
+	return config.SaveJSON(settingsFile, settings)
+}
+
+// Notifier delivers events asynchronously to the configured targets
+type Notifier struct {
+	mu       sync.RWMutex
+	settings Settings
+	source   string
+	client   *http.Client
+
+	queue chan Event
+	done  chan struct{}
+	wg    sync.WaitGroup
+
+	// retryDelay is the base backoff between webhook attempts (doubled each retry)
+	retryDelay time.Duration
+}
+
+// New creates a notifier and starts its delivery goroutine
+func New(settings Settings) *Notifier {
+	source, _ := os.Hostname()
+	n := &Notifier{
+		settings:   settings,
+		source:     source,
+		client:     &http.Client{},
+		queue:      make(chan Event, queueSize),
+		done:       make(chan struct{}),
+		retryDelay: time.Second,
+	}
+	n.wg.Add(1)
+	go n.run()
+	return n
+}
+
+// Settings returns a copy of the current settings
+func (n *Notifier) Settings() Settings {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return Settings{Targets: append([]Target(nil), n.settings.Targets...)}
+}
+
+// SetSettings replaces the current settings
+func (n *Notifier) SetSettings(settings Settings) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.settings = settings
+}
+
+// Notify queues an event for delivery. It never blocks; events are dropped if the queue is full.
+func (n *Notifier) Notify(evt Event) {
+	if evt.Time.IsZero() {
+		evt.Time = time.Now()
+	}
+	if evt.Source == "" {
+		evt.Source = n.source
+	}
+	select {
+	case n.queue <- evt:
+	case <-n.done:
+	default:
+		log.Printf("NOTIFY: Queue full, dropping %s event\n", evt.Type)
+	}
+}
+
+// Test delivers a test event to a single target synchronously and returns the delivery error
+func (n *Notifier) Test(target Target) error {
+	evt := Event{
+		Type:    EventTest,
+		Time:    time.Now(),
+		Source:  n.source,
+		Message: "admin:admin test notification",
+	}
+	return n.deliver(target, evt)
+}
+
+// Close stops the delivery goroutine after the queued events are delivered
+func (n *Notifier) Close() {
+	select {
+	case <-n.done:
+		return
+	default:
+	}
+	close(n.done)
+	n.wg.Wait()
+}
+
+func (n *Notifier) run() {
+	defer n.wg.Done()
+	for {
+		select {
+		case evt := <-n.queue:
+			n.dispatch(evt)
+		case <-n.done:
+			// Drain what is already queued so shutdown doesn't lose events
+			for {
+				select {
+				case evt := <-n.queue:
+					n.dispatch(evt)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// dispatch delivers one event to every interested target in parallel
+func (n *Notifier) dispatch(evt Event) {
+	var wg sync.WaitGroup
+	for _, target := range n.Settings().Targets {
+		if !target.Enabled || !target.Wants(evt.Type) {
+			continue
+		}
+		wg.Add(1)
+		go func(t Target) {
+			defer wg.Done()
+			if err := n.deliver(t, evt); err != nil {
+				log.Printf("NOTIFY ERROR: %s event to %q failed: %v\n", evt.Type, t.Name, err)
+			}
+		}(target)
+	}
+	wg.Wait()
+}
+
+func (n *Notifier) deliver(target Target, evt Event) error {
+	body, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	switch target.Kind {
+	case KindWebhook:
+		return n.postWebhook(target, body)
+	case KindScript:
+		return runScript(target, evt.Type, body)
+	default:
+		return fmt.Errorf("unknown target kind %q", target.Kind)
+	}
+}
+
+// postWebhook POSTs the event with retries and exponential backoff.
+// Network errors, 429 and 5xx responses are retried; other 4xx responses are not.
+func (n *Notifier) postWebhook(target Target, body []byte) error {
+	retries := target.MaxRetries
+	if retries <= 0 {
+		retries = defaultMaxRetries
+	}
+
+	delay := n.retryDelay
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		retry, err := n.postOnce(target, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+		log.Printf("NOTIFY: Webhook %q attempt %d failed: %v\n", target.Name, attempt+1, err)
+	}
+	return lastErr
+}
+
+// postOnce performs a single webhook request and reports whether a failure is retryable
+func (n *Notifier) postOnce(target Target, body []byte) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), target.timeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "adminadmin-notifier")
+	for k, v := range target.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook returned %s", resp.Status)
+}
+
+// runScript runs the target command with the event JSON on stdin
+func runScript(target Target, evtType EventType, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), target.timeout())
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, target.Command, target.Args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(), "ADMINADMIN_EVENT="+string(evtType))
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("script failed: %w (output: %s)", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
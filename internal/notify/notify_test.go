@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookServer answers each request with the next status of statuses, repeating the
+// last one, and records what it received
+type webhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	events   []Event
+	headers  []http.Header
+}
+
+func newWebhookServer(t *testing.T, statuses ...int) *webhookServer {
+	s := &webhookServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var evt Event
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&evt) != nil {
+			t.Errorf("webhook got a %s request without an event", r.Method)
+		}
+		s.mu.Lock()
+		status := s.statuses[min(len(s.events), len(s.statuses)-1)]
+		s.events = append(s.events, evt)
+		s.headers = append(s.headers, r.Header.Clone())
+		s.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookServer) attempts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events)
+}
+
+// newTestNotifier returns a notifier that retries without waiting
+func newTestNotifier(t *testing.T, targets ...Target) *Notifier {
+	n := New(Settings{Targets: targets})
+	n.retryDelay = time.Millisecond
+	t.Cleanup(n.Close)
+	return n
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		maxRetries int
+		attempts   int
+		wantErr    string
+	}{
+		{"success", []int{http.StatusOK}, 0, 1, ""},
+		{"accepted", []int{http.StatusNoContent}, 0, 1, ""},
+		{"retry on 5xx", []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK}, 0, 3, ""},
+		{"retry on 429", []int{http.StatusTooManyRequests, http.StatusOK}, 0, 2, ""},
+		{"gives up after the retries", []int{http.StatusServiceUnavailable}, 2, 3, "503"},
+		{"default retries", []int{http.StatusInternalServerError}, 0, defaultMaxRetries + 1, "500"},
+		{"no retry on 4xx", []int{http.StatusNotFound}, 0, 1, "404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newWebhookServer(t, tt.statuses...)
+			target := Target{Name: "hook", Kind: KindWebhook, Enabled: true, URL: server.URL, MaxRetries: tt.maxRetries}
+			err := newTestNotifier(t).Test(target)
+
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("delivery failed: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("got error %v, want one mentioning %s", err, tt.wantErr)
+			}
+			if got := server.attempts(); got != tt.attempts {
+				t.Errorf("%d attempts, want %d", got, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestWebhookRequest(t *testing.T) {
+	server := newWebhookServer(t, http.StatusOK)
+	target := Target{
+		Name:    "hook",
+		Kind:    KindWebhook,
+		Enabled: true,
+		URL:     server.URL,
+		Headers: map[string]string{"Authorization": "Bearer token"},
+		Events:  []EventType{EventAlertFired},
+	}
+	n := newTestNotifier(t, target)
+	n.Notify(Event{Type: EventAdminConnected, Message: "not subscribed"})
+	n.Notify(Event{Type: EventAlertFired, Worker: "10.0.0.1", Message: "CPU high"})
+	n.Close() // Delivers what is queued
+
+	if server.attempts() != 1 {
+		t.Fatalf("%d deliveries, want only the subscribed event", server.attempts())
+	}
+	evt, header := server.events[0], server.headers[0]
+	if evt.Type != EventAlertFired || evt.Worker != "10.0.0.1" || evt.Message != "CPU high" || evt.Time.IsZero() {
+		t.Errorf("delivered %+v", evt)
+	}
+	if header.Get("Content-Type") != "application/json" || header.Get("Authorization") != "Bearer token" {
+		t.Errorf("request headers %v", header)
+	}
+}
+
+func TestWebhookUnreachable(t *testing.T) {
+	server := newWebhookServer(t, http.StatusOK)
+	url := server.URL
+	server.Close()
+
+	target := Target{Name: "hook", Kind: KindWebhook, Enabled: true, URL: url, MaxRetries: 1}
+	if err := newTestNotifier(t).Test(target); err == nil {
+		t.Error("delivery to a closed server succeeded")
+	}
+}
@@ -1,6 +1,11 @@
 package state
 
-import "sync"
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
 
 type Role int
 
@@ -23,10 +28,58 @@ type DeviceInfo struct {
 	RAMUsed       uint64
 	GPUName       string
 	GPUUsage      float64
+	DiskUsage     float64
 	InternetSpeed string
 	Uptime        uint64
+	LastSeen      time.Time // Time of the last metrics update from the worker
 	SSHEnabled    bool
 	SSHPort       int
+
+	// Tags declared by the worker itself and tags assigned by the admin
+	Tags      []string
+	AdminTags []string
+
+	// Results of the last on-demand speed test (zero if never run)
+	LatencyMs     float64
+	DownloadMbps  float64
+	UploadMbps    float64
+	SpeedTestedAt time.Time
+}
+
+// AllTags returns the worker's own and admin-assigned tags, sorted and de-duplicated
+func (d *DeviceInfo) AllTags() []string {
+	return NormalizeTags(append(append([]string(nil), d.Tags...), d.AdminTags...))
+}
+
+// HasTag reports whether the worker carries a tag (either source)
+func (d *DeviceInfo) HasTag(tag string) bool {
+	for _, t := range d.AllTags() {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeTags lower-cases, trims, de-duplicates and sorts tags, dropping empty ones
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		result = append(result, t)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ParseTags splits a comma-separated tag list (as typed by the user) into normalized tags
+func ParseTags(s string) []string {
+	return NormalizeTags(strings.Split(s, ","))
 }
 
 // AdminInfo contains info about the connected admin (for worker)
@@ -145,6 +198,39 @@ func (s *AppState) GetConnectedDeviceByID(id string) *DeviceInfo {
 	return s.connectedDevices[id]
 }
 
+// SetDeviceAdminTags sets the admin-assigned tags of a worker
+func (s *AppState) SetDeviceAdminTags(id string, tags []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if device, ok := s.connectedDevices[id]; ok {
+		device.AdminTags = NormalizeTags(tags)
+	}
+}
+
+// GetAllTags returns every tag carried by at least one connected worker, sorted
+func (s *AppState) GetAllTags() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var tags []string
+	for _, d := range s.connectedDevices {
+		tags = append(tags, d.AllTags()...)
+	}
+	return NormalizeTags(tags)
+}
+
+// GetDevicesWithTag returns the connected workers carrying a tag
+func (s *AppState) GetDevicesWithTag(tag string) []*DeviceInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var list []*DeviceInfo
+	for _, d := range s.connectedDevices {
+		if d.HasTag(tag) {
+			list = append(list, d)
+		}
+	}
+	return list
+}
+
 // Legacy methods for compatibility
 func (s *AppState) SetConnectedDevice(device *DeviceInfo) {
 	s.AddConnectedDevice(device)
@@ -167,13 +253,28 @@ func (s *AppState) UpdateDeviceMetrics(cpuUsage, ramUsage, gpuUsage float64) {
 }
 
 // UpdateDeviceMetricsByID updates metrics for a specific worker
-func (s *AppState) UpdateDeviceMetricsByID(id string, cpuUsage, ramUsage, gpuUsage float64) {
+func (s *AppState) UpdateDeviceMetricsByID(id string, cpuUsage, ramUsage, gpuUsage, diskUsage float64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if device, ok := s.connectedDevices[id]; ok {
 		device.CPUUsage = cpuUsage
 		device.RAMUsage = ramUsage
 		device.GPUUsage = gpuUsage
+		device.DiskUsage = diskUsage
+		device.LastSeen = time.Now()
+	}
+}
+
+// UpdateDeviceSpeedTestByID stores speed test results for a specific worker
+func (s *AppState) UpdateDeviceSpeedTestByID(id string, latencyMs, downloadMbps, uploadMbps float64, summary string, testedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if device, ok := s.connectedDevices[id]; ok {
+		device.LatencyMs = latencyMs
+		device.DownloadMbps = downloadMbps
+		device.UploadMbps = uploadMbps
+		device.InternetSpeed = summary
+		device.SpeedTestedAt = testedAt
 	}
 }
 
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/shirou/gopsutil/v3/host"
 	"github.com/shirou/gopsutil/v3/mem"
 )
@@ -25,6 +26,7 @@ type SystemInfo struct {
 	RAMUsed       uint64
 	GPUName       string
 	GPUUsage      float64
+	DiskUsage     float64
 	InternetSpeed string
 	LocalIP       string
 	Uptime        uint64
@@ -74,7 +76,8 @@ func GetLocalSystemInfo() SystemInfo {
 		RAMUsed:       ramUsed,
 		GPUName:       gpuName,
 		GPUUsage:      gpuUsage,
-		InternetSpeed: "N/A", // Will be measured on demand
+		DiskUsage:     GetDiskUsage(),
+		InternetSpeed: "N/A", // Measured on demand by the admin (network.AdminClient.RunSpeedTest)
 		LocalIP:       localIP,
 		Uptime:        uptime,
 	}
@@ -100,6 +103,22 @@ func GetRealTimeMetrics() (cpuUsage, ramUsage, gpuUsage float64) {
 	return
 }
 
+// GetDiskUsage returns the used percentage of the system disk
+func GetDiskUsage() float64 {
+	path := "/"
+	if runtime.GOOS == "windows" {
+		path = os.Getenv("SystemDrive") + "\\"
+		if path == "\\" {
+			path = "C:\\"
+		}
+	}
+	usage, err := disk.Usage(path)
+	if err != nil {
+		return 0
+	}
+	return usage.UsedPercent
+}
+
 // getOSName returns a human-readable OS name
 func getOSName() string {
 	switch runtime.GOOS {
@@ -0,0 +1,167 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png" // Screenshot tools write PNG files
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// FakeScreenEnv makes NewScreenCapturer return a FakeScreen test pattern instead of the
+// real screen, so screen viewing can be tried on machines without a display. Set it to
+// a size like "1280x720", or any other value for the default size.
+const FakeScreenEnv = "ADMINADMIN_FAKE_SCREEN"
+
+// ScreenCapturer takes pictures of the local screen
+type ScreenCapturer interface {
+	Capture() (image.Image, error)
+}
+
+// NewScreenCapturer returns the capturer for this machine: a FakeScreen if FakeScreenEnv
+// is set, otherwise one using the platform's screenshot tool
+func NewScreenCapturer() ScreenCapturer {
+	if value := os.Getenv(FakeScreenEnv); value != "" {
+		width, height := 1280, 720
+		var w, h int
+		if _, err := fmt.Sscanf(value, "%dx%d", &w, &h); err == nil && w > 0 && h > 0 {
+			width, height = w, h
+		}
+		return &FakeScreen{Width: width, Height: height}
+	}
+	return toolScreen{}
+}
+
+// toolScreen captures the screen by running a screenshot tool that writes a PNG file
+type toolScreen struct{}
+
+// Linux screenshot tools tried in order, with the arguments before the output file
+var linuxScreenTools = [][]string{
+	{"grim"},                      // Wayland (wlroots)
+	{"gnome-screenshot", "-f"},    // GNOME
+	{"import", "-window", "root"}, // ImageMagick on X11
+	{"scrot"},
+}
+
+// windowsScreenScript saves the primary screen as a PNG to the path %s (a quoted literal)
+const windowsScreenScript = `Add-Type -AssemblyName System.Windows.Forms, System.Drawing
+$b = [System.Windows.Forms.Screen]::PrimaryScreen.Bounds
+$bmp = New-Object System.Drawing.Bitmap $b.Width, $b.Height
+$g = [System.Drawing.Graphics]::FromImage($bmp)
+$g.CopyFromScreen($b.Location, [System.Drawing.Point]::Empty, $b.Size)
+$bmp.Save(%s, [System.Drawing.Imaging.ImageFormat]::Png)`
+
+func (toolScreen) Capture() (image.Image, error) {
+	file, err := os.CreateTemp("", "adminadmin-screen-*.png")
+	if err != nil {
+		return nil, err
+	}
+	// Only the name is reserved: some tools refuse to overwrite a file
+	path := file.Name()
+	file.Close()
+	os.Remove(path)
+	defer os.Remove(path)
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		quoted := "'" + strings.ReplaceAll(path, "'", "''") + "'"
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command",
+			fmt.Sprintf(windowsScreenScript, quoted))
+	case "darwin":
+		cmd = exec.Command("screencapture", "-x", "-t", "png", path)
+	default:
+		for _, tool := range linuxScreenTools {
+			if _, err := exec.LookPath(tool[0]); err == nil {
+				cmd = exec.Command(tool[0], append(tool[1:], path)...)
+				break
+			}
+		}
+		if cmd == nil {
+			return nil, fmt.Errorf("no screenshot tool found (install grim, gnome-screenshot, ImageMagick or scrot), "+
+				"or set %s to show a test pattern", FakeScreenEnv)
+		}
+	}
+	cmd.SysProcAttr = getHiddenWindowAttr()
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%s failed: %v %s", cmd.Args[0], err, strings.TrimSpace(string(output)))
+	}
+
+	file, err = os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("unreadable screenshot: %w", err)
+	}
+	return img, nil
+}
+
+// FakeScreen is a test pattern standing in for the screen: color bars, and a marker
+// that moves and a bar that grows once per second, so a live view sees the picture
+// change at that rate however often it captures
+type FakeScreen struct {
+	Width, Height int
+	Now           func() time.Time // Defaults to time.Now
+}
+
+// fakeScreenBars are the colors of the test pattern's bars
+var fakeScreenBars = []color.RGBA{
+	{192, 192, 192, 255}, {192, 192, 0, 255}, {0, 192, 192, 255}, {0, 192, 0, 255},
+	{192, 0, 192, 255}, {192, 0, 0, 255}, {0, 0, 192, 255},
+}
+
+func (f *FakeScreen) Capture() (image.Image, error) {
+	if f.Width <= 0 || f.Height <= 0 {
+		return nil, errors.New("fake screen has no size")
+	}
+	now := time.Now
+	if f.Now != nil {
+		now = f.Now
+	}
+	second := int(now().Unix() % 60)
+
+	img := image.NewRGBA(image.Rect(0, 0, f.Width, f.Height))
+	barsBottom := f.Height * 2 / 3
+	for y := 0; y < f.Height; y++ {
+		for x := 0; x < f.Width; x++ {
+			var c color.RGBA
+			if y < barsBottom {
+				c = fakeScreenBars[x*len(fakeScreenBars)/f.Width]
+			} else {
+				// Gray ramp below the bars
+				v := uint8(x * 255 / f.Width)
+				c = color.RGBA{v, v, v, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+
+	// Seconds bar along the bottom
+	barHeight := max(f.Height/40, 2)
+	barWidth := f.Width * (second + 1) / 60
+	fillRect(img, image.Rect(0, f.Height-barHeight, barWidth, f.Height), color.RGBA{255, 255, 255, 255})
+
+	// Marker moving along a row of 60 positions
+	size := max(f.Height/12, 4)
+	x := (f.Width - size) * second / 59
+	y := barsBottom + (f.Height-barsBottom-size)/2
+	fillRect(img, image.Rect(x, y, x+size, y+size), color.RGBA{255, 64, 64, 255})
+	return img, nil
+}
+
+func fillRect(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	r = r.Intersect(img.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
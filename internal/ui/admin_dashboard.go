@@ -7,6 +7,8 @@ import (
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/widget"
+	"sort"
+	"strings"
 	"sync"
 )
 
@@ -62,6 +64,19 @@ type AdminDashboardController struct {
 	onAddWorker    func()
 	onSelectWorker func(string)
 	onSSH          func(string)
+	onSpeedTest    func(string)
+	onAlerts       func()
+	onNotify       func()
+	onEditTags     func(string)
+	onBroadcast    func()
+	onPairKey      func(string)
+	onScreen       func(string)
+	onKnownHosts   func()
+	onTranscripts  func()
+
+	// Bulk actions on all workers carrying a tag
+	onGroupCommand    func(string)
+	onGroupDisconnect func(string)
 
 	// State
 	appState *state.AppState
@@ -74,10 +89,23 @@ type AdminDashboardController struct {
 	// Labels that need updating
 	ramDetailsLabel *widget.Label
 	uptimeLabel     *widget.Label
+	speedLabel      *widget.Label
+
+	// Alerts button in the bottom bar (text shows the active alert count)
+	alertsButton *widget.Button
 
 	// Current worker ID being displayed
 	currentWorkerID string
 
+	// Fleet overview grid (persistent so tiles update in place across rebuilds)
+	overview     *FleetOverview
+	overviewMode bool
+	viewButton   *widget.Button
+
+	// Main area: holds either the overview or the list/details split
+	body        *fyne.Container
+	detailsView fyne.CanvasObject
+
 	// Root container
 	rootContainer fyne.CanvasObject
 }
@@ -108,10 +136,152 @@ func NewAdminDashboardController(
 	// Create persistent labels
 	ctrl.ramDetailsLabel = widget.NewLabel("")
 	ctrl.uptimeLabel = widget.NewLabel("")
+	ctrl.speedLabel = widget.NewLabel("")
+
+	ctrl.alertsButton = widget.NewButton("Alerts", func() {
+		if ctrl.onAlerts != nil {
+			ctrl.onAlerts()
+		}
+	})
+
+	// Overview click-through switches to the details view for that worker
+	ctrl.overview = NewFleetOverview(func(id string) {
+		ctrl.setOverviewMode(false)
+		ctrl.onSelectWorker(id)
+	})
+	ctrl.viewButton = widget.NewButton("Fleet Overview", func() {
+		ctrl.setOverviewMode(!ctrl.overviewMode)
+	})
+	ctrl.body = container.NewStack()
 
 	return ctrl
 }
 
+// setOverviewMode switches the main area between the fleet overview and the worker details
+func (ctrl *AdminDashboardController) setOverviewMode(on bool) {
+	ctrl.overviewMode = on
+	if on {
+		ctrl.viewButton.SetText("Worker Details")
+		ctrl.overview.Update(ctrl.appState.GetConnectedDevicesList())
+		ctrl.body.Objects = []fyne.CanvasObject{ctrl.overview.Content()}
+	} else {
+		ctrl.viewButton.SetText("Fleet Overview")
+		if ctrl.detailsView != nil {
+			ctrl.body.Objects = []fyne.CanvasObject{ctrl.detailsView}
+		}
+	}
+	ctrl.body.Refresh()
+}
+
+// SetOnBroadcast sets the callback for the "Run on Selection" button
+func (ctrl *AdminDashboardController) SetOnBroadcast(onBroadcast func()) {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	ctrl.onBroadcast = onBroadcast
+}
+
+// SetOnPairKey sets the callback for the "Pair SSH Key" button (receives the worker ID)
+func (ctrl *AdminDashboardController) SetOnPairKey(onPairKey func(string)) {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	ctrl.onPairKey = onPairKey
+}
+
+// SetOnScreen sets the callback for the "View Screen" button (receives the worker ID)
+func (ctrl *AdminDashboardController) SetOnScreen(onScreen func(string)) {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	ctrl.onScreen = onScreen
+}
+
+// SetOnKnownHosts sets the callback for the "Known Hosts" button
+func (ctrl *AdminDashboardController) SetOnKnownHosts(onKnownHosts func()) {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	ctrl.onKnownHosts = onKnownHosts
+}
+
+// SetOnTranscripts sets the callback for the "Transcripts" button
+func (ctrl *AdminDashboardController) SetOnTranscripts(onTranscripts func()) {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	ctrl.onTranscripts = onTranscripts
+}
+
+// SetOnEditTags sets the callback for the "Edit Tags" button (receives the worker ID)
+func (ctrl *AdminDashboardController) SetOnEditTags(onEditTags func(string)) {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	ctrl.onEditTags = onEditTags
+}
+
+// SetOnGroupActions sets the callbacks for the per-group "Run..." and "Disconnect" buttons (receive the tag)
+func (ctrl *AdminDashboardController) SetOnGroupActions(onCommand func(string), onDisconnect func(string)) {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	ctrl.onGroupCommand = onCommand
+	ctrl.onGroupDisconnect = onDisconnect
+}
+
+// SetOnNotifications sets the callback for the "Notifications" button
+func (ctrl *AdminDashboardController) SetOnNotifications(onNotify func()) {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	ctrl.onNotify = onNotify
+}
+
+// SetOnAlerts sets the callback for the "Alerts" button
+func (ctrl *AdminDashboardController) SetOnAlerts(onAlerts func()) {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	ctrl.onAlerts = onAlerts
+}
+
+// SetAlertCount updates the alerts button with the number of unacknowledged alerts
+func (ctrl *AdminDashboardController) SetAlertCount(count int) {
+	ctrl.runOnMain(func() {
+		if count > 0 {
+			ctrl.alertsButton.SetText(fmt.Sprintf("Alerts (%d)", count))
+			ctrl.alertsButton.Importance = widget.DangerImportance
+		} else {
+			ctrl.alertsButton.SetText("Alerts")
+			ctrl.alertsButton.Importance = widget.MediumImportance
+		}
+		ctrl.alertsButton.Refresh()
+	})
+}
+
+// SetOnSpeedTest sets the callback for the "Run Speed Test" button (receives the worker ID)
+func (ctrl *AdminDashboardController) SetOnSpeedTest(onSpeedTest func(string)) {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	ctrl.onSpeedTest = onSpeedTest
+}
+
+// SetSpeedTestStatus shows a transient status (e.g. "running") for a worker's speed test.
+// It is ignored if another worker is currently displayed.
+func (ctrl *AdminDashboardController) SetSpeedTestStatus(workerID, status string) {
+	ctrl.mu.RLock()
+	current := ctrl.currentWorkerID
+	ctrl.mu.RUnlock()
+
+	if current != workerID {
+		return
+	}
+	ctrl.runOnMain(func() {
+		ctrl.speedLabel.SetText(status)
+	})
+}
+
+// formatSpeedTest returns the speed label text for a device
+func formatSpeedTest(device *state.DeviceInfo) string {
+	if device.SpeedTestedAt.IsZero() {
+		return "Network Speed: not measured"
+	}
+	return fmt.Sprintf("Network Speed: %s (tested %s)",
+		device.InternetSpeed, device.SpeedTestedAt.Format("15:04:05"))
+}
+
 // runOnMain safely runs a function on the main UI thread
 func (ctrl *AdminDashboardController) runOnMain(fn func()) {
 	if drv := fyne.CurrentApp().Driver(); drv != nil {
@@ -145,6 +315,7 @@ func (ctrl *AdminDashboardController) GetContent() fyne.CanvasObject {
 			system.FormatBytes(device.RAMUsed),
 			system.FormatBytes(device.RAMTotal)))
 		ctrl.uptimeLabel.SetText(fmt.Sprintf("Uptime: %s", system.FormatUptime(device.Uptime)))
+		ctrl.speedLabel.SetText(formatSpeedTest(device))
 	}
 
 	// Only rebuild UI if worker selection changed or first time
@@ -156,6 +327,20 @@ func (ctrl *AdminDashboardController) GetContent() fyne.CanvasObject {
 	return ctrl.rootContainer
 }
 
+// UpdateWorkerMetrics refreshes the display after a metrics update from one worker:
+// the overview tiles (if shown) and the details gauges (if it is the selected worker)
+func (ctrl *AdminDashboardController) UpdateWorkerMetrics(workerID string) {
+	if ctrl.overviewMode {
+		devices := ctrl.appState.GetConnectedDevicesList()
+		ctrl.runOnMain(func() {
+			ctrl.overview.Update(devices)
+		})
+	}
+	if ctrl.appState.GetSelectedWorkerID() == workerID {
+		ctrl.UpdateMetricsOnly()
+	}
+}
+
 // UpdateMetricsOnly updates only the gauge values without rebuilding UI
 func (ctrl *AdminDashboardController) UpdateMetricsOnly() {
 	ctrl.mu.RLock()
@@ -172,8 +357,10 @@ func (ctrl *AdminDashboardController) UpdateMetricsOnly() {
 		ramText := fmt.Sprintf("RAM: %s / %s",
 			system.FormatBytes(device.RAMUsed),
 			system.FormatBytes(device.RAMTotal))
+		speedText := formatSpeedTest(device)
 		ctrl.runOnMain(func() {
 			ctrl.ramDetailsLabel.SetText(ramText)
+			ctrl.speedLabel.SetText(speedText)
 		})
 	}
 }
@@ -196,18 +383,24 @@ func (ctrl *AdminDashboardController) buildFullUI(workers []*state.DeviceInfo, d
 
 	workerCountLabel := widget.NewLabel(fmt.Sprintf("Connected Workers: %d", len(workers)))
 
-	// Worker list (left side)
+	// Worker list (left side), grouped by tag; a worker appears under each of its tags
 	workerList := container.NewVBox()
 
-	for _, worker := range workers {
-		w := worker
-		workerBtn := widget.NewButton(fmt.Sprintf("%s (%s)", w.Hostname, w.IPAddress), func() {
-			ctrl.onSelectWorker(w.ID)
-		})
-		if w.ID == selectedID {
-			workerBtn.Importance = widget.HighImportance
+	groups, untagged := groupWorkersByTag(workers)
+	for _, group := range groups {
+		workerList.Add(ctrl.buildGroupHeader(group.tag, len(group.workers)))
+		for _, w := range group.workers {
+			workerList.Add(ctrl.buildWorkerButton(w, selectedID))
+		}
+	}
+	if len(untagged) > 0 {
+		if len(groups) > 0 {
+			workerList.Add(widget.NewLabelWithStyle(fmt.Sprintf("Untagged (%d)", len(untagged)),
+				fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		}
+		for _, w := range untagged {
+			workerList.Add(ctrl.buildWorkerButton(w, selectedID))
 		}
-		workerList.Add(workerBtn)
 	}
 
 	addWorkerBtn := widget.NewButton("+ Add Worker", ctrl.onAddWorker)
@@ -235,23 +428,120 @@ func (ctrl *AdminDashboardController) buildFullUI(workers []*state.DeviceInfo, d
 		container.NewVScroll(detailsContent),
 	)
 	split.SetOffset(0.25)
+	ctrl.detailsView = split
+	ctrl.setOverviewMode(ctrl.overviewMode)
 
 	// Bottom buttons
 	disconnectButton := widget.NewButton("Disconnect All", ctrl.onDisconnect)
 	disconnectButton.Importance = widget.DangerImportance
 	backButton := widget.NewButton("Back to Role Selection", ctrl.onBack)
-	buttonSection := container.NewHBox(disconnectButton, backButton)
+	notifyButton := widget.NewButton("Notifications", func() {
+		if ctrl.onNotify != nil {
+			ctrl.onNotify()
+		}
+	})
+	broadcastButton := widget.NewButton("Run on Selection", func() {
+		if ctrl.onBroadcast != nil {
+			ctrl.onBroadcast()
+		}
+	})
+	knownHostsButton := widget.NewButton("Known Hosts", func() {
+		if ctrl.onKnownHosts != nil {
+			ctrl.onKnownHosts()
+		}
+	})
+	transcriptsButton := widget.NewButton("Transcripts", func() {
+		if ctrl.onTranscripts != nil {
+			ctrl.onTranscripts()
+		}
+	})
+	buttonSection := container.NewHBox(disconnectButton, backButton, broadcastButton, ctrl.alertsButton, notifyButton, knownHostsButton, transcriptsButton)
 
 	content := container.NewBorder(
-		container.NewVBox(title, workerCountLabel, widget.NewSeparator()),
+		container.NewVBox(title, container.NewBorder(nil, nil, nil, ctrl.viewButton, workerCountLabel), widget.NewSeparator()),
 		container.NewVBox(widget.NewSeparator(), buttonSection),
 		nil, nil,
-		split,
+		ctrl.body,
 	)
 
 	return content
 }
 
+// workerGroup is one tag group in the sidebar
+type workerGroup struct {
+	tag     string
+	workers []*state.DeviceInfo
+}
+
+// groupWorkersByTag groups workers by tag (sorted by tag, then hostname) and returns the untagged ones separately
+func groupWorkersByTag(workers []*state.DeviceInfo) ([]workerGroup, []*state.DeviceInfo) {
+	sorted := append([]*state.DeviceInfo(nil), workers...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Hostname != sorted[j].Hostname {
+			return sorted[i].Hostname < sorted[j].Hostname
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	byTag := make(map[string][]*state.DeviceInfo)
+	var untagged []*state.DeviceInfo
+	for _, w := range sorted {
+		tags := w.AllTags()
+		if len(tags) == 0 {
+			untagged = append(untagged, w)
+		}
+		for _, t := range tags {
+			byTag[t] = append(byTag[t], w)
+		}
+	}
+
+	groups := make([]workerGroup, 0, len(byTag))
+	for tag, list := range byTag {
+		groups = append(groups, workerGroup{tag: tag, workers: list})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].tag < groups[j].tag })
+	return groups, untagged
+}
+
+// buildGroupHeader creates a tag group header with its bulk action buttons
+func (ctrl *AdminDashboardController) buildGroupHeader(tag string, count int) fyne.CanvasObject {
+	label := widget.NewLabelWithStyle(fmt.Sprintf("%s (%d)", tag, count), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+
+	runBtn := widget.NewButton("Run...", func() {
+		if ctrl.onGroupCommand != nil {
+			ctrl.onGroupCommand(tag)
+		}
+	})
+	disconnectBtn := widget.NewButton("Disconnect", func() {
+		if ctrl.onGroupDisconnect != nil {
+			ctrl.onGroupDisconnect(tag)
+		}
+	})
+	disconnectBtn.Importance = widget.DangerImportance
+
+	return container.NewBorder(nil, nil, nil, container.NewHBox(runBtn, disconnectBtn), label)
+}
+
+// buildWorkerButton creates the sidebar button for a worker
+func (ctrl *AdminDashboardController) buildWorkerButton(w *state.DeviceInfo, selectedID string) fyne.CanvasObject {
+	workerBtn := widget.NewButton(fmt.Sprintf("%s (%s)", w.Hostname, w.IPAddress), func() {
+		ctrl.onSelectWorker(w.ID)
+	})
+	if w.ID == selectedID {
+		workerBtn.Importance = widget.HighImportance
+	}
+	return workerBtn
+}
+
+// formatTags returns the tags label text for a device
+func formatTags(device *state.DeviceInfo) string {
+	tags := device.AllTags()
+	if len(tags) == 0 {
+		return "Tags: none"
+	}
+	return fmt.Sprintf("Tags: %s", strings.Join(tags, ", "))
+}
+
 // buildWorkerDetailsView creates the detailed view using the persistent gauges
 func (ctrl *AdminDashboardController) buildWorkerDetailsView(device *state.DeviceInfo) fyne.CanvasObject {
 	deviceHeader := widget.NewLabelWithStyle(
@@ -263,11 +553,19 @@ func (ctrl *AdminDashboardController) buildWorkerDetailsView(device *state.Devic
 	osLabel := widget.NewLabel(fmt.Sprintf("OS: %s (%s)", device.OS, device.Architecture))
 	ipLabel := widget.NewLabel(fmt.Sprintf("IP: %s", device.IPAddress))
 
+	editTagsBtn := widget.NewButton("Edit Tags", func() {
+		if ctrl.onEditTags != nil {
+			ctrl.onEditTags(device.ID)
+		}
+	})
+	tagsRow := container.NewBorder(nil, nil, nil, editTagsBtn, widget.NewLabel(formatTags(device)))
+
 	infoSection := container.NewVBox(
 		deviceHeader,
 		osLabel,
 		ipLabel,
 		ctrl.uptimeLabel,
+		tagsRow,
 	)
 
 	// Use the persistent gauges
@@ -285,6 +583,27 @@ func (ctrl *AdminDashboardController) buildWorkerDetailsView(device *state.Devic
 	})
 	sshButton.Importance = widget.MediumImportance
 
+	// Sends the admin's public key so SSH logins need no password
+	pairKeyButton := widget.NewButton("Pair SSH Key", func() {
+		if ctrl.onPairKey != nil {
+			ctrl.onPairKey(device.ID)
+		}
+	})
+
+	// Shows what is on the worker's display, e.g. a kiosk
+	screenButton := widget.NewButton("View Screen", func() {
+		if ctrl.onScreen != nil {
+			ctrl.onScreen(device.ID)
+		}
+	})
+
+	// Speed test button - measures admin <-> worker throughput on demand
+	speedTestButton := widget.NewButton("Run Speed Test", func() {
+		if ctrl.onSpeedTest != nil {
+			ctrl.onSpeedTest(device.ID)
+		}
+	})
+
 	return container.NewVBox(
 		infoSection,
 		widget.NewSeparator(),
@@ -293,7 +612,11 @@ func (ctrl *AdminDashboardController) buildWorkerDetailsView(device *state.Devic
 		ctrl.ramDetailsLabel,
 		gpuLabel,
 		widget.NewSeparator(),
-		sshButton,
+		ctrl.speedLabel,
+		speedTestButton,
+		widget.NewSeparator(),
+		container.NewGridWithColumns(2, sshButton, pairKeyButton),
+		screenButton,
 	)
 }
 
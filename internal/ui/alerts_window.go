@@ -0,0 +1,264 @@
+package ui
+
+import (
+	"adminadmin/internal/alerts"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// AlertsWindow shows active alerts, alert history and the rule editor
+type AlertsWindow struct {
+	window  fyne.Window
+	engine  *alerts.Engine
+	onClose func()
+
+	// Called after rules, acknowledgements or silences change so the caller
+	// can persist rules and update alert counts elsewhere
+	onChanged func()
+
+	activeBox  *fyne.Container
+	historyBox *fyne.Container
+	rulesBox   *fyne.Container
+}
+
+// NewAlertsWindow creates the alerts window for an engine
+func NewAlertsWindow(app fyne.App, engine *alerts.Engine, onChanged func(), onClose func()) *AlertsWindow {
+	w := &AlertsWindow{
+		engine:     engine,
+		onClose:    onClose,
+		onChanged:  onChanged,
+		activeBox:  container.NewVBox(),
+		historyBox: container.NewVBox(),
+		rulesBox:   container.NewVBox(),
+	}
+
+	w.window = app.NewWindow("admin:admin - Alerts")
+	w.window.Resize(fyne.NewSize(700, 450))
+	w.window.SetOnClosed(func() {
+		if w.onClose != nil {
+			w.onClose()
+		}
+	})
+
+	clearHistoryBtn := widget.NewButton("Clear History", func() {
+		w.engine.ClearHistory()
+		w.Refresh()
+	})
+
+	addRuleBtn := widget.NewButton("+ Add Rule", w.showAddRuleDialog)
+	addRuleBtn.Importance = widget.HighImportance
+
+	tabs := container.NewAppTabs(
+		container.NewTabItem("Active", container.NewVScroll(w.activeBox)),
+		container.NewTabItem("History", container.NewBorder(
+			nil, container.NewHBox(clearHistoryBtn), nil, nil,
+			container.NewVScroll(w.historyBox),
+		)),
+		container.NewTabItem("Rules", container.NewBorder(
+			nil, container.NewHBox(addRuleBtn), nil, nil,
+			container.NewVScroll(w.rulesBox),
+		)),
+	)
+
+	w.window.SetContent(tabs)
+	w.rebuild()
+	return w
+}
+
+// Show shows the alerts window
+func (w *AlertsWindow) Show() {
+	w.window.Show()
+}
+
+// Close closes the alerts window
+func (w *AlertsWindow) Close() {
+	w.window.Close()
+}
+
+// Refresh rebuilds the lists from the engine; safe to call from any goroutine
+func (w *AlertsWindow) Refresh() {
+	if app := fyne.CurrentApp(); app != nil {
+		if drv := app.Driver(); drv != nil {
+			drv.DoFromGoroutine(w.rebuild, false)
+		}
+	}
+}
+
+func (w *AlertsWindow) rebuild() {
+	w.activeBox.RemoveAll()
+	active := w.engine.Active()
+	if len(active) == 0 {
+		w.activeBox.Add(widget.NewLabel("No active alerts"))
+	}
+	for _, a := range active {
+		w.activeBox.Add(w.buildActiveRow(a))
+	}
+	w.activeBox.Refresh()
+
+	w.historyBox.RemoveAll()
+	history := w.engine.History()
+	if len(history) == 0 {
+		w.historyBox.Add(widget.NewLabel("No alerts fired yet"))
+	}
+	for _, a := range history {
+		w.historyBox.Add(widget.NewLabel(formatHistoryEntry(a)))
+	}
+	w.historyBox.Refresh()
+
+	w.rulesBox.RemoveAll()
+	for _, r := range w.engine.Rules() {
+		w.rulesBox.Add(w.buildRuleRow(r))
+	}
+	w.rulesBox.Refresh()
+}
+
+// buildActiveRow creates one row in the active alerts list
+func (w *AlertsWindow) buildActiveRow(a alerts.Alert) fyne.CanvasObject {
+	text := fmt.Sprintf("%s — %s (%s)  value %.1f  since %s",
+		a.RuleName, a.Hostname, a.WorkerID, a.Value, a.FiredAt.Format("15:04:05"))
+	label := widget.NewLabel(text)
+
+	var status []string
+	if a.Acknowledged {
+		status = append(status, "acknowledged")
+	}
+	if a.Silenced {
+		status = append(status, "silenced")
+	}
+	statusLabel := widget.NewLabelWithStyle(strings.Join(status, ", "), fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+
+	ackBtn := widget.NewButton("Acknowledge", func() {
+		w.engine.Acknowledge(a.ID)
+		w.changed()
+		w.rebuild()
+	})
+	if a.Acknowledged {
+		ackBtn.Disable()
+	}
+
+	silenceBtn := widget.NewButton("Silence 1h", func() {
+		w.engine.Silence(a.RuleID, a.WorkerID, time.Hour, time.Now())
+		w.changed()
+		w.rebuild()
+	})
+	if a.Silenced {
+		silenceBtn.Disable()
+	}
+
+	return container.NewBorder(nil, nil, nil,
+		container.NewHBox(statusLabel, ackBtn, silenceBtn),
+		label,
+	)
+}
+
+// buildRuleRow creates one row in the rules list
+func (w *AlertsWindow) buildRuleRow(r alerts.Rule) fyne.CanvasObject {
+	label := widget.NewLabel(fmt.Sprintf("%s — %s", r.Name, r.Describe()))
+
+	enabled := widget.NewCheck("Enabled", nil)
+	enabled.SetChecked(r.Enabled)
+	enabled.OnChanged = func(on bool) {
+		r.Enabled = on
+		w.engine.AddRule(r, time.Now())
+		w.changed()
+	}
+
+	removeBtn := widget.NewButton("Remove", func() {
+		w.engine.RemoveRule(r.ID, time.Now())
+		w.changed()
+		w.rebuild()
+	})
+	removeBtn.Importance = widget.DangerImportance
+
+	return container.NewBorder(nil, nil, nil, container.NewHBox(enabled, removeBtn), label)
+}
+
+// showAddRuleDialog shows the form for creating a new rule
+func (w *AlertsWindow) showAddRuleDialog() {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("e.g. High RAM")
+
+	metricOptions := make([]string, len(alerts.Metrics))
+	for i, m := range alerts.Metrics {
+		metricOptions[i] = string(m)
+	}
+	metricSelect := widget.NewSelect(metricOptions, nil)
+	metricSelect.SetSelected(string(alerts.MetricCPU))
+
+	thresholdEntry := widget.NewEntry()
+	thresholdEntry.SetText("90")
+
+	forEntry := widget.NewEntry()
+	forEntry.SetText("120")
+
+	workerEntry := widget.NewEntry()
+	workerEntry.SetPlaceHolder("Worker IP (empty = all)")
+
+	tagEntry := widget.NewEntry()
+	tagEntry.SetPlaceHolder("Tag (empty = all)")
+
+	formItems := []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Metric", metricSelect),
+		widget.NewFormItem("Threshold (%)", thresholdEntry),
+		widget.NewFormItem("For (seconds)", forEntry),
+		widget.NewFormItem("Worker", workerEntry),
+		widget.NewFormItem("Tag", tagEntry),
+	}
+
+	dialog.ShowForm("Add Alert Rule", "Add", "Cancel", formItems, func(ok bool) {
+		if !ok {
+			return
+		}
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(thresholdEntry.Text), 64)
+		if err != nil && metricSelect.Selected != string(alerts.MetricUnreachable) {
+			dialog.ShowError(fmt.Errorf("invalid threshold: %q", thresholdEntry.Text), w.window)
+			return
+		}
+		forSeconds, err := strconv.Atoi(strings.TrimSpace(forEntry.Text))
+		if err != nil || forSeconds < 0 {
+			dialog.ShowError(fmt.Errorf("invalid duration: %q", forEntry.Text), w.window)
+			return
+		}
+
+		rule := alerts.Rule{
+			Name:       strings.TrimSpace(nameEntry.Text),
+			Metric:     alerts.Metric(metricSelect.Selected),
+			Threshold:  threshold,
+			ForSeconds: forSeconds,
+			WorkerID:   strings.TrimSpace(workerEntry.Text),
+			Tag:        strings.TrimSpace(tagEntry.Text),
+			Enabled:    true,
+		}
+		if rule.Name == "" {
+			rule.Name = rule.Describe()
+		}
+		w.engine.AddRule(rule, time.Now())
+		w.changed()
+		w.rebuild()
+	}, w.window)
+}
+
+func (w *AlertsWindow) changed() {
+	if w.onChanged != nil {
+		w.onChanged()
+	}
+}
+
+// formatHistoryEntry formats a history entry as a single line
+func formatHistoryEntry(a alerts.Alert) string {
+	if a.Active() {
+		return fmt.Sprintf("%s  FIRED     %s — %s (%s) value %.1f",
+			a.FiredAt.Format("2006-01-02 15:04:05"), a.RuleName, a.Hostname, a.WorkerID, a.Value)
+	}
+	return fmt.Sprintf("%s  RESOLVED  %s — %s (%s) after %s",
+		a.ResolvedAt.Format("2006-01-02 15:04:05"), a.RuleName, a.Hostname, a.WorkerID,
+		a.ResolvedAt.Sub(a.FiredAt).Round(time.Second))
+}
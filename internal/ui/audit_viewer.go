@@ -0,0 +1,401 @@
+package ui
+
+import (
+	"adminadmin/internal/network"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// auditEventLimit is how many of a worker's most recent events the viewer fetches
+const auditEventLimit = 2000
+
+// Columns of the audit event table
+var auditColumns = []struct {
+	title string
+	width float32
+}{
+	{"Time", 150},
+	{"User", 90},
+	{"From", 150},
+	{"Event", 110},
+	{"Command / Detail", 360},
+	{"", 50}, // Replay button
+}
+
+// AuditViewer shows a worker's SSH audit log and replays its session recordings
+type AuditViewer struct {
+	client *network.SSHClient
+	window fyne.Window
+
+	mu       sync.Mutex
+	events   []network.AuditEvent // Newest first
+	filtered []network.AuditEvent
+
+	table       *widget.Table
+	filterEntry *widget.Entry
+	statusLabel *widget.Label
+}
+
+// NewAuditViewer creates a viewer and starts fetching the worker's audit log
+func NewAuditViewer(client *network.SSHClient, window fyne.Window) *AuditViewer {
+	v := &AuditViewer{
+		client:      client,
+		window:      window,
+		filterEntry: widget.NewEntry(),
+		statusLabel: widget.NewLabel(""),
+	}
+	v.filterEntry.SetPlaceHolder("Filter by user, address, event or command")
+	v.filterEntry.OnChanged = func(string) { v.applyFilter() }
+
+	v.table = widget.NewTable(
+		func() (int, int) {
+			v.mu.Lock()
+			defer v.mu.Unlock()
+			return len(v.filtered), len(auditColumns)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			playBtn := widget.NewButtonWithIcon("", theme.MediaPlayIcon(), nil)
+			playBtn.Importance = widget.LowImportance
+			return container.NewStack(label, playBtn)
+		},
+		func(id widget.TableCellID, obj fyne.CanvasObject) {
+			v.mu.Lock()
+			if id.Row >= len(v.filtered) {
+				v.mu.Unlock()
+				return
+			}
+			event := v.filtered[id.Row]
+			v.mu.Unlock()
+
+			cell := obj.(*fyne.Container)
+			label := cell.Objects[0].(*widget.Label)
+			playBtn := cell.Objects[1].(*widget.Button)
+			label.Show()
+			playBtn.Hide()
+
+			switch id.Col {
+			case 0:
+				label.SetText(event.Time.Local().Format("2006-01-02 15:04:05"))
+			case 1:
+				label.SetText(event.User)
+			case 2:
+				label.SetText(event.Remote)
+			case 3:
+				label.SetText(event.Event)
+			case 4:
+				label.SetText(strings.TrimSpace(event.Command + " " + event.Detail))
+			case 5:
+				label.Hide()
+				if event.Recording != "" {
+					playBtn.OnTapped = func() { v.play(event) }
+					playBtn.Show()
+				}
+			}
+		},
+	)
+	v.table.ShowHeaderRow = true
+	v.table.CreateHeader = func() fyne.CanvasObject {
+		return widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	}
+	v.table.UpdateHeader = func(id widget.TableCellID, obj fyne.CanvasObject) {
+		obj.(*widget.Label).SetText(auditColumns[id.Col].title)
+	}
+	for i, col := range auditColumns {
+		v.table.SetColumnWidth(i, col.width)
+	}
+
+	v.Refresh()
+	return v
+}
+
+// Content returns the viewer UI
+func (v *AuditViewer) Content() fyne.CanvasObject {
+	refreshBtn := widget.NewButtonWithIcon("Refresh", theme.ViewRefreshIcon(), v.Refresh)
+	top := container.NewBorder(nil, nil, nil, refreshBtn, v.filterEntry)
+	return container.NewBorder(
+		container.NewVBox(top, widget.NewSeparator()),
+		container.NewVBox(widget.NewSeparator(), v.statusLabel),
+		nil, nil,
+		v.table,
+	)
+}
+
+// Refresh fetches the latest events from the worker
+func (v *AuditViewer) Refresh() {
+	v.statusLabel.SetText("Loading audit log...")
+	go func() {
+		events, err := v.client.AuditEvents(auditEventLimit)
+		runOnMainThread(func() {
+			if err != nil {
+				v.statusLabel.SetText("Failed to load audit log: " + err.Error())
+				return
+			}
+			// Newest first
+			for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+				events[i], events[j] = events[j], events[i]
+			}
+			v.mu.Lock()
+			v.events = events
+			v.mu.Unlock()
+			v.applyFilter()
+		})
+	}()
+}
+
+// applyFilter shows the events matching the filter text
+func (v *AuditViewer) applyFilter() {
+	query := strings.ToLower(strings.TrimSpace(v.filterEntry.Text))
+	v.mu.Lock()
+	v.filtered = v.filtered[:0]
+	for _, e := range v.events {
+		text := strings.ToLower(strings.Join([]string{e.User, e.Remote, e.Event, e.Command, e.Detail, e.Session}, " "))
+		if query == "" || strings.Contains(text, query) {
+			v.filtered = append(v.filtered, e)
+		}
+	}
+	shown, total := len(v.filtered), len(v.events)
+	v.mu.Unlock()
+
+	v.table.Refresh()
+	v.statusLabel.SetText(fmt.Sprintf("%d of %d events", shown, total))
+}
+
+// play downloads an event's recording and opens it in a player window
+func (v *AuditViewer) play(event network.AuditEvent) {
+	v.statusLabel.SetText("Downloading " + event.Recording + "...")
+	go func() {
+		data, err := v.client.AuditRecording(event.Recording)
+		runOnMainThread(func() {
+			if err != nil {
+				v.statusLabel.SetText("")
+				dialog.ShowError(fmt.Errorf("failed to download recording: %w", err), v.window)
+				return
+			}
+			v.statusLabel.SetText("")
+			if err := ShowRecordingPlayer(event.Recording, data); err != nil {
+				dialog.ShowError(err, v.window)
+			}
+		})
+	}()
+}
+
+// recordingPlayer replays an asciicast recording into a terminal widget
+type recordingPlayer struct {
+	cast *network.Asciicast
+	term *TerminalWidget
+
+	mu       sync.Mutex
+	now      float64 // Position in the recording, in seconds
+	next     int     // Index of the first event not yet shown
+	playing  bool
+	speed    float64
+	skipIdle bool
+
+	playBtn   *widget.Button
+	slider    *widget.Slider
+	timeLabel *widget.Label
+	seeking   bool // The slider is being moved by playback, not the user
+	stop      chan struct{}
+}
+
+// Longest pause kept when idle time is skipped
+const playerMaxIdle = 1.0
+
+// ShowRecordingPlayer opens a window replaying an asciicast v2 recording
+func ShowRecordingPlayer(name string, data []byte) error {
+	cast, err := network.ParseAsciicast(data)
+	if err != nil {
+		return err
+	}
+
+	p := &recordingPlayer{
+		cast:      cast,
+		term:      NewTerminalWidget(nil),
+		speed:     1,
+		skipIdle:  true,
+		timeLabel: widget.NewLabel(""),
+		stop:      make(chan struct{}),
+	}
+
+	title := cast.Title
+	if title == "" {
+		title = name
+	}
+	win := fyne.CurrentApp().NewWindow("Recording: " + title)
+
+	p.playBtn = widget.NewButtonWithIcon("Play", theme.MediaPlayIcon(), p.togglePlay)
+	restartBtn := widget.NewButtonWithIcon("", theme.MediaReplayIcon(), func() { p.seek(0) })
+	speedSelect := widget.NewSelect([]string{"0.5x", "1x", "2x", "4x", "8x"}, func(s string) {
+		var speed float64
+		fmt.Sscanf(s, "%gx", &speed)
+		p.mu.Lock()
+		p.speed = speed
+		p.mu.Unlock()
+	})
+	speedSelect.SetSelected("1x")
+	idleCheck := widget.NewCheck("Skip idle time", func(on bool) {
+		p.mu.Lock()
+		p.skipIdle = on
+		p.mu.Unlock()
+	})
+	idleCheck.SetChecked(true)
+
+	p.slider = widget.NewSlider(0, max(cast.Duration, 0.1))
+	p.slider.Step = 0.1
+	p.slider.OnChanged = func(pos float64) {
+		if !p.seeking {
+			p.seek(pos)
+		}
+	}
+
+	saveBtn := widget.NewButtonWithIcon("Save...", theme.DocumentSaveIcon(), func() {
+		dialog.ShowFileSave(func(w fyne.URIWriteCloser, err error) {
+			if err != nil || w == nil {
+				return
+			}
+			defer w.Close()
+			if _, err := w.Write(data); err != nil {
+				dialog.ShowError(err, win)
+			}
+		}, win)
+	})
+
+	info := widget.NewLabel(fmt.Sprintf("Recorded %s, %dx%d", cast.Started.Local().Format("2006-01-02 15:04:05"), cast.Width, cast.Height))
+	info.Importance = widget.LowImportance
+
+	controls := container.NewBorder(nil, nil,
+		container.NewHBox(p.playBtn, restartBtn, speedSelect, idleCheck),
+		container.NewHBox(p.timeLabel, saveBtn),
+		p.slider,
+	)
+
+	win.SetContent(container.NewBorder(info, controls, nil, nil, p.term))
+	cell := p.term.cellSize()
+	win.Resize(fyne.NewSize(cell.Width*float32(cast.Width)+2*theme.Padding(), cell.Height*float32(cast.Height)+120))
+	win.SetOnClosed(func() { close(p.stop) })
+	win.Show()
+
+	p.updateTime()
+	go p.run()
+	p.togglePlay()
+	return nil
+}
+
+// run advances playback until the window closes
+func (p *recordingPlayer) run() {
+	const tick = 30 * time.Millisecond
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.Lock()
+		if !p.playing {
+			p.mu.Unlock()
+			continue
+		}
+		p.now += tick.Seconds() * p.speed
+		if p.skipIdle && p.next < len(p.cast.Events) {
+			if gap := p.cast.Events[p.next].Time - p.now; gap > playerMaxIdle {
+				p.now = p.cast.Events[p.next].Time - playerMaxIdle
+			}
+		}
+		var out []byte
+		for p.next < len(p.cast.Events) && p.cast.Events[p.next].Time <= p.now {
+			if e := p.cast.Events[p.next]; e.Kind == "o" {
+				out = append(out, e.Data...)
+			}
+			p.next++
+		}
+		finished := p.next >= len(p.cast.Events)
+		if finished {
+			p.now = p.cast.Duration
+			p.playing = false
+		}
+		p.mu.Unlock()
+
+		if len(out) > 0 {
+			p.term.Write(out)
+		}
+		runOnMainThread(func() {
+			if finished {
+				p.playBtn.SetText("Play")
+				p.playBtn.SetIcon(theme.MediaPlayIcon())
+			}
+			p.updateTime()
+		})
+	}
+}
+
+// togglePlay pauses or resumes playback, restarting if the recording has ended
+func (p *recordingPlayer) togglePlay() {
+	p.mu.Lock()
+	restart := !p.playing && p.next >= len(p.cast.Events)
+	p.playing = !p.playing
+	playing := p.playing
+	p.mu.Unlock()
+
+	if restart {
+		p.seek(0)
+		p.mu.Lock()
+		p.playing = true
+		p.mu.Unlock()
+	}
+	if playing {
+		p.playBtn.SetText("Pause")
+		p.playBtn.SetIcon(theme.MediaPauseIcon())
+	} else {
+		p.playBtn.SetText("Play")
+		p.playBtn.SetIcon(theme.MediaPlayIcon())
+	}
+}
+
+// seek redraws the terminal as it was at pos seconds into the recording
+func (p *recordingPlayer) seek(pos float64) {
+	p.mu.Lock()
+	p.now = pos
+	p.next = 0
+	out := []byte("\x1bc")
+	for p.next < len(p.cast.Events) && p.cast.Events[p.next].Time <= pos {
+		if e := p.cast.Events[p.next]; e.Kind == "o" {
+			out = append(out, e.Data...)
+		}
+		p.next++
+	}
+	p.mu.Unlock()
+
+	p.term.Screen().ClearScrollback()
+	p.term.Write(out)
+	p.updateTime()
+}
+
+// updateTime shows the playback position; must run on the main thread
+func (p *recordingPlayer) updateTime() {
+	p.mu.Lock()
+	now := p.now
+	p.mu.Unlock()
+	p.timeLabel.SetText(fmt.Sprintf("%s / %s", formatPlayTime(now), formatPlayTime(p.cast.Duration)))
+	p.seeking = true
+	p.slider.SetValue(now)
+	p.seeking = false
+}
+
+// formatPlayTime formats seconds as m:ss
+func formatPlayTime(seconds float64) string {
+	s := int(seconds)
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
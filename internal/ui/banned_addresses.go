@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"adminadmin/internal/network"
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ShowBannedAddressesDialog lists the addresses the SSH server refuses after failed
+// logins. list returns the current bans; unban lifts one, or all for an empty ip.
+func ShowBannedAddressesDialog(list func() []network.BannedAddress, unban func(ip string), window fyne.Window) {
+	listBox := container.NewVBox()
+
+	var refresh func()
+	refresh = func() {
+		listBox.RemoveAll()
+		bans := list()
+		if len(bans) == 0 {
+			listBox.Add(widget.NewLabel("No banned addresses"))
+		}
+		for _, b := range bans {
+			ip := b.IP
+			label := widget.NewLabelWithStyle(ip, fyne.TextAlignLeading, fyne.TextStyle{Bold: true, Monospace: true})
+			detail := widget.NewLabel(fmt.Sprintf("until %s (%s left), ban #%d",
+				b.Until.Format("15:04:05"), time.Until(b.Until).Round(time.Second), b.Bans))
+			unbanBtn := widget.NewButton("Unban", func() {
+				unban(ip)
+				refresh()
+			})
+			listBox.Add(container.NewBorder(nil, nil, label, unbanBtn, detail))
+		}
+	}
+	refresh()
+
+	help := widget.NewLabel("An address is banned after 5 failed SSH passwords within 10 minutes. " +
+		"Bans last 15 minutes at first and double each time the address is banned again, up to a day.")
+	help.Wrapping = fyne.TextWrapWord
+
+	unbanAllBtn := widget.NewButton("Unban All", func() {
+		unban("")
+		refresh()
+	})
+	refreshBtn := widget.NewButton("Refresh", refresh)
+
+	content := container.NewBorder(
+		container.NewVBox(help, widget.NewSeparator()),
+		container.NewHBox(refreshBtn, unbanAllBtn),
+		nil, nil,
+		container.NewVScroll(listBox),
+	)
+	d := dialog.NewCustom("Banned Addresses", "Close", content, window)
+	d.Resize(fyne.NewSize(560, 380))
+	d.Show()
+}
@@ -0,0 +1,306 @@
+package ui
+
+import (
+	"adminadmin/internal/network"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// BroadcastWorker is a worker that can be selected in the broadcast window
+type BroadcastWorker struct {
+	ID   string
+	Name string
+	Tags []string
+}
+
+// BroadcastRequest is what the user asked to run
+type BroadcastRequest struct {
+	WorkerIDs   []string
+	Command     string
+	User        string
+	Password    string
+	Concurrency int
+	Timeout     time.Duration
+}
+
+// BroadcastWindow runs one command on a selection of workers and shows per-worker
+// and grouped-by-output results
+type BroadcastWindow struct {
+	window  fyne.Window
+	onRun   func(BroadcastRequest)
+	onClose func()
+
+	workers []BroadcastWorker
+	checks  map[string]*widget.Check
+
+	commandEntry     *widget.Entry
+	userEntry        *widget.Entry
+	passwordEntry    *widget.Entry
+	concurrencyEntry *widget.Entry
+	timeoutEntry     *widget.Entry
+	runButton        *widget.Button
+
+	progressLabel *widget.Label
+	perWorker     *widget.Accordion
+	grouped       *widget.Accordion
+
+	total, done, failed int
+}
+
+// NewBroadcastWindow creates the broadcast window. Workers carrying preselectTag
+// are checked initially (all workers if it is empty).
+func NewBroadcastWindow(app fyne.App, workers []BroadcastWorker, preselectTag string, onRun func(BroadcastRequest), onClose func()) *BroadcastWindow {
+	w := &BroadcastWindow{
+		onRun:   onRun,
+		onClose: onClose,
+		workers: workers,
+		checks:  make(map[string]*widget.Check),
+	}
+
+	w.window = app.NewWindow("admin:admin - Run on Selection")
+	w.window.Resize(fyne.NewSize(900, 600))
+	w.window.SetOnClosed(func() {
+		if w.onClose != nil {
+			w.onClose()
+		}
+	})
+
+	w.window.SetContent(container.NewBorder(
+		w.buildCommandBar(), nil,
+		w.buildSelectionPanel(preselectTag), nil,
+		w.buildResultsPanel(),
+	))
+	return w
+}
+
+// Show shows the broadcast window
+func (w *BroadcastWindow) Show() {
+	w.window.Show()
+	w.window.Canvas().Focus(w.commandEntry)
+}
+
+// Close closes the broadcast window
+func (w *BroadcastWindow) Close() {
+	w.window.Close()
+}
+
+// buildSelectionPanel creates the worker checklist with tag quick-select
+func (w *BroadcastWindow) buildSelectionPanel(preselectTag string) fyne.CanvasObject {
+	list := container.NewVBox()
+	tagSet := make(map[string]bool)
+	for _, worker := range w.workers {
+		check := widget.NewCheck(fmt.Sprintf("%s (%s)", worker.Name, worker.ID), nil)
+		check.SetChecked(preselectTag == "" || containsString(worker.Tags, preselectTag))
+		w.checks[worker.ID] = check
+		list.Add(check)
+		for _, t := range worker.Tags {
+			tagSet[t] = true
+		}
+	}
+
+	tags := make([]string, 0, len(tagSet))
+	for t := range tagSet {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+
+	tagSelect := widget.NewSelect(tags, func(tag string) {
+		for _, worker := range w.workers {
+			w.checks[worker.ID].SetChecked(containsString(worker.Tags, tag))
+		}
+	})
+	tagSelect.PlaceHolder = "Select by tag"
+	if preselectTag != "" {
+		tagSelect.Selected = preselectTag
+	}
+
+	allBtn := widget.NewButton("All", func() { w.setAllChecked(true) })
+	noneBtn := widget.NewButton("None", func() { w.setAllChecked(false) })
+
+	header := container.NewVBox(
+		widget.NewLabelWithStyle("Workers", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		tagSelect,
+		container.NewGridWithColumns(2, allBtn, noneBtn),
+		widget.NewSeparator(),
+	)
+	panel := container.NewBorder(header, nil, nil, nil, container.NewVScroll(list))
+	return container.NewGridWrap(fyne.NewSize(260, 500), panel)
+}
+
+// buildCommandBar creates the command entry, options and Run button
+func (w *BroadcastWindow) buildCommandBar() fyne.CanvasObject {
+	w.commandEntry = widget.NewEntry()
+	w.commandEntry.SetPlaceHolder("Command to run on every selected worker")
+	w.commandEntry.OnSubmitted = func(string) { w.run() }
+
+	w.userEntry = widget.NewEntry()
+	w.userEntry.SetText(network.DefaultSSHUsername)
+	w.passwordEntry = widget.NewPasswordEntry()
+	w.passwordEntry.SetPlaceHolder("Empty = SSH key")
+	w.concurrencyEntry = widget.NewEntry()
+	w.concurrencyEntry.SetText(strconv.Itoa(network.DefaultBroadcastConcurrency))
+	w.timeoutEntry = widget.NewEntry()
+	w.timeoutEntry.SetText(strconv.Itoa(int(network.DefaultBroadcastTimeout.Seconds())))
+
+	w.runButton = widget.NewButton("Run", w.run)
+	w.runButton.Importance = widget.HighImportance
+
+	options := container.NewGridWithColumns(8,
+		widget.NewLabel("User"), w.userEntry,
+		widget.NewLabel("Password"), w.passwordEntry,
+		widget.NewLabel("Parallel"), w.concurrencyEntry,
+		widget.NewLabel("Timeout (s)"), w.timeoutEntry,
+	)
+
+	return container.NewVBox(
+		container.NewBorder(nil, nil, widget.NewLabel("$"), w.runButton, w.commandEntry),
+		options,
+		widget.NewSeparator(),
+	)
+}
+
+// buildResultsPanel creates the per-worker and grouped result tabs
+func (w *BroadcastWindow) buildResultsPanel() fyne.CanvasObject {
+	w.progressLabel = widget.NewLabel("Select workers, enter a command and press Run")
+	w.perWorker = widget.NewAccordion()
+	w.grouped = widget.NewAccordion()
+
+	tabs := container.NewAppTabs(
+		container.NewTabItem("Per Worker", container.NewVScroll(w.perWorker)),
+		container.NewTabItem("Grouped by Output", container.NewVScroll(w.grouped)),
+	)
+	return container.NewBorder(w.progressLabel, nil, nil, nil, tabs)
+}
+
+func (w *BroadcastWindow) setAllChecked(on bool) {
+	for _, check := range w.checks {
+		check.SetChecked(on)
+	}
+}
+
+// run validates the form and hands the request to the caller
+func (w *BroadcastWindow) run() {
+	command := strings.TrimSpace(w.commandEntry.Text)
+	if command == "" {
+		return
+	}
+
+	var ids []string
+	for _, worker := range w.workers {
+		if w.checks[worker.ID].Checked {
+			ids = append(ids, worker.ID)
+		}
+	}
+	if len(ids) == 0 {
+		dialog.ShowInformation("No Workers Selected", "Select at least one worker", w.window)
+		return
+	}
+
+	concurrency, err := strconv.Atoi(strings.TrimSpace(w.concurrencyEntry.Text))
+	if err != nil || concurrency < 1 {
+		dialog.ShowError(fmt.Errorf("invalid parallelism: %q", w.concurrencyEntry.Text), w.window)
+		return
+	}
+	timeoutSecs, err := strconv.Atoi(strings.TrimSpace(w.timeoutEntry.Text))
+	if err != nil || timeoutSecs < 1 {
+		dialog.ShowError(fmt.Errorf("invalid timeout: %q", w.timeoutEntry.Text), w.window)
+		return
+	}
+
+	w.total, w.done, w.failed = len(ids), 0, 0
+	w.perWorker.Items = nil
+	w.perWorker.Refresh()
+	w.grouped.Items = nil
+	w.grouped.Refresh()
+	w.runButton.Disable()
+	w.updateProgress(command)
+
+	if w.onRun != nil {
+		w.onRun(BroadcastRequest{
+			WorkerIDs:   ids,
+			Command:     command,
+			User:        w.userEntry.Text,
+			Password:    w.passwordEntry.Text,
+			Concurrency: concurrency,
+			Timeout:     time.Duration(timeoutSecs) * time.Second,
+		})
+	}
+}
+
+func (w *BroadcastWindow) updateProgress(command string) {
+	text := fmt.Sprintf("%d / %d done, %d failed", w.done, w.total, w.failed)
+	if command != "" {
+		text = fmt.Sprintf("Running %q: %s", command, text)
+	}
+	w.progressLabel.SetText(text)
+}
+
+// AddResult adds one worker's result as it arrives; safe to call from any goroutine
+func (w *BroadcastWindow) AddResult(result network.BroadcastResult) {
+	runOnMainThread(func() {
+		w.done++
+		if !result.OK() {
+			w.failed++
+		}
+		w.updateProgress("")
+
+		mark := "✓"
+		if !result.OK() {
+			mark = "✗"
+		}
+		title := fmt.Sprintf("%s  %s (%s) — %s — %s", mark, result.Target.Name, result.Target.ID,
+			result.Status(), result.Duration.Round(time.Millisecond))
+		w.perWorker.Append(widget.NewAccordionItem(title, newOutputLabel(result.Output)))
+	})
+}
+
+// Finish shows the grouped view once all workers are done; safe to call from any goroutine
+func (w *BroadcastWindow) Finish(results []network.BroadcastResult) {
+	runOnMainThread(func() {
+		for _, group := range network.GroupByOutput(results) {
+			names := make([]string, len(group.Targets))
+			for i, t := range group.Targets {
+				names[i] = t.Name
+			}
+			title := fmt.Sprintf("%d worker(s) — %s: %s", len(group.Targets), group.Status, strings.Join(names, ", "))
+			w.grouped.Append(widget.NewAccordionItem(title, newOutputLabel(group.Output)))
+		}
+		if len(w.grouped.Items) > 0 {
+			w.grouped.Open(0)
+		}
+		w.runButton.Enable()
+		w.updateProgress("")
+	})
+}
+
+// newOutputLabel creates a selectable monospace view of command output
+func newOutputLabel(output string) fyne.CanvasObject {
+	output = strings.TrimRight(output, "\r\n")
+	if output == "" {
+		output = "(no output)"
+	}
+	label := widget.NewLabel(output)
+	label.TextStyle = fyne.TextStyle{Monospace: true}
+	label.Wrapping = fyne.TextWrapBreak
+	label.Selectable = true
+	return label
+}
+
+// runOnMainThread runs fn on the UI thread (directly if no driver is available)
+func runOnMainThread(fn func()) {
+	if app := fyne.CurrentApp(); app != nil {
+		if drv := app.Driver(); drv != nil {
+			drv.DoFromGoroutine(fn, false)
+			return
+		}
+	}
+	fn()
+}
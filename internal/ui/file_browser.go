@@ -0,0 +1,378 @@
+package ui
+
+import (
+	"adminadmin/internal/network"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// FileBrowser is a file manager for one worker: it browses directories, uploads and
+// downloads files and folders with progress and cancellation, and renames, deletes
+// and creates entries
+type FileBrowser struct {
+	files  *network.RemoteFiles
+	window fyne.Window
+
+	mu       sync.Mutex
+	dir      string
+	entries  []network.RemoteFile
+	selected int // Index into entries, -1 if nothing is selected
+
+	pathEntry    *widget.Entry
+	list         *widget.List
+	statusLabel  *widget.Label
+	transfersBox *fyne.Container
+}
+
+// NewFileBrowser creates a file browser starting in the SFTP session's home directory
+func NewFileBrowser(files *network.RemoteFiles, window fyne.Window) *FileBrowser {
+	b := &FileBrowser{
+		files:        files,
+		window:       window,
+		selected:     -1,
+		pathEntry:    widget.NewEntry(),
+		statusLabel:  widget.NewLabel(""),
+		transfersBox: container.NewVBox(),
+	}
+	b.pathEntry.OnSubmitted = func(p string) { b.Open(p) }
+
+	b.list = widget.NewList(
+		func() int {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			return len(b.entries)
+		},
+		func() fyne.CanvasObject {
+			icon := widget.NewIcon(theme.FileIcon())
+			name := widget.NewLabel("")
+			name.Truncation = fyne.TextTruncateEllipsis
+			details := widget.NewLabel("")
+			openBtn := widget.NewButtonWithIcon("", theme.NavigateNextIcon(), nil)
+			openBtn.Importance = widget.LowImportance
+			return container.NewBorder(nil, nil, icon, container.NewHBox(details, openBtn), name)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			b.mu.Lock()
+			if id >= len(b.entries) {
+				b.mu.Unlock()
+				return
+			}
+			entry := b.entries[id]
+			b.mu.Unlock()
+
+			row := obj.(*fyne.Container)
+			name := row.Objects[0].(*widget.Label)
+			icon := row.Objects[1].(*widget.Icon)
+			right := row.Objects[2].(*fyne.Container)
+			details := right.Objects[0].(*widget.Label)
+			openBtn := right.Objects[1].(*widget.Button)
+
+			name.SetText(entry.Name)
+			if entry.IsDir {
+				icon.SetResource(theme.FolderIcon())
+				details.SetText(entry.ModTime.Format("2006-01-02 15:04"))
+				openBtn.OnTapped = func() { b.Open(entry.Path) }
+				openBtn.Show()
+			} else {
+				icon.SetResource(theme.FileIcon())
+				details.SetText(fmt.Sprintf("%s   %s", formatBytes(entry.Size), entry.ModTime.Format("2006-01-02 15:04")))
+				openBtn.Hide()
+			}
+		},
+	)
+	b.list.OnSelected = func(id widget.ListItemID) {
+		b.mu.Lock()
+		b.selected = id
+		b.mu.Unlock()
+	}
+	b.list.OnUnselected = func(widget.ListItemID) {
+		b.mu.Lock()
+		b.selected = -1
+		b.mu.Unlock()
+	}
+
+	b.Open(files.Home())
+	return b
+}
+
+// Content returns the browser UI
+func (b *FileBrowser) Content() fyne.CanvasObject {
+	upBtn := widget.NewButtonWithIcon("", theme.MoveUpIcon(), func() {
+		b.mu.Lock()
+		dir := b.dir
+		b.mu.Unlock()
+		b.Open(path.Dir(dir))
+	})
+	refreshBtn := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() { b.Refresh() })
+	pathBar := container.NewBorder(nil, nil, container.NewHBox(upBtn, refreshBtn), nil, b.pathEntry)
+
+	toolbar := container.NewHBox(
+		widget.NewButtonWithIcon("Upload File", theme.UploadIcon(), b.uploadFile),
+		widget.NewButtonWithIcon("Upload Folder", theme.FolderOpenIcon(), b.uploadFolder),
+		widget.NewButtonWithIcon("Download", theme.DownloadIcon(), b.download),
+		widget.NewSeparator(),
+		widget.NewButtonWithIcon("New Folder", theme.FolderNewIcon(), b.newFolder),
+		widget.NewButtonWithIcon("Rename", theme.DocumentCreateIcon(), b.rename),
+		widget.NewButtonWithIcon("Delete", theme.DeleteIcon(), b.delete),
+	)
+
+	hint := widget.NewLabel("Drop files or folders on this window to upload them here")
+	hint.Importance = widget.LowImportance
+
+	transfers := container.NewVScroll(b.transfersBox)
+	transfers.SetMinSize(fyne.NewSize(0, 90))
+
+	return container.NewBorder(
+		container.NewVBox(pathBar, toolbar, widget.NewSeparator()),
+		container.NewVBox(widget.NewSeparator(), b.statusLabel, hint,
+			widget.NewLabelWithStyle("Transfers", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}), transfers),
+		nil, nil,
+		b.list,
+	)
+}
+
+// Open switches to a directory and lists it
+func (b *FileBrowser) Open(dir string) {
+	dir = path.Clean("/" + dir)
+	go func() {
+		entries, err := b.files.List(dir)
+		runOnMainThread(func() {
+			if err != nil {
+				b.statusLabel.SetText(fmt.Sprintf("Cannot open %s: %v", dir, err))
+				b.mu.Lock()
+				current := b.dir
+				b.mu.Unlock()
+				b.pathEntry.SetText(current)
+				return
+			}
+			b.mu.Lock()
+			b.dir = dir
+			b.entries = entries
+			b.selected = -1
+			b.mu.Unlock()
+
+			b.pathEntry.SetText(dir)
+			b.list.UnselectAll()
+			b.list.ScrollToTop()
+			b.list.Refresh()
+			b.statusLabel.SetText(fmt.Sprintf("%d items", len(entries)))
+		})
+	}()
+}
+
+// Refresh lists the current directory again
+func (b *FileBrowser) Refresh() {
+	b.mu.Lock()
+	dir := b.dir
+	b.mu.Unlock()
+	b.Open(dir)
+}
+
+// selection returns the selected entry
+func (b *FileBrowser) selection() (network.RemoteFile, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.selected < 0 || b.selected >= len(b.entries) {
+		return network.RemoteFile{}, false
+	}
+	return b.entries[b.selected], true
+}
+
+// currentDir returns the directory being shown
+func (b *FileBrowser) currentDir() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dir
+}
+
+// runAction runs a remote change in the background, then refreshes the listing
+func (b *FileBrowser) runAction(what string, action func() error) {
+	b.statusLabel.SetText(what + "...")
+	go func() {
+		err := action()
+		runOnMainThread(func() {
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("%s failed: %w", what, err), b.window)
+				b.statusLabel.SetText("")
+				return
+			}
+			b.Refresh()
+		})
+	}()
+}
+
+func (b *FileBrowser) newFolder() {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Folder name")
+	dialog.ShowForm("New Folder", "Create", "Cancel",
+		[]*widget.FormItem{widget.NewFormItem("Name", nameEntry)},
+		func(ok bool) {
+			name := strings.TrimSpace(nameEntry.Text)
+			if !ok || name == "" {
+				return
+			}
+			target := path.Join(b.currentDir(), name)
+			b.runAction("Creating "+name, func() error { return b.files.Mkdir(target) })
+		}, b.window)
+}
+
+func (b *FileBrowser) rename() {
+	entry, ok := b.selection()
+	if !ok {
+		dialog.ShowInformation("Rename", "Select a file or folder first.", b.window)
+		return
+	}
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(entry.Name)
+	dialog.ShowForm("Rename", "Rename", "Cancel",
+		[]*widget.FormItem{widget.NewFormItem("New name", nameEntry)},
+		func(ok bool) {
+			name := strings.TrimSpace(nameEntry.Text)
+			if !ok || name == "" || name == entry.Name {
+				return
+			}
+			target := path.Join(path.Dir(entry.Path), name)
+			b.runAction("Renaming "+entry.Name, func() error { return b.files.Rename(entry.Path, target) })
+		}, b.window)
+}
+
+func (b *FileBrowser) delete() {
+	entry, ok := b.selection()
+	if !ok {
+		dialog.ShowInformation("Delete", "Select a file or folder first.", b.window)
+		return
+	}
+	message := fmt.Sprintf("Delete %s?", entry.Path)
+	if entry.IsDir {
+		message = fmt.Sprintf("Delete the folder %s and everything in it?", entry.Path)
+	}
+	dialog.ShowConfirm("Delete", message, func(ok bool) {
+		if ok {
+			b.runAction("Deleting "+entry.Name, func() error { return b.files.Remove(entry.Path) })
+		}
+	}, b.window)
+}
+
+func (b *FileBrowser) uploadFile() {
+	dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil || reader == nil {
+			return
+		}
+		reader.Close()
+		b.Upload(reader.URI().Path())
+	}, b.window)
+}
+
+func (b *FileBrowser) uploadFolder() {
+	dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+		if err != nil || dir == nil {
+			return
+		}
+		b.Upload(dir.Path())
+	}, b.window)
+}
+
+func (b *FileBrowser) download() {
+	entry, ok := b.selection()
+	if !ok {
+		dialog.ShowInformation("Download", "Select a file or folder first.", b.window)
+		return
+	}
+	dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+		if err != nil || dir == nil {
+			return
+		}
+		localDir := dir.Path()
+		b.startTransfer("Download "+entry.Name, func(ctx context.Context, progress func(network.TransferProgress)) error {
+			return b.files.Download(ctx, entry.Path, localDir, progress)
+		}, false)
+	}, b.window)
+}
+
+// Upload copies a local file or folder into the current directory
+func (b *FileBrowser) Upload(localPath string) {
+	dir := b.currentDir()
+	name := localPath[strings.LastIndexAny(localPath, `/\`)+1:]
+	b.startTransfer("Upload "+name, func(ctx context.Context, progress func(network.TransferProgress)) error {
+		return b.files.Upload(ctx, localPath, dir, progress)
+	}, true)
+}
+
+// UploadURIs uploads dropped files and folders into the current directory
+func (b *FileBrowser) UploadURIs(uris []fyne.URI) {
+	for _, u := range uris {
+		if u.Scheme() == "file" {
+			b.Upload(u.Path())
+		}
+	}
+}
+
+// startTransfer runs a transfer in the background with a progress row and a Cancel button
+func (b *FileBrowser) startTransfer(title string, run func(context.Context, func(network.TransferProgress)) error, refresh bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	label := widget.NewLabel(title + ": starting...")
+	label.Truncation = fyne.TextTruncateEllipsis
+	bar := widget.NewProgressBar()
+	var row *fyne.Container
+	cancelBtn := widget.NewButtonWithIcon("", theme.CancelIcon(), cancel)
+	removeBtn := widget.NewButtonWithIcon("", theme.ContentClearIcon(), func() {
+		b.transfersBox.Remove(row)
+	})
+	removeBtn.Hide()
+	row = container.NewBorder(nil, nil, nil, container.NewHBox(cancelBtn, removeBtn), container.NewVBox(label, bar))
+	b.transfersBox.Add(row)
+
+	go func() {
+		err := run(ctx, func(p network.TransferProgress) {
+			runOnMainThread(func() {
+				if p.Total > 0 {
+					bar.SetValue(float64(p.Done) / float64(p.Total))
+				}
+				label.SetText(fmt.Sprintf("%s: %s (%d/%d files, %s of %s)", title, p.File,
+					p.FilesDone, p.FilesTotal, formatBytes(p.Done), formatBytes(p.Total)))
+			})
+		})
+		cancel()
+		runOnMainThread(func() {
+			switch {
+			case errors.Is(err, context.Canceled):
+				label.SetText(title + ": cancelled")
+			case err != nil:
+				label.SetText(fmt.Sprintf("%s: failed: %v", title, err))
+			default:
+				bar.SetValue(1)
+				label.SetText(title + ": done")
+			}
+			cancelBtn.Hide()
+			removeBtn.Show()
+			if refresh {
+				b.Refresh()
+			}
+		})
+	}()
+}
+
+// formatBytes formats a size for display
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,288 @@
+package ui
+
+import (
+	"adminadmin/internal/state"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Sort orders offered by the fleet overview
+const (
+	SortByHostname = "Hostname"
+	SortByLoad     = "Load (highest first)"
+	SortByCPU      = "CPU (highest first)"
+	SortByRAM      = "RAM (highest first)"
+	SortByGPU      = "GPU (highest first)"
+)
+
+// filterAll is the filter option that shows every worker
+const filterAll = "All workers"
+
+// staleAfter is how long without metrics before a worker tile shows as stale
+const staleAfter = 5 * time.Second
+
+// fleetTileSize is the fixed size of each worker tile in the grid
+var fleetTileSize = fyne.NewSize(240, 200)
+
+// fleetTile is one worker's tile; its widgets are updated in place
+type fleetTile struct {
+	root        fyne.CanvasObject
+	hostLabel   *widget.Label
+	addrLabel   *widget.Label
+	statusLabel *widget.Label
+	cpu         *CompactGauge
+	ram         *CompactGauge
+	gpu         *CompactGauge
+}
+
+// FleetOverview shows all workers as a grid of compact tiles.
+// Tiles are created once per worker and updated in place; only the grid order changes.
+type FleetOverview struct {
+	onSelect func(string)
+
+	tiles   map[string]*fleetTile
+	devices []*state.DeviceInfo
+
+	grid         *fyne.Container
+	sortSelect   *widget.Select
+	filterSelect *widget.Select
+	countLabel   *widget.Label
+	content      fyne.CanvasObject
+}
+
+// NewFleetOverview creates an empty overview; onSelect is called with a worker ID on click-through
+func NewFleetOverview(onSelect func(string)) *FleetOverview {
+	o := &FleetOverview{
+		onSelect:   onSelect,
+		tiles:      make(map[string]*fleetTile),
+		grid:       container.NewGridWrap(fleetTileSize),
+		countLabel: widget.NewLabel(""),
+	}
+
+	o.sortSelect = widget.NewSelect([]string{SortByHostname, SortByLoad, SortByCPU, SortByRAM, SortByGPU}, func(string) {
+		o.layoutTiles()
+	})
+	o.sortSelect.SetSelected(SortByHostname)
+
+	o.filterSelect = widget.NewSelect([]string{filterAll}, func(string) {
+		o.layoutTiles()
+	})
+	o.filterSelect.SetSelected(filterAll)
+
+	toolbar := container.NewHBox(
+		widget.NewLabel("Sort:"), o.sortSelect,
+		widget.NewLabel("Show:"), o.filterSelect,
+		o.countLabel,
+	)
+	o.content = container.NewBorder(toolbar, nil, nil, nil, container.NewVScroll(o.grid))
+	return o
+}
+
+// Content returns the overview UI
+func (o *FleetOverview) Content() fyne.CanvasObject {
+	return o.content
+}
+
+// Update refreshes every tile from the given devices, adding and removing tiles as
+// workers come and go. Must be called on the main UI thread.
+func (o *FleetOverview) Update(devices []*state.DeviceInfo) {
+	o.devices = devices
+
+	seen := make(map[string]bool, len(devices))
+	for _, d := range devices {
+		seen[d.ID] = true
+		tile, ok := o.tiles[d.ID]
+		if !ok {
+			tile = o.newTile(d.ID)
+			o.tiles[d.ID] = tile
+		}
+		tile.update(d)
+	}
+	for id := range o.tiles {
+		if !seen[id] {
+			delete(o.tiles, id)
+		}
+	}
+
+	o.updateFilterOptions()
+	o.layoutTiles()
+}
+
+// newTile creates the widgets for one worker
+func (o *FleetOverview) newTile(id string) *fleetTile {
+	t := &fleetTile{
+		hostLabel:   widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		addrLabel:   widget.NewLabel(""),
+		statusLabel: widget.NewLabel(""),
+		cpu:         NewCompactGauge("CPU", "%"),
+		ram:         NewCompactGauge("RAM", "%"),
+		gpu:         NewCompactGauge("GPU", "%"),
+	}
+	t.hostLabel.Truncation = fyne.TextTruncateEllipsis
+	t.addrLabel.Truncation = fyne.TextTruncateEllipsis
+
+	detailsBtn := widget.NewButton("Details", func() {
+		if o.onSelect != nil {
+			o.onSelect(id)
+		}
+	})
+
+	t.root = widget.NewCard("", "", container.NewVBox(
+		container.NewBorder(nil, nil, nil, t.statusLabel, t.hostLabel),
+		t.addrLabel,
+		t.cpu,
+		t.ram,
+		t.gpu,
+		detailsBtn,
+	))
+	return t
+}
+
+// update copies a device's current values into the tile widgets
+func (t *fleetTile) update(d *state.DeviceInfo) {
+	t.hostLabel.SetText(d.Hostname)
+	t.addrLabel.SetText(fmt.Sprintf("%s · %s", d.IPAddress, d.OS))
+	t.statusLabel.SetText(workerStatus(d, time.Now()))
+	t.cpu.SetValue(d.CPUUsage)
+	t.ram.SetValue(d.RAMUsage)
+	t.gpu.SetValue(d.GPUUsage)
+}
+
+// workerStatus returns a short status for a worker based on when it last reported
+func workerStatus(d *state.DeviceInfo, now time.Time) string {
+	if d.LastSeen.IsZero() {
+		return "●  new"
+	}
+	if age := now.Sub(d.LastSeen); age > staleAfter {
+		return fmt.Sprintf("○  %s ago", age.Round(time.Second))
+	}
+	return "●  online"
+}
+
+// updateFilterOptions rebuilds the filter choices from the tags and OSes currently present
+func (o *FleetOverview) updateFilterOptions() {
+	options := []string{filterAll}
+	seen := make(map[string]bool)
+	var osNames, tags []string
+	for _, d := range o.devices {
+		if d.OS != "" && !seen[d.OS] {
+			seen[d.OS] = true
+			osNames = append(osNames, d.OS)
+		}
+		tags = append(tags, d.AllTags()...)
+	}
+	for _, tag := range state.NormalizeTags(tags) {
+		options = append(options, "Tag: "+tag)
+	}
+	sort.Strings(osNames)
+	for _, name := range osNames {
+		options = append(options, "OS: "+name)
+	}
+
+	if strings.Join(options, "\x00") == strings.Join(o.filterSelect.Options, "\x00") {
+		return
+	}
+	selected := o.filterSelect.Selected
+	o.filterSelect.SetOptions(options)
+	if !containsString(options, selected) {
+		o.filterSelect.SetSelected(filterAll)
+	}
+}
+
+// matchesFilter reports whether a device passes the selected filter
+func (o *FleetOverview) matchesFilter(d *state.DeviceInfo) bool {
+	filter := o.filterSelect.Selected
+	if filter == "" || filter == filterAll {
+		return true
+	}
+	if tag, ok := strings.CutPrefix(filter, "Tag: "); ok {
+		return d.HasTag(tag)
+	}
+	if osName, ok := strings.CutPrefix(filter, "OS: "); ok {
+		return d.OS == osName
+	}
+	return true
+}
+
+// layoutTiles places the visible tiles in the grid in the selected sort order.
+// Tiles are reused, so this only touches the grid when the order actually changes.
+func (o *FleetOverview) layoutTiles() {
+	if o.grid == nil || o.sortSelect == nil || o.filterSelect == nil {
+		return // Called from a select callback during construction
+	}
+
+	visible := make([]*state.DeviceInfo, 0, len(o.devices))
+	for _, d := range o.devices {
+		if _, ok := o.tiles[d.ID]; ok && o.matchesFilter(d) {
+			visible = append(visible, d)
+		}
+	}
+	sortDevices(visible, o.sortSelect.Selected)
+
+	objects := make([]fyne.CanvasObject, len(visible))
+	for i, d := range visible {
+		objects[i] = o.tiles[d.ID].root
+	}
+	o.countLabel.SetText(fmt.Sprintf("%d of %d workers", len(visible), len(o.devices)))
+
+	if sameObjects(o.grid.Objects, objects) {
+		return
+	}
+	o.grid.Objects = objects
+	o.grid.Refresh()
+}
+
+// sortDevices orders devices in place by the given sort option
+func sortDevices(devices []*state.DeviceInfo, by string) {
+	key := func(d *state.DeviceInfo) float64 {
+		switch by {
+		case SortByCPU:
+			return d.CPUUsage
+		case SortByRAM:
+			return d.RAMUsage
+		case SortByGPU:
+			return d.GPUUsage
+		default:
+			return math.Max(d.CPUUsage, math.Max(d.RAMUsage, d.GPUUsage))
+		}
+	}
+	sort.SliceStable(devices, func(i, j int) bool {
+		if by != SortByHostname {
+			if ki, kj := key(devices[i]), key(devices[j]); ki != kj {
+				return ki > kj
+			}
+		}
+		if devices[i].Hostname != devices[j].Hostname {
+			return devices[i].Hostname < devices[j].Hostname
+		}
+		return devices[i].ID < devices[j].ID
+	})
+}
+
+func sameObjects(a, b []fyne.CanvasObject) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
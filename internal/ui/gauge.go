@@ -445,48 +445,137 @@ func CreateSpeedometerGauge(label string, value float64, unit string) fyne.Canva
 
 // CreateCompactGauge creates a compact horizontal progress bar gauge
 func CreateCompactGauge(label string, value float64, unit string) fyne.CanvasObject {
-	percentage := value
-	if percentage > 100 {
-		percentage = 100
-	}
+	gauge := NewCompactGauge(label, unit)
+	gauge.SetValue(value)
+	return gauge
+}
 
-	// Determine color based on value
-	var barColor color.Color
+// compactGaugeColor returns the bar color for a percentage (green/yellow/red)
+func compactGaugeColor(percentage float64) color.Color {
 	if percentage < 50 {
-		barColor = color.NRGBA{R: 76, G: 175, B: 80, A: 255} // Green
+		return color.NRGBA{R: 76, G: 175, B: 80, A: 255} // Green
 	} else if percentage < 80 {
-		barColor = color.NRGBA{R: 255, G: 193, B: 7, A: 255} // Yellow
-	} else {
-		barColor = color.NRGBA{R: 244, G: 67, B: 54, A: 255} // Red
+		return color.NRGBA{R: 255, G: 193, B: 7, A: 255} // Yellow
+	}
+	return color.NRGBA{R: 244, G: 67, B: 54, A: 255} // Red
+}
+
+// CompactGauge is a horizontal progress bar gauge whose value can be updated in place
+type CompactGauge struct {
+	widget.BaseWidget
+
+	mu    sync.RWMutex
+	label string
+	unit  string
+	value float64
+}
+
+// NewCompactGauge creates a compact gauge with a zero value
+func NewCompactGauge(label, unit string) *CompactGauge {
+	g := &CompactGauge{label: label, unit: unit}
+	g.ExtendBaseWidget(g)
+	return g
+}
+
+// SetValue updates the displayed value; safe to call from any goroutine
+func (g *CompactGauge) SetValue(value float64) {
+	g.mu.Lock()
+	changed := g.value != value
+	g.value = value
+	g.mu.Unlock()
+
+	if changed {
+		if app := fyne.CurrentApp(); app != nil {
+			if drv := app.Driver(); drv != nil {
+				drv.DoFromGoroutine(g.Refresh, false)
+			}
+		}
 	}
+}
+
+// GetValue returns the current value
+func (g *CompactGauge) GetValue() float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.value
+}
 
+// CreateRenderer implements fyne.Widget
+func (g *CompactGauge) CreateRenderer() fyne.WidgetRenderer {
 	// Background bar
 	bgBar := canvas.NewRectangle(color.NRGBA{R: 50, G: 40, B: 60, A: 255})
-	bgBar.Resize(fyne.NewSize(200, 20))
 	bgBar.CornerRadius = 10
 
 	// Value bar
-	valueBar := canvas.NewRectangle(barColor)
-	valueBar.Resize(fyne.NewSize(float32(percentage*2), 20))
+	valueBar := canvas.NewRectangle(color.Transparent)
 	valueBar.CornerRadius = 10
 
-	barStack := container.NewStack(bgBar, container.NewWithoutLayout(valueBar))
+	// Label and value text
+	titleLabel := widget.NewLabel(g.label)
+	valueText := widget.NewLabelWithStyle("", fyne.TextAlignTrailing, fyne.TextStyle{Bold: true})
+
+	r := &compactGaugeRenderer{
+		gauge:      g,
+		bgBar:      bgBar,
+		valueBar:   valueBar,
+		titleLabel: titleLabel,
+		valueText:  valueText,
+		topRow:     container.NewBorder(nil, nil, titleLabel, valueText),
+	}
+	r.Refresh()
+	return r
+}
+
+// compactGaugeRenderer lays out the label row above the bar
+type compactGaugeRenderer struct {
+	gauge *CompactGauge
 
-	// Label
-	titleLabel := widget.NewLabel(label)
+	bgBar      *canvas.Rectangle
+	valueBar   *canvas.Rectangle
+	titleLabel *widget.Label
+	valueText  *widget.Label
+	topRow     *fyne.Container
+}
+
+const compactBarHeight = 20
+
+// Layout positions the label row and scales the value bar to the current value
+func (r *compactGaugeRenderer) Layout(size fyne.Size) {
+	rowHeight := r.topRow.MinSize().Height
+	r.topRow.Resize(fyne.NewSize(size.Width, rowHeight))
+	r.topRow.Move(fyne.NewPos(0, 0))
+
+	r.bgBar.Resize(fyne.NewSize(size.Width, compactBarHeight))
+	r.bgBar.Move(fyne.NewPos(0, rowHeight))
+
+	percentage := math.Max(0, math.Min(100, r.gauge.GetValue()))
+	r.valueBar.Resize(fyne.NewSize(size.Width*float32(percentage/100), compactBarHeight))
+	r.valueBar.Move(fyne.NewPos(0, rowHeight))
+}
 
-	// Value text
-	valueText := widget.NewLabelWithStyle(
-		fmt.Sprintf("%.1f%s", value, unit),
-		fyne.TextAlignTrailing,
-		fyne.TextStyle{Bold: true},
-	)
+// MinSize returns the label row height plus the bar
+func (r *compactGaugeRenderer) MinSize() fyne.Size {
+	row := r.topRow.MinSize()
+	return fyne.NewSize(fyne.Max(row.Width, 100), row.Height+compactBarHeight)
+}
 
-	topRow := container.NewBorder(nil, nil, titleLabel, valueText)
+// Refresh updates the text and bar color from the gauge value
+func (r *compactGaugeRenderer) Refresh() {
+	value := r.gauge.GetValue()
+	r.valueText.SetText(fmt.Sprintf("%.1f%s", value, r.gauge.unit))
+	r.valueBar.FillColor = compactGaugeColor(math.Min(100, value))
+	r.Layout(r.gauge.Size())
+	canvas.Refresh(r.valueBar)
+}
 
-	return container.NewVBox(topRow, barStack)
+// Objects returns all canvas objects for rendering
+func (r *compactGaugeRenderer) Objects() []fyne.CanvasObject {
+	return []fyne.CanvasObject{r.topRow, r.bgBar, r.valueBar}
 }
 
+// Destroy implements fyne.WidgetRenderer
+func (r *compactGaugeRenderer) Destroy() {}
+
 // ================== Gauge Panel for Multiple Gauges ==================
 
 // GaugePanel holds multiple gauges for a monitoring panel
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"adminadmin/internal/network"
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// KnownHostsWindow lists the trusted worker host keys and lets the admin remove them
+type KnownHostsWindow struct {
+	window  fyne.Window
+	onClose func()
+	listBox *fyne.Container
+}
+
+// NewKnownHostsWindow creates the known hosts window
+func NewKnownHostsWindow(app fyne.App, onClose func()) *KnownHostsWindow {
+	w := &KnownHostsWindow{
+		onClose: onClose,
+		listBox: container.NewVBox(),
+	}
+
+	w.window = app.NewWindow("admin:admin - Known Hosts")
+	w.window.Resize(fyne.NewSize(700, 400))
+	w.window.SetOnClosed(func() {
+		if w.onClose != nil {
+			w.onClose()
+		}
+	})
+
+	help := widget.NewLabel("Host keys trusted for SSH. Remove an entry after a worker was reinstalled " +
+		"or its host key was regenerated; you will be asked to confirm the new key on the next connection.")
+	help.Wrapping = fyne.TextWrapWord
+
+	w.window.SetContent(container.NewBorder(
+		container.NewVBox(help, widget.NewSeparator()), nil, nil, nil,
+		container.NewVScroll(w.listBox),
+	))
+	w.Refresh()
+	return w
+}
+
+// Show shows the known hosts window
+func (w *KnownHostsWindow) Show() {
+	w.window.Show()
+}
+
+// Close closes the known hosts window
+func (w *KnownHostsWindow) Close() {
+	w.window.Close()
+}
+
+// Refresh reloads the entries from the known_hosts file
+func (w *KnownHostsWindow) Refresh() {
+	w.listBox.RemoveAll()
+
+	hosts, err := network.LoadKnownHosts()
+	if err != nil {
+		w.listBox.Add(widget.NewLabel(fmt.Sprintf("Error: %v", err)))
+	}
+	if len(hosts) == 0 && err == nil {
+		w.listBox.Add(widget.NewLabel("No known hosts yet"))
+	}
+
+	for _, h := range hosts {
+		fingerprint := h.Fingerprint
+		hostsLabel := widget.NewLabelWithStyle(strings.Join(h.Hosts, ", "), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+		keyLabel := widget.NewLabelWithStyle(fmt.Sprintf("%s  %s", h.Key.Type(), fingerprint),
+			fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+		keyLabel.Selectable = true
+
+		removeBtn := widget.NewButton("Remove", func() {
+			dialog.ShowConfirm("Remove Known Host",
+				fmt.Sprintf("Stop trusting %s?\n\n%s", strings.Join(h.Hosts, ", "), fingerprint),
+				func(ok bool) {
+					if !ok {
+						return
+					}
+					if err := network.RemoveKnownHost(fingerprint); err != nil {
+						dialog.ShowError(err, w.window)
+					}
+					w.Refresh()
+				}, w.window)
+		})
+		removeBtn.Importance = widget.DangerImportance
+
+		w.listBox.Add(container.NewBorder(nil, nil, nil, removeBtn, container.NewVBox(hostsLabel, keyLabel)))
+		w.listBox.Add(widget.NewSeparator())
+	}
+}
@@ -0,0 +1,263 @@
+package ui
+
+import (
+	"adminadmin/internal/notify"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// NotificationsWindow edits webhook and script notification targets
+type NotificationsWindow struct {
+	window   fyne.Window
+	notifier *notify.Notifier
+	onClose  func()
+
+	// Called with the new settings after any change so the caller can persist them
+	onChanged func(notify.Settings)
+
+	targetsBox *fyne.Container
+}
+
+// NewNotificationsWindow creates the notification settings window
+func NewNotificationsWindow(app fyne.App, notifier *notify.Notifier, onChanged func(notify.Settings), onClose func()) *NotificationsWindow {
+	w := &NotificationsWindow{
+		notifier:   notifier,
+		onChanged:  onChanged,
+		onClose:    onClose,
+		targetsBox: container.NewVBox(),
+	}
+
+	w.window = app.NewWindow("admin:admin - Notifications")
+	w.window.Resize(fyne.NewSize(650, 400))
+	w.window.SetOnClosed(func() {
+		if w.onClose != nil {
+			w.onClose()
+		}
+	})
+
+	addWebhookBtn := widget.NewButton("+ Add Webhook", w.showAddWebhookDialog)
+	addWebhookBtn.Importance = widget.HighImportance
+	addScriptBtn := widget.NewButton("+ Add Script", w.showAddScriptDialog)
+
+	help := widget.NewLabel("Events are POSTed as JSON to webhooks, or written to a script's stdin.")
+	help.Wrapping = fyne.TextWrapWord
+
+	w.window.SetContent(container.NewBorder(
+		container.NewVBox(help, widget.NewSeparator()),
+		container.NewHBox(addWebhookBtn, addScriptBtn),
+		nil, nil,
+		container.NewVScroll(w.targetsBox),
+	))
+	w.rebuild()
+	return w
+}
+
+// Show shows the notifications window
+func (w *NotificationsWindow) Show() {
+	w.window.Show()
+}
+
+// Close closes the notifications window
+func (w *NotificationsWindow) Close() {
+	w.window.Close()
+}
+
+func (w *NotificationsWindow) rebuild() {
+	w.targetsBox.RemoveAll()
+	targets := w.notifier.Settings().Targets
+	if len(targets) == 0 {
+		w.targetsBox.Add(widget.NewLabel("No notification targets configured"))
+	}
+	for i, t := range targets {
+		w.targetsBox.Add(w.buildTargetRow(i, t))
+	}
+	w.targetsBox.Refresh()
+}
+
+// buildTargetRow creates one row in the targets list
+func (w *NotificationsWindow) buildTargetRow(index int, t notify.Target) fyne.CanvasObject {
+	events := "all events"
+	if len(t.Events) > 0 {
+		names := make([]string, len(t.Events))
+		for i, e := range t.Events {
+			names[i] = string(e)
+		}
+		events = strings.Join(names, ", ")
+	}
+	label := widget.NewLabel(fmt.Sprintf("%s — %s (%s)", t.Name, t.Describe(), events))
+	label.Wrapping = fyne.TextWrapWord
+
+	enabled := widget.NewCheck("Enabled", nil)
+	enabled.SetChecked(t.Enabled)
+	enabled.OnChanged = func(on bool) {
+		w.update(func(s *notify.Settings) {
+			s.Targets[index].Enabled = on
+		})
+	}
+
+	testBtn := widget.NewButton("Test", func() {
+		go func() {
+			err := w.notifier.Test(t)
+			drv := fyne.CurrentApp().Driver()
+			if drv == nil {
+				return
+			}
+			drv.DoFromGoroutine(func() {
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("test notification failed: %w", err), w.window)
+				} else {
+					dialog.ShowInformation("Test Sent", fmt.Sprintf("Test notification delivered to %s", t.Name), w.window)
+				}
+			}, false)
+		}()
+	})
+
+	removeBtn := widget.NewButton("Remove", func() {
+		w.update(func(s *notify.Settings) {
+			s.Targets = append(s.Targets[:index], s.Targets[index+1:]...)
+		})
+		w.rebuild()
+	})
+	removeBtn.Importance = widget.DangerImportance
+
+	return container.NewBorder(nil, nil, nil, container.NewHBox(enabled, testBtn, removeBtn), label)
+}
+
+// update applies a change to the settings and notifies the caller
+func (w *NotificationsWindow) update(change func(*notify.Settings)) {
+	settings := w.notifier.Settings()
+	change(&settings)
+	w.notifier.SetSettings(settings)
+	if w.onChanged != nil {
+		w.onChanged(settings)
+	}
+}
+
+// newEventSelector creates a check group of event types (none checked = all events)
+func newEventSelector() *widget.CheckGroup {
+	options := make([]string, len(notify.EventTypes))
+	for i, e := range notify.EventTypes {
+		options[i] = string(e)
+	}
+	return widget.NewCheckGroup(options, nil)
+}
+
+func selectedEvents(group *widget.CheckGroup) []notify.EventType {
+	events := make([]notify.EventType, len(group.Selected))
+	for i, s := range group.Selected {
+		events[i] = notify.EventType(s)
+	}
+	return events
+}
+
+func (w *NotificationsWindow) showAddWebhookDialog() {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("e.g. Team chat")
+
+	urlEntry := widget.NewEntry()
+	urlEntry.SetPlaceHolder("https://example.com/hooks/adminadmin")
+
+	authEntry := widget.NewPasswordEntry()
+	authEntry.SetPlaceHolder("Optional Authorization header value")
+
+	retriesEntry := widget.NewEntry()
+	retriesEntry.SetText("3")
+
+	events := newEventSelector()
+
+	formItems := []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("URL", urlEntry),
+		widget.NewFormItem("Authorization", authEntry),
+		widget.NewFormItem("Retries", retriesEntry),
+		widget.NewFormItem("Events", container.NewVScroll(events)),
+	}
+
+	d := dialog.NewForm("Add Webhook", "Add", "Cancel", formItems, func(ok bool) {
+		if !ok {
+			return
+		}
+		url := strings.TrimSpace(urlEntry.Text)
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			dialog.ShowError(fmt.Errorf("webhook URL must start with http:// or https://"), w.window)
+			return
+		}
+		retries, err := strconv.Atoi(strings.TrimSpace(retriesEntry.Text))
+		if err != nil || retries < 0 {
+			dialog.ShowError(fmt.Errorf("invalid retry count: %q", retriesEntry.Text), w.window)
+			return
+		}
+
+		target := notify.Target{
+			Name:       strings.TrimSpace(nameEntry.Text),
+			Kind:       notify.KindWebhook,
+			Enabled:    true,
+			Events:     selectedEvents(events),
+			URL:        url,
+			MaxRetries: retries,
+		}
+		if target.Name == "" {
+			target.Name = url
+		}
+		if auth := strings.TrimSpace(authEntry.Text); auth != "" {
+			target.Headers = map[string]string{"Authorization": auth}
+		}
+		w.update(func(s *notify.Settings) { s.Targets = append(s.Targets, target) })
+		w.rebuild()
+	}, w.window)
+	d.Resize(fyne.NewSize(500, 500))
+	d.Show()
+}
+
+func (w *NotificationsWindow) showAddScriptDialog() {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("e.g. Log to file")
+
+	commandEntry := widget.NewEntry()
+	commandEntry.SetPlaceHolder("/usr/local/bin/on-event.sh")
+
+	argsEntry := widget.NewEntry()
+	argsEntry.SetPlaceHolder("Optional arguments, space separated")
+
+	events := newEventSelector()
+
+	formItems := []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Command", commandEntry),
+		widget.NewFormItem("Arguments", argsEntry),
+		widget.NewFormItem("Events", container.NewVScroll(events)),
+	}
+
+	d := dialog.NewForm("Add Script", "Add", "Cancel", formItems, func(ok bool) {
+		if !ok {
+			return
+		}
+		command := strings.TrimSpace(commandEntry.Text)
+		if command == "" {
+			dialog.ShowError(fmt.Errorf("command is required"), w.window)
+			return
+		}
+
+		target := notify.Target{
+			Name:    strings.TrimSpace(nameEntry.Text),
+			Kind:    notify.KindScript,
+			Enabled: true,
+			Events:  selectedEvents(events),
+			Command: command,
+			Args:    strings.Fields(argsEntry.Text),
+		}
+		if target.Name == "" {
+			target.Name = command
+		}
+		w.update(func(s *notify.Settings) { s.Targets = append(s.Targets, target) })
+		w.rebuild()
+	}, w.window)
+	d.Resize(fyne.NewSize(500, 500))
+	d.Show()
+}
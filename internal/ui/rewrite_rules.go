@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"adminadmin/internal/network"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// OS choices of a rewrite rule in the add dialog
+var rewriteOSOptions = []string{"Any", "linux", "windows", "darwin"}
+
+// ShowRewriteRulesDialog edits the command rewriting settings of the worker's SSH server;
+// onSave is called with the new settings when the user saves
+func ShowRewriteRulesDialog(settings network.RewriteSettings, window fyne.Window, onSave func(network.RewriteSettings)) {
+	rules := append([]network.RewriteRule(nil), settings.Rules...)
+
+	enabledCheck := widget.NewCheck("Rewrite matching SSH commands", nil)
+	enabledCheck.SetChecked(settings.Enabled)
+
+	rulesBox := container.NewVBox()
+	var refresh func()
+	refresh = func() {
+		rulesBox.RemoveAll()
+		if len(rules) == 0 {
+			rulesBox.Add(widget.NewLabel("No rules"))
+		}
+		for i, rule := range rules {
+			osName := rule.OS
+			if osName == "" {
+				osName = "any OS"
+			}
+			match := widget.NewLabelWithStyle(rule.Match, fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+			detail := widget.NewLabel("→ add " + rule.Append + " (" + osName + ")")
+			detail.Truncation = fyne.TextTruncateEllipsis
+			deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				rules = append(rules[:i], rules[i+1:]...)
+				refresh()
+			})
+			deleteBtn.Importance = widget.LowImportance
+			rulesBox.Add(container.NewBorder(nil, nil, match, deleteBtn, detail))
+		}
+	}
+	refresh()
+
+	addBtn := widget.NewButtonWithIcon("Add Rule", theme.ContentAddIcon(), func() {
+		matchEntry := widget.NewEntry()
+		matchEntry.SetPlaceHolder(`\bapt install\b`)
+		appendEntry := widget.NewEntry()
+		appendEntry.SetPlaceHolder("-y")
+		osSelect := widget.NewSelect(rewriteOSOptions, nil)
+		osSelect.SetSelected(rewriteOSOptions[0])
+
+		formItems := []*widget.FormItem{
+			widget.NewFormItem("Pattern", matchEntry),
+			widget.NewFormItem("Add flags", appendEntry),
+			widget.NewFormItem("Only on", osSelect),
+		}
+		formItems[0].HintText = "Regular expression; flags are inserted right after the match"
+		dialog.ShowForm("Add Rewrite Rule", "Add", "Cancel", formItems, func(ok bool) {
+			if !ok {
+				return
+			}
+			rule := network.RewriteRule{Match: matchEntry.Text, Append: appendEntry.Text}
+			if osSelect.Selected != rewriteOSOptions[0] {
+				rule.OS = osSelect.Selected
+			}
+			if err := rule.Validate(); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			rules = append(rules, rule)
+			refresh()
+		}, window)
+	})
+	resetBtn := widget.NewButton("Restore Defaults", func() {
+		rules = network.DefaultRewriteSettings().Rules
+		refresh()
+	})
+
+	help := widget.NewLabel("When on, SSH commands matching a rule get its flags added, e.g. -y so package " +
+		"managers don't wait for a confirmation. The client is told the command that was actually run.")
+	help.Wrapping = fyne.TextWrapWord
+
+	content := container.NewBorder(
+		container.NewVBox(help, enabledCheck, widget.NewSeparator()),
+		container.NewHBox(addBtn, resetBtn),
+		nil, nil,
+		container.NewVScroll(rulesBox),
+	)
+	d := dialog.NewCustomConfirm("Command Rewriting", "Save", "Cancel", content, func(ok bool) {
+		if ok {
+			onSave(network.RewriteSettings{Enabled: enabledCheck.Checked, Rules: rules})
+		}
+	}, window)
+	d.Resize(fyne.NewSize(620, 420))
+	d.Show()
+}
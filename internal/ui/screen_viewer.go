@@ -0,0 +1,323 @@
+package ui
+
+import (
+	"adminadmin/internal/network"
+	"fmt"
+	"image/png"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ScreenSource takes pictures of a worker's screen (implemented by network.AdminClient)
+type ScreenSource interface {
+	CaptureScreen() (*network.ScreenFrame, error)
+	StartScreenStream(opts network.ScreenStreamOptions, onFrame func(frame *network.ScreenFrame, err error)) error
+	StopScreenStream() error
+}
+
+// Zoom levels of the screen viewer, relative to the worker's screen size
+var screenZoomLevels = []float32{0.25, 0.5, 0.75, 1, 1.5, 2, 3, 4}
+
+// Live view intervals offered by the screen viewer
+var screenIntervals = []struct {
+	label    string
+	interval time.Duration
+}{
+	{"0.5 s", 500 * time.Millisecond},
+	{"1 s", time.Second},
+	{"2 s", 2 * time.Second},
+	{"5 s", 5 * time.Second},
+}
+
+// ScreenViewer is a window showing a worker's screen, as a screenshot or a live view
+type ScreenViewer struct {
+	window   fyne.Window
+	hostname string
+	source   ScreenSource
+	onClosed func()
+
+	frame  *network.ScreenFrame // Shown picture, nil before the first one
+	zoom   int                  // Index into screenZoomLevels, or -1 to fit the window
+	stream int                  // Incremented per live view, so frames of a stopped one are dropped
+	live   bool
+	closed bool
+	gone   bool // The worker disconnected
+
+	image          *canvas.Image
+	scroll         *container.Scroll // Holds the image while zoomed
+	body           *fyne.Container
+	captureBtn     *widget.Button
+	liveCheck      *widget.Check
+	intervalSelect *widget.Select
+	zoomLabel      *widget.Label
+	saveBtn        *widget.Button
+	statusLabel    *widget.Label
+}
+
+// NewScreenViewer creates a viewer for the screen of the worker hostname and takes a
+// first screenshot; onClosed is called when the window closes
+func NewScreenViewer(app fyne.App, hostname string, source ScreenSource, onClosed func()) *ScreenViewer {
+	v := &ScreenViewer{
+		hostname: hostname,
+		source:   source,
+		onClosed: onClosed,
+		zoom:     -1,
+	}
+
+	v.window = app.NewWindow(fmt.Sprintf("admin:admin - Screen of %s", hostname))
+	v.window.Resize(fyne.NewSize(1000, 700))
+	v.window.SetOnClosed(func() {
+		v.closed = true
+		v.setLive(false)
+		if v.onClosed != nil {
+			v.onClosed()
+		}
+	})
+
+	v.image = canvas.NewImageFromImage(nil)
+	v.image.ScaleMode = canvas.ImageScaleSmooth
+	v.scroll = container.NewScroll(container.NewCenter(v.image))
+	placeholder := widget.NewLabelWithStyle("Waiting for the first screenshot...", fyne.TextAlignCenter, fyne.TextStyle{Italic: true})
+	v.body = container.NewStack(container.NewCenter(placeholder))
+	v.statusLabel = widget.NewLabel("")
+	v.statusLabel.Truncation = fyne.TextTruncateEllipsis
+
+	v.window.SetContent(container.NewBorder(
+		container.NewVBox(v.buildToolbar(), widget.NewSeparator()),
+		container.NewVBox(widget.NewSeparator(), v.statusLabel),
+		nil, nil,
+		v.body,
+	))
+
+	v.Capture()
+	return v
+}
+
+// buildToolbar creates the capture, live view, zoom and save controls
+func (v *ScreenViewer) buildToolbar() fyne.CanvasObject {
+	v.captureBtn = widget.NewButtonWithIcon("Screenshot", theme.ViewRefreshIcon(), v.Capture)
+	v.captureBtn.Importance = widget.HighImportance
+
+	labels := make([]string, len(screenIntervals))
+	for i, option := range screenIntervals {
+		labels[i] = option.label
+	}
+	v.intervalSelect = widget.NewSelect(labels, func(string) {
+		if v.live {
+			v.startStream() // Restart at the new rate
+		}
+	})
+	v.intervalSelect.SetSelectedIndex(1)
+	v.liveCheck = widget.NewCheck("Live view every", v.setLive)
+
+	zoomOut := widget.NewButtonWithIcon("", theme.ZoomOutIcon(), func() { v.stepZoom(-1) })
+	zoomIn := widget.NewButtonWithIcon("", theme.ZoomInIcon(), func() { v.stepZoom(1) })
+	fit := widget.NewButtonWithIcon("Fit", theme.ZoomFitIcon(), func() { v.setZoom(-1) })
+	v.zoomLabel = widget.NewLabel("Fit")
+
+	v.saveBtn = widget.NewButtonWithIcon("Save PNG", theme.DocumentSaveIcon(), v.save)
+	v.saveBtn.Disable()
+
+	return container.NewHBox(
+		v.captureBtn,
+		widget.NewSeparator(),
+		v.liveCheck, v.intervalSelect,
+		widget.NewSeparator(),
+		fit, zoomOut, v.zoomLabel, zoomIn,
+		widget.NewSeparator(),
+		v.saveBtn,
+	)
+}
+
+// Show shows the viewer window
+func (v *ScreenViewer) Show() {
+	v.window.Show()
+	v.window.RequestFocus()
+}
+
+// Close closes the viewer window
+func (v *ScreenViewer) Close() {
+	v.window.Close()
+}
+
+// SetDisconnected stops the live view and disables capturing after the worker's
+// connection ended; the last picture can still be zoomed and saved
+func (v *ScreenViewer) SetDisconnected() {
+	v.gone = true
+	v.live = false
+	v.stream++
+	v.liveCheck.SetChecked(false)
+	v.liveCheck.Disable()
+	v.intervalSelect.Disable()
+	v.captureBtn.Disable()
+	v.statusLabel.SetText("Worker disconnected")
+}
+
+// Capture takes a full-size screenshot
+func (v *ScreenViewer) Capture() {
+	v.captureBtn.Disable()
+	v.statusLabel.SetText("Taking a screenshot...")
+	go func() {
+		frame, err := v.source.CaptureScreen()
+		runOnMainThread(func() {
+			if v.closed {
+				return
+			}
+			if !v.gone {
+				v.captureBtn.Enable()
+			}
+			if err != nil {
+				v.statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+				return
+			}
+			v.show(frame)
+		})
+	}()
+}
+
+// setLive starts or stops the live view
+func (v *ScreenViewer) setLive(on bool) {
+	if on == v.live {
+		return
+	}
+	v.live = on
+	if on {
+		v.startStream()
+		return
+	}
+	v.stream++
+	go v.source.StopScreenStream()
+	if !v.closed && !v.gone {
+		v.statusLabel.SetText("Live view stopped")
+	}
+}
+
+// startStream (re)starts the live view at the selected interval
+func (v *ScreenViewer) startStream() {
+	v.stream++
+	stream := v.stream
+	interval := screenIntervals[max(v.intervalSelect.SelectedIndex(), 0)].interval
+	v.statusLabel.SetText("Starting live view...")
+
+	onFrame := func(frame *network.ScreenFrame, err error) {
+		runOnMainThread(func() {
+			if v.closed || stream != v.stream {
+				return
+			}
+			if err != nil {
+				v.live = false
+				v.liveCheck.SetChecked(false)
+				v.statusLabel.SetText(fmt.Sprintf("Live view stopped: %v", err))
+				return
+			}
+			v.show(frame)
+		})
+	}
+	go func() {
+		err := v.source.StartScreenStream(network.ScreenStreamOptions{Interval: interval}, onFrame)
+		if err != nil {
+			onFrame(nil, err)
+		}
+	}()
+}
+
+// show displays a frame and describes it in the status bar
+func (v *ScreenViewer) show(frame *network.ScreenFrame) {
+	previous := v.frame
+	v.frame = frame
+	v.image.Image = frame.Image
+	if previous == nil {
+		v.saveBtn.Enable()
+	}
+	if previous == nil || previous.ScreenWidth != frame.ScreenWidth || previous.ScreenHeight != frame.ScreenHeight {
+		v.layoutImage()
+	}
+	v.image.Refresh()
+
+	status := fmt.Sprintf("%d×%d", frame.ScreenWidth, frame.ScreenHeight)
+	if size := frame.Image.Bounds().Size(); size.X != frame.ScreenWidth {
+		status += fmt.Sprintf(" (shown at %d×%d)", size.X, size.Y)
+	}
+	status += fmt.Sprintf(" · %s %.1f KB · captured %s", frame.Format, float64(frame.Bytes)/1024,
+		frame.CapturedAt.Local().Format("15:04:05"))
+	if frame.Seq > 0 {
+		status += fmt.Sprintf(" · live, frame %d", frame.Seq)
+		if frame.Unchanged > 0 {
+			status += fmt.Sprintf(", %d unchanged capture(s) skipped", frame.Unchanged)
+		}
+	}
+	v.statusLabel.SetText(status)
+}
+
+// stepZoom moves to the next larger (1) or smaller (-1) zoom level; from fit it
+// starts at 100%
+func (v *ScreenViewer) stepZoom(step int) {
+	if v.zoom < 0 {
+		for i, level := range screenZoomLevels {
+			if level == 1 {
+				v.setZoom(i)
+				return
+			}
+		}
+	}
+	v.setZoom(min(max(v.zoom+step, 0), len(screenZoomLevels)-1))
+}
+
+// setZoom sets the zoom level index, or -1 to fit the picture to the window
+func (v *ScreenViewer) setZoom(zoom int) {
+	v.zoom = zoom
+	if zoom < 0 {
+		v.zoomLabel.SetText("Fit")
+	} else {
+		v.zoomLabel.SetText(fmt.Sprintf("%.0f%%", screenZoomLevels[zoom]*100))
+	}
+	v.layoutImage()
+}
+
+// layoutImage scales the picture to the window, or shows it at the zoom level in a
+// scrollable area
+func (v *ScreenViewer) layoutImage() {
+	if v.frame == nil {
+		return
+	}
+	if v.zoom < 0 {
+		v.image.FillMode = canvas.ImageFillContain
+		v.image.SetMinSize(fyne.NewSize(1, 1))
+		v.body.Objects = []fyne.CanvasObject{v.image}
+	} else {
+		// Zoom is relative to the screen, so live frames scaled down by the worker
+		// show at the same size as full screenshots
+		level := screenZoomLevels[v.zoom]
+		v.image.FillMode = canvas.ImageFillStretch
+		v.image.SetMinSize(fyne.NewSize(float32(v.frame.ScreenWidth)*level, float32(v.frame.ScreenHeight)*level))
+		v.scroll.Content.Refresh()
+		v.body.Objects = []fyne.CanvasObject{v.scroll}
+	}
+	v.body.Refresh()
+}
+
+// save writes the shown picture to a PNG file
+func (v *ScreenViewer) save() {
+	if v.frame == nil {
+		return
+	}
+	frame := v.frame
+	d := dialog.NewFileSave(func(w fyne.URIWriteCloser, err error) {
+		if err != nil || w == nil {
+			return
+		}
+		defer w.Close()
+		if err := png.Encode(w, frame.Image); err != nil {
+			dialog.ShowError(err, v.window)
+		}
+	}, v.window)
+	name := unsafeTranscriptChars.Replace(v.hostname)
+	d.SetFileName(fmt.Sprintf("%s-screen-%s.png", name, frame.CapturedAt.Local().Format("20060102-150405")))
+	d.Show()
+}
@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"adminadmin/internal/network"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// sessionLimitEntries edits one server's SessionLimits
+type sessionLimitEntries struct {
+	idle, duration, sessions *widget.Entry
+}
+
+func newSessionLimitEntries(limits network.SessionLimits) *sessionLimitEntries {
+	entry := func(value int) *widget.Entry {
+		e := widget.NewEntry()
+		e.SetText(strconv.Itoa(value))
+		return e
+	}
+	return &sessionLimitEntries{
+		idle:     entry(limits.IdleMinutes),
+		duration: entry(limits.MaxSessionMinutes),
+		sessions: entry(limits.MaxSessions),
+	}
+}
+
+// formItems returns the entries as form rows, their labels prefixed with server
+func (e *sessionLimitEntries) formItems(server string) []*widget.FormItem {
+	items := []*widget.FormItem{
+		widget.NewFormItem(server+" idle timeout", e.idle),
+		widget.NewFormItem(server+" max duration", e.duration),
+		widget.NewFormItem(server+" max sessions", e.sessions),
+	}
+	items[0].HintText = "Minutes without activity; 0 = never"
+	items[1].HintText = "Minutes after login; 0 = unlimited"
+	items[2].HintText = "Open at once; 0 = unlimited"
+	return items
+}
+
+// limits parses the entries, naming server in errors
+func (e *sessionLimitEntries) limits(server string) (network.SessionLimits, error) {
+	var limits network.SessionLimits
+	fields := []struct {
+		entry *widget.Entry
+		name  string
+		value *int
+	}{
+		{e.idle, "idle timeout", &limits.IdleMinutes},
+		{e.duration, "max duration", &limits.MaxSessionMinutes},
+		{e.sessions, "max sessions", &limits.MaxSessions},
+	}
+	for _, f := range fields {
+		n, err := strconv.Atoi(strings.TrimSpace(f.entry.Text))
+		if err != nil || n < 0 {
+			return limits, fmt.Errorf("invalid %s %s: %q", server, f.name, f.entry.Text)
+		}
+		*f.value = n
+	}
+	return limits, nil
+}
+
+// ShowSessionLimitsDialog edits the idle timeouts and session limits of the worker's
+// SSH server and admin connections; onSave is called with the new settings
+func ShowSessionLimitsDialog(settings network.SessionLimitSettings, window fyne.Window, onSave func(network.SessionLimitSettings)) {
+	ssh := newSessionLimitEntries(settings.SSH)
+	admin := newSessionLimitEntries(settings.Admin)
+
+	formItems := append(ssh.formItems("SSH"), admin.formItems("Admin")...)
+	d := dialog.NewForm("Session Limits", "Save", "Cancel", formItems, func(ok bool) {
+		if !ok {
+			return
+		}
+		var saved network.SessionLimitSettings
+		var err error
+		if saved.SSH, err = ssh.limits("SSH"); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if saved.Admin, err = admin.limits("admin"); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		onSave(saved)
+	}, window)
+	d.Resize(fyne.NewSize(520, 0))
+	d.Show()
+}
@@ -0,0 +1,153 @@
+package ui
+
+import (
+	"adminadmin/internal/network"
+	"fmt"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// SessionsPanel lists the detachable shell sessions the account runs on a worker and
+// lets the user attach to, start and kill them
+type SessionsPanel struct {
+	client *network.SSHClient
+	window fyne.Window
+
+	onAttach func(id string)
+	onNew    func()
+
+	mu       sync.Mutex
+	sessions []network.DetachableSessionInfo
+
+	list        *widget.List
+	statusLabel *widget.Label
+}
+
+// NewSessionsPanel creates a panel for the worker's sessions and starts loading them.
+// onAttach opens a terminal attached to a session and onNew one attached to a new session.
+func NewSessionsPanel(client *network.SSHClient, window fyne.Window, onAttach func(id string), onNew func()) *SessionsPanel {
+	p := &SessionsPanel{
+		client:      client,
+		window:      window,
+		onAttach:    onAttach,
+		onNew:       onNew,
+		statusLabel: widget.NewLabel("Loading..."),
+	}
+
+	p.list = widget.NewList(
+		func() int {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			return len(p.sessions)
+		},
+		func() fyne.CanvasObject {
+			id := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true, Monospace: true})
+			details := widget.NewLabel("")
+			details.Truncation = fyne.TextTruncateEllipsis
+			attachBtn := widget.NewButtonWithIcon("Attach", theme.LoginIcon(), nil)
+			attachBtn.Importance = widget.HighImportance
+			killBtn := widget.NewButtonWithIcon("Kill", theme.CancelIcon(), nil)
+			killBtn.Importance = widget.DangerImportance
+			return container.NewBorder(nil, nil, id, container.NewHBox(attachBtn, killBtn), details)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			p.mu.Lock()
+			if id >= len(p.sessions) {
+				p.mu.Unlock()
+				return
+			}
+			session := p.sessions[id]
+			p.mu.Unlock()
+
+			row := obj.(*fyne.Container)
+			details := row.Objects[0].(*widget.Label)
+			idLabel := row.Objects[1].(*widget.Label)
+			buttons := row.Objects[2].(*fyne.Container)
+			attachBtn := buttons.Objects[0].(*widget.Button)
+			killBtn := buttons.Objects[1].(*widget.Button)
+
+			idLabel.SetText(session.ID)
+			details.SetText(describeDetachableSession(session))
+			attachBtn.OnTapped = func() { p.onAttach(session.ID) }
+			killBtn.OnTapped = func() { p.confirmKill(session.ID) }
+		},
+	)
+
+	p.Refresh()
+	return p
+}
+
+// Content returns the panel UI
+func (p *SessionsPanel) Content() fyne.CanvasObject {
+	toolbar := container.NewHBox(
+		widget.NewButtonWithIcon("New Session", theme.ContentAddIcon(), p.onNew),
+		widget.NewButtonWithIcon("Refresh", theme.ViewRefreshIcon(), p.Refresh),
+		p.statusLabel,
+	)
+	hint := widget.NewLabel("Detachable sessions keep running on the worker when their tab closes or the " +
+		"connection drops. Attach again later, from this or another admin machine, to pick up where you " +
+		"left off; recent output is replayed. Attaching a session that is open elsewhere takes it over.")
+	hint.Wrapping = fyne.TextWrapWord
+	hint.Importance = widget.LowImportance
+
+	return container.NewBorder(
+		container.NewVBox(toolbar, widget.NewSeparator()),
+		container.NewVBox(widget.NewSeparator(), hint),
+		nil, nil,
+		p.list,
+	)
+}
+
+// Refresh reloads the sessions from the worker
+func (p *SessionsPanel) Refresh() {
+	go func() {
+		sessions, err := p.client.DetachableSessions()
+		runOnMainThread(func() {
+			if err != nil {
+				p.statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+				return
+			}
+			p.mu.Lock()
+			p.sessions = sessions
+			p.mu.Unlock()
+			p.statusLabel.SetText(fmt.Sprintf("%d session(s)", len(sessions)))
+			p.list.UnselectAll()
+			p.list.Refresh()
+		})
+	}()
+}
+
+// describeDetachableSession summarizes when a session started and who is attached
+func describeDetachableSession(session network.DetachableSessionInfo) string {
+	started := fmt.Sprintf("started %s", session.Started.Local().Format("2006-01-02 15:04:05"))
+	size := fmt.Sprintf("%dx%d", session.Cols, session.Rows)
+	if session.Attached {
+		return fmt.Sprintf("%s · %s · attached from %s", started, size, session.AttachedFrom)
+	}
+	return fmt.Sprintf("%s · %s · detached %s ago", started, size, time.Since(session.LastDetached).Round(time.Second))
+}
+
+// confirmKill asks before ending a session and everything running in it
+func (p *SessionsPanel) confirmKill(id string) {
+	dialog.ShowConfirm("Kill Session", fmt.Sprintf("End session %s and everything running in it?", id), func(ok bool) {
+		if !ok {
+			return
+		}
+		go func() {
+			err := p.client.KillDetachableSession(id)
+			runOnMainThread(func() {
+				if err != nil {
+					dialog.ShowError(err, p.window)
+					return
+				}
+				p.Refresh()
+			})
+		}()
+	}, p.window)
+}
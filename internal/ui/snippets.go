@@ -0,0 +1,164 @@
+package ui
+
+import (
+	"adminadmin/internal/network"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ShowSnippetsDialog shows the snippet library. Using a snippet asks for its parameters
+// and passes the command to onUse, with run set for Run rather than Insert; every edit
+// of the library is passed to onSave.
+func ShowSnippetsDialog(snippets []network.Snippet, window fyne.Window, onSave func([]network.Snippet), onUse func(command string, run bool)) {
+	snippets = append([]network.Snippet(nil), snippets...)
+	var d dialog.Dialog
+
+	save := func() {
+		if onSave != nil {
+			onSave(snippets)
+		}
+	}
+	use := func(snippet network.Snippet, run bool) {
+		d.Hide()
+		askSnippetParams(snippet, window, func(command string) {
+			onUse(command, run)
+		})
+	}
+
+	listBox := container.NewVBox()
+	var refresh func()
+	refresh = func() {
+		listBox.RemoveAll()
+		if len(snippets) == 0 {
+			listBox.Add(widget.NewLabel("No snippets yet. Save a command you run often with New Snippet."))
+		}
+		for i, snippet := range snippets {
+			name := widget.NewLabelWithStyle(snippet.Name, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			command := widget.NewLabelWithStyle(snippet.Command, fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+			command.Truncation = fyne.TextTruncateEllipsis
+			info := container.NewVBox(name, command)
+			if snippet.Description != "" {
+				description := widget.NewLabel(snippet.Description)
+				description.Importance = widget.LowImportance
+				description.Truncation = fyne.TextTruncateEllipsis
+				info.Add(description)
+			}
+
+			insertBtn := widget.NewButton("Insert", func() { use(snippet, false) })
+			runBtn := widget.NewButtonWithIcon("Run", theme.MediaPlayIcon(), func() { use(snippet, true) })
+			runBtn.Importance = widget.HighImportance
+			editBtn := widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), func() {
+				editSnippet(snippet, window, func(edited network.Snippet) {
+					snippets[i] = edited
+					save()
+					refresh()
+				})
+			})
+			deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				snippets = append(snippets[:i], snippets[i+1:]...)
+				save()
+				refresh()
+			})
+			deleteBtn.Importance = widget.LowImportance
+
+			buttons := container.NewHBox(insertBtn, runBtn, editBtn, deleteBtn)
+			listBox.Add(container.NewBorder(nil, nil, nil, buttons, info))
+			listBox.Add(widget.NewSeparator())
+		}
+	}
+	refresh()
+
+	newBtn := widget.NewButtonWithIcon("New Snippet", theme.ContentAddIcon(), func() {
+		editSnippet(network.Snippet{}, window, func(snippet network.Snippet) {
+			snippets = append(snippets, snippet)
+			save()
+			refresh()
+		})
+	})
+
+	help := widget.NewLabel("Saved commands for any worker. Write {{name}} for a parameter; " +
+		"you are asked for its value when the snippet is used.")
+	help.Wrapping = fyne.TextWrapWord
+
+	content := container.NewBorder(
+		container.NewVBox(help, widget.NewSeparator()),
+		container.NewHBox(newBtn),
+		nil, nil,
+		container.NewVScroll(listBox),
+	)
+	d = dialog.NewCustom("Snippets", "Close", content, window)
+	d.Resize(fyne.NewSize(700, 460))
+	d.Show()
+}
+
+// editSnippet shows a form to create or change a snippet; onDone gets the valid result
+func editSnippet(snippet network.Snippet, window fyne.Window, onDone func(network.Snippet)) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(snippet.Name)
+	nameEntry.SetPlaceHolder("Tail a log")
+	commandEntry := widget.NewEntry()
+	commandEntry.SetText(snippet.Command)
+	commandEntry.SetPlaceHolder("tail -n {{lines}} {{file}}")
+	descriptionEntry := widget.NewEntry()
+	descriptionEntry.SetText(snippet.Description)
+
+	formItems := []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Command", commandEntry),
+		widget.NewFormItem("Description", descriptionEntry),
+	}
+	formItems[1].HintText = "{{name}} marks a parameter"
+
+	title := "Edit Snippet"
+	if snippet.Name == "" {
+		title = "New Snippet"
+	}
+	d := dialog.NewForm(title, "Save", "Cancel", formItems, func(ok bool) {
+		if !ok {
+			return
+		}
+		edited := network.Snippet{Name: nameEntry.Text, Command: commandEntry.Text, Description: descriptionEntry.Text}
+		if err := edited.Validate(); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		onDone(edited)
+	}, window)
+	d.Resize(fyne.NewSize(560, 0))
+	d.Show()
+}
+
+// askSnippetParams asks for the values of the snippet's parameters, if it has any, and
+// passes the expanded command to onDone
+func askSnippetParams(snippet network.Snippet, window fyne.Window, onDone func(command string)) {
+	params := snippet.Params()
+	if len(params) == 0 {
+		onDone(snippet.Command)
+		return
+	}
+
+	entries := make(map[string]*widget.Entry)
+	var formItems []*widget.FormItem
+	for _, param := range params {
+		entry := widget.NewEntry()
+		entries[param] = entry
+		formItems = append(formItems, widget.NewFormItem(param, entry))
+	}
+	d := dialog.NewForm(fmt.Sprintf("Run %s", snippet.Name), "OK", "Cancel", formItems, func(ok bool) {
+		if !ok {
+			return
+		}
+		values := make(map[string]string)
+		for param, entry := range entries {
+			values[param] = entry.Text
+		}
+		onDone(snippet.Expand(values))
+	}, window)
+	d.Resize(fyne.NewSize(460, 0))
+	d.Show()
+}
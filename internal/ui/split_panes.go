@@ -0,0 +1,244 @@
+package ui
+
+import (
+	"adminadmin/internal/network"
+	"fmt"
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// SplitTarget is a worker a shell tab can be split to
+type SplitTarget struct {
+	ID   string
+	Name string
+}
+
+// SplitRequest is what the split dialog asks for: the worker to open a pane for and
+// how to log in
+type SplitRequest struct {
+	WorkerID   string
+	User       string
+	Password   string
+	Detachable bool // Start a detachable session instead of a plain shell
+}
+
+// ShellPane is a connected shell to show in a new pane
+type ShellPane struct {
+	Hostname string
+	IP       string
+	Shell    *network.ShellSession
+	OnClose  func() // Ends the session; called once when the pane or its tab closes
+	Actions  TerminalActions
+}
+
+// PaneOpener connects the shell for a new pane; it is called off the main thread
+type PaneOpener func(req SplitRequest) (ShellPane, error)
+
+// shellPane is one terminal of a split shell tab
+type shellPane struct {
+	term    *TerminalWidget
+	content fyne.CanvasObject
+	frame   *canvas.Rectangle // Outlines the pane while input is synchronized
+	close   func()            // Ends the pane's session
+}
+
+// shellPaneGroup lays out the panes of a shell tab side by side (or stacked) and can
+// mirror what is typed in one pane to all others. Only used on the main thread.
+type shellPaneGroup struct {
+	panes     []*shellPane
+	body      *fyne.Container
+	stacked   bool // Panes above each other instead of side by side
+	syncing   bool
+	syncCheck *widget.Check
+
+	onEmpty func()                     // Called when the last pane closed
+	onFocus func(term *TerminalWidget) // Called when a pane gains the focus
+}
+
+func newShellPaneGroup(onEmpty func(), onFocus func(term *TerminalWidget)) *shellPaneGroup {
+	g := &shellPaneGroup{
+		body:    container.NewStack(),
+		onEmpty: onEmpty,
+		onFocus: onFocus,
+	}
+	g.syncCheck = widget.NewCheck("Sync input", g.setSyncing)
+	return g
+}
+
+// add opens a pane for pane's shell after the existing ones and returns its terminal
+func (g *shellPaneGroup) add(pane ShellPane) *TerminalWidget {
+	p := &shellPane{close: onceFunc(pane.OnClose)}
+	p.term, p.content = createShellTerminalUI(pane.Hostname, pane.IP, pane.Shell, p.close, func() {
+		g.remove(p)
+	}, pane.Actions)
+
+	p.frame = canvas.NewRectangle(color.Transparent)
+	p.frame.StrokeColor = termPromptColor
+	p.frame.CornerRadius = 4
+	p.content = container.NewStack(p.content, p.frame)
+
+	p.term.SetOnTyped(func(data []byte) { g.mirror(p, data) })
+	p.term.SetOnFocus(func() { g.onFocus(p.term) })
+
+	g.panes = append(g.panes, p)
+	g.layout()
+	return p.term
+}
+
+// remove closes a pane, and the tab with its last pane
+func (g *shellPaneGroup) remove(p *shellPane) {
+	for i, pane := range g.panes {
+		if pane == p {
+			g.panes = append(g.panes[:i], g.panes[i+1:]...)
+			break
+		}
+	}
+	p.close()
+	if len(g.panes) == 0 {
+		g.onEmpty()
+		return
+	}
+	g.layout()
+	g.onFocus(g.panes[0].term)
+}
+
+// closeAll ends every pane's session
+func (g *shellPaneGroup) closeAll() {
+	for _, p := range g.panes {
+		p.close()
+	}
+}
+
+// mirror sends what was typed in from to every other pane while input is synchronized
+func (g *shellPaneGroup) mirror(from *shellPane, data []byte) {
+	if !g.syncing {
+		return
+	}
+	for _, p := range g.panes {
+		if p != from {
+			p.term.send(data)
+		}
+	}
+}
+
+func (g *shellPaneGroup) setSyncing(on bool) {
+	g.syncing = on
+	g.updateFrames()
+}
+
+// updateFrames outlines every pane while typing goes to all of them
+func (g *shellPaneGroup) updateFrames() {
+	width := float32(0)
+	if g.syncing && len(g.panes) > 1 {
+		width = 2
+	}
+	for _, p := range g.panes {
+		p.frame.StrokeWidth = width
+		p.frame.Refresh()
+	}
+}
+
+// layout splits the tab evenly between the panes
+func (g *shellPaneGroup) layout() {
+	var build func(panes []*shellPane) fyne.CanvasObject
+	build = func(panes []*shellPane) fyne.CanvasObject {
+		if len(panes) == 1 {
+			return panes[0].content
+		}
+		var split *container.Split
+		if g.stacked {
+			split = container.NewVSplit(panes[0].content, build(panes[1:]))
+		} else {
+			split = container.NewHSplit(panes[0].content, build(panes[1:]))
+		}
+		split.Offset = 1 / float64(len(panes))
+		return split
+	}
+	g.body.Objects = []fyne.CanvasObject{build(g.panes)}
+	g.body.Refresh()
+	g.updateFrames()
+}
+
+// content returns the tab's content: a toolbar over the panes. onSplit opens the dialog
+// adding a pane; nil hides the split button.
+func (g *shellPaneGroup) content(onSplit func()) fyne.CanvasObject {
+	orientBtn := widget.NewButtonWithIcon("Stack Panes", theme.MenuDropDownIcon(), nil)
+	orientBtn.OnTapped = func() {
+		g.stacked = !g.stacked
+		if g.stacked {
+			orientBtn.SetText("Panes Side by Side")
+			orientBtn.SetIcon(theme.MenuDropUpIcon())
+		} else {
+			orientBtn.SetText("Stack Panes")
+			orientBtn.SetIcon(theme.MenuDropDownIcon())
+		}
+		g.layout()
+	}
+	orientBtn.Importance = widget.LowImportance
+
+	toolbar := container.NewHBox()
+	if onSplit != nil {
+		toolbar.Add(widget.NewButtonWithIcon("Split", theme.ContentAddIcon(), onSplit))
+	}
+	toolbar.Add(orientBtn)
+	toolbar.Add(g.syncCheck)
+
+	bg := canvas.NewRectangle(termBgColor)
+	return container.NewStack(bg, container.NewBorder(toolbar, nil, nil, nil, g.body))
+}
+
+// showSplitDialog asks for a worker and login for a new pane, connects its shell with
+// open and passes it to onOpened on the main thread
+func showSplitDialog(window fyne.Window, targets []SplitTarget, open PaneOpener, onOpened func(ShellPane)) {
+	if len(targets) == 0 {
+		dialog.ShowInformation("Split", "No workers are connected.", window)
+		return
+	}
+	names := make([]string, len(targets))
+	for i, target := range targets {
+		names[i] = fmt.Sprintf("%s (%s)", target.Name, target.ID)
+	}
+	workerSelect := widget.NewSelect(names, nil)
+	workerSelect.SetSelectedIndex(0)
+	userEntry := widget.NewEntry()
+	userEntry.SetText(network.DefaultSSHUsername)
+	passwordEntry := widget.NewPasswordEntry()
+	passwordEntry.SetPlaceHolder("Password (empty = SSH key)")
+	detachableCheck := widget.NewCheck("Detachable session", nil)
+
+	formItems := []*widget.FormItem{
+		widget.NewFormItem("Worker", workerSelect),
+		widget.NewFormItem("Username", userEntry),
+		widget.NewFormItem("Password", passwordEntry),
+		widget.NewFormItem("", detachableCheck),
+	}
+	d := dialog.NewForm("Split: Open a Worker in a New Pane", "Connect", "Cancel", formItems, func(ok bool) {
+		if !ok || workerSelect.SelectedIndex() < 0 {
+			return
+		}
+		req := SplitRequest{
+			WorkerID:   targets[workerSelect.SelectedIndex()].ID,
+			User:       userEntry.Text,
+			Password:   passwordEntry.Text,
+			Detachable: detachableCheck.Checked,
+		}
+		go func() {
+			pane, err := open(req)
+			runOnMainThread(func() {
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("SSH connection failed: %v", err), window)
+					return
+				}
+				onOpened(pane)
+			})
+		}()
+	}, window)
+	d.Resize(fyne.NewSize(460, 0))
+	d.Show()
+}
@@ -1,17 +1,23 @@
 package ui
 
 import (
+	"adminadmin/internal/network"
 	"fmt"
 	"image/color"
+	"io"
+	"log"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
@@ -159,6 +165,30 @@ type SSHTab struct {
 	Hostname string
 	IP       string
 	Terminal *SSHTerminal
+	Shell    *TerminalWidget // Set for interactive shell tabs
+	Files    *FileBrowser    // Set for file browser tabs
+
+	item    *container.TabItem
+	onClose func() // Ends the tab's session
+}
+
+// TerminalActions are optional tools offered in a terminal tab's header
+type TerminalActions struct {
+	OnFiles    func() // Opens a file browser tab
+	OnTunnels  func() // Opens a port forwarding tab
+	OnAudit    func() // Opens the worker's audit log
+	OnUsers    func() // Opens the worker's SSH account editor
+	OnSessions func() // Opens the worker's detachable sessions
+}
+
+// CommandTools are the optional input helpers of a command mode tab
+type CommandTools struct {
+	History        []string                                      // Earlier commands run on the worker, oldest first
+	OnHistory      func(cmd string)                              // Records a command run in the tab
+	Complete       func(line string) (network.Completion, error) // Completes the last word of line on the worker
+	Snippets       func() []network.Snippet                      // Returns the snippet library
+	OnSaveSnippets func([]network.Snippet)                       // Persists the edited snippet library
+	TranscriptLog  io.Writer                                     // Receives the tab's transcript as it grows; nil to not log it
 }
 
 // SSHTerminalWindow manages the SSH terminal window with tabs
@@ -168,18 +198,35 @@ type SSHTerminalWindow struct {
 	tabBar  *container.AppTabs
 	mu      sync.RWMutex
 	onClose func()
+
+	focusMu    sync.Mutex
+	shellFocus map[*container.TabItem]*TerminalWidget // Terminal to focus when a shell tab is selected
+
+	// Splitting shell tabs; nil openPane disables it
+	splitTargets func() []SplitTarget
+	openPane     PaneOpener
 }
 
 // NewSSHTerminalWindow creates a new SSH terminal window
 func NewSSHTerminalWindow(app fyne.App, onClose func()) *SSHTerminalWindow {
 	w := &SSHTerminalWindow{
-		tabs:    make(map[string]*SSHTab),
-		onClose: onClose,
+		tabs:       make(map[string]*SSHTab),
+		onClose:    onClose,
+		shellFocus: make(map[*container.TabItem]*TerminalWidget),
 	}
 
 	w.window = app.NewWindow("admin:admin - SSH Terminal")
 	w.window.Resize(fyne.NewSize(800, 500))
 	w.window.SetOnClosed(func() {
+		w.mu.Lock()
+		tabs := w.tabs
+		w.tabs = make(map[string]*SSHTab)
+		w.mu.Unlock()
+		for _, tab := range tabs {
+			if tab.onClose != nil {
+				tab.onClose()
+			}
+		}
 		if w.onClose != nil {
 			w.onClose()
 		}
@@ -188,6 +235,15 @@ func NewSSHTerminalWindow(app fyne.App, onClose func()) *SSHTerminalWindow {
 	// Create tabs container
 	w.tabBar = container.NewAppTabs()
 	w.tabBar.SetTabLocation(container.TabLocationTop)
+	w.tabBar.OnSelected = func(item *container.TabItem) {
+		// Called while w.mu is held by AddTab/AddShellTab, so use focusMu
+		w.focusMu.Lock()
+		term := w.shellFocus[item]
+		w.focusMu.Unlock()
+		if term != nil {
+			w.window.Canvas().Focus(term)
+		}
+	}
 
 	// Placeholder when no tabs
 	placeholder := container.NewCenter(
@@ -196,65 +252,327 @@ func NewSSHTerminalWindow(app fyne.App, onClose func()) *SSHTerminalWindow {
 
 	w.window.SetContent(container.NewStack(placeholder, w.tabBar))
 
+	// Files dropped on the window are uploaded by the selected file browser tab
+	w.window.SetOnDropped(func(_ fyne.Position, uris []fyne.URI) {
+		selected := w.tabBar.Selected()
+		w.mu.RLock()
+		var browser *FileBrowser
+		for _, tab := range w.tabs {
+			if tab.item == selected {
+				browser = tab.Files
+			}
+		}
+		w.mu.RUnlock()
+		if browser != nil {
+			browser.UploadURIs(uris)
+		}
+	})
+
 	return w
 }
 
-// AddTab adds a new SSH tab
-func (w *SSHTerminalWindow) AddTab(id, hostname, ip string, onCommand func(string) string) {
+// CommandStarter starts a command in a command mode tab, streaming its output to onOutput
+type CommandStarter func(cmd string, onOutput func(data []byte, stderr bool)) (*network.RunningCommand, error)
+
+// AddTab adds a new SSH tab running one command at a time with onCommand. onClose
+// (optional) is called once when the tab or window is closed; actions adds buttons
+// for the worker's other tools to the header, and tools the input helpers.
+func (w *SSHTerminalWindow) AddTab(id, hostname, ip string, onCommand CommandStarter, onClose func(), actions TerminalActions, tools CommandTools) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	// Generate unique tab ID with timestamp to allow multiple tabs per worker
-	tabID := fmt.Sprintf("%s-%d", id, time.Now().UnixNano())
-	sessionNum := 1
+	tabID, displayName := w.newTabName(id, hostname)
 
-	// Count existing sessions for this worker
-	for existingID := range w.tabs {
-		if strings.HasPrefix(existingID, id+"-") {
-			sessionNum++
+	// Create terminal content; the tab title shows how long a command has been running
+	var tab *container.TabItem
+	terminalContent := createTerminalUI(hostname, ip, onCommand, func() {
+		w.RemoveTab(tabID)
+	}, func(elapsed time.Duration, running bool) {
+		tab.Text = displayName
+		if running {
+			tab.Text = fmt.Sprintf("%s ⏱ %s", displayName, elapsed.Round(time.Second))
 		}
-	}
+		w.tabBar.Refresh()
+	}, actions, tools, w.window)
 
-	// Create display name with session number if multiple
-	displayName := hostname
-	if sessionNum > 1 {
-		displayName = fmt.Sprintf("%s (%d)", hostname, sessionNum)
+	// Create tab
+	tab = container.NewTabItem(displayName, terminalContent)
+	w.tabBar.Append(tab)
+	w.tabBar.Select(tab)
+
+	// Store tab info
+	w.tabs[tabID] = &SSHTab{
+		ID:       tabID,
+		Hostname: displayName,
+		IP:       ip,
+		item:     tab,
+		onClose:  onceFunc(onClose),
 	}
 
-	// Create terminal content
-	terminalContent := createTerminalUI(hostname, ip, onCommand, func() {
+	// Update window content
+	w.window.SetContent(w.tabBar)
+}
+
+// SetSplitSupport lets shell tabs be split into panes showing other workers: targets
+// lists the workers to offer and open connects a pane's shell
+func (w *SSHTerminalWindow) SetSplitSupport(targets func() []SplitTarget, open PaneOpener) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.splitTargets = targets
+	w.openPane = open
+}
+
+// AddShellTab adds a tab running an interactive shell in a terminal emulator.
+// onClose is called once when the tab or window is closed and should end the session;
+// actions adds buttons for the worker's other tools to the header. The tab can be split
+// into more panes if SetSplitSupport was called.
+func (w *SSHTerminalWindow) AddShellTab(id, hostname, ip string, shell *network.ShellSession, onClose func(), actions TerminalActions) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	tabID, displayName := w.newTabName(id, hostname)
+
+	var tab *container.TabItem
+	group := newShellPaneGroup(func() {
 		w.RemoveTab(tabID)
+	}, func(term *TerminalWidget) {
+		w.focusMu.Lock()
+		w.shellFocus[tab] = term
+		w.focusMu.Unlock()
 	})
+	term := group.add(ShellPane{Hostname: hostname, IP: ip, Shell: shell, OnClose: onClose, Actions: actions})
+
+	var onSplit func()
+	if w.openPane != nil {
+		targets, open := w.splitTargets, w.openPane
+		onSplit = func() {
+			showSplitDialog(w.window, targets(), open, func(pane ShellPane) {
+				if !w.HasTab(tabID) {
+					pane.OnClose()
+					return
+				}
+				w.window.Canvas().Focus(group.add(pane))
+			})
+		}
+	}
 
-	// Create tab
-	tab := container.NewTabItem(displayName, terminalContent)
+	tab = container.NewTabItem(displayName, group.content(onSplit))
+	w.focusMu.Lock()
+	w.shellFocus[tab] = term
+	w.focusMu.Unlock()
 	w.tabBar.Append(tab)
+	w.tabs[tabID] = &SSHTab{
+		ID:       tabID,
+		Hostname: displayName,
+		IP:       ip,
+		Shell:    term,
+		item:     tab,
+		onClose:  group.closeAll,
+	}
+
+	w.window.SetContent(w.tabBar)
 	w.tabBar.Select(tab)
+	w.window.Canvas().Focus(term)
+}
 
-	// Store tab info
+// AddFilesTab adds a file browser tab for a worker next to its terminal tabs.
+// onClose is called once when the tab or window is closed and should end the SFTP session.
+func (w *SSHTerminalWindow) AddFilesTab(id, hostname, ip string, files *network.RemoteFiles, onClose func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	tabID := fmt.Sprintf("files:%s-%d", id, time.Now().UnixNano())
+	displayName := "Files: " + hostname
+
+	browser := NewFileBrowser(files, w.window)
+	header := newTerminalHeader(hostname, ip, func() {
+		w.RemoveTab(tabID)
+	}, TerminalActions{})
+	content := container.NewBorder(container.NewVBox(header, widget.NewSeparator()), nil, nil, nil, browser.Content())
+
+	tab := container.NewTabItem(displayName, content)
+	w.tabBar.Append(tab)
 	w.tabs[tabID] = &SSHTab{
 		ID:       tabID,
 		Hostname: displayName,
 		IP:       ip,
+		Files:    browser,
+		item:     tab,
+		onClose:  onceFunc(onClose),
+	}
+
+	w.window.SetContent(w.tabBar)
+	w.tabBar.Select(tab)
+}
+
+// AddTunnelsTab adds a tab managing port forwards for a worker next to its terminal tabs.
+// onClose is called once when the tab or window is closed, after the panel's tunnels stopped.
+func (w *SSHTerminalWindow) AddTunnelsTab(id, hostname, ip string, client *network.SSHClient, specs []network.TunnelSpec, onSave func([]network.TunnelSpec), onClose func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	tabID := fmt.Sprintf("tunnels:%s-%d", id, time.Now().UnixNano())
+	displayName := "Tunnels: " + hostname
+
+	panel := NewTunnelsPanel(client, specs, w.window, onSave)
+	header := newTerminalHeader(hostname, ip, func() {
+		w.RemoveTab(tabID)
+	}, TerminalActions{})
+	content := container.NewBorder(container.NewVBox(header, widget.NewSeparator()), nil, nil, nil, panel.Content())
+
+	tab := container.NewTabItem(displayName, content)
+	w.tabBar.Append(tab)
+	w.tabs[tabID] = &SSHTab{
+		ID:       tabID,
+		Hostname: displayName,
+		IP:       ip,
+		item:     tab,
+		onClose: onceFunc(func() {
+			panel.Close()
+			if onClose != nil {
+				onClose()
+			}
+		}),
+	}
+
+	w.window.SetContent(w.tabBar)
+	w.tabBar.Select(tab)
+}
+
+// AddAuditTab adds a tab showing a worker's SSH audit log and session recordings.
+// onClose is called once when the tab or window is closed.
+func (w *SSHTerminalWindow) AddAuditTab(id, hostname, ip string, client *network.SSHClient, onClose func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	tabID := fmt.Sprintf("audit:%s-%d", id, time.Now().UnixNano())
+	displayName := "Audit: " + hostname
+
+	viewer := NewAuditViewer(client, w.window)
+	header := newTerminalHeader(hostname, ip, func() {
+		w.RemoveTab(tabID)
+	}, TerminalActions{})
+	content := container.NewBorder(container.NewVBox(header, widget.NewSeparator()), nil, nil, nil, viewer.Content())
+
+	tab := container.NewTabItem(displayName, content)
+	w.tabBar.Append(tab)
+	w.tabs[tabID] = &SSHTab{
+		ID:       tabID,
+		Hostname: displayName,
+		IP:       ip,
+		item:     tab,
+		onClose:  onceFunc(onClose),
+	}
+
+	w.window.SetContent(w.tabBar)
+	w.tabBar.Select(tab)
+}
+
+// AddUsersTab adds a tab managing a worker's SSH accounts over an existing connection.
+// onClose is called once when the tab or window is closed.
+func (w *SSHTerminalWindow) AddUsersTab(id, hostname, ip string, client *network.SSHClient, onClose func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	tabID := fmt.Sprintf("users:%s-%d", id, time.Now().UnixNano())
+	displayName := "Users: " + hostname
+
+	panel := NewSSHUsersPanel(client.RemoteUsers(), w.window)
+	header := newTerminalHeader(hostname, ip, func() {
+		w.RemoveTab(tabID)
+	}, TerminalActions{})
+	content := container.NewBorder(container.NewVBox(header, widget.NewSeparator()), nil, nil, nil, panel.Content())
+
+	tab := container.NewTabItem(displayName, content)
+	w.tabBar.Append(tab)
+	w.tabs[tabID] = &SSHTab{
+		ID:       tabID,
+		Hostname: displayName,
+		IP:       ip,
+		item:     tab,
+		onClose:  onceFunc(onClose),
 	}
 
-	// Update window content
 	w.window.SetContent(w.tabBar)
+	w.tabBar.Select(tab)
+}
+
+// AddSessionsTab adds a tab listing a worker's detachable shell sessions over an existing
+// connection. onAttach and onNew open shell tabs; onClose is called once when the tab
+// or window is closed.
+func (w *SSHTerminalWindow) AddSessionsTab(id, hostname, ip string, client *network.SSHClient, onAttach func(sessionID string), onNew func(), onClose func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	tabID := fmt.Sprintf("sessions:%s-%d", id, time.Now().UnixNano())
+	displayName := "Sessions: " + hostname
+
+	panel := NewSessionsPanel(client, w.window, onAttach, onNew)
+	header := newTerminalHeader(hostname, ip, func() {
+		w.RemoveTab(tabID)
+	}, TerminalActions{})
+	content := container.NewBorder(container.NewVBox(header, widget.NewSeparator()), nil, nil, nil, panel.Content())
+
+	tab := container.NewTabItem(displayName, content)
+	w.tabBar.Append(tab)
+	w.tabs[tabID] = &SSHTab{
+		ID:       tabID,
+		Hostname: displayName,
+		IP:       ip,
+		item:     tab,
+		onClose:  onceFunc(onClose),
+	}
+
+	w.window.SetContent(w.tabBar)
+	w.tabBar.Select(tab)
+}
+
+// onceFunc wraps an optional callback so it runs at most once
+func onceFunc(fn func()) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if fn != nil {
+				fn()
+			}
+		})
+	}
+}
+
+// newTabName returns a unique tab ID and the display name for a new session; w.mu must be held
+func (w *SSHTerminalWindow) newTabName(id, hostname string) (string, string) {
+	// Generate unique tab ID with timestamp to allow multiple tabs per worker
+	tabID := fmt.Sprintf("%s-%d", id, time.Now().UnixNano())
+	sessionNum := 1
+
+	// Count existing sessions for this worker
+	for existingID := range w.tabs {
+		if strings.HasPrefix(existingID, id+"-") {
+			sessionNum++
+		}
+	}
+
+	// Create display name with session number if multiple
+	displayName := hostname
+	if sessionNum > 1 {
+		displayName = fmt.Sprintf("%s (%d)", hostname, sessionNum)
+	}
+	return tabID, displayName
 }
 
 // RemoveTab removes an SSH tab
 func (w *SSHTerminalWindow) RemoveTab(id string) {
 	w.mu.Lock()
-	defer w.mu.Unlock()
 
 	tab, exists := w.tabs[id]
 	if !exists {
+		w.mu.Unlock()
 		return
 	}
 
 	// Find and remove the tab
 	for i, item := range w.tabBar.Items {
-		if item.Text == tab.Hostname {
+		if item == tab.item {
 			w.tabBar.Remove(item)
 			if len(w.tabBar.Items) > 0 && i > 0 {
 				w.tabBar.SelectIndex(i - 1)
@@ -264,9 +582,18 @@ func (w *SSHTerminalWindow) RemoveTab(id string) {
 	}
 
 	delete(w.tabs, id)
+	empty := len(w.tabs) == 0
+	w.focusMu.Lock()
+	delete(w.shellFocus, tab.item)
+	w.focusMu.Unlock()
+	w.mu.Unlock()
+
+	if tab.onClose != nil {
+		tab.onClose()
+	}
 
 	// If no tabs left, close the window
-	if len(w.tabs) == 0 {
+	if empty {
 		w.window.Close()
 	}
 }
@@ -289,8 +616,9 @@ func (w *SSHTerminalWindow) HasTab(id string) bool {
 	return exists
 }
 
-// createTerminalUI creates the terminal-like UI for a single tab
-func createTerminalUI(hostname, ip string, onCommand func(string) string, onClose func()) fyne.CanvasObject {
+// createTerminalUI creates the terminal-like UI for a single tab. onRunning is called
+// on the main thread every second while a command runs, and once when it ends.
+func createTerminalUI(hostname, ip string, onCommand CommandStarter, onClose func(), onRunning func(elapsed time.Duration, running bool), actions TerminalActions, tools CommandTools, window fyne.Window) fyne.CanvasObject {
 	// Output area - custom rich text display with color support
 	outputText := widget.NewRichText()
 	outputText.Wrapping = fyne.TextWrapWord
@@ -298,17 +626,42 @@ func createTerminalUI(hostname, ip string, onCommand func(string) string, onClos
 	outputScroll := container.NewVScroll(outputText)
 	outputScroll.SetMinSize(fyne.NewSize(750, 350))
 
-	// Command history
-	var history []string
-	historyIndex := 0
-	_ = historyIndex // Used in future for up/down arrow navigation
+	// Command history, navigated with up/down; draft keeps what was typed before
+	// navigating. Only used on the main thread.
+	history := append([]string(nil), tools.History...)
+	historyIndex := len(history)
+	draft := ""
+
+	// Exit status of the last command shown in the prompt ("1", "KILL", "!" for a
+	// failed connection); empty after a success. Only used on the main thread.
+	promptStatus := ""
 
 	// Output lines storage (raw text with ANSI codes stripped for copy)
 	var outputSegments []widget.RichTextSegment
 	var outputMu sync.Mutex
 
+	// Everything shown after the welcome banner, for saving and logging
+	var tr *transcript
+
+	// Search in the output (Ctrl+F): matches of findQuery are highlighted and the
+	// findIndex-th is scrolled to when findScroll is set. Only used on the main thread.
+	findQuery, findIndex, findScroll := "", 0, false
+	findLabel := widget.NewLabel("")
+
+	// Streamed output redraws at most every outputRefreshInterval
+	var updatePending atomic.Bool
+	var updateOutput func()
+	scheduleUpdate := func() {
+		if updatePending.CompareAndSwap(false, true) {
+			time.AfterFunc(outputRefreshInterval, func() {
+				updatePending.Store(false)
+				updateOutput()
+			})
+		}
+	}
+
 	// Function to update output display
-	updateOutput := func() {
+	updateOutput = func() {
 		if app := fyne.CurrentApp(); app != nil {
 			if drv := app.Driver(); drv != nil {
 				drv.DoFromGoroutine(func() {
@@ -317,9 +670,29 @@ func createTerminalUI(hostname, ip string, onCommand func(string) string, onClos
 					copy(segs, outputSegments)
 					outputMu.Unlock()
 
-					outputText.Segments = segs
+					if findQuery == "" {
+						outputText.Segments = segs
+						outputText.Refresh()
+						outputScroll.ScrollToBottom()
+						return
+					}
+
+					// While searching the view stays where the current match is
+					var found outputMatches
+					if found = findMatches(segs, findQuery, findIndex); found.count > 0 {
+						findIndex = found.current
+						findLabel.SetText(fmt.Sprintf("%d of %d", findIndex+1, found.count))
+					} else {
+						findLabel.SetText("No matches")
+					}
+					outputText.Segments = found.segments
 					outputText.Refresh()
-					outputScroll.ScrollToBottom()
+					if findScroll && found.count > 0 {
+						findScroll = false
+						height := outputText.MinSize().Height
+						y := height*float32(found.line)/float32(max(found.lines, 1)) - outputScroll.Size().Height/2
+						outputScroll.ScrollToOffset(fyne.NewPos(0, max(0, min(y, height-outputScroll.Size().Height))))
+					}
 				}, false)
 				return
 			}
@@ -331,6 +704,16 @@ func createTerminalUI(hostname, ip string, onCommand func(string) string, onClos
 		outputMu.Lock()
 		defer outputMu.Unlock()
 
+		switch {
+		case isPrompt:
+			_, cmd, _ := strings.Cut(text, "$ ")
+			tr.add(transcriptCommand, cmd)
+		case isError:
+			tr.add(transcriptError, text)
+		default:
+			tr.add(transcriptOutput, text)
+		}
+
 		if isPrompt {
 			// Prompt line: [hostname] $ command
 			outputSegments = append(outputSegments, &widget.TextSegment{
@@ -348,7 +731,25 @@ func createTerminalUI(hostname, ip string, onCommand func(string) string, onClos
 				},
 			})
 			outputSegments = append(outputSegments, &widget.TextSegment{
-				Text: "] $ ",
+				Text: "]",
+				Style: widget.RichTextStyle{
+					Inline:    true,
+					TextStyle: fyne.TextStyle{Monospace: true},
+				},
+			})
+			// Status of the previous command, if it failed
+			if promptStatus != "" {
+				outputSegments = append(outputSegments, &widget.TextSegment{
+					Text: " " + promptStatus,
+					Style: widget.RichTextStyle{
+						Inline:    true,
+						ColorName: theme.ColorNameError,
+						TextStyle: fyne.TextStyle{Monospace: true, Bold: true},
+					},
+				})
+			}
+			outputSegments = append(outputSegments, &widget.TextSegment{
+				Text: " $ ",
 				Style: widget.RichTextStyle{
 					Inline:    true,
 					TextStyle: fyne.TextStyle{Monospace: true},
@@ -371,21 +772,42 @@ func createTerminalUI(hostname, ip string, onCommand func(string) string, onClos
 				})
 			}
 		} else {
-			// Regular output line
-			style := fyne.TextStyle{Monospace: true}
+			// Regular output line; stderr is shown in the error color
+			style := widget.RichTextStyle{
+				Inline:    true,
+				TextStyle: fyne.TextStyle{Monospace: true},
+			}
 			if isError {
-				style.Bold = true
+				style.ColorName = theme.ColorNameError
 			}
 			outputSegments = append(outputSegments, &widget.TextSegment{
-				Text: text + "\n",
-				Style: widget.RichTextStyle{
-					Inline:    true,
-					TextStyle: style,
-				},
+				Text:  text + "\n",
+				Style: style,
 			})
 		}
 	}
 
+	// addStatus shows how a command ended and how long it took, e.g. "✗ exit 1 · 230ms"
+	addStatus := func(result network.ExecResult) {
+		outputMu.Lock()
+		defer outputMu.Unlock()
+
+		mark, colorName, kind := "✓", theme.ColorNameSuccess, transcriptSuccess
+		if !result.Success() {
+			mark, colorName, kind = "✗", theme.ColorNameError, transcriptFailure
+		}
+		status := fmt.Sprintf("%s %s · %s", mark, result.Status(), result.Duration.Round(time.Millisecond))
+		tr.add(kind, status)
+		outputSegments = append(outputSegments, &widget.TextSegment{
+			Text: status + "\n",
+			Style: widget.RichTextStyle{
+				Inline:    true,
+				ColorName: colorName,
+				TextStyle: fyne.TextStyle{Monospace: true, Italic: true},
+			},
+		})
+	}
+
 	// Add welcome message with styling
 	addLine("╔══════════════════════════════════════════════════════════════╗", false, false)
 	addLine(fmt.Sprintf("║  SSH Session: %s", padRight(hostname+" ("+ip+")", 48)+"║"), false, false)
@@ -395,9 +817,42 @@ func createTerminalUI(hostname, ip string, onCommand func(string) string, onClos
 	addLine("╚══════════════════════════════════════════════════════════════╝", false, false)
 	addLine("", false, false)
 	updateOutput()
-
-	// Command input - custom styled
-	cmdEntry := widget.NewEntry()
+	tr = newTranscript(hostname, ip, tools.TranscriptLog)
+
+	// Set once the prompt label exists
+	var setPromptStatus func(result network.ExecResult, err error)
+
+	// The command currently running, if any; only used on the main thread
+	var running *network.RunningCommand
+	runningLabel := widget.NewLabel("")
+	runningLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	runningLabel.Importance = widget.LowImportance
+	cancelBtn := widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), func() {
+		if running != nil {
+			addLine("^C (canceled)", false, true)
+			updateOutput()
+			go running.Cancel()
+		}
+	})
+	cancelBtn.Importance = widget.DangerImportance
+	cancelBtn.Hide()
+
+	// Command input - custom styled; Ctrl+C interrupts the running command
+	cmdEntry := NewTerminalEntry()
+	cmdEntry.onInterrupt = func() bool {
+		if running == nil {
+			return false
+		}
+		addLine("^C", false, true)
+		updateOutput()
+		r := running
+		go func() {
+			if err := r.Interrupt(); err != nil {
+				log.Printf("SSH Client: Failed to interrupt command: %v\n", err)
+			}
+		}()
+		return true
+	}
 	cmdEntry.SetPlaceHolder("Type command and press Enter...")
 
 	// Execute command function
@@ -407,9 +862,20 @@ func createTerminalUI(hostname, ip string, onCommand func(string) string, onClos
 			return
 		}
 
+		// One command at a time; the input is kept for when this one ends
+		if running != nil {
+			addLine("A command is still running; press Ctrl+C or Cancel to stop it.", false, true)
+			updateOutput()
+			return
+		}
+
 		// Add to history
-		history = append(history, cmd)
+		history = network.AppendHistory(history, cmd)
 		historyIndex = len(history)
+		draft = ""
+		if tools.OnHistory != nil {
+			tools.OnHistory(cmd)
+		}
 
 		// Show command in output with prompt styling
 		addLine(fmt.Sprintf("[%s] $ %s", hostname, cmd), true, false)
@@ -447,42 +913,284 @@ func createTerminalUI(hostname, ip string, onCommand func(string) string, onClos
 			return
 		}
 
-		// Execute on remote
+		// Execute on remote, streaming output line by line as it arrives
 		if onCommand != nil {
-			// Show loading indicator
-			addLine("Executing...", false, false)
+			streams := newOutputStreams(func(line string, isError bool) {
+				addLine(line, false, isError)
+				scheduleUpdate()
+			})
+			r, err := onCommand(cmd, streams.write)
+			if err != nil {
+				addLine(fmt.Sprintf("Error: %v", err), false, true)
+				addLine("", false, false)
+				updateOutput()
+				setPromptStatus(network.ExecResult{}, err)
+				return
+			}
+			running = r
+			cmdEntry.SetPlaceHolder("Running... Ctrl+C interrupts")
+			cancelBtn.Show()
 			updateOutput()
 
 			go func() {
-				result := onCommand(cmd)
-
-				// Remove "Executing..." line
-				outputMu.Lock()
-				if len(outputSegments) > 0 {
-					outputSegments = outputSegments[:len(outputSegments)-1]
-				}
-				outputMu.Unlock()
-
-				if result != "" {
-					result = stripANSI(result)
-					lines := strings.Split(strings.TrimRight(result, "\n\r"), "\n")
-					for _, line := range lines {
-						isErr := strings.Contains(strings.ToLower(line), "error") ||
-							strings.Contains(strings.ToLower(line), "failed")
-						addLine(line, false, isErr)
+				ticker := time.NewTicker(time.Second)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ticker.C:
+						elapsed := r.Elapsed()
+						runOnMainThread(func() {
+							runningLabel.SetText("⏱ " + elapsed.Round(time.Second).String())
+							if onRunning != nil {
+								onRunning(elapsed, true)
+							}
+						})
+						continue
+					case <-r.Done():
 					}
+					break
+				}
+
+				result, err := r.Wait()
+				streams.flush()
+				if err != nil {
+					addLine(fmt.Sprintf("Error: %v", err), false, true)
+				} else {
+					addStatus(result)
 				}
 				addLine("", false, false)
 				updateOutput()
+				setPromptStatus(result, err)
+				runOnMainThread(func() {
+					running = nil
+					runningLabel.SetText("")
+					cancelBtn.Hide()
+					cmdEntry.SetPlaceHolder("Type command and press Enter...")
+					if onRunning != nil {
+						onRunning(result.Duration, false)
+					}
+				})
+			}()
+		}
+	}
+
+	// setInput replaces the command being typed, with the cursor at its end
+	setInput := func(text string) {
+		cmdEntry.SetText(text)
+		cmdEntry.CursorColumn = len([]rune(text))
+		cmdEntry.Refresh()
+	}
+
+	// Reverse history search (Ctrl+R): while searching the entry holds the query and
+	// searchLabel the newest matching command older than searchFrom
+	searching := false
+	searchFrom, searchMatch := 0, -1
+	searchLabel := widget.NewLabel("")
+	searchLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	searchLabel.Hide()
+
+	findMatch := func() {
+		query := cmdEntry.Text
+		searchMatch = -1
+		for i := searchFrom - 1; i >= 0; i-- {
+			if strings.Contains(history[i], query) {
+				searchMatch = i
+				break
+			}
+		}
+		if searchMatch < 0 {
+			searchLabel.SetText(fmt.Sprintf("(failed reverse-i-search) `%s'", query))
+			return
+		}
+		searchLabel.SetText(fmt.Sprintf("(reverse-i-search) `%s': %s", query, history[searchMatch]))
+	}
+	// endSearch leaves search mode with the matched command, or what was typed before
+	endSearch := func(accept bool) {
+		searching = false
+		searchLabel.Hide()
+		text := draft
+		if accept && searchMatch >= 0 {
+			text, historyIndex = history[searchMatch], searchMatch
+		}
+		setInput(text)
+	}
+	cmdEntry.onSearch = func() {
+		if !searching {
+			searching = true
+			draft = cmdEntry.Text
+			searchFrom = len(history)
+			setInput("")
+			searchLabel.Show()
+		} else if searchMatch >= 0 {
+			// Ctrl+R again finds the next older match
+			searchFrom = searchMatch
+		}
+		findMatch()
+	}
+	cmdEntry.OnChanged = func(string) {
+		if searching {
+			searchFrom = len(history)
+			findMatch()
+		}
+	}
+	// Up/down walk through the history
+	cmdEntry.onKeyUp = func() {
+		if searching {
+			endSearch(true)
+			return
+		}
+		if historyIndex == 0 {
+			return
+		}
+		if historyIndex == len(history) {
+			draft = cmdEntry.Text
+		}
+		historyIndex--
+		setInput(history[historyIndex])
+	}
+	cmdEntry.onKeyDown = func() {
+		if searching {
+			endSearch(true)
+			return
+		}
+		if historyIndex >= len(history) {
+			return
+		}
+		historyIndex++
+		if historyIndex == len(history) {
+			setInput(draft)
+		} else {
+			setInput(history[historyIndex])
+		}
+	}
+
+	// Tab completes the last word on the worker: a unique candidate is taken, several
+	// are completed as far as they agree and listed when they don't
+	if tools.Complete != nil {
+		cmdEntry.onTab = func() {
+			if searching {
+				endSearch(true)
+				return
+			}
+			line := cmdEntry.Text
+			if strings.TrimSpace(line) == "" {
+				return
+			}
+			go func() {
+				completion, err := tools.Complete(line)
+				runOnMainThread(func() {
+					if err != nil {
+						addLine(fmt.Sprintf("Completion failed: %v", err), false, true)
+						updateOutput()
+						return
+					}
+					if cmdEntry.Text != line || !strings.HasSuffix(line, completion.Word) {
+						return // Typed on meanwhile
+					}
+					base := line[:len(line)-len(completion.Word)]
+					switch len(completion.Candidates) {
+					case 0:
+					case 1:
+						candidate := completion.Candidates[0]
+						if !strings.HasSuffix(candidate, "/") && !strings.HasSuffix(candidate, `\`) {
+							candidate += " "
+						}
+						setInput(base + candidate)
+					default:
+						if prefix := completion.CommonPrefix(); len(prefix) > len(completion.Word) {
+							setInput(base + prefix)
+							return
+						}
+						addLine(strings.Join(completion.Candidates, "  "), false, false)
+						updateOutput()
+					}
+				})
 			}()
 		}
 	}
 
-	// Handle Enter key
+	// Search bar above the output
+	findEntry := widget.NewEntry()
+	findEntry.SetPlaceHolder("Search output")
+	findEntry.OnChanged = func(query string) {
+		findQuery, findIndex, findScroll = query, 0, true
+		if query == "" {
+			findLabel.SetText("")
+		}
+		updateOutput()
+	}
+	findStep := func(delta int) {
+		if findQuery != "" {
+			findIndex += delta
+			findScroll = true
+			updateOutput()
+		}
+	}
+	findEntry.OnSubmitted = func(string) { findStep(1) }
+	prevBtn := widget.NewButtonWithIcon("", theme.MoveUpIcon(), func() { findStep(-1) })
+	nextBtn := widget.NewButtonWithIcon("", theme.MoveDownIcon(), func() { findStep(1) })
+	var findBar *fyne.Container
+	closeFind := func() {
+		findBar.Hide()
+		findEntry.SetText("")
+		window.Canvas().Focus(cmdEntry)
+	}
+	closeFindBtn := widget.NewButtonWithIcon("", theme.CancelIcon(), closeFind)
+	closeFindBtn.Importance = widget.LowImportance
+	findBar = container.NewBorder(nil, nil,
+		widget.NewIcon(theme.SearchIcon()),
+		container.NewHBox(findLabel, prevBtn, nextBtn, closeFindBtn),
+		findEntry,
+	)
+	findBar.Hide()
+	openFind := func() {
+		findBar.Show()
+		window.Canvas().Focus(findEntry)
+	}
+	cmdEntry.onFind = openFind
+	cmdEntry.onEscape = func() {
+		switch {
+		case searching:
+			endSearch(false)
+		case findBar.Visible():
+			closeFind()
+		}
+	}
+
+	findBtn := widget.NewButtonWithIcon("", theme.SearchIcon(), openFind)
+	findBtn.Importance = widget.LowImportance
+	saveBtn := widget.NewButtonWithIcon("", theme.DocumentSaveIcon(), func() {
+		showSaveTranscript(tr, window)
+	})
+	saveBtn.Importance = widget.LowImportance
+
+	// Handle Enter key; in a search it picks the match for editing
 	cmdEntry.OnSubmitted = func(s string) {
+		if searching {
+			endSearch(true)
+			return
+		}
 		executeCmd()
 	}
 
+	// Snippets are inserted for editing or run right away
+	var snippetsBtn *widget.Button
+	if tools.Snippets != nil {
+		snippetsBtn = widget.NewButtonWithIcon("Snippets", theme.DocumentIcon(), func() {
+			ShowSnippetsDialog(tools.Snippets(), window, tools.OnSaveSnippets, func(command string, run bool) {
+				if searching {
+					endSearch(false)
+				}
+				setInput(command)
+				if run {
+					executeCmd()
+				} else {
+					window.Canvas().Focus(cmdEntry)
+				}
+			})
+		})
+	}
+
 	// Background
 	bg := canvas.NewRectangle(termBgColor)
 
@@ -495,18 +1203,155 @@ func createTerminalUI(hostname, ip string, onCommand func(string) string, onClos
 	promptLabel.TextStyle = fyne.TextStyle{Monospace: true, Bold: true}
 	promptLabel.TextSize = 14
 
+	// The prompt shows the last command's exit status when it failed, like $? in a shell prompt
+	setPromptStatus = func(result network.ExecResult, err error) {
+		runOnMainThread(func() {
+			switch {
+			case err != nil:
+				promptStatus = "!"
+			case result.Canceled:
+				promptStatus = "^C"
+			case result.Signal != "":
+				promptStatus = result.Signal
+			case !result.Success():
+				promptStatus = strconv.Itoa(result.ExitCode)
+			default:
+				promptStatus = ""
+			}
+			if promptStatus != "" {
+				promptLabel.Text = fmt.Sprintf("[%s] %s $", hostname, promptStatus)
+				promptLabel.Color = termErrorColor
+			} else {
+				promptLabel.Text = fmt.Sprintf("[%s] $", hostname)
+				promptLabel.Color = termPromptColor
+			}
+			promptLabel.Refresh()
+		})
+	}
+
 	// Input row with styled background
+	inputRight := container.NewHBox(runningLabel, cancelBtn)
+	if snippetsBtn != nil {
+		inputRight.Add(snippetsBtn)
+	}
+	inputRight.Add(findBtn)
+	inputRight.Add(saveBtn)
 	inputRow := container.NewBorder(nil, nil,
 		container.NewPadded(promptLabel),
-		nil,
+		inputRight,
 		cmdEntry,
 	)
 
 	inputContainer := container.NewStack(
 		inputBg,
-		container.NewPadded(inputRow),
+		container.NewPadded(container.NewVBox(searchLabel, inputRow)),
+	)
+
+	header := newTerminalHeader(hostname, ip, onClose, actions)
+
+	// Output container with border effect
+	outputBorder := canvas.NewRectangle(termBorderColor)
+	outputBorder.CornerRadius = 4
+	outputBorder.StrokeWidth = 1
+	outputBorder.StrokeColor = termBorderColor
+
+	outputContainer := container.NewStack(
+		outputBorder,
+		container.NewPadded(outputScroll),
 	)
 
+	// Main layout with spacing
+	mainContent := container.NewBorder(
+		container.NewVBox(header, widget.NewSeparator(), findBar),
+		container.NewVBox(widget.NewSeparator(), inputContainer),
+		nil, nil,
+		outputContainer,
+	)
+
+	return container.NewStack(bg, container.NewPadded(mainContent))
+}
+
+// createShellTerminalUI creates the terminal emulator UI for an interactive shell tab.
+// Shell output is fed to the emulator until the shell exits; closeSession ends the session.
+func createShellTerminalUI(hostname, ip string, shell *network.ShellSession, closeSession, onClose func(), actions TerminalActions) (*TerminalWidget, fyne.CanvasObject) {
+	// Keystrokes are written from a goroutine so a slow connection never blocks the UI
+	input := make(chan []byte, 256)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case p := <-input:
+				if _, err := shell.Write(p); err != nil {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	term := NewTerminalWidget(func(p []byte) {
+		select {
+		case input <- append([]byte(nil), p...):
+		case <-done:
+		}
+	})
+	term.SetOnResize(func(cols, rows int) {
+		go func() {
+			if err := shell.Resize(cols, rows); err != nil {
+				log.Printf("SSH Client: Failed to resize terminal: %v\n", err)
+			}
+		}()
+	})
+
+	go func() {
+		defer close(done)
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := shell.Read(buf)
+			if n > 0 {
+				term.Write(buf[:n])
+			}
+			if err != nil {
+				break
+			}
+		}
+		status := "Session closed"
+		if err := shell.Wait(); err != nil {
+			status = fmt.Sprintf("Session closed: %v", err)
+		}
+		term.Write([]byte("\r\n\x1b[2m[" + status + "]\x1b[0m\r\n"))
+		closeSession()
+	}()
+
+	if !shell.HasPTY {
+		term.Write([]byte("\x1b[2m[The worker has no PTY support; running a plain shell]\x1b[0m\r\n"))
+	}
+	if shell.SessionID != "" {
+		term.Write([]byte("\x1b[2m[Detachable session " + shell.SessionID + ": closing this tab detaches; the shell keeps running]\x1b[0m\r\n"))
+	}
+
+	// Scrolls rather than keeping narrow split panes from shrinking
+	header := container.NewHScroll(newTerminalHeader(hostname, ip, onClose, actions))
+
+	outputBorder := canvas.NewRectangle(termBorderColor)
+	outputBorder.CornerRadius = 4
+	outputBorder.StrokeWidth = 1
+	outputBorder.StrokeColor = termBorderColor
+
+	mainContent := container.NewBorder(
+		container.NewVBox(header, widget.NewSeparator()),
+		nil, nil, nil,
+		container.NewStack(outputBorder, container.NewPadded(term)),
+	)
+
+	bg := canvas.NewRectangle(termBgColor)
+	return term, container.NewStack(bg, container.NewPadded(mainContent))
+}
+
+// newTerminalHeader creates the header bar of a terminal tab with the host and a close button,
+// plus a button for each set action
+func newTerminalHeader(hostname, ip string, onClose func(), actions TerminalActions) fyne.CanvasObject {
 	// Close button - styled
 	closeBtn := widget.NewButton("✕ Close", func() {
 		if onClose != nil {
@@ -541,30 +1386,144 @@ func createTerminalUI(hostname, ip string, onCommand func(string) string, onClos
 	headerRight := container.NewHBox(
 		closeBtn,
 	)
+	if actions.OnSessions != nil {
+		sessionsBtn := widget.NewButtonWithIcon("Sessions", theme.StorageIcon(), actions.OnSessions)
+		headerRight.Objects = append([]fyne.CanvasObject{sessionsBtn}, headerRight.Objects...)
+	}
+	if actions.OnUsers != nil {
+		usersBtn := widget.NewButtonWithIcon("Users", theme.AccountIcon(), actions.OnUsers)
+		headerRight.Objects = append([]fyne.CanvasObject{usersBtn}, headerRight.Objects...)
+	}
+	if actions.OnAudit != nil {
+		auditBtn := widget.NewButtonWithIcon("Audit", theme.HistoryIcon(), actions.OnAudit)
+		headerRight.Objects = append([]fyne.CanvasObject{auditBtn}, headerRight.Objects...)
+	}
+	if actions.OnTunnels != nil {
+		tunnelsBtn := widget.NewButtonWithIcon("Tunnels", theme.MailForwardIcon(), actions.OnTunnels)
+		headerRight.Objects = append([]fyne.CanvasObject{tunnelsBtn}, headerRight.Objects...)
+	}
+	if actions.OnFiles != nil {
+		filesBtn := widget.NewButtonWithIcon("Files", theme.FolderIcon(), actions.OnFiles)
+		headerRight.Objects = append([]fyne.CanvasObject{filesBtn}, headerRight.Objects...)
+	}
 
 	headerContent := container.NewBorder(nil, nil, headerLeft, headerRight)
-	header := container.NewStack(headerBg, container.NewPadded(headerContent))
+	return container.NewStack(headerBg, container.NewPadded(headerContent))
+}
 
-	// Output container with border effect
-	outputBorder := canvas.NewRectangle(termBorderColor)
-	outputBorder.CornerRadius = 4
-	outputBorder.StrokeWidth = 1
-	outputBorder.StrokeColor = termBorderColor
+// outputRefreshInterval is how often streamed command output is redrawn at most
+const outputRefreshInterval = 50 * time.Millisecond
 
-	outputContainer := container.NewStack(
-		outputBorder,
-		container.NewPadded(outputScroll),
-	)
+// outputStreams splits a running command's stdout and stderr into lines as the
+// chunks arrive, keeping each stream's unfinished line until it is completed
+type outputStreams struct {
+	mu      sync.Mutex
+	partial [2]string // Indexed by stderr: 0 = stdout, 1 = stderr
+	onLine  func(line string, isError bool)
+}
 
-	// Main layout with spacing
-	mainContent := container.NewBorder(
-		container.NewVBox(header, widget.NewSeparator()),
-		container.NewVBox(widget.NewSeparator(), inputContainer),
-		nil, nil,
-		outputContainer,
-	)
+func newOutputStreams(onLine func(line string, isError bool)) *outputStreams {
+	return &outputStreams{onLine: onLine}
+}
 
-	return container.NewStack(bg, container.NewPadded(mainContent))
+// write takes a chunk of output from either stream
+func (o *outputStreams) write(data []byte, stderr bool) {
+	idx := 0
+	if stderr {
+		idx = 1
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	lines := strings.Split(o.partial[idx]+string(data), "\n")
+	o.partial[idx] = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		o.onLine(strings.TrimRight(stripANSI(line), "\r"), stderr)
+	}
+}
+
+// flush emits the unfinished lines once the command has ended
+func (o *outputStreams) flush() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for idx, line := range o.partial {
+		if line = strings.TrimRight(stripANSI(line), "\r"); line != "" {
+			o.onLine(line, idx == 1)
+		}
+		o.partial[idx] = ""
+	}
+}
+
+// outputMatches is the output with the matches of a search highlighted
+type outputMatches struct {
+	segments []widget.RichTextSegment
+	count    int
+	current  int // Index of the current match, wrapped into range
+	line     int // Line of the current match
+	lines    int // Lines of output
+}
+
+// findMatches highlights the case-insensitive matches of query in the output segments,
+// the current one (index, wrapped around) more strongly than the rest
+func findMatches(segs []widget.RichTextSegment, query string, index int) outputMatches {
+	// Count first so the index can be wrapped
+	lowerQuery := strings.ToLower(query)
+	count := 0
+	for _, seg := range segs {
+		if text, ok := seg.(*widget.TextSegment); ok {
+			if lower := strings.ToLower(text.Text); len(lower) == len(text.Text) {
+				count += strings.Count(lower, lowerQuery)
+			}
+		}
+	}
+	found := outputMatches{count: count}
+	if count > 0 {
+		found.current = ((index % count) + count) % count
+	}
+
+	n := 0
+	for _, seg := range segs {
+		text, ok := seg.(*widget.TextSegment)
+		lower := ""
+		if ok {
+			lower = strings.ToLower(text.Text)
+		}
+		// ToLower can change the length of some characters; such segments aren't split
+		if !ok || len(lower) != len(text.Text) || !strings.Contains(lower, lowerQuery) {
+			found.segments = append(found.segments, seg)
+			if ok {
+				found.lines += strings.Count(text.Text, "\n")
+			}
+			continue
+		}
+		rest, lowerRest := text.Text, lower
+		for {
+			i := strings.Index(lowerRest, lowerQuery)
+			if i < 0 {
+				break
+			}
+			before, match := rest[:i], rest[i:i+len(query)]
+			found.lines += strings.Count(before, "\n")
+			if before != "" {
+				found.segments = append(found.segments, &widget.TextSegment{Text: before, Style: text.Style})
+			}
+			style := text.Style
+			style.TextStyle.Bold = true
+			style.ColorName = theme.ColorNameWarning
+			if n == found.current {
+				style.ColorName = theme.ColorNamePrimary
+				style.TextStyle.Underline = true
+				found.line = found.lines
+			}
+			found.segments = append(found.segments, &widget.TextSegment{Text: match, Style: style})
+			n++
+			rest, lowerRest = rest[i+len(query):], lowerRest[i+len(query):]
+		}
+		found.lines += strings.Count(rest, "\n")
+		if rest != "" {
+			found.segments = append(found.segments, &widget.TextSegment{Text: rest, Style: text.Style})
+		}
+	}
+	return found
 }
 
 // stripANSI removes ANSI escape codes from a string
@@ -584,8 +1543,13 @@ func padRight(s string, length int) string {
 // TerminalEntry is a custom entry with key handling
 type TerminalEntry struct {
 	widget.Entry
-	onKeyUp   func()
-	onKeyDown func()
+	onKeyUp     func()
+	onKeyDown   func()
+	onTab       func()      // Tab; without it Tab moves the focus as usual
+	onEscape    func()      // Escape
+	onSearch    func()      // Ctrl+R
+	onFind      func()      // Ctrl+F
+	onInterrupt func() bool // Ctrl+C without a selection; returns false to copy as usual
 }
 
 func NewTerminalEntry() *TerminalEntry {
@@ -606,18 +1570,44 @@ func (e *TerminalEntry) TypedKey(key *fyne.KeyEvent) {
 			e.onKeyDown()
 			return
 		}
+	case fyne.KeyTab:
+		if e.onTab != nil {
+			e.onTab()
+			return
+		}
+	case fyne.KeyEscape:
+		if e.onEscape != nil {
+			e.onEscape()
+			return
+		}
 	}
 	e.Entry.TypedKey(key)
 }
 
-func (e *TerminalEntry) KeyDown(key *fyne.KeyEvent) {
-	if key.Name == fyne.KeyReturn || key.Name == fyne.KeyEnter {
-		if e.OnSubmitted != nil {
-			e.OnSubmitted(e.Text)
+// AcceptsTab keeps Tab in the entry when it completes commands
+func (e *TerminalEntry) AcceptsTab() bool {
+	return e.onTab != nil
+}
+
+// TypedShortcut turns Ctrl+C into an interrupt when nothing is selected, Ctrl+R into
+// a history search and Ctrl+F into a search of the output
+func (e *TerminalEntry) TypedShortcut(s fyne.Shortcut) {
+	if _, ok := s.(*fyne.ShortcutCopy); ok && e.onInterrupt != nil && e.SelectedText() == "" {
+		if e.onInterrupt() {
+			return
+		}
+	}
+	if c, ok := s.(*desktop.CustomShortcut); ok && (c.Modifier == fyne.KeyModifierControl || c.Modifier == fyne.KeyModifierShortcutDefault) {
+		switch {
+		case c.KeyName == fyne.KeyR && e.onSearch != nil:
+			e.onSearch()
+			return
+		case c.KeyName == fyne.KeyF && e.onFind != nil:
+			e.onFind()
+			return
 		}
-		return
 	}
-	e.Entry.KeyDown(key)
+	e.Entry.TypedShortcut(s)
 }
 
 // Ensure TerminalEntry implements desktop.Keyable
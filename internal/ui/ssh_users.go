@@ -0,0 +1,329 @@
+package ui
+
+import (
+	"adminadmin/internal/network"
+	"fmt"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// SSHUserStore holds SSH accounts: the worker's own database or, on the admin, a
+// worker's accounts managed over SSH
+type SSHUserStore interface {
+	List() ([]network.SSHUser, error)
+	Save(user network.SSHUser, password string) error
+	Delete(username string) error
+}
+
+// Labels of the command policies in the account form
+var sshPolicyLabels = []struct {
+	policy, label string
+}{
+	{network.PolicyShell, "Full shell"},
+	{network.PolicyExec, "Commands only (no interactive shell)"},
+	{network.PolicyAllowlist, "Allowed commands only"},
+}
+
+func sshPolicyLabel(policy string) string {
+	for _, p := range sshPolicyLabels {
+		if p.policy == policy {
+			return p.label
+		}
+	}
+	return policy
+}
+
+// SSHUsersPanel lists SSH accounts and lets the user add, edit and delete them
+type SSHUsersPanel struct {
+	store  SSHUserStore
+	window fyne.Window
+
+	mu    sync.Mutex
+	users []network.SSHUser
+
+	list        *widget.List
+	statusLabel *widget.Label
+}
+
+// NewSSHUsersPanel creates a panel for the accounts in store and starts loading them
+func NewSSHUsersPanel(store SSHUserStore, window fyne.Window) *SSHUsersPanel {
+	p := &SSHUsersPanel{
+		store:       store,
+		window:      window,
+		statusLabel: widget.NewLabel("Loading..."),
+	}
+
+	p.list = widget.NewList(
+		func() int {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			return len(p.users)
+		},
+		func() fyne.CanvasObject {
+			name := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			details := widget.NewLabel("")
+			details.Truncation = fyne.TextTruncateEllipsis
+			editBtn := widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), nil)
+			editBtn.Importance = widget.LowImportance
+			deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), nil)
+			deleteBtn.Importance = widget.LowImportance
+			return container.NewBorder(nil, nil, name, container.NewHBox(editBtn, deleteBtn), details)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			p.mu.Lock()
+			if id >= len(p.users) {
+				p.mu.Unlock()
+				return
+			}
+			user := p.users[id]
+			p.mu.Unlock()
+
+			row := obj.(*fyne.Container)
+			details := row.Objects[0].(*widget.Label)
+			name := row.Objects[1].(*widget.Label)
+			buttons := row.Objects[2].(*fyne.Container)
+			editBtn := buttons.Objects[0].(*widget.Button)
+			deleteBtn := buttons.Objects[1].(*widget.Button)
+
+			name.SetText(user.Username)
+			details.SetText(describeSSHUser(user))
+			editBtn.OnTapped = func() { p.showEditDialog(&user) }
+			deleteBtn.OnTapped = func() { p.confirmDelete(user.Username) }
+		},
+	)
+
+	p.Refresh()
+	return p
+}
+
+// Content returns the panel UI
+func (p *SSHUsersPanel) Content() fyne.CanvasObject {
+	toolbar := container.NewHBox(
+		widget.NewButtonWithIcon("Add Account", theme.ContentAddIcon(), func() { p.showEditDialog(nil) }),
+		widget.NewButtonWithIcon("Refresh", theme.ViewRefreshIcon(), p.Refresh),
+		p.statusLabel,
+	)
+	hint := widget.NewLabel("Passwords are stored as bcrypt hashes. At least one enabled account with a " +
+		"full shell or commands policy must remain; only those accounts can manage users remotely.")
+	hint.Wrapping = fyne.TextWrapWord
+	hint.Importance = widget.LowImportance
+
+	return container.NewBorder(
+		container.NewVBox(toolbar, widget.NewSeparator()),
+		container.NewVBox(widget.NewSeparator(), hint),
+		nil, nil,
+		p.list,
+	)
+}
+
+// Refresh reloads the accounts from the store
+func (p *SSHUsersPanel) Refresh() {
+	go func() {
+		users, err := p.store.List()
+		runOnMainThread(func() {
+			if err != nil {
+				p.statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+				return
+			}
+			p.mu.Lock()
+			p.users = users
+			p.mu.Unlock()
+			p.statusLabel.SetText(fmt.Sprintf("%d account(s)", len(users)))
+			p.list.UnselectAll()
+			p.list.Refresh()
+		})
+	}()
+}
+
+// describeSSHUser summarizes an account's policy and login methods for the list
+func describeSSHUser(user network.SSHUser) string {
+	var parts []string
+	if user.Disabled {
+		parts = append(parts, "DISABLED")
+	}
+	policy := sshPolicyLabel(user.Policy)
+	if user.Policy == network.PolicyAllowlist {
+		policy += ": " + strings.Join(user.AllowedCommands, ", ")
+	}
+	parts = append(parts, policy)
+
+	var logins []string
+	if user.HasPassword() {
+		logins = append(logins, "password")
+	}
+	if len(user.Keys) > 0 {
+		logins = append(logins, fmt.Sprintf("%d key(s)", len(user.Keys)))
+	}
+	if len(logins) == 0 {
+		logins = append(logins, "no login method")
+	}
+	parts = append(parts, strings.Join(logins, " + "))
+	if user.WorkDir != "" {
+		parts = append(parts, "starts in "+user.WorkDir)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// showEditDialog edits an existing account, or adds one if user is nil
+func (p *SSHUsersPanel) showEditDialog(user *network.SSHUser) {
+	isNew := user == nil
+	if isNew {
+		user = &network.SSHUser{Policy: network.PolicyShell}
+	}
+
+	usernameEntry := widget.NewEntry()
+	usernameEntry.SetText(user.Username)
+	if !isNew {
+		usernameEntry.Disable()
+	}
+
+	passwordCheck := widget.NewCheck("Allow password login", nil)
+	passwordCheck.SetChecked(isNew || user.HasPassword())
+	passwordEntry := widget.NewPasswordEntry()
+	if user.HasPassword() {
+		passwordEntry.SetPlaceHolder("Leave empty to keep the current password")
+	}
+	generateBtn := widget.NewButton("Generate", func() {
+		if password, err := network.GeneratePassword(16); err == nil {
+			passwordEntry.SetText(password)
+			passwordEntry.Password = false
+			passwordEntry.Refresh()
+		}
+	})
+
+	keysEntry := widget.NewMultiLineEntry()
+	keysEntry.SetText(strings.Join(user.Keys, "\n"))
+	keysEntry.SetPlaceHolder("ssh-ed25519 AAAA... comment")
+	keysEntry.SetMinRowsVisible(3)
+	keysEntry.Wrapping = fyne.TextWrapOff
+
+	labels := make([]string, len(sshPolicyLabels))
+	for i, opt := range sshPolicyLabels {
+		labels[i] = opt.label
+	}
+	policySelect := widget.NewSelect(labels, nil)
+	policySelect.SetSelected(sshPolicyLabel(user.Policy))
+
+	commandsEntry := widget.NewEntry()
+	commandsEntry.SetText(strings.Join(user.AllowedCommands, ", "))
+	commandsEntry.SetPlaceHolder("uptime, df, systemctl")
+	policySelect.OnChanged = func(label string) {
+		if label == sshPolicyLabel(network.PolicyAllowlist) {
+			commandsEntry.Enable()
+		} else {
+			commandsEntry.Disable()
+		}
+	}
+	policySelect.OnChanged(policySelect.Selected)
+
+	workDirEntry := widget.NewEntry()
+	workDirEntry.SetText(user.WorkDir)
+	workDirEntry.SetPlaceHolder("Home directory")
+
+	disabledCheck := widget.NewCheck("Account disabled", nil)
+	disabledCheck.SetChecked(user.Disabled)
+
+	formItems := []*widget.FormItem{
+		widget.NewFormItem("Username", usernameEntry),
+		widget.NewFormItem("", passwordCheck),
+		widget.NewFormItem("Password", container.NewBorder(nil, nil, nil, generateBtn, passwordEntry)),
+		widget.NewFormItem("Public keys", keysEntry),
+		widget.NewFormItem("Policy", policySelect),
+		widget.NewFormItem("Allowed commands", commandsEntry),
+		widget.NewFormItem("Working directory", workDirEntry),
+		widget.NewFormItem("", disabledCheck),
+	}
+	formItems[3].HintText = "One authorized_keys line per key"
+	formItems[5].HintText = "Program names; add sftp to allow file transfers"
+
+	title := "Edit Account " + user.Username
+	if isNew {
+		title = "Add Account"
+	}
+	form := dialog.NewForm(title, "Save", "Cancel", formItems, func(ok bool) {
+		if !ok {
+			return
+		}
+		edited := network.SSHUser{
+			Username: strings.TrimSpace(usernameEntry.Text),
+			WorkDir:  strings.TrimSpace(workDirEntry.Text),
+			Disabled: disabledCheck.Checked,
+		}
+		for _, opt := range sshPolicyLabels {
+			if opt.label == policySelect.Selected {
+				edited.Policy = opt.policy
+			}
+		}
+		for _, line := range strings.Split(keysEntry.Text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				edited.Keys = append(edited.Keys, line)
+			}
+		}
+		if edited.Policy == network.PolicyAllowlist {
+			edited.AllowedCommands = strings.FieldsFunc(commandsEntry.Text, func(r rune) bool {
+				return r == ',' || r == ' '
+			})
+		}
+		password := ""
+		if passwordCheck.Checked {
+			password = passwordEntry.Text
+			if password == "" && !user.HasPassword() {
+				dialog.ShowError(fmt.Errorf("enter or generate a password, or turn off password login"), p.window)
+				return
+			}
+			// A non-empty hash keeps the current password, see network.SSHUserDB.Save
+			edited.PasswordHash = user.PasswordHash
+		}
+		p.save(edited, password)
+	}, p.window)
+	form.Resize(fyne.NewSize(560, 0))
+	form.Show()
+}
+
+// save stores an account and reloads the list, showing errors in a dialog
+func (p *SSHUsersPanel) save(user network.SSHUser, password string) {
+	go func() {
+		err := p.store.Save(user, password)
+		runOnMainThread(func() {
+			if err != nil {
+				dialog.ShowError(err, p.window)
+			}
+			p.Refresh()
+		})
+	}()
+}
+
+func (p *SSHUsersPanel) confirmDelete(username string) {
+	dialog.ShowConfirm("Delete Account", fmt.Sprintf("Delete the SSH account %s?", username), func(ok bool) {
+		if !ok {
+			return
+		}
+		go func() {
+			err := p.store.Delete(username)
+			runOnMainThread(func() {
+				if err != nil {
+					dialog.ShowError(err, p.window)
+				}
+				p.Refresh()
+			})
+		}()
+	}, p.window)
+}
+
+// ShowSSHUsersDialog shows the account editor for store in a dialog over window;
+// onClosed (optional) is called when it is dismissed
+func ShowSSHUsersDialog(store SSHUserStore, window fyne.Window, onClosed func()) {
+	panel := NewSSHUsersPanel(store, window)
+	d := dialog.NewCustom("SSH Users", "Close", panel.Content(), window)
+	if onClosed != nil {
+		d.SetOnClosed(onClosed)
+	}
+	d.Resize(fyne.NewSize(720, 460))
+	d.Show()
+}
@@ -0,0 +1,1263 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// TermColorKind says how a TermColor is interpreted
+type TermColorKind uint8
+
+const (
+	TermColorDefault TermColorKind = iota // Terminal default foreground/background
+	TermColorIndexed                      // One of the 256 xterm palette colors
+	TermColorRGB                          // 24-bit color
+)
+
+// TermColor is a cell's foreground or background color
+type TermColor struct {
+	Kind    TermColorKind
+	Index   uint8
+	R, G, B uint8
+}
+
+// TermAttr is a set of text attributes (SGR)
+type TermAttr uint8
+
+const (
+	TermAttrBold TermAttr = 1 << iota
+	TermAttrDim
+	TermAttrItalic
+	TermAttrUnderline
+	TermAttrBlink
+	TermAttrReverse
+	TermAttrHidden
+	TermAttrStrike
+)
+
+// TermCell is one character cell of the screen
+type TermCell struct {
+	Rune rune
+	FG   TermColor
+	BG   TermColor
+	Attr TermAttr
+}
+
+// blankCell is an empty cell with default colors
+var blankCell = TermCell{Rune: ' '}
+
+const (
+	// DefaultScrollback is how many lines scrolled off the top are kept
+	DefaultScrollback = 5000
+	// maxCSIParams bounds the parameters collected for one control sequence
+	maxCSIParams = 32
+	// maxCSIParamValue caps each numeric parameter, so counts from the host can't
+	// overflow cursor arithmetic or make a sequence loop for long
+	maxCSIParamValue = 65535
+	// maxOSCLength bounds operating system commands (window title etc.)
+	maxOSCLength = 4096
+)
+
+// Parser states
+const (
+	stateGround = iota
+	stateEscape
+	stateEscapeIntermediate
+	stateCSI
+	stateOSC
+	stateOSCEscape
+	stateIgnoreString // DCS, APC, PM, SOS: skipped until ST
+	stateIgnoreStringEscape
+)
+
+// savedCursor is the state stored by DECSC / CSI s
+type savedCursor struct {
+	x, y        int
+	pen         TermCell
+	originMode  bool
+	charsets    [2]bool
+	wrapPending bool
+}
+
+// TerminalScreen is a VT100/xterm screen buffer. Bytes written to it are
+// interpreted as terminal output (text, cursor movement, colors, alternate screen,
+// scrolling) and the resulting screen can be inspected cell by cell.
+// It has no UI dependencies, so it can be driven and checked without a window.
+type TerminalScreen struct {
+	mu sync.Mutex
+
+	cols, rows int
+	primary    [][]TermCell
+	alternate  [][]TermCell
+	lines      [][]TermCell // primary or alternate
+	altActive  bool
+
+	scrollback    [][]TermCell
+	maxScrollback int
+
+	cursorX, cursorY int
+	wrapPending      bool // Cursor is past the last column; the next character wraps
+	pen              TermCell
+	saved            savedCursor
+	savedAlt         savedCursor
+
+	scrollTop, scrollBottom int // Scroll region, inclusive
+	tabStops                []bool
+
+	// Modes
+	appCursorKeys  bool
+	autoWrap       bool
+	originMode     bool
+	insertMode     bool
+	cursorVisible  bool
+	bracketedPaste bool
+
+	// Character sets: true selects DEC special graphics (line drawing) for G0/G1
+	charsets      [2]bool
+	activeCharset int
+
+	title    string
+	lastRune rune
+
+	// Parser state
+	state        int
+	params       []byte
+	intermediate byte
+	osc          []byte
+	utf8Buf      []byte
+
+	// Called with replies the terminal must send back to the host (e.g. cursor position reports)
+	OnResponse func([]byte)
+	// Called when the host sets the window title
+	OnTitle func(string)
+	// Called on BEL
+	OnBell func()
+}
+
+// NewTerminalScreen creates a screen of the given size
+func NewTerminalScreen(cols, rows int) *TerminalScreen {
+	if cols < 1 {
+		cols = 80
+	}
+	if rows < 1 {
+		rows = 24
+	}
+	s := &TerminalScreen{maxScrollback: DefaultScrollback}
+	s.cols, s.rows = cols, rows
+	s.reset()
+	return s
+}
+
+// reset restores the power-on state (RIS), keeping the size and scrollback
+func (s *TerminalScreen) reset() {
+	s.primary = newTermLines(s.cols, s.rows)
+	s.alternate = newTermLines(s.cols, s.rows)
+	s.lines = s.primary
+	s.altActive = false
+	s.cursorX, s.cursorY = 0, 0
+	s.wrapPending = false
+	s.pen = blankCell
+	s.saved = savedCursor{}
+	s.savedAlt = savedCursor{}
+	s.scrollTop, s.scrollBottom = 0, s.rows-1
+	s.resetTabStops()
+	s.appCursorKeys = false
+	s.autoWrap = true
+	s.originMode = false
+	s.insertMode = false
+	s.cursorVisible = true
+	s.bracketedPaste = false
+	s.charsets = [2]bool{}
+	s.activeCharset = 0
+	s.state = stateGround
+}
+
+func newTermLines(cols, rows int) [][]TermCell {
+	lines := make([][]TermCell, rows)
+	for i := range lines {
+		lines[i] = newTermLine(cols, blankCell)
+	}
+	return lines
+}
+
+func newTermLine(cols int, fill TermCell) []TermCell {
+	line := make([]TermCell, cols)
+	for i := range line {
+		line[i] = fill
+	}
+	return line
+}
+
+func (s *TerminalScreen) resetTabStops() {
+	s.tabStops = make([]bool, s.cols)
+	for i := 8; i < s.cols; i += 8 {
+		s.tabStops[i] = true
+	}
+}
+
+// ================== Inspection ==================
+
+// Size returns the screen size in cells
+func (s *TerminalScreen) Size() (cols, rows int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cols, s.rows
+}
+
+// Cursor returns the cursor position (0-based column and row)
+func (s *TerminalScreen) Cursor() (x, y int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cursorX, s.cursorY
+}
+
+// CursorVisible reports whether the host has the cursor shown (DECTCEM)
+func (s *TerminalScreen) CursorVisible() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cursorVisible
+}
+
+// Cell returns the cell at column x, row y of the visible screen
+func (s *TerminalScreen) Cell(x, y int) TermCell {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if y < 0 || y >= s.rows || x < 0 || x >= s.cols {
+		return blankCell
+	}
+	return s.lines[y][x]
+}
+
+// LineText returns the text of screen row y without trailing blanks
+func (s *TerminalScreen) LineText(y int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if y < 0 || y >= s.rows {
+		return ""
+	}
+	return cellsText(s.lines[y])
+}
+
+// Text returns the visible screen as text, one line per row, without trailing blank lines
+func (s *TerminalScreen) Text() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lines := make([]string, s.rows)
+	for y := range s.lines {
+		lines[y] = cellsText(s.lines[y])
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// ScrollbackLen returns how many lines have scrolled off the top of the primary screen
+func (s *TerminalScreen) ScrollbackLen() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.scrollback)
+}
+
+// ScrollbackLine returns the text of scrollback line i (0 is the oldest)
+func (s *TerminalScreen) ScrollbackLine(i int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i < 0 || i >= len(s.scrollback) {
+		return ""
+	}
+	return cellsText(s.scrollback[i])
+}
+
+// AlternateScreen reports whether the alternate screen (used by full-screen programs) is active
+func (s *TerminalScreen) AlternateScreen() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.altActive
+}
+
+// AppCursorKeys reports whether arrow keys must be sent in application mode (ESC O A)
+func (s *TerminalScreen) AppCursorKeys() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.appCursorKeys
+}
+
+// BracketedPaste reports whether pasted text must be wrapped in ESC [200~ ... ESC [201~
+func (s *TerminalScreen) BracketedPaste() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bracketedPaste
+}
+
+// Title returns the window title last set by the host
+func (s *TerminalScreen) Title() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.title
+}
+
+// TerminalView is a copy of the visible lines at some scrollback offset
+type TerminalView struct {
+	Lines         [][]TermCell
+	CursorX       int
+	CursorY       int // -1 if the cursor row is scrolled out of view
+	CursorVisible bool
+	FirstLine     int // Absolute line number of Lines[0] (scrollback lines come first)
+}
+
+// View returns the rows visible when scrolled back by offset lines into the scrollback
+func (s *TerminalScreen) View(offset int) TerminalView {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	offset = max(0, min(offset, s.scrollbackForView()))
+	first := len(s.scrollback) - offset
+	v := TerminalView{
+		Lines:         make([][]TermCell, s.rows),
+		CursorX:       s.cursorX,
+		CursorY:       s.cursorY + offset,
+		CursorVisible: s.cursorVisible,
+		FirstLine:     first,
+	}
+	if v.CursorY >= s.rows {
+		v.CursorY = -1
+	}
+	for y := 0; y < s.rows; y++ {
+		v.Lines[y] = append([]TermCell(nil), s.absoluteLine(first+y)...)
+	}
+	return v
+}
+
+// MaxScrollOffset returns how far the view can be scrolled back
+func (s *TerminalScreen) MaxScrollOffset() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scrollbackForView()
+}
+
+// scrollbackForView is the usable scrollback: none while a full-screen program runs
+func (s *TerminalScreen) scrollbackForView() int {
+	if s.altActive {
+		return 0
+	}
+	return len(s.scrollback)
+}
+
+// absoluteLine returns a line by absolute number: scrollback first, then the screen
+func (s *TerminalScreen) absoluteLine(n int) []TermCell {
+	if s.altActive {
+		n -= len(s.scrollback)
+	} else if n < len(s.scrollback) {
+		if n < 0 {
+			return nil
+		}
+		return s.scrollback[n]
+	} else {
+		n -= len(s.scrollback)
+	}
+	if n < 0 || n >= s.rows {
+		return nil
+	}
+	return s.lines[n]
+}
+
+// TextRange returns the text between two absolute positions (line, column), inclusive,
+// as used for copying a selection
+func (s *TerminalScreen) TextRange(startLine, startCol, endLine, endCol int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if startLine > endLine || (startLine == endLine && startCol > endCol) {
+		startLine, startCol, endLine, endCol = endLine, endCol, startLine, startCol
+	}
+	var b strings.Builder
+	for n := startLine; n <= endLine; n++ {
+		line := s.absoluteLine(n)
+		from, to := 0, len(line)
+		if n == startLine {
+			from = min(max(startCol, 0), len(line))
+		}
+		if n == endLine {
+			to = min(max(endCol+1, 0), len(line))
+		}
+		if from < to {
+			b.WriteString(cellsText(line[from:to]))
+		}
+		if n != endLine {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+func cellsText(cells []TermCell) string {
+	var b strings.Builder
+	for _, c := range cells {
+		if c.Rune == 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteRune(c.Rune)
+		}
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+// ================== Resizing ==================
+
+// Resize changes the screen size. Lines pushed off the top of the primary screen
+// go to the scrollback; content is not reflowed.
+func (s *TerminalScreen) Resize(cols, rows int) {
+	if cols < 1 || rows < 1 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cols == s.cols && rows == s.rows {
+		return
+	}
+
+	// Keep the cursor on screen by scrolling the primary screen up when shrinking
+	if excess := s.cursorY - (rows - 1); excess > 0 {
+		if !s.altActive {
+			s.pushScrollback(s.primary[:excess])
+		}
+		s.primary = s.primary[excess:]
+		if s.altActive {
+			s.alternate = s.alternate[excess:]
+		}
+		s.cursorY -= excess
+	}
+
+	s.primary = resizeTermLines(s.primary, cols, rows)
+	s.alternate = resizeTermLines(s.alternate, cols, rows)
+	if s.altActive {
+		s.lines = s.alternate
+	} else {
+		s.lines = s.primary
+	}
+
+	s.cols, s.rows = cols, rows
+	s.scrollTop, s.scrollBottom = 0, rows-1
+	s.cursorX = min(s.cursorX, cols-1)
+	s.cursorY = min(s.cursorY, rows-1)
+	s.wrapPending = false
+	s.resetTabStops()
+}
+
+func resizeTermLines(lines [][]TermCell, cols, rows int) [][]TermCell {
+	if len(lines) > rows {
+		lines = lines[:rows]
+	}
+	for i, line := range lines {
+		if len(line) > cols {
+			lines[i] = line[:cols]
+		} else if len(line) < cols {
+			lines[i] = append(line, newTermLine(cols-len(line), blankCell)...)
+		}
+	}
+	for len(lines) < rows {
+		lines = append(lines, newTermLine(cols, blankCell))
+	}
+	return lines
+}
+
+func (s *TerminalScreen) pushScrollback(lines [][]TermCell) {
+	for _, line := range lines {
+		s.scrollback = append(s.scrollback, append([]TermCell(nil), line...))
+	}
+	if over := len(s.scrollback) - s.maxScrollback; over > 0 {
+		s.scrollback = append([][]TermCell(nil), s.scrollback[over:]...)
+	}
+}
+
+// ClearScrollback drops all scrollback lines
+func (s *TerminalScreen) ClearScrollback() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scrollback = nil
+}
+
+// ================== Parsing ==================
+
+// Write interprets terminal output. It never fails; unknown sequences are ignored.
+func (s *TerminalScreen) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	var responses [][]byte
+	var title *string
+	bell := false
+	for _, b := range p {
+		resp, t, bel := s.feed(b)
+		if resp != nil {
+			responses = append(responses, resp)
+		}
+		if t != nil {
+			title = t
+		}
+		bell = bell || bel
+	}
+	s.mu.Unlock()
+
+	// Callbacks run without the lock so they may inspect the screen
+	if s.OnResponse != nil {
+		for _, r := range responses {
+			s.OnResponse(r)
+		}
+	}
+	if title != nil && s.OnTitle != nil {
+		s.OnTitle(*title)
+	}
+	if bell && s.OnBell != nil {
+		s.OnBell()
+	}
+	return len(p), nil
+}
+
+// feed processes one byte; it returns any reply for the host, a new title and whether BEL rang
+func (s *TerminalScreen) feed(b byte) (response []byte, title *string, bell bool) {
+	// C0 controls act in every state except inside strings
+	if b < 0x20 && s.state != stateOSC && s.state != stateIgnoreString {
+		switch b {
+		case 0x1b: // ESC
+			s.utf8Buf = s.utf8Buf[:0]
+			s.state = stateEscape
+			s.intermediate = 0
+			return
+		case 0x18, 0x1a: // CAN, SUB abort a sequence
+			s.state = stateGround
+			return
+		}
+		bell = s.execute(b)
+		return
+	}
+
+	switch s.state {
+	case stateGround:
+		s.ground(b)
+
+	case stateEscape:
+		switch {
+		case b == '[':
+			s.state = stateCSI
+			s.params = s.params[:0]
+			s.intermediate = 0
+		case b == ']':
+			s.state = stateOSC
+			s.osc = s.osc[:0]
+		case b == 'P' || b == '_' || b == '^' || b == 'X':
+			s.state = stateIgnoreString
+		case b >= 0x20 && b <= 0x2f:
+			s.intermediate = b
+			s.state = stateEscapeIntermediate
+		default:
+			s.state = stateGround
+			response = s.escape(b)
+		}
+
+	case stateEscapeIntermediate:
+		s.state = stateGround
+		s.escapeIntermediate(s.intermediate, b)
+
+	case stateCSI:
+		switch {
+		case b >= 0x30 && b <= 0x3f:
+			if len(s.params) < maxCSIParams*4 {
+				s.params = append(s.params, b)
+			}
+		case b >= 0x20 && b <= 0x2f:
+			s.intermediate = b
+		case b >= 0x40 && b <= 0x7e:
+			s.state = stateGround
+			response = s.csi(b)
+		default:
+			s.state = stateGround
+		}
+
+	case stateOSC:
+		switch b {
+		case 0x07:
+			s.state = stateGround
+			title = s.finishOSC()
+		case 0x1b:
+			s.state = stateOSCEscape
+		default:
+			if len(s.osc) < maxOSCLength {
+				s.osc = append(s.osc, b)
+			}
+		}
+
+	case stateOSCEscape:
+		s.state = stateGround
+		if b == '\\' {
+			title = s.finishOSC()
+		}
+
+	case stateIgnoreString:
+		if b == 0x1b {
+			s.state = stateIgnoreStringEscape
+		} else if b == 0x07 {
+			s.state = stateGround
+		}
+
+	case stateIgnoreStringEscape:
+		if b == '\\' {
+			s.state = stateGround
+		} else {
+			s.state = stateIgnoreString
+		}
+	}
+	return
+}
+
+// ground handles printable bytes, decoding UTF-8 across writes
+func (s *TerminalScreen) ground(b byte) {
+	if b == 0x7f {
+		return // DEL is ignored
+	}
+	if b < 0x80 && len(s.utf8Buf) == 0 {
+		s.print(rune(b))
+		return
+	}
+	s.utf8Buf = append(s.utf8Buf, b)
+	if !utf8.FullRune(s.utf8Buf) {
+		if len(s.utf8Buf) >= utf8.UTFMax {
+			s.utf8Buf = s.utf8Buf[:0]
+			s.print(utf8.RuneError)
+		}
+		return
+	}
+	r, _ := utf8.DecodeRune(s.utf8Buf)
+	s.utf8Buf = s.utf8Buf[:0]
+	s.print(r)
+}
+
+// execute runs a C0 control character; it returns true for BEL
+func (s *TerminalScreen) execute(b byte) bool {
+	switch b {
+	case 0x07: // BEL
+		return true
+	case 0x08: // BS
+		if s.cursorX > 0 {
+			s.cursorX--
+		}
+		s.wrapPending = false
+	case 0x09: // HT
+		s.tab(1)
+	case 0x0a, 0x0b, 0x0c: // LF, VT, FF
+		s.lineFeed()
+	case 0x0d: // CR
+		s.cursorX = 0
+		s.wrapPending = false
+	case 0x0e: // SO: use G1
+		s.activeCharset = 1
+	case 0x0f: // SI: use G0
+		s.activeCharset = 0
+	}
+	return false
+}
+
+// print puts a character at the cursor and advances it
+func (s *TerminalScreen) print(r rune) {
+	if s.charsets[s.activeCharset] {
+		if g, ok := decGraphics[r]; ok {
+			r = g
+		}
+	}
+	if s.wrapPending && s.autoWrap {
+		s.cursorX = 0
+		s.lineFeed()
+	}
+	s.wrapPending = false
+
+	line := s.lines[s.cursorY]
+	if s.insertMode {
+		copy(line[s.cursorX+1:], line[s.cursorX:])
+	}
+	cell := s.pen
+	cell.Rune = r
+	line[s.cursorX] = cell
+	s.lastRune = r
+
+	if s.cursorX == s.cols-1 {
+		s.wrapPending = true
+	} else {
+		s.cursorX++
+	}
+}
+
+// lineFeed moves the cursor down, scrolling the region at its bottom
+func (s *TerminalScreen) lineFeed() {
+	s.wrapPending = false
+	if s.cursorY == s.scrollBottom {
+		s.scrollUp(1)
+	} else if s.cursorY < s.rows-1 {
+		s.cursorY++
+	}
+}
+
+// reverseIndex moves the cursor up, scrolling the region at its top
+func (s *TerminalScreen) reverseIndex() {
+	s.wrapPending = false
+	if s.cursorY == s.scrollTop {
+		s.scrollDown(1)
+	} else if s.cursorY > 0 {
+		s.cursorY--
+	}
+}
+
+// blank returns an erased cell: blank with the current background
+func (s *TerminalScreen) blank() TermCell {
+	return TermCell{Rune: ' ', BG: s.pen.BG}
+}
+
+// scrollUp scrolls the scroll region up by n lines; lines leaving the top of the
+// full primary screen are kept in the scrollback
+func (s *TerminalScreen) scrollUp(n int) {
+	height := s.scrollBottom - s.scrollTop + 1
+	n = min(n, height)
+	if n <= 0 {
+		return
+	}
+	if s.scrollTop == 0 && !s.altActive {
+		s.pushScrollback(s.lines[:n])
+	}
+	region := s.lines[s.scrollTop : s.scrollBottom+1]
+	copy(region, region[n:])
+	for i := height - n; i < height; i++ {
+		region[i] = newTermLine(s.cols, s.blank())
+	}
+}
+
+// scrollDown scrolls the scroll region down by n lines
+func (s *TerminalScreen) scrollDown(n int) {
+	height := s.scrollBottom - s.scrollTop + 1
+	n = min(n, height)
+	if n <= 0 {
+		return
+	}
+	region := s.lines[s.scrollTop : s.scrollBottom+1]
+	copy(region[n:], region[:height-n])
+	for i := 0; i < n; i++ {
+		region[i] = newTermLine(s.cols, s.blank())
+	}
+}
+
+// tab moves to the n-th next tab stop (or back with negative n)
+func (s *TerminalScreen) tab(n int) {
+	s.wrapPending = false
+	// There are at most cols tab stops to pass, whatever the host asks for
+	n = max(-s.cols, min(n, s.cols))
+	for ; n > 0; n-- {
+		x := s.cursorX + 1
+		for x < s.cols-1 && !s.tabStops[x] {
+			x++
+		}
+		s.cursorX = min(x, s.cols-1)
+	}
+	for ; n < 0; n++ {
+		x := s.cursorX - 1
+		for x > 0 && !s.tabStops[x] {
+			x--
+		}
+		s.cursorX = max(x, 0)
+	}
+}
+
+// moveTo places the cursor, honouring origin mode for the row
+func (s *TerminalScreen) moveTo(x, y int) {
+	top, bottom := 0, s.rows-1
+	if s.originMode {
+		top, bottom = s.scrollTop, s.scrollBottom
+		y += s.scrollTop
+	}
+	s.cursorX = max(0, min(x, s.cols-1))
+	s.cursorY = max(top, min(y, bottom))
+	s.wrapPending = false
+}
+
+func (s *TerminalScreen) saveCursor() savedCursor {
+	return savedCursor{
+		x: s.cursorX, y: s.cursorY, pen: s.pen, originMode: s.originMode,
+		charsets: s.charsets, wrapPending: s.wrapPending,
+	}
+}
+
+func (s *TerminalScreen) restoreCursor(c savedCursor) {
+	s.cursorX = min(c.x, s.cols-1)
+	s.cursorY = min(c.y, s.rows-1)
+	s.pen = c.pen
+	s.originMode = c.originMode
+	s.charsets = c.charsets
+	s.wrapPending = c.wrapPending
+}
+
+// setAlternate switches between the primary and alternate screens
+func (s *TerminalScreen) setAlternate(on bool, clear bool) {
+	if on == s.altActive {
+		return
+	}
+	s.altActive = on
+	if on {
+		s.lines = s.alternate
+		if clear {
+			for i := range s.lines {
+				s.lines[i] = newTermLine(s.cols, blankCell)
+			}
+		}
+	} else {
+		s.lines = s.primary
+	}
+	s.wrapPending = false
+}
+
+// escape handles ESC followed by a final byte
+func (s *TerminalScreen) escape(b byte) []byte {
+	switch b {
+	case '7': // DECSC
+		s.saved = s.saveCursor()
+	case '8': // DECRC
+		s.restoreCursor(s.saved)
+	case 'D': // IND
+		s.lineFeed()
+	case 'E': // NEL
+		s.cursorX = 0
+		s.lineFeed()
+	case 'M': // RI
+		s.reverseIndex()
+	case 'H': // HTS
+		s.tabStops[s.cursorX] = true
+	case 'c': // RIS
+		s.reset()
+		s.scrollback = nil
+	case '=', '>': // Keypad modes: the keypad sends the same keys either way here
+	}
+	return nil
+}
+
+// escapeIntermediate handles ESC <intermediate> <final>, e.g. charset designation
+func (s *TerminalScreen) escapeIntermediate(intermediate, b byte) {
+	switch intermediate {
+	case '(', ')':
+		g := 0
+		if intermediate == ')' {
+			g = 1
+		}
+		s.charsets[g] = b == '0'
+	case '#':
+		if b == '8' { // DECALN: fill the screen with E
+			for _, line := range s.lines {
+				for x := range line {
+					line[x] = TermCell{Rune: 'E'}
+				}
+			}
+		}
+	}
+}
+
+// finishOSC handles a complete operating system command; returns the new title if any
+func (s *TerminalScreen) finishOSC() *string {
+	cmd, arg, ok := strings.Cut(string(s.osc), ";")
+	if !ok {
+		return nil
+	}
+	switch cmd {
+	case "0", "2":
+		s.title = arg
+		return &arg
+	}
+	return nil
+}
+
+// csiParams splits the collected parameter bytes into the private marker and numbers
+func (s *TerminalScreen) csiParams() (private byte, params []int) {
+	raw := string(s.params)
+	if raw != "" && raw[0] >= '<' && raw[0] <= '?' {
+		private = raw[0]
+		raw = raw[1:]
+	}
+	if raw == "" {
+		return private, nil
+	}
+	// Colon sub-parameters (e.g. 38:2:r:g:b) are treated like semicolons
+	for _, f := range strings.Split(strings.ReplaceAll(raw, ":", ";"), ";") {
+		n, err := strconv.Atoi(f)
+		switch {
+		case errors.Is(err, strconv.ErrRange):
+			n = maxCSIParamValue
+		case err != nil:
+			n = 0 // Empty parameters mean default
+		}
+		n = min(max(n, 0), maxCSIParamValue)
+		if len(params) < maxCSIParams {
+			params = append(params, n)
+		}
+	}
+	return private, params
+}
+
+// param returns parameter i, or def if it is missing or zero
+func param(params []int, i, def int) int {
+	if i < len(params) && params[i] != 0 {
+		return params[i]
+	}
+	return def
+}
+
+// csi handles a complete control sequence; it returns a reply for the host, if any
+func (s *TerminalScreen) csi(final byte) []byte {
+	private, params := s.csiParams()
+	intermediate := s.intermediate
+
+	if intermediate != 0 {
+		switch {
+		case intermediate == '!' && final == 'p': // DECSTR soft reset
+			s.pen = blankCell
+			s.insertMode = false
+			s.originMode = false
+			s.autoWrap = true
+			s.cursorVisible = true
+			s.appCursorKeys = false
+			s.scrollTop, s.scrollBottom = 0, s.rows-1
+		}
+		return nil // e.g. DECSCUSR (cursor shape) is not supported
+	}
+
+	switch final {
+	case 'h', 'l':
+		s.setModes(private, params, final == 'h')
+		return nil
+	case 'n':
+		return s.deviceStatus(private, params)
+	case 'c':
+		switch private {
+		case 0:
+			return []byte("\x1b[?62;22c") // VT220 with ANSI color
+		case '>':
+			return []byte("\x1b[>1;10;0c")
+		}
+		return nil
+	}
+	if private != 0 {
+		return nil
+	}
+
+	n := param(params, 0, 1)
+	switch final {
+	case '@': // ICH
+		line := s.lines[s.cursorY]
+		n = min(n, s.cols-s.cursorX)
+		copy(line[s.cursorX+n:], line[s.cursorX:])
+		for i := 0; i < n; i++ {
+			line[s.cursorX+i] = s.blank()
+		}
+		s.wrapPending = false
+	case 'A': // CUU
+		top := 0
+		if s.cursorY >= s.scrollTop {
+			top = s.scrollTop
+		}
+		s.cursorY = max(top, s.cursorY-n)
+		s.wrapPending = false
+	case 'B': // CUD
+		bottom := s.rows - 1
+		if s.cursorY <= s.scrollBottom {
+			bottom = s.scrollBottom
+		}
+		s.cursorY = min(bottom, s.cursorY+n)
+		s.wrapPending = false
+	case 'C', 'a': // CUF, HPR
+		s.cursorX = min(s.cols-1, s.cursorX+n)
+		s.wrapPending = false
+	case 'D': // CUB
+		s.cursorX = max(0, s.cursorX-n)
+		s.wrapPending = false
+	case 'E': // CNL
+		s.cursorX = 0
+		s.cursorY = min(s.rows-1, s.cursorY+n)
+		s.wrapPending = false
+	case 'F': // CPL
+		s.cursorX = 0
+		s.cursorY = max(0, s.cursorY-n)
+		s.wrapPending = false
+	case 'G', '`': // CHA, HPA
+		s.cursorX = max(0, min(s.cols-1, n-1))
+		s.wrapPending = false
+	case 'H', 'f': // CUP
+		s.moveTo(param(params, 1, 1)-1, n-1)
+	case 'd': // VPA
+		s.moveTo(s.cursorX, n-1)
+	case 'e': // VPR
+		s.cursorY = min(s.rows-1, s.cursorY+n)
+		s.wrapPending = false
+	case 'I': // CHT
+		s.tab(n)
+	case 'Z': // CBT
+		s.tab(-n)
+	case 'J': // ED
+		s.eraseDisplay(param(params, 0, 0))
+	case 'K': // EL
+		s.eraseLine(param(params, 0, 0))
+	case 'L': // IL
+		if s.cursorY >= s.scrollTop && s.cursorY <= s.scrollBottom {
+			top := s.scrollTop
+			s.scrollTop = s.cursorY
+			s.scrollDown(n)
+			s.scrollTop = top
+			s.cursorX = 0
+		}
+	case 'M': // DL
+		if s.cursorY >= s.scrollTop && s.cursorY <= s.scrollBottom {
+			top := s.scrollTop
+			s.scrollTop = s.cursorY
+			// Deleted lines never go to the scrollback
+			alt := s.altActive
+			s.altActive = true
+			s.scrollUp(n)
+			s.altActive = alt
+			s.scrollTop = top
+			s.cursorX = 0
+		}
+	case 'P': // DCH
+		line := s.lines[s.cursorY]
+		n = min(n, s.cols-s.cursorX)
+		copy(line[s.cursorX:], line[s.cursorX+n:])
+		for i := s.cols - n; i < s.cols; i++ {
+			line[i] = s.blank()
+		}
+		s.wrapPending = false
+	case 'X': // ECH
+		line := s.lines[s.cursorY]
+		for i := s.cursorX; i < min(s.cols, s.cursorX+n); i++ {
+			line[i] = s.blank()
+		}
+		s.wrapPending = false
+	case 'S': // SU
+		s.scrollUp(n)
+	case 'T': // SD
+		s.scrollDown(n)
+	case 'b': // REP
+		if s.lastRune != 0 {
+			for i := 0; i < min(n, s.cols*s.rows); i++ {
+				s.print(s.lastRune)
+			}
+		}
+	case 'g': // TBC
+		switch param(params, 0, 0) {
+		case 0:
+			s.tabStops[s.cursorX] = false
+		case 3:
+			s.tabStops = make([]bool, s.cols)
+		}
+	case 'm':
+		s.setGraphics(params)
+	case 'r': // DECSTBM
+		top := param(params, 0, 1) - 1
+		bottom := param(params, 1, s.rows) - 1
+		if top < bottom && bottom < s.rows {
+			s.scrollTop, s.scrollBottom = top, bottom
+			s.moveTo(0, 0)
+		}
+	case 's':
+		s.saved = s.saveCursor()
+	case 'u':
+		s.restoreCursor(s.saved)
+	}
+	return nil
+}
+
+// eraseDisplay implements ED
+func (s *TerminalScreen) eraseDisplay(mode int) {
+	switch mode {
+	case 0: // Cursor to end
+		s.eraseLine(0)
+		for y := s.cursorY + 1; y < s.rows; y++ {
+			s.lines[y] = newTermLine(s.cols, s.blank())
+		}
+	case 1: // Start to cursor
+		s.eraseLine(1)
+		for y := 0; y < s.cursorY; y++ {
+			s.lines[y] = newTermLine(s.cols, s.blank())
+		}
+	case 2: // Whole screen
+		for y := range s.lines {
+			s.lines[y] = newTermLine(s.cols, s.blank())
+		}
+	case 3: // Scrollback (xterm)
+		s.scrollback = nil
+	}
+	s.wrapPending = false
+}
+
+// eraseLine implements EL
+func (s *TerminalScreen) eraseLine(mode int) {
+	line := s.lines[s.cursorY]
+	from, to := s.cursorX, s.cols
+	switch mode {
+	case 1:
+		from, to = 0, s.cursorX+1
+	case 2:
+		from, to = 0, s.cols
+	}
+	for x := from; x < to; x++ {
+		line[x] = s.blank()
+	}
+	s.wrapPending = false
+}
+
+// setModes implements SM/RM and DECSET/DECRST
+func (s *TerminalScreen) setModes(private byte, params []int, on bool) {
+	for _, p := range params {
+		if private == 0 {
+			if p == 4 {
+				s.insertMode = on
+			}
+			continue
+		}
+		if private != '?' {
+			continue
+		}
+		switch p {
+		case 1:
+			s.appCursorKeys = on
+		case 6:
+			s.originMode = on
+			s.moveTo(0, 0)
+		case 7:
+			s.autoWrap = on
+		case 25:
+			s.cursorVisible = on
+		case 47, 1047:
+			s.setAlternate(on, on && p == 1047)
+		case 1048:
+			if on {
+				s.saved = s.saveCursor()
+			} else {
+				s.restoreCursor(s.saved)
+			}
+		case 1049:
+			if on {
+				s.savedAlt = s.saveCursor()
+				s.setAlternate(true, true)
+			} else {
+				s.setAlternate(false, false)
+				s.restoreCursor(s.savedAlt)
+			}
+		case 2004:
+			s.bracketedPaste = on
+		}
+	}
+}
+
+// deviceStatus answers DSR queries
+func (s *TerminalScreen) deviceStatus(private byte, params []int) []byte {
+	switch param(params, 0, 0) {
+	case 5:
+		return []byte("\x1b[0n")
+	case 6:
+		y := s.cursorY
+		if s.originMode {
+			y -= s.scrollTop
+		}
+		prefix := ""
+		if private == '?' {
+			prefix = "?"
+		}
+		return []byte(fmt.Sprintf("\x1b[%s%d;%dR", prefix, y+1, s.cursorX+1))
+	}
+	return nil
+}
+
+// setGraphics implements SGR
+func (s *TerminalScreen) setGraphics(params []int) {
+	if len(params) == 0 {
+		params = []int{0}
+	}
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		switch {
+		case p == 0:
+			s.pen = blankCell
+		case p == 1:
+			s.pen.Attr |= TermAttrBold
+		case p == 2:
+			s.pen.Attr |= TermAttrDim
+		case p == 3:
+			s.pen.Attr |= TermAttrItalic
+		case p == 4:
+			s.pen.Attr |= TermAttrUnderline
+		case p == 5 || p == 6:
+			s.pen.Attr |= TermAttrBlink
+		case p == 7:
+			s.pen.Attr |= TermAttrReverse
+		case p == 8:
+			s.pen.Attr |= TermAttrHidden
+		case p == 9:
+			s.pen.Attr |= TermAttrStrike
+		case p == 21 || p == 22:
+			s.pen.Attr &^= TermAttrBold | TermAttrDim
+		case p == 23:
+			s.pen.Attr &^= TermAttrItalic
+		case p == 24:
+			s.pen.Attr &^= TermAttrUnderline
+		case p == 25:
+			s.pen.Attr &^= TermAttrBlink
+		case p == 27:
+			s.pen.Attr &^= TermAttrReverse
+		case p == 28:
+			s.pen.Attr &^= TermAttrHidden
+		case p == 29:
+			s.pen.Attr &^= TermAttrStrike
+		case p >= 30 && p <= 37:
+			s.pen.FG = TermColor{Kind: TermColorIndexed, Index: uint8(p - 30)}
+		case p == 38:
+			var ok bool
+			s.pen.FG, i, ok = extendedColor(params, i)
+			if !ok {
+				return
+			}
+		case p == 39:
+			s.pen.FG = TermColor{}
+		case p >= 40 && p <= 47:
+			s.pen.BG = TermColor{Kind: TermColorIndexed, Index: uint8(p - 40)}
+		case p == 48:
+			var ok bool
+			s.pen.BG, i, ok = extendedColor(params, i)
+			if !ok {
+				return
+			}
+		case p == 49:
+			s.pen.BG = TermColor{}
+		case p >= 90 && p <= 97:
+			s.pen.FG = TermColor{Kind: TermColorIndexed, Index: uint8(p - 90 + 8)}
+		case p >= 100 && p <= 107:
+			s.pen.BG = TermColor{Kind: TermColorIndexed, Index: uint8(p - 100 + 8)}
+		}
+	}
+}
+
+// extendedColor parses "38;5;n" or "38;2;r;g;b" starting at params[i] (the 38/48);
+// it returns the color and the index of the last parameter consumed
+func extendedColor(params []int, i int) (TermColor, int, bool) {
+	if i+1 >= len(params) {
+		return TermColor{}, i, false
+	}
+	switch params[i+1] {
+	case 5:
+		if i+2 < len(params) {
+			return TermColor{Kind: TermColorIndexed, Index: uint8(params[i+2])}, i + 2, true
+		}
+	case 2:
+		if i+4 < len(params) {
+			return TermColor{Kind: TermColorRGB, R: uint8(params[i+2]), G: uint8(params[i+3]), B: uint8(params[i+4])}, i + 4, true
+		}
+	}
+	return TermColor{}, i, false
+}
+
+// decGraphics maps the DEC special graphics character set to Unicode line drawing
+var decGraphics = map[rune]rune{
+	'`': '◆', 'a': '▒', 'f': '°', 'g': '±', 'j': '┘', 'k': '┐', 'l': '┌', 'm': '└',
+	'n': '┼', 'o': '⎺', 'p': '⎻', 'q': '─', 'r': '⎼', 's': '⎽', 't': '├', 'u': '┤',
+	'v': '┴', 'w': '┬', 'x': '│', 'y': '≤', 'z': '≥', '{': 'π', '|': '≠', '}': '£', '~': '·',
+}
@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// screenWith returns a screen of the given size after writing the byte stream to it
+func screenWith(cols, rows int, stream string) *TerminalScreen {
+	s := NewTerminalScreen(cols, rows)
+	s.Write([]byte(stream))
+	return s
+}
+
+func TestTerminalScreenText(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   string
+		x, y   int // Cursor afterwards
+	}{
+		{"plain", "hello", "hello", 5, 0},
+		{"newline", "ab\r\ncd", "ab\ncd", 2, 1},
+		{"wrap", "abcdefghijkl", "abcdefghij\nkl", 2, 1},
+		{"cursor position", "\x1b[2;4Hx", "\n   x", 4, 1},
+		{"cursor movement", "\x1b[3;3H\x1b[Aa\x1b[2Db\x1b[Bc", "\n ba\n  c", 3, 2},
+		{"erase line", "abcdef\x1b[3G\x1b[K", "ab", 2, 0},
+		{"erase display", "one\r\ntwo\x1b[2J", "", 3, 1},
+		{"insert and delete chars", "abcd\x1b[2G\x1b[2@\x1b[1P", "a bcd", 1, 0},
+		{"tab", "a\tb", "a       b", 9, 0},
+		{"backspace", "ab\bc", "ac", 2, 0},
+		{"line drawing", "\x1b(0qx\x1b(Bq", "─│q", 3, 0},
+		{"utf-8", "grüße", "grüße", 5, 0},
+		{"ignored string", "a\x1bPsecret\x1b\\b", "ab", 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := screenWith(10, 4, tt.stream)
+			if got := s.Text(); got != tt.want {
+				t.Errorf("text %q, want %q", got, tt.want)
+			}
+			if x, y := s.Cursor(); x != tt.x || y != tt.y {
+				t.Errorf("cursor at %d,%d, want %d,%d", x, y, tt.x, tt.y)
+			}
+		})
+	}
+}
+
+func TestTerminalScreenScrolling(t *testing.T) {
+	s := screenWith(10, 3, "1\r\n2\r\n3\r\n4")
+	if got := s.Text(); got != "2\n3\n4" {
+		t.Errorf("text %q, want the screen scrolled up", got)
+	}
+	if s.ScrollbackLen() != 1 || s.ScrollbackLine(0) != "1" {
+		t.Errorf("scrollback has %d line(s), want the scrolled off line", s.ScrollbackLen())
+	}
+
+	// Scrolling inside a region leaves the other lines alone
+	s = screenWith(10, 4, "a\r\nb\r\nc\r\nd\x1b[2;3r\x1b[3;1H\n")
+	if got := s.Text(); got != "a\nc\n\nd" {
+		t.Errorf("region scroll gave %q", got)
+	}
+	if s.ScrollbackLen() != 0 {
+		t.Error("region scroll filled the scrollback")
+	}
+}
+
+func TestTerminalScreenAlternate(t *testing.T) {
+	s := screenWith(10, 3, "shell\x1b[?1049h\x1b[Hfull screen")
+	if !s.AlternateScreen() || s.Text() != "full scree\nn" {
+		t.Fatalf("alternate screen shows %q", s.Text())
+	}
+	s.Write([]byte("\x1b[?1049l"))
+	if s.AlternateScreen() || s.Text() != "shell" {
+		t.Errorf("after leaving the alternate screen: %q", s.Text())
+	}
+}
+
+func TestTerminalScreenGraphics(t *testing.T) {
+	s := screenWith(10, 2, "\x1b[1;31mR\x1b[0m\x1b[38;2;1;2;3;48;5;200mX")
+	red := s.Cell(0, 0)
+	if red.Attr&TermAttrBold == 0 || red.FG.Kind != TermColorIndexed || red.FG.Index != 1 {
+		t.Errorf("bold red cell is %+v", red)
+	}
+	rgb := s.Cell(1, 0)
+	if rgb.Attr != 0 || rgb.FG.Kind != TermColorRGB || rgb.FG.R != 1 || rgb.FG.B != 3 ||
+		rgb.BG.Kind != TermColorIndexed || rgb.BG.Index != 200 {
+		t.Errorf("true color cell is %+v", rgb)
+	}
+}
+
+func TestTerminalScreenReplies(t *testing.T) {
+	s := NewTerminalScreen(10, 4)
+	var replies []string
+	var title string
+	s.OnResponse = func(b []byte) { replies = append(replies, string(b)) }
+	s.OnTitle = func(t string) { title = t }
+	s.Write([]byte("\x1b[3;5H\x1b[6n\x1b]0;my title\x07"))
+	if len(replies) != 1 || replies[0] != "\x1b[3;5R" {
+		t.Errorf("cursor position report %q", replies)
+	}
+	if title != "my title" {
+		t.Errorf("title %q", title)
+	}
+}
+
+// Counts from the host are clamped to the screen, so huge ones neither hang the
+// parser (which holds the screen lock) nor move the cursor off the screen
+func TestTerminalScreenOversizedParams(t *testing.T) {
+	huge := []string{"999999999", "999999999999", "99999999999999999999999"}
+	sequences := []string{"I", "Z", "A", "B", "C", "D", "E", "F", "G", "d", "e", "@", "P", "X", "L", "M", "S", "T", "b"}
+	for _, n := range huge {
+		for _, final := range sequences {
+			stream := fmt.Sprintf("abc\x1b[2;3H\x1b[%s%s", n, final)
+			start := time.Now()
+			s := screenWith(80, 24, stream)
+			if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+				t.Errorf("CSI %s %s took %s", n, final, elapsed)
+			}
+			if x, y := s.Cursor(); x < 0 || x >= 80 || y < 0 || y >= 24 {
+				t.Errorf("CSI %s %s left the cursor at %d,%d", n, final, x, y)
+			}
+		}
+	}
+
+	// Tab stops: forward to the last column, back to the first
+	if x, _ := screenWith(80, 24, "\x1b[999999999999I").Cursor(); x != 79 {
+		t.Errorf("CHT moved to column %d, want 79", x)
+	}
+	if x, _ := screenWith(80, 24, "\x1b[40G\x1b[999999999999Z").Cursor(); x != 0 {
+		t.Errorf("CBT moved to column %d, want 0", x)
+	}
+
+	// A huge position lands on the last row and column
+	s := screenWith(80, 24, "\x1b[99999999999999999999;99999999999999999999Hx")
+	if s.LineText(23) != strings.Repeat(" ", 79)+"x" {
+		t.Errorf("CUP to a huge position wrote %q on the last row", s.LineText(23))
+	}
+}
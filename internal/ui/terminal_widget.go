@@ -0,0 +1,717 @@
+package ui
+
+import (
+	"image/color"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// termPalette is the 16 base ANSI colors, tuned to the purple terminal theme
+var termPalette = [16]color.NRGBA{
+	{R: 40, G: 30, B: 55, A: 255},    // Black
+	termErrorColor,                   // Red
+	termSuccessColor,                 // Green
+	termWarningColor,                 // Yellow
+	termInfoColor,                    // Blue
+	termPromptColor,                  // Magenta
+	{R: 90, G: 210, B: 220, A: 255},  // Cyan
+	termFgColor,                      // White
+	termDimColor,                     // Bright black
+	{R: 255, G: 140, B: 155, A: 255}, // Bright red
+	{R: 140, G: 240, B: 180, A: 255}, // Bright green
+	{R: 255, G: 225, B: 140, A: 255}, // Bright yellow
+	{R: 140, G: 200, B: 255, A: 255}, // Bright blue
+	{R: 210, G: 150, B: 255, A: 255}, // Bright magenta
+	{R: 140, G: 235, B: 240, A: 255}, // Bright cyan
+	{R: 250, G: 248, B: 255, A: 255}, // Bright white
+}
+
+var termSelectionColor = color.NRGBA{R: 90, G: 60, B: 140, A: 255}
+
+// termColorValue resolves a cell color against the palette
+func termColorValue(c TermColor, def color.NRGBA) color.NRGBA {
+	switch c.Kind {
+	case TermColorIndexed:
+		i := int(c.Index)
+		switch {
+		case i < 16:
+			return termPalette[i]
+		case i < 232: // 6x6x6 color cube
+			i -= 16
+			level := func(v int) uint8 {
+				if v == 0 {
+					return 0
+				}
+				return uint8(55 + v*40)
+			}
+			return color.NRGBA{R: level(i / 36), G: level(i / 6 % 6), B: level(i % 6), A: 255}
+		default: // Grayscale ramp
+			v := uint8(8 + (i-232)*10)
+			return color.NRGBA{R: v, G: v, B: v, A: 255}
+		}
+	case TermColorRGB:
+		return color.NRGBA{R: c.R, G: c.G, B: c.B, A: 255}
+	}
+	return def
+}
+
+// termStyleKey identifies a cell style so styles can be shared between cells
+type termStyleKey struct {
+	fg, bg TermColor
+	attr   TermAttr
+	mark   uint8 // 1 = selected, 2 = cursor
+}
+
+// termPos is an absolute position in the scrollback + screen
+type termPos struct {
+	line, col int
+}
+
+// TerminalWidget displays a TerminalScreen and turns keyboard and mouse input into
+// the bytes a terminal would send: control keys, arrows (honouring application cursor
+// mode), function keys and bracketed paste. Output is fed with Write; the size in cells
+// follows the widget size and is reported with the resize callback.
+type TerminalWidget struct {
+	widget.BaseWidget
+
+	screen *TerminalScreen
+	grid   *widget.TextGrid
+	bg     *canvas.Rectangle
+
+	mu           sync.Mutex
+	scrollOffset int // Lines scrolled back into the scrollback; 0 follows the output
+	focused      bool
+	shiftDown    bool
+	selecting    bool
+	hasSelection bool
+	selStart     termPos
+	selEnd       termPos
+	firstLine    int // Absolute line shown in the top row at the last render
+	styles       map[termStyleKey]*widget.CustomTextGridStyle
+
+	refreshPending atomic.Bool
+
+	onInput  func([]byte)
+	onResize func(cols, rows int)
+	onTyped  func([]byte) // Also gets what the user typed or pasted, e.g. to mirror it
+	onFocus  func()
+}
+
+// NewTerminalWidget creates a terminal widget. onInput receives the bytes to send to the
+// host (keystrokes, pastes and replies to terminal queries).
+func NewTerminalWidget(onInput func([]byte)) *TerminalWidget {
+	t := &TerminalWidget{
+		screen:  NewTerminalScreen(80, 24),
+		grid:    widget.NewTextGrid(),
+		bg:      canvas.NewRectangle(termBgColor),
+		styles:  make(map[termStyleKey]*widget.CustomTextGridStyle),
+		onInput: onInput,
+	}
+	t.grid.Scroll = fyne.ScrollNone
+	t.screen.OnResponse = t.send
+	t.ExtendBaseWidget(t)
+	return t
+}
+
+// Screen returns the underlying screen buffer
+func (t *TerminalWidget) Screen() *TerminalScreen {
+	return t.screen
+}
+
+// SetOnResize sets the callback for terminal size changes (in cells)
+func (t *TerminalWidget) SetOnResize(fn func(cols, rows int)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onResize = fn
+}
+
+// SetOnTyped sets a callback getting a copy of everything the user types or pastes,
+// but not the terminal's own replies to the host
+func (t *TerminalWidget) SetOnTyped(fn func(p []byte)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onTyped = fn
+}
+
+// SetOnFocus sets a callback for when the terminal gains the keyboard focus
+func (t *TerminalWidget) SetOnFocus(fn func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onFocus = fn
+}
+
+// Write feeds host output to the terminal. Safe to call from any goroutine.
+func (t *TerminalWidget) Write(p []byte) (int, error) {
+	t.screen.Write(p)
+	t.scheduleRender()
+	return len(p), nil
+}
+
+// send passes input to the host
+func (t *TerminalWidget) send(p []byte) {
+	if t.onInput != nil && len(p) > 0 {
+		t.onInput(p)
+	}
+}
+
+// sendTyped sends user input, returning the view to the live screen
+func (t *TerminalWidget) sendTyped(p []byte) {
+	t.mu.Lock()
+	scrolled := t.scrollOffset != 0
+	t.scrollOffset = 0
+	onTyped := t.onTyped
+	t.mu.Unlock()
+	if scrolled {
+		t.scheduleRender()
+	}
+	t.send(p)
+	if onTyped != nil && len(p) > 0 {
+		onTyped(p)
+	}
+}
+
+// scheduleRender redraws on the UI thread, coalescing bursts of output into one redraw
+func (t *TerminalWidget) scheduleRender() {
+	if !t.refreshPending.CompareAndSwap(false, true) {
+		return
+	}
+	runOnMainThread(func() {
+		t.refreshPending.Store(false)
+		t.render()
+	})
+}
+
+// cellSize returns the size of one character cell, as the TextGrid lays them out
+func (t *TerminalWidget) cellSize() fyne.Size {
+	size := fyne.MeasureText("M", t.Theme().Size(theme.SizeNameText), fyne.TextStyle{Monospace: true})
+	return fyne.NewSize(float32(math.Round(float64(size.Width))), float32(math.Round(float64(size.Height))))
+}
+
+// render copies the visible part of the screen into the text grid
+func (t *TerminalWidget) render() {
+	maxOffset := t.screen.MaxScrollOffset()
+	t.mu.Lock()
+	t.scrollOffset = min(t.scrollOffset, maxOffset)
+	offset := t.scrollOffset
+	t.mu.Unlock()
+
+	view := t.screen.View(offset)
+
+	t.mu.Lock()
+	t.firstLine = view.FirstLine
+	showCursor := view.CursorVisible && view.CursorY >= 0 && t.focused
+	rows := make([]widget.TextGridRow, len(view.Lines))
+	for y, line := range view.Lines {
+		cells := make([]widget.TextGridCell, len(line))
+		for x, c := range line {
+			var mark uint8
+			if t.isSelected(termPos{view.FirstLine + y, x}) {
+				mark = 1
+			}
+			if showCursor && y == view.CursorY && x == view.CursorX {
+				mark = 2
+			}
+			r := c.Rune
+			if r == 0 || c.Attr&TermAttrHidden != 0 {
+				r = ' '
+			}
+			cells[x] = widget.TextGridCell{Rune: r, Style: t.style(c, mark)}
+		}
+		rows[y] = widget.TextGridRow{Cells: cells}
+	}
+	t.mu.Unlock()
+
+	t.grid.Rows = rows
+	t.grid.Refresh()
+}
+
+// style returns the (shared) grid style for a cell; mark 1 highlights a selection, 2 the cursor
+func (t *TerminalWidget) style(c TermCell, mark uint8) *widget.CustomTextGridStyle {
+	key := termStyleKey{fg: c.FG, bg: c.BG, attr: c.Attr, mark: mark}
+	if s, ok := t.styles[key]; ok {
+		return s
+	}
+
+	fg := termColorValue(c.FG, termFgColor)
+	if c.Attr&TermAttrBold != 0 && c.FG.Kind == TermColorIndexed && c.FG.Index < 8 {
+		fg = termPalette[c.FG.Index+8]
+	}
+	bg := termColorValue(c.BG, termBgColor)
+	if c.Attr&TermAttrDim != 0 {
+		fg = color.NRGBA{R: fg.R / 2, G: fg.G / 2, B: fg.B / 2, A: 255}
+	}
+	if c.Attr&TermAttrReverse != 0 {
+		fg, bg = bg, fg
+	}
+	switch mark {
+	case 1:
+		bg = termSelectionColor
+	case 2:
+		fg, bg = bg, fg
+		if bg == termBgColor {
+			bg = termFgColor
+		}
+	}
+
+	s := &widget.CustomTextGridStyle{
+		TextStyle: fyne.TextStyle{
+			Monospace: true,
+			Bold:      c.Attr&TermAttrBold != 0,
+			Italic:    c.Attr&TermAttrItalic != 0,
+			Underline: c.Attr&TermAttrUnderline != 0,
+		},
+		FGColor: fg,
+		BGColor: bg,
+	}
+	if len(t.styles) > 4096 {
+		t.styles = make(map[termStyleKey]*widget.CustomTextGridStyle)
+	}
+	t.styles[key] = s
+	return s
+}
+
+// resizeTo adapts the screen to the widget size, in whole cells
+func (t *TerminalWidget) resizeTo(size fyne.Size) {
+	cell := t.cellSize()
+	if cell.Width <= 0 || cell.Height <= 0 {
+		return
+	}
+	cols := max(int(size.Width/cell.Width), 2)
+	rows := max(int(size.Height/cell.Height), 1)
+	if oldCols, oldRows := t.screen.Size(); oldCols == cols && oldRows == rows {
+		return
+	}
+	t.screen.Resize(cols, rows)
+
+	t.mu.Lock()
+	onResize := t.onResize
+	t.hasSelection = false
+	t.mu.Unlock()
+	if onResize != nil {
+		onResize(cols, rows)
+	}
+	t.scheduleRender()
+}
+
+// CreateRenderer implements fyne.Widget
+func (t *TerminalWidget) CreateRenderer() fyne.WidgetRenderer {
+	t.render()
+	return &terminalWidgetRenderer{t: t, objects: []fyne.CanvasObject{t.bg, t.grid}}
+}
+
+type terminalWidgetRenderer struct {
+	t       *TerminalWidget
+	objects []fyne.CanvasObject
+}
+
+func (r *terminalWidgetRenderer) Layout(size fyne.Size) {
+	r.t.bg.Resize(size)
+	r.t.grid.Resize(size)
+	r.t.resizeTo(size)
+}
+
+func (r *terminalWidgetRenderer) MinSize() fyne.Size {
+	cell := r.t.cellSize()
+	return fyne.NewSize(cell.Width*20, cell.Height*5)
+}
+
+func (r *terminalWidgetRenderer) Refresh() {
+	r.t.bg.FillColor = termBgColor
+	r.t.bg.Refresh()
+	r.t.render()
+}
+
+func (r *terminalWidgetRenderer) Objects() []fyne.CanvasObject { return r.objects }
+func (r *terminalWidgetRenderer) Destroy()                     {}
+
+// ================== Focus and keyboard ==================
+
+// Tapped focuses the terminal and clears any selection
+func (t *TerminalWidget) Tapped(*fyne.PointEvent) {
+	t.mu.Lock()
+	t.hasSelection = false
+	t.mu.Unlock()
+	if c := fyne.CurrentApp().Driver().CanvasForObject(t); c != nil {
+		c.Focus(t)
+	}
+	t.scheduleRender()
+}
+
+// TappedSecondary shows the Copy/Paste context menu
+func (t *TerminalWidget) TappedSecondary(ev *fyne.PointEvent) {
+	c := fyne.CurrentApp().Driver().CanvasForObject(t)
+	if c == nil {
+		return
+	}
+	clipboard := fyne.CurrentApp().Clipboard()
+	menu := fyne.NewMenu("",
+		fyne.NewMenuItem("Copy", func() { t.copySelection(clipboard) }),
+		fyne.NewMenuItem("Paste", func() { t.paste(clipboard) }),
+		fyne.NewMenuItem("Clear Scrollback", func() {
+			t.screen.ClearScrollback()
+			t.scheduleRender()
+		}),
+	)
+	widget.ShowPopUpMenuAtPosition(menu, c, ev.AbsolutePosition)
+}
+
+// FocusGained implements fyne.Focusable
+func (t *TerminalWidget) FocusGained() {
+	t.mu.Lock()
+	t.focused = true
+	onFocus := t.onFocus
+	t.mu.Unlock()
+	t.scheduleRender()
+	if onFocus != nil {
+		onFocus()
+	}
+}
+
+// FocusLost implements fyne.Focusable
+func (t *TerminalWidget) FocusLost() {
+	t.mu.Lock()
+	t.focused = false
+	t.shiftDown = false
+	t.mu.Unlock()
+	t.scheduleRender()
+}
+
+// AcceptsTab keeps Tab in the terminal (for shell completion) instead of moving focus
+func (t *TerminalWidget) AcceptsTab() bool {
+	return true
+}
+
+// TypedRune sends a typed character
+func (t *TerminalWidget) TypedRune(r rune) {
+	t.sendTyped([]byte(string(r)))
+}
+
+// KeyDown tracks Shift, which Fyne does not report with plain key events
+func (t *TerminalWidget) KeyDown(ev *fyne.KeyEvent) {
+	if ev.Name == desktop.KeyShiftLeft || ev.Name == desktop.KeyShiftRight {
+		t.mu.Lock()
+		t.shiftDown = true
+		t.mu.Unlock()
+	}
+}
+
+// KeyUp implements desktop.Keyable
+func (t *TerminalWidget) KeyUp(ev *fyne.KeyEvent) {
+	if ev.Name == desktop.KeyShiftLeft || ev.Name == desktop.KeyShiftRight {
+		t.mu.Lock()
+		t.shiftDown = false
+		t.mu.Unlock()
+	}
+}
+
+// TypedKey sends special keys as terminal escape sequences
+func (t *TerminalWidget) TypedKey(ev *fyne.KeyEvent) {
+	t.mu.Lock()
+	shift := t.shiftDown
+	t.mu.Unlock()
+
+	if shift {
+		switch ev.Name {
+		case fyne.KeyPageUp:
+			t.scrollLines(t.pageSize())
+			return
+		case fyne.KeyPageDown:
+			t.scrollLines(-t.pageSize())
+			return
+		case fyne.KeyTab:
+			t.sendTyped([]byte("\x1b[Z"))
+			return
+		}
+	}
+
+	if seq := keySequence(ev.Name, 0, t.screen.AppCursorKeys()); seq != "" {
+		t.sendTyped([]byte(seq))
+	}
+}
+
+// keySequence encodes a special key; modifiers uses the xterm encoding (0 for none)
+func keySequence(key fyne.KeyName, modifiers int, appCursor bool) string {
+	// Keys of the form CSI <n> ~
+	tilde := map[fyne.KeyName]string{
+		fyne.KeyInsert: "2", fyne.KeyDelete: "3", fyne.KeyPageUp: "5", fyne.KeyPageDown: "6",
+		fyne.KeyF5: "15", fyne.KeyF6: "17", fyne.KeyF7: "18", fyne.KeyF8: "19",
+		fyne.KeyF9: "20", fyne.KeyF10: "21", fyne.KeyF11: "23", fyne.KeyF12: "24",
+	}
+	// Keys of the form CSI <letter> (or SS3 <letter> in application mode)
+	letter := map[fyne.KeyName]string{
+		fyne.KeyUp: "A", fyne.KeyDown: "B", fyne.KeyRight: "C", fyne.KeyLeft: "D",
+		fyne.KeyHome: "H", fyne.KeyEnd: "F",
+		fyne.KeyF1: "P", fyne.KeyF2: "Q", fyne.KeyF3: "R", fyne.KeyF4: "S",
+	}
+
+	mod := ""
+	if modifiers > 0 {
+		mod = ";" + string(rune('1'+modifiers))
+	}
+	if n, ok := tilde[key]; ok {
+		return "\x1b[" + n + mod + "~"
+	}
+	if l, ok := letter[key]; ok {
+		isFunction := l >= "P" && l <= "S"
+		switch {
+		case mod != "":
+			return "\x1b[1" + mod + l
+		case isFunction || appCursor:
+			return "\x1bO" + l
+		default:
+			return "\x1b[" + l
+		}
+	}
+
+	switch key {
+	case fyne.KeyReturn, fyne.KeyEnter:
+		return "\r"
+	case fyne.KeyBackspace:
+		return "\x7f"
+	case fyne.KeyTab:
+		return "\t"
+	case fyne.KeyEscape:
+		return "\x1b"
+	}
+	return ""
+}
+
+// controlBytes maps Ctrl+<key> to the control character a terminal sends
+func controlBytes(key fyne.KeyName) []byte {
+	name := string(key)
+	if len(name) == 1 && name[0] >= 'A' && name[0] <= 'Z' {
+		return []byte{name[0] - 'A' + 1}
+	}
+	switch key {
+	case fyne.KeySpace, fyne.Key2:
+		return []byte{0}
+	case fyne.KeyLeftBracket, fyne.Key3:
+		return []byte{0x1b}
+	case fyne.KeyBackslash, fyne.Key4:
+		return []byte{0x1c}
+	case fyne.KeyRightBracket, fyne.Key5:
+		return []byte{0x1d}
+	case fyne.Key6:
+		return []byte{0x1e}
+	case fyne.KeySlash, fyne.KeyMinus, fyne.Key7:
+		return []byte{0x1f}
+	case fyne.KeyBackspace:
+		return []byte{0x08}
+	}
+	return nil
+}
+
+// TypedShortcut handles key combinations: Ctrl+<key> sends control characters,
+// Ctrl+Shift+C/V (and Ctrl+C with a selection) copy and paste
+func (t *TerminalWidget) TypedShortcut(s fyne.Shortcut) {
+	switch sc := s.(type) {
+	case *fyne.ShortcutCopy:
+		t.mu.Lock()
+		selected := t.hasSelection
+		t.mu.Unlock()
+		if selected {
+			t.copySelection(sc.Clipboard)
+		} else {
+			t.sendTyped([]byte{0x03})
+		}
+	case *fyne.ShortcutPaste:
+		t.paste(sc.Clipboard)
+	case *fyne.ShortcutCut:
+		if t.shiftOnly() {
+			t.sendTyped([]byte("\x1b[3;2~")) // Shift+Delete
+		} else {
+			t.sendTyped([]byte{0x18})
+		}
+	case *fyne.ShortcutSelectAll:
+		t.sendTyped([]byte{0x01})
+	case *fyne.ShortcutUndo:
+		t.sendTyped([]byte{0x1a})
+	case *fyne.ShortcutRedo:
+		t.sendTyped([]byte{0x19})
+	case *desktop.CustomShortcut:
+		t.customShortcut(sc)
+	}
+}
+
+// shiftOnly reports whether Shift is the only modifier held (Shift+Delete arrives as Cut)
+func (t *TerminalWidget) shiftOnly() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.shiftDown
+}
+
+func (t *TerminalWidget) customShortcut(sc *desktop.CustomShortcut) {
+	ctrl := sc.Modifier&fyne.KeyModifierControl != 0
+	alt := sc.Modifier&fyne.KeyModifierAlt != 0
+	shift := sc.Modifier&fyne.KeyModifierShift != 0
+
+	if ctrl && shift && !alt {
+		switch sc.KeyName {
+		case fyne.KeyC:
+			t.copySelection(fyne.CurrentApp().Clipboard())
+			return
+		case fyne.KeyV:
+			t.paste(fyne.CurrentApp().Clipboard())
+			return
+		}
+	}
+
+	// Modified special keys, e.g. Ctrl+Left is ESC [1;5D
+	modifiers := 0
+	if shift {
+		modifiers |= 1
+	}
+	if alt {
+		modifiers |= 2
+	}
+	if ctrl {
+		modifiers |= 4
+	}
+	if seq := keySequence(sc.KeyName, modifiers, false); strings.HasPrefix(seq, "\x1b[") {
+		t.sendTyped([]byte(seq))
+		return
+	}
+
+	var out []byte
+	if ctrl {
+		out = controlBytes(sc.KeyName)
+	} else if name := string(sc.KeyName); len(name) == 1 {
+		out = []byte(strings.ToLower(name))
+		if shift {
+			out = []byte(name)
+		}
+	} else {
+		out = []byte(keySequence(sc.KeyName, 0, t.screen.AppCursorKeys()))
+	}
+	if len(out) == 0 {
+		return
+	}
+	if alt {
+		out = append([]byte{0x1b}, out...) // Meta sends ESC prefix
+	}
+	t.sendTyped(out)
+}
+
+// paste sends clipboard text, wrapped for bracketed paste when the host asked for it
+func (t *TerminalWidget) paste(clipboard fyne.Clipboard) {
+	if clipboard == nil {
+		return
+	}
+	text := clipboard.Content()
+	if text == "" {
+		return
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\r")
+	text = strings.ReplaceAll(text, "\n", "\r")
+	if t.screen.BracketedPaste() {
+		text = "\x1b[200~" + strings.ReplaceAll(text, "\x1b[201~", "") + "\x1b[201~"
+	}
+	t.sendTyped([]byte(text))
+}
+
+// ================== Scrollback and selection ==================
+
+// pageSize is how many lines a page scroll moves
+func (t *TerminalWidget) pageSize() int {
+	_, rows := t.screen.Size()
+	return max(rows-1, 1)
+}
+
+// scrollLines moves the view n lines back into the scrollback (negative goes forward)
+func (t *TerminalWidget) scrollLines(n int) {
+	t.mu.Lock()
+	t.scrollOffset = max(0, min(t.scrollOffset+n, t.screen.MaxScrollOffset()))
+	t.mu.Unlock()
+	t.scheduleRender()
+}
+
+// Scrolled scrolls through the scrollback with the mouse wheel
+func (t *TerminalWidget) Scrolled(ev *fyne.ScrollEvent) {
+	lines := int(ev.Scrolled.DY / t.cellSize().Height)
+	if lines == 0 {
+		switch {
+		case ev.Scrolled.DY > 0:
+			lines = 1
+		case ev.Scrolled.DY < 0:
+			lines = -1
+		}
+	}
+	t.scrollLines(lines * 3)
+}
+
+// cellAt converts a widget position into an absolute line and column
+func (t *TerminalWidget) cellAt(pos fyne.Position) termPos {
+	cell := t.cellSize()
+	cols, rows := t.screen.Size()
+	col := max(0, min(int(pos.X/cell.Width), cols-1))
+	row := max(0, min(int(pos.Y/cell.Height), rows-1))
+	return termPos{line: t.firstLine + row, col: col}
+}
+
+// isSelected reports whether an absolute position is inside the selection; t.mu must be held
+func (t *TerminalWidget) isSelected(p termPos) bool {
+	if !t.hasSelection {
+		return false
+	}
+	start, end := t.selStart, t.selEnd
+	if end.line < start.line || (end.line == start.line && end.col < start.col) {
+		start, end = end, start
+	}
+	if p.line < start.line || p.line > end.line {
+		return false
+	}
+	if p.line == start.line && p.col < start.col {
+		return false
+	}
+	if p.line == end.line && p.col > end.col {
+		return false
+	}
+	return true
+}
+
+// Dragged selects text
+func (t *TerminalWidget) Dragged(ev *fyne.DragEvent) {
+	t.mu.Lock()
+	if !t.selecting {
+		t.selecting = true
+		t.hasSelection = true
+		t.selStart = t.cellAt(ev.Position.Subtract(ev.Dragged))
+	}
+	t.selEnd = t.cellAt(ev.Position)
+	t.mu.Unlock()
+	t.scheduleRender()
+}
+
+// DragEnd finishes a selection
+func (t *TerminalWidget) DragEnd() {
+	t.mu.Lock()
+	t.selecting = false
+	t.mu.Unlock()
+}
+
+// SelectedText returns the selected text, or "" without a selection
+func (t *TerminalWidget) SelectedText() string {
+	t.mu.Lock()
+	if !t.hasSelection {
+		t.mu.Unlock()
+		return ""
+	}
+	start, end := t.selStart, t.selEnd
+	t.mu.Unlock()
+	return t.screen.TextRange(start.line, start.col, end.line, end.col)
+}
+
+// copySelection puts the selected text on the clipboard
+func (t *TerminalWidget) copySelection(clipboard fyne.Clipboard) {
+	if text := t.SelectedText(); text != "" && clipboard != nil {
+		clipboard.SetContent(text)
+	}
+}
@@ -0,0 +1,191 @@
+package ui
+
+import (
+	"adminadmin/internal/network"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Kinds of transcript lines
+const (
+	transcriptOutput = iota
+	transcriptError  // stderr and local errors
+	transcriptCommand
+	transcriptSuccess // Status line of a command that succeeded
+	transcriptFailure // Status line of a command that failed
+)
+
+// transcriptLine is one line of a command mode tab's output
+type transcriptLine struct {
+	at   time.Time
+	kind int
+	text string // The command itself for transcriptCommand
+}
+
+// transcript records everything a command mode tab shows, with the time of every line,
+// so it can be saved and, when auto-logging is on, appended to the worker's log file.
+// Clearing the screen doesn't clear the transcript.
+type transcript struct {
+	hostname, ip string
+	started      time.Time
+
+	mu    sync.Mutex
+	lines []transcriptLine
+	log   io.Writer // Auto log; nil when off or after a write failed
+}
+
+// newTranscript starts a tab's transcript, writing a session header to logTo if set
+func newTranscript(hostname, ip string, logTo io.Writer) *transcript {
+	t := &transcript{hostname: hostname, ip: ip, started: time.Now(), log: logTo}
+	t.writeLog(fmt.Sprintf("\n=== %s (%s) session started %s ===\n", hostname, ip, t.started.Format("2006-01-02 15:04:05")))
+	return t
+}
+
+// add records a line; nil transcripts ignore it
+func (t *transcript) add(kind int, text string) {
+	if t == nil {
+		return
+	}
+	line := transcriptLine{at: time.Now(), kind: kind, text: text}
+	t.mu.Lock()
+	t.lines = append(t.lines, line)
+	t.mu.Unlock()
+	t.writeLog(t.plainLine(line) + "\n")
+}
+
+func (t *transcript) writeLog(s string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.log == nil {
+		return
+	}
+	if _, err := io.WriteString(t.log, s); err != nil {
+		log.Printf("SSH Client: Transcript logging for %s stopped: %v\n", t.hostname, err)
+		t.log = nil
+	}
+}
+
+// plainLine renders a line as text; commands are prefixed with the time they were run
+func (t *transcript) plainLine(line transcriptLine) string {
+	if line.kind == transcriptCommand {
+		return fmt.Sprintf("[%s] [%s] $ %s", line.at.Format("15:04:05"), t.hostname, line.text)
+	}
+	return line.text
+}
+
+// PlainText returns the transcript as text
+func (t *transcript) PlainText() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var b strings.Builder
+	fmt.Fprintf(&b, "Transcript of %s (%s), session started %s\n\n", t.hostname, t.ip, t.started.Format("2006-01-02 15:04:05"))
+	for _, line := range t.lines {
+		b.WriteString(t.plainLine(line))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// HTML returns the transcript as a standalone HTML page in the terminal's colors
+func (t *transcript) HTML() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	title := html.EscapeString(fmt.Sprintf("Transcript of %s (%s)", t.hostname, t.ip))
+	var b strings.Builder
+	fmt.Fprintf(&b, `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>%s</title>
+<style>
+body { background: #120c1c; color: #dcd7e6; font-family: monospace; }
+h1 { font-size: 1.1em; color: #b464ff; }
+.time { color: #786e8c; }
+.cmd { color: #b464ff; font-weight: bold; }
+.err { color: #ff6478; }
+.ok { color: #64dc96; font-style: italic; }
+.fail { color: #ff6478; font-style: italic; }
+</style></head>
+<body><h1>%s, session started %s</h1>
+<pre>
+`, title, title, t.started.Format("2006-01-02 15:04:05"))
+	for _, line := range t.lines {
+		text := html.EscapeString(line.text)
+		switch line.kind {
+		case transcriptCommand:
+			fmt.Fprintf(&b, `<span class="time">[%s]</span> <span class="cmd">[%s] $ %s</span>`,
+				line.at.Format("15:04:05"), html.EscapeString(t.hostname), text)
+		case transcriptError:
+			fmt.Fprintf(&b, `<span class="err">%s</span>`, text)
+		case transcriptSuccess:
+			fmt.Fprintf(&b, `<span class="ok">%s</span>`, text)
+		case transcriptFailure:
+			fmt.Fprintf(&b, `<span class="fail">%s</span>`, text)
+		default:
+			b.WriteString(text)
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString("</pre></body></html>\n")
+	return b.String()
+}
+
+// showSaveTranscript asks where to save the transcript; a name ending in .html or .htm
+// saves it as HTML, anything else as plain text
+func showSaveTranscript(t *transcript, window fyne.Window) {
+	d := dialog.NewFileSave(func(w fyne.URIWriteCloser, err error) {
+		if err != nil || w == nil {
+			return
+		}
+		defer w.Close()
+		content := t.PlainText()
+		if ext := strings.ToLower(w.URI().Extension()); ext == ".html" || ext == ".htm" {
+			content = t.HTML()
+		}
+		if _, err := io.WriteString(w, content); err != nil {
+			dialog.ShowError(err, window)
+		}
+	}, window)
+	name := unsafeTranscriptChars.Replace(t.hostname)
+	d.SetFileName(fmt.Sprintf("%s-%s.txt", name, t.started.Format("20060102-150405")))
+	d.Show()
+}
+
+// unsafeTranscriptChars replaces path separators in hostnames used as file names
+var unsafeTranscriptChars = strings.NewReplacer("/", "_", `\`, "_", ":", "_")
+
+// ShowTranscriptLoggingDialog edits whether every command mode tab's transcript is
+// appended to a per-worker log file; onSave is called with the new settings
+func ShowTranscriptLoggingDialog(settings network.TranscriptSettings, window fyne.Window, onSave func(network.TranscriptSettings)) {
+	autoLog := widget.NewCheck("Log every command mode session", nil)
+	autoLog.SetChecked(settings.AutoLog)
+	dirEntry := widget.NewEntry()
+	dirEntry.SetText(settings.Dir)
+	dirEntry.SetPlaceHolder(network.TranscriptSettings{}.LogDir())
+
+	formItems := []*widget.FormItem{
+		widget.NewFormItem("", autoLog),
+		widget.NewFormItem("Directory", dirEntry),
+	}
+	formItems[1].HintText = "One <hostname>.log per worker; empty for the default"
+	d := dialog.NewForm("Transcript Logging", "Save", "Cancel", formItems, func(ok bool) {
+		if !ok {
+			return
+		}
+		dir := strings.TrimSpace(dirEntry.Text)
+		if dir != "" && !filepath.IsAbs(dir) {
+			dialog.ShowError(fmt.Errorf("directory must be an absolute path"), window)
+			return
+		}
+		onSave(network.TranscriptSettings{AutoLog: autoLog.Checked, Dir: dir})
+	}, window)
+	d.Resize(fyne.NewSize(560, 0))
+	d.Show()
+}
@@ -0,0 +1,297 @@
+package ui
+
+import (
+	"adminadmin/internal/network"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Labels of the tunnel kinds in the add dialog
+const (
+	tunnelKindLocal  = "Local (admin port → worker side)"
+	tunnelKindRemote = "Remote (worker port → admin side)"
+)
+
+// tunnelEntry is a defined forward and, while it runs, its tunnel
+type tunnelEntry struct {
+	spec     network.TunnelSpec
+	tunnel   *network.Tunnel
+	err      string // Why the last start failed
+	starting bool
+	removed  bool // Deleted or panel closed; a start finishing late stops at once
+}
+
+// TunnelsPanel lists the port forwards defined for one worker and starts and stops
+// them over its SSH connection, showing the bytes each one has transferred
+type TunnelsPanel struct {
+	client *network.SSHClient
+	window fyne.Window
+	onSave func([]network.TunnelSpec)
+
+	mu      sync.Mutex
+	entries []*tunnelEntry
+
+	list *widget.List
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewTunnelsPanel creates a panel for the given definitions; onSave (optional) is called
+// with the new list whenever a forward is added or deleted
+func NewTunnelsPanel(client *network.SSHClient, specs []network.TunnelSpec, window fyne.Window, onSave func([]network.TunnelSpec)) *TunnelsPanel {
+	p := &TunnelsPanel{
+		client: client,
+		window: window,
+		onSave: onSave,
+		stop:   make(chan struct{}),
+	}
+	for _, spec := range specs {
+		p.entries = append(p.entries, &tunnelEntry{spec: spec})
+	}
+
+	p.list = widget.NewList(
+		func() int {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			return len(p.entries)
+		},
+		func() fyne.CanvasObject {
+			icon := widget.NewIcon(theme.MediaStopIcon())
+			name := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+			name.Truncation = fyne.TextTruncateEllipsis
+			stats := widget.NewLabel("")
+			toggleBtn := widget.NewButton("Start", nil)
+			deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), nil)
+			deleteBtn.Importance = widget.LowImportance
+			return container.NewBorder(nil, nil, icon, container.NewHBox(stats, toggleBtn, deleteBtn), name)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			p.mu.Lock()
+			if id >= len(p.entries) {
+				p.mu.Unlock()
+				return
+			}
+			entry := p.entries[id]
+			spec, tunnel, lastErr := entry.spec, entry.tunnel, entry.err
+			p.mu.Unlock()
+
+			row := obj.(*fyne.Container)
+			name := row.Objects[0].(*widget.Label)
+			icon := row.Objects[1].(*widget.Icon)
+			right := row.Objects[2].(*fyne.Container)
+			stats := right.Objects[0].(*widget.Label)
+			toggleBtn := right.Objects[1].(*widget.Button)
+			deleteBtn := right.Objects[2].(*widget.Button)
+
+			name.SetText(spec.String())
+			deleteBtn.OnTapped = func() { p.remove(entry) }
+			if tunnel != nil {
+				sent, received, active := tunnel.Stats()
+				icon.SetResource(theme.MediaPlayIcon())
+				stats.SetText(fmt.Sprintf("↑ %s  ↓ %s  %d open", formatBytes(sent), formatBytes(received), active))
+				toggleBtn.SetText("Stop")
+				toggleBtn.OnTapped = func() { p.stopTunnel(entry) }
+			} else {
+				icon.SetResource(theme.MediaStopIcon())
+				stats.SetText(lastErr)
+				toggleBtn.SetText("Start")
+				toggleBtn.OnTapped = func() { p.startTunnel(entry) }
+			}
+		},
+	)
+
+	go p.refreshLoop()
+	return p
+}
+
+// Content returns the panel UI
+func (p *TunnelsPanel) Content() fyne.CanvasObject {
+	toolbar := container.NewHBox(
+		widget.NewButtonWithIcon("Add Tunnel", theme.ContentAddIcon(), p.showAddDialog),
+		widget.NewButton("Start All", p.startAll),
+		widget.NewButton("Stop All", p.StopAll),
+	)
+	hint := widget.NewLabel("Local tunnels listen on this computer and connect from the worker; remote tunnels listen on the worker and connect from here. Both listen on loopback only.")
+	hint.Wrapping = fyne.TextWrapWord
+	hint.Importance = widget.LowImportance
+
+	return container.NewBorder(
+		container.NewVBox(toolbar, widget.NewSeparator()),
+		container.NewVBox(widget.NewSeparator(), hint),
+		nil, nil,
+		p.list,
+	)
+}
+
+// refreshLoop updates the byte counters once a second while tunnels run
+func (p *TunnelsPanel) refreshLoop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			running := false
+			for _, entry := range p.entries {
+				running = running || entry.tunnel != nil
+			}
+			p.mu.Unlock()
+			if running {
+				runOnMainThread(p.list.Refresh)
+			}
+		}
+	}
+}
+
+func (p *TunnelsPanel) startTunnel(entry *tunnelEntry) {
+	p.mu.Lock()
+	if entry.tunnel != nil || entry.starting || entry.removed {
+		p.mu.Unlock()
+		return
+	}
+	entry.starting = true
+	spec := entry.spec
+	p.mu.Unlock()
+
+	go func() {
+		tunnel, err := p.client.StartTunnel(spec)
+		p.mu.Lock()
+		entry.starting = false
+		switch {
+		case err != nil:
+			entry.err = err.Error()
+		case entry.removed:
+			defer tunnel.Stop()
+		default:
+			entry.tunnel, entry.err = tunnel, ""
+		}
+		p.mu.Unlock()
+		runOnMainThread(p.list.Refresh)
+	}()
+}
+
+func (p *TunnelsPanel) stopTunnel(entry *tunnelEntry) {
+	p.mu.Lock()
+	tunnel := entry.tunnel
+	entry.tunnel = nil
+	p.mu.Unlock()
+	if tunnel != nil {
+		tunnel.Stop()
+	}
+	p.list.Refresh()
+}
+
+func (p *TunnelsPanel) startAll() {
+	p.mu.Lock()
+	entries := append([]*tunnelEntry(nil), p.entries...)
+	p.mu.Unlock()
+	for _, entry := range entries {
+		p.startTunnel(entry)
+	}
+}
+
+// StopAll stops every running tunnel
+func (p *TunnelsPanel) StopAll() {
+	p.mu.Lock()
+	entries := append([]*tunnelEntry(nil), p.entries...)
+	p.mu.Unlock()
+	for _, entry := range entries {
+		p.stopTunnel(entry)
+	}
+}
+
+// Close stops every tunnel and the counter updates; the panel can't be used afterwards
+func (p *TunnelsPanel) Close() {
+	p.once.Do(func() { close(p.stop) })
+	p.mu.Lock()
+	for _, entry := range p.entries {
+		entry.removed = true
+	}
+	p.mu.Unlock()
+	p.StopAll()
+}
+
+func (p *TunnelsPanel) remove(entry *tunnelEntry) {
+	p.mu.Lock()
+	entry.removed = true
+	p.mu.Unlock()
+	p.stopTunnel(entry)
+	p.mu.Lock()
+	for i, e := range p.entries {
+		if e == entry {
+			p.entries = append(p.entries[:i], p.entries[i+1:]...)
+			break
+		}
+	}
+	p.mu.Unlock()
+	p.list.UnselectAll()
+	p.list.Refresh()
+	p.save()
+}
+
+// save passes the current definitions to onSave
+func (p *TunnelsPanel) save() {
+	if p.onSave == nil {
+		return
+	}
+	p.mu.Lock()
+	specs := make([]network.TunnelSpec, 0, len(p.entries))
+	for _, entry := range p.entries {
+		specs = append(specs, entry.spec)
+	}
+	p.mu.Unlock()
+	p.onSave(specs)
+}
+
+// showAddDialog asks for a new forward, adds it and starts it
+func (p *TunnelsPanel) showAddDialog() {
+	kindSelect := widget.NewSelect([]string{tunnelKindLocal, tunnelKindRemote}, nil)
+	kindSelect.SetSelected(tunnelKindLocal)
+	listenEntry := widget.NewEntry()
+	listenEntry.SetPlaceHolder("8080")
+	hostEntry := widget.NewEntry()
+	hostEntry.SetText("localhost")
+	targetEntry := widget.NewEntry()
+	targetEntry.SetPlaceHolder("80")
+
+	formItems := []*widget.FormItem{
+		widget.NewFormItem("Type", kindSelect),
+		widget.NewFormItem("Listen port", listenEntry),
+		widget.NewFormItem("Target host", hostEntry),
+		widget.NewFormItem("Target port", targetEntry),
+	}
+
+	dialog.ShowForm("Add Tunnel", "Add", "Cancel", formItems, func(ok bool) {
+		if !ok {
+			return
+		}
+		spec := network.TunnelSpec{Kind: network.TunnelLocal, TargetHost: hostEntry.Text}
+		if kindSelect.Selected == tunnelKindRemote {
+			spec.Kind = network.TunnelRemote
+		}
+		spec.ListenPort, _ = strconv.Atoi(listenEntry.Text)
+		spec.TargetPort, _ = strconv.Atoi(targetEntry.Text)
+		if err := spec.Validate(); err != nil {
+			dialog.ShowError(err, p.window)
+			return
+		}
+
+		entry := &tunnelEntry{spec: spec}
+		p.mu.Lock()
+		p.entries = append(p.entries, entry)
+		p.mu.Unlock()
+		p.list.Refresh()
+		p.save()
+		p.startTunnel(entry)
+	}, p.window)
+}
@@ -4,14 +4,22 @@ import (
 	"adminadmin/internal/network"
 	"adminadmin/internal/state"
 	"fmt"
+	"strings"
+
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/widget"
 )
 
 // WorkerWaitingScreen shows the screen when waiting for admin connection
-// onCredentialsChange is called when SSH credentials are updated
-func NewWorkerWaitingScreen(localIP string, port int, onBack func(), onCredentialsChange func(username, password string)) fyne.CanvasObject {
+// users are the SSH accounts to summarize, passwordAuth whether they may log in with their
+// passwords (changed through onPasswordAuth), onUsers opens the account editor and onBans
+// the addresses banned after failed logins
+// onNotifications opens the notification settings, onTags the worker's own tags, onSFTP,
+// onForwarding, onRewrite, onAudit and onScreen the file sharing, port forwarding, command
+// rewriting, audit and screen sharing settings (buttons hidden if nil)
+func NewWorkerWaitingScreen(localIP string, port int, onBack func(), users []network.SSHUser, passwordAuth bool, onPasswordAuth func(bool), onUsers func(), onBans func(), onNotifications func(), onTags func(), onSFTP func(), onForwarding func(), onRewrite func(), onSessionLimits func(), onAudit func(), onScreen func()) fyne.CanvasObject {
 	title := widget.NewLabelWithStyle(
 		"admin:admin - Worker Node",
 		fyne.TextAlignCenter,
@@ -46,37 +54,43 @@ func NewWorkerWaitingScreen(localIP string, port int, onBack func(), onCredentia
 		instructionLabel,
 	)
 
-	// SSH Credentials Section
-	sshHeader := widget.NewLabelWithStyle("SSH Credentials", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
-
-	usernameEntry := widget.NewEntry()
-	usernameEntry.SetPlaceHolder("Username")
-	usernameEntry.SetText(network.DefaultSSHUsername)
+	// SSH Accounts Section
+	sshHeader := widget.NewLabelWithStyle("SSH Accounts", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
 
-	passwordEntry := widget.NewPasswordEntry()
-	passwordEntry.SetPlaceHolder("Password")
-	passwordEntry.SetText(network.DefaultSSHPassword)
-
-	// Update credentials when changed
-	updateCredentials := func() {
-		if onCredentialsChange != nil {
-			onCredentialsChange(usernameEntry.Text, passwordEntry.Text)
-		}
+	accountsBox := container.NewVBox()
+	for _, user := range users {
+		accountsBox.Add(widget.NewLabel(fmt.Sprintf("%s - %s", user.Username, describeSSHUser(user))))
+	}
+	if len(users) == 0 {
+		accountsBox.Add(widget.NewLabel("No accounts"))
 	}
-
-	usernameEntry.OnChanged = func(s string) { updateCredentials() }
-	passwordEntry.OnChanged = func(s string) { updateCredentials() }
 
 	sshPortLabel := widget.NewLabel(fmt.Sprintf("SSH Port: %d", network.DefaultSSHPort))
 
+	// Admins are asked to confirm this on their first SSH connection
+	hostKeyLabel := widget.NewLabelWithStyle(fmt.Sprintf("Host key: %s", network.HostKeyFingerprint()),
+		fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+	hostKeyLabel.Selectable = true
+
 	sshSection := container.NewVBox(
 		sshHeader,
-		widget.NewLabel("Username:"),
-		usernameEntry,
-		widget.NewLabel("Password:"),
-		passwordEntry,
-		sshPortLabel,
+		accountsBox,
+		widget.NewLabel("Admins can also pair their SSH key for password-less login"),
 	)
+	if onPasswordAuth != nil {
+		passwordAuthCheck := widget.NewCheck("Allow password logins on this worker (off: SSH keys only)", nil)
+		passwordAuthCheck.SetChecked(passwordAuth)
+		passwordAuthCheck.OnChanged = onPasswordAuth // Set after, so showing the state isn't a change
+		sshSection.Add(passwordAuthCheck)
+	}
+	sshSection.Add(sshPortLabel)
+	sshSection.Add(hostKeyLabel)
+	if onUsers != nil {
+		sshSection.Add(widget.NewButton("Manage SSH Users...", onUsers))
+	}
+	if onBans != nil {
+		sshSection.Add(widget.NewButton("Banned Addresses...", onBans))
+	}
 
 	backButton := widget.NewButton("Back to Role Selection", onBack)
 
@@ -89,8 +103,32 @@ func NewWorkerWaitingScreen(localIP string, port int, onBack func(), onCredentia
 		widget.NewSeparator(),
 		sshSection,
 		widget.NewSeparator(),
-		backButton,
 	)
+	if onTags != nil {
+		content.Add(widget.NewButton("Worker Tags...", onTags))
+	}
+	if onSFTP != nil {
+		content.Add(widget.NewButton("File Sharing (SFTP)...", onSFTP))
+	}
+	if onForwarding != nil {
+		content.Add(widget.NewButton("Port Forwarding...", onForwarding))
+	}
+	if onRewrite != nil {
+		content.Add(widget.NewButton("Command Rewriting...", onRewrite))
+	}
+	if onSessionLimits != nil {
+		content.Add(widget.NewButton("Session Limits...", onSessionLimits))
+	}
+	if onAudit != nil {
+		content.Add(widget.NewButton("Audit Log...", onAudit))
+	}
+	if onScreen != nil {
+		content.Add(widget.NewButton("Screen Sharing...", onScreen))
+	}
+	if onNotifications != nil {
+		content.Add(widget.NewButton("Notifications...", onNotifications))
+	}
+	content.Add(backButton)
 
 	return container.NewCenter(content)
 }
@@ -135,5 +173,39 @@ func WorkerConnectedScreenCompact() bool {
 
 // NewWorkerDashboard creates the worker dashboard (legacy, for compatibility)
 func NewWorkerDashboard(onBack func()) fyne.CanvasObject {
-	return NewWorkerWaitingScreen("", network.DefaultWorkerPort, onBack, nil)
+	return NewWorkerWaitingScreen("", network.DefaultWorkerPort, onBack, nil, false, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+}
+
+// ScreenSharingBanner is a red bar telling the worker's user which admins are looking
+// at the screen; it is hidden while nobody is
+type ScreenSharingBanner struct {
+	label   *widget.Label
+	content *fyne.Container
+}
+
+// NewScreenSharingBanner creates a hidden banner
+func NewScreenSharingBanner() *ScreenSharingBanner {
+	b := &ScreenSharingBanner{
+		label: widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+	}
+	b.label.Wrapping = fyne.TextWrapWord
+	bg := canvas.NewRectangle(termErrorColor)
+	b.content = container.NewStack(bg, b.label)
+	b.content.Hide()
+	return b
+}
+
+// Content returns the banner UI
+func (b *ScreenSharingBanner) Content() fyne.CanvasObject {
+	return b.content
+}
+
+// SetViewers shows the admins looking at the screen, or hides the banner if there are none
+func (b *ScreenSharingBanner) SetViewers(admins []string) {
+	if len(admins) == 0 {
+		b.content.Hide()
+		return
+	}
+	b.label.SetText(fmt.Sprintf("● Your screen is being viewed by %s", strings.Join(admins, ", ")))
+	b.content.Show()
 }